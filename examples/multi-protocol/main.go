@@ -16,18 +16,18 @@ import (
 
 func main() {
 	// Parse command-line flags
-	protocol := flag.String("protocol", "stdio", "Communication protocol (stdio or http)")
-	address := flag.String("address", "localhost:8080", "HTTP server address (when using http protocol)")
+	protocol := flag.String("protocol", "stdio", "Communication protocol (stdio, http or all)")
+	address := flag.String("address", "localhost:8080", "HTTP server address (when using http or all protocols)")
 	flag.Parse()
 
-	// Create a logger
-	logger := log.New(os.Stdout, "[cortex] ", log.LstdFlags)
+	// Create a logger that writes to stderr, as stdout is reserved for JSON-RPC messages in stdio mode
+	logger := log.New(os.Stderr, "[cortex] ", log.LstdFlags)
 
 	// Create the MCP server
 	mcpServer := server.NewMCPServer("Multi-Protocol Server", "1.0.0", logger)
 
-	// Set HTTP address if using HTTP protocol
-	if *protocol == "http" {
+	// Set HTTP address if serving over HTTP
+	if *protocol != "stdio" {
 		mcpServer.SetAddress(*address)
 	}
 
@@ -55,50 +55,40 @@ func main() {
 		logger.Fatalf("Failed to register database provider: %v", err)
 	}
 
-	// Set up graceful shutdown
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
-
-	// Start the server based on the specified protocol
-	if *protocol == "stdio" {
-		// Print server ready message for stdio
-		fmt.Println("Server ready. You can now send JSON-RPC requests via stdin.")
-		fmt.Println("Example weather tool request:")
-		fmt.Println(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"weather","parameters":{"location":"New York"}}}`)
-		fmt.Println("Example database set tool request:")
-		fmt.Println(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"db.set","parameters":{"key":"user1","value":{"name":"John","age":30}}}}`)
+	// Stop all transports on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Select the transports based on the specified protocol
+	var transports []server.Transport
+	switch *protocol {
+	case "stdio":
+		transports = []server.Transport{server.StdioTransport()}
+	case "http":
+		transports = []server.Transport{server.HTTPTransport()}
+	case "all":
+		transports = []server.Transport{server.StdioTransport(), server.HTTPTransport()}
+	default:
+		logger.Fatalf("Unknown protocol: %s (must be 'stdio', 'http' or 'all')", *protocol)
+	}
 
-		// Start the stdio server (this will block until terminated)
-		go func() {
-			if err := mcpServer.ServeStdio(); err != nil {
-				logger.Fatalf("Error serving stdio: %v", err)
-			}
-		}()
-	} else if *protocol == "http" {
+	if *protocol != "http" {
+		// Print server ready message for stdio to stderr, as stdout carries JSON-RPC messages
+		fmt.Fprintln(os.Stderr, "Server ready. You can now send JSON-RPC requests via stdin.")
+		fmt.Fprintln(os.Stderr, "Example weather tool request:")
+		fmt.Fprintln(os.Stderr, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"weather","parameters":{"location":"New York"}}}`)
+		fmt.Fprintln(os.Stderr, "Example database set tool request:")
+		fmt.Fprintln(os.Stderr, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"db.set","parameters":{"key":"user1","value":{"name":"John","age":30}}}}`)
+	}
+	if *protocol != "stdio" {
 		// Print server ready message for HTTP
-		fmt.Printf("HTTP server starting on %s\n", *address)
-		fmt.Println("You can query tools using HTTP POST requests to /tools/call")
-
-		// Start the HTTP server (this will block until terminated)
-		go func() {
-			if err := mcpServer.ServeHTTP(); err != nil {
-				logger.Fatalf("Error serving HTTP: %v", err)
-			}
-		}()
-	} else {
-		logger.Fatalf("Unknown protocol: %s (must be 'stdio' or 'http')", *protocol)
+		logger.Printf("HTTP server starting on %s", *address)
+		logger.Println("You can query tools using HTTP POST requests to /jsonrpc, or connect over SSE at /sse")
 	}
 
-	// Wait for shutdown signal
-	<-stop
-	logger.Println("Shutting down server...")
-
-	// Shutdown the server gracefully
-	if *protocol == "http" {
-		shutdownCtx := context.Background()
-		if err := mcpServer.Shutdown(shutdownCtx); err != nil {
-			logger.Fatalf("Error shutting down server: %v", err)
-		}
+	// Serve all selected transports over the same tools until a signal arrives
+	if err := mcpServer.Serve(ctx, transports...); err != nil {
+		logger.Fatalf("Error serving: %v", err)
 	}
 
 	logger.Println("Server stopped")
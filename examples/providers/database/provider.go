@@ -66,6 +66,20 @@ func (db *SimpleDatabase) Keys() []string {
 	return keys
 }
 
+// Ping checks that the database can serve requests.
+func (db *SimpleDatabase) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.data == nil {
+		return fmt.Errorf("database is not initialized")
+	}
+	return nil
+}
+
 // DBProvider implements the plugin.Provider interface for database operations.
 type DBProvider struct {
 	*plugin.BaseProvider
@@ -105,6 +119,12 @@ func NewDBProvider(logger *log.Logger) (*DBProvider, error) {
 	return provider, nil
 }
 
+// CheckHealth implements plugin.HealthChecker by pinging the database, so that
+// the server is only reported as ready while the database is available.
+func (p *DBProvider) CheckHealth(ctx context.Context) error {
+	return p.db.Ping(ctx)
+}
+
 // registerTools registers all database tools with the provider.
 func (p *DBProvider) registerTools() error {
 	// Register get tool
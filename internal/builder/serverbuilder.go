@@ -3,10 +3,15 @@ package builder
 
 import (
 	"context"
+	"crypto/tls"
+	"slices"
 
 	"github.com/FreePeak/cortex/internal/domain"
+	"github.com/FreePeak/cortex/internal/infrastructure/auth"
 	"github.com/FreePeak/cortex/internal/infrastructure/logging"
+	"github.com/FreePeak/cortex/internal/infrastructure/metrics"
 	"github.com/FreePeak/cortex/internal/infrastructure/server"
+	"github.com/FreePeak/cortex/internal/infrastructure/tracing"
 	"github.com/FreePeak/cortex/internal/interfaces/rest"
 	"github.com/FreePeak/cortex/internal/interfaces/stdio"
 	"github.com/FreePeak/cortex/internal/usecases"
@@ -22,10 +27,34 @@ type ServerBuilder struct {
 	toolRepo           domain.ToolRepository
 	promptRepo         domain.PromptRepository
 	sessionRepo        domain.SessionRepository
+	sessionStore       domain.SessionStoreBackend
+	sessionLimits      *usecases.SessionLimits
+	sessionLabeler     usecases.SessionLabeler
 	notificationSender domain.NotificationSender
+	notificationConfig server.NotificationConfig
+	logger             *logging.Logger
+	authenticator      auth.Authenticator
+	oauth              *auth.OAuthResourceServer
+	toolPolicy         usecases.ToolPolicy
+	rateLimits         *usecases.RateLimitConfig
+	concurrency        *usecases.ConcurrencyConfig
+	pageSize           int
+	metrics            *metrics.MCPMetrics
+	tracer             *tracing.Tracer
+	cors               *server.CORSConfig
+	localhostOnly      bool
+	tlsConfig          *tls.Config
+	basePath           string
+	baseURL            string
+	httpTransports     []string
 
-	// Maintain a single instance of the server service
+	// Tool handlers and health checks added before the service is built
+	toolHandlers map[string]usecases.ToolHandlerFunc
+	healthChecks map[string]usecases.HealthChecker
+
+	// Maintain a single instance of the server service and transport
 	serverService *usecases.ServerService
+	mcpServer     *rest.MCPServer
 }
 
 // NewServerBuilder creates a new ServerBuilder.
@@ -45,6 +74,9 @@ func NewServerBuilder() *ServerBuilder {
 		toolRepo:      toolRepo,
 		promptRepo:    promptRepo,
 		sessionRepo:   sessionRepo,
+		sessionStore:  server.NewInMemorySessionStore(),
+		toolHandlers:  make(map[string]usecases.ToolHandlerFunc),
+		healthChecks:  make(map[string]usecases.HealthChecker),
 		serverService: nil, // Will be initialized when first needed
 	}
 }
@@ -97,12 +129,210 @@ func (b *ServerBuilder) WithSessionRepository(repo domain.SessionRepository) *Se
 	return b
 }
 
+// WithSessionStore sets the backend of the key/value stores of sessions
+func (b *ServerBuilder) WithSessionStore(store domain.SessionStoreBackend) *ServerBuilder {
+	b.sessionStore = store
+	if b.serverService != nil {
+		b.serverService.SetSessionStore(store)
+	}
+	return b
+}
+
+// WithSessionLimits sets the idle timeout and max lifetime of sessions
+func (b *ServerBuilder) WithSessionLimits(limits usecases.SessionLimits) *ServerBuilder {
+	b.sessionLimits = &limits
+	if b.serverService != nil {
+		b.serverService.SetSessionLimits(b.sessionLimits)
+	}
+	return b
+}
+
+// WithSessionLabeler sets the function that labels sessions when they are initialized
+func (b *ServerBuilder) WithSessionLabeler(labeler usecases.SessionLabeler) *ServerBuilder {
+	b.sessionLabeler = labeler
+	if b.serverService != nil {
+		b.serverService.SetSessionLabeler(labeler)
+	}
+	return b
+}
+
 // WithNotificationSender sets the notification sender
 func (b *ServerBuilder) WithNotificationSender(sender domain.NotificationSender) *ServerBuilder {
 	b.notificationSender = sender
 	return b
 }
 
+// WithNotificationConfig sets the queue size and overflow policy of the default
+// notification sender. It has no effect with WithNotificationSender. It panics
+// once the service is built, as the sender is created with the service.
+func (b *ServerBuilder) WithNotificationConfig(config server.NotificationConfig) *ServerBuilder {
+	if b.serverService != nil {
+		panic("builder: notification config set after the server was built")
+	}
+	b.notificationConfig = config
+	return b
+}
+
+// WithLogger sets the logger used by the server service and the HTTP server
+func (b *ServerBuilder) WithLogger(logger *logging.Logger) *ServerBuilder {
+	b.logger = logger
+	return b
+}
+
+// WithAuthenticator sets the authenticator for the HTTP and SSE endpoints. It
+// panics if the HTTP server was already built.
+func (b *ServerBuilder) WithAuthenticator(authenticator auth.Authenticator) *ServerBuilder {
+	b.mustNotBeBuilt("authenticator")
+	b.authenticator = authenticator
+	return b
+}
+
+// WithOAuthResourceServer protects the HTTP and SSE endpoints with OAuth 2.1
+// access tokens. It panics if the HTTP server was already built.
+func (b *ServerBuilder) WithOAuthResourceServer(rs *auth.OAuthResourceServer) *ServerBuilder {
+	b.mustNotBeBuilt("OAuth resource server")
+	b.oauth = rs
+	return b
+}
+
+// WithToolPolicy restricts which tools each principal may list and call
+func (b *ServerBuilder) WithToolPolicy(policy usecases.ToolPolicy) *ServerBuilder {
+	b.toolPolicy = policy
+	if b.serverService != nil {
+		b.serverService.SetToolPolicy(policy)
+	}
+	return b
+}
+
+// WithPageSize sets the number of entries per page of list requests, 0 for all
+func (b *ServerBuilder) WithPageSize(size int) *ServerBuilder {
+	b.pageSize = size
+	if b.serverService != nil {
+		b.serverService.SetPageSize(size)
+	}
+	return b
+}
+
+// WithRateLimits sets the rate limits applied to tool calls
+func (b *ServerBuilder) WithRateLimits(config usecases.RateLimitConfig) *ServerBuilder {
+	b.rateLimits = &config
+	if b.serverService != nil {
+		b.serverService.SetRateLimits(b.rateLimits)
+	}
+	return b
+}
+
+// WithConcurrencyLimits sets the concurrency limits applied to tool calls
+func (b *ServerBuilder) WithConcurrencyLimits(config usecases.ConcurrencyConfig) *ServerBuilder {
+	b.concurrency = &config
+	if b.serverService != nil {
+		b.serverService.SetConcurrencyLimits(b.concurrency)
+	}
+	return b
+}
+
+// WithMetrics sets the metrics that record requests and tool calls. It panics
+// if the HTTP server was already built.
+func (b *ServerBuilder) WithMetrics(m *metrics.MCPMetrics) *ServerBuilder {
+	b.mustNotBeBuilt("metrics")
+	b.metrics = m
+	return b
+}
+
+// WithTracer sets the tracer that records a span for every request and tool call
+func (b *ServerBuilder) WithTracer(tracer *tracing.Tracer) *ServerBuilder {
+	b.tracer = tracer
+	if b.serverService != nil {
+		b.serverService.SetTracer(serviceTracer(tracer))
+	}
+	return b
+}
+
+// WithCORS sets the cross-origin policy of the HTTP and SSE endpoints. It
+// panics if the HTTP server was already built.
+func (b *ServerBuilder) WithCORS(config server.CORSConfig) *ServerBuilder {
+	b.mustNotBeBuilt("CORS policy")
+	b.cors = &config
+	return b
+}
+
+// WithLocalhostOnly binds the HTTP server to the loopback interface only. It
+// panics if the HTTP server was already built.
+func (b *ServerBuilder) WithLocalhostOnly() *ServerBuilder {
+	b.mustNotBeBuilt("localhost only binding")
+	b.localhostOnly = true
+	return b
+}
+
+// WithTLS serves HTTPS with the given configuration, see server.NewTLSConfig.
+// It panics if the HTTP server was already built.
+func (b *ServerBuilder) WithTLS(config *tls.Config) *ServerBuilder {
+	b.mustNotBeBuilt("TLS configuration")
+	b.tlsConfig = config
+	return b
+}
+
+// WithBasePath serves every HTTP endpoint under the given path prefix. It
+// panics if the HTTP server was already built with another prefix.
+func (b *ServerBuilder) WithBasePath(basePath string) *ServerBuilder {
+	if b.mcpServer != nil && basePath != b.basePath {
+		panic("builder: base path set after the HTTP server was built")
+	}
+	b.basePath = basePath
+	return b
+}
+
+// WithBaseURL sets the scheme and host of the message endpoint URL sent to SSE
+// clients. It panics if the HTTP server was already built with another URL.
+func (b *ServerBuilder) WithBaseURL(baseURL string) *ServerBuilder {
+	if b.mcpServer != nil && baseURL != b.baseURL {
+		panic("builder: base URL set after the HTTP server was built")
+	}
+	b.baseURL = baseURL
+	return b
+}
+
+// WithHTTPTransports serves only the given HTTP transports, domain.TransportSSE
+// and domain.TransportHTTP. By default both are served. It panics if the HTTP
+// server was already built with other transports.
+func (b *ServerBuilder) WithHTTPTransports(transports ...string) *ServerBuilder {
+	transports = normalizeHTTPTransports(transports)
+	if b.mcpServer != nil && !slices.Equal(transports, b.httpTransports) {
+		panic("builder: HTTP transports set after the HTTP server was built")
+	}
+	b.httpTransports = transports
+	return b
+}
+
+// normalizeHTTPTransports sorts and deduplicates a set of HTTP transports, and
+// returns nil for the default set of every transport.
+func normalizeHTTPTransports(transports []string) []string {
+	transports = slices.Compact(slices.Sorted(slices.Values(transports)))
+	if slices.Contains(transports, domain.TransportSSE) && slices.Contains(transports, domain.TransportHTTP) {
+		return nil
+	}
+	return transports
+}
+
+// mustNotBeBuilt panics if the HTTP server was already built, as it would
+// silently ignore the setting.
+func (b *ServerBuilder) mustNotBeBuilt(setting string) {
+	if b.mcpServer != nil {
+		panic("builder: " + setting + " set after the HTTP server was built")
+	}
+}
+
+// Address returns the address the HTTP server listens on, without building it.
+func (b *ServerBuilder) Address() string {
+	if b.mcpServer != nil {
+		return b.mcpServer.GetAddress()
+	}
+	if b.localhostOnly {
+		return server.LocalhostAddr(b.address)
+	}
+	return b.address
+}
+
 // AddTool adds a tool to the server's tool repository
 func (b *ServerBuilder) AddTool(ctx context.Context, tool *domain.Tool) *ServerBuilder {
 	if b.toolRepo != nil {
@@ -127,6 +357,45 @@ func (b *ServerBuilder) AddPrompt(ctx context.Context, prompt *domain.Prompt) *S
 	return b
 }
 
+// RegisterToolHandler registers the handler of a tool with the service, once
+// it is built, so that adding tools does not fix the configuration early
+func (b *ServerBuilder) RegisterToolHandler(name string, handler usecases.ToolHandlerFunc) *ServerBuilder {
+	if b.serverService != nil {
+		b.serverService.RegisterToolHandler(name, handler)
+		return b
+	}
+	if _, exists := b.toolHandlers[name]; !exists {
+		b.toolHandlers[name] = handler
+	}
+	return b
+}
+
+// AddHealthCheck adds a readiness check to the service, once it is built
+func (b *ServerBuilder) AddHealthCheck(name string, checker usecases.HealthChecker) *ServerBuilder {
+	if b.serverService != nil {
+		b.serverService.AddHealthCheck(name, checker)
+		return b
+	}
+	b.healthChecks[name] = checker
+	return b
+}
+
+// RemoveHealthCheck removes a readiness check added with AddHealthCheck
+func (b *ServerBuilder) RemoveHealthCheck(name string) *ServerBuilder {
+	if b.serverService != nil {
+		b.serverService.RemoveHealthCheck(name)
+		return b
+	}
+	delete(b.healthChecks, name)
+	return b
+}
+
+// Built reports whether the service has been built, after which the settings
+// that cannot change while serving are fixed
+func (b *ServerBuilder) Built() bool {
+	return b.serverService != nil
+}
+
 // BuildService builds and returns the server service
 func (b *ServerBuilder) BuildService() *usecases.ServerService {
 	// If we already have a server service, return it
@@ -136,7 +405,12 @@ func (b *ServerBuilder) BuildService() *usecases.ServerService {
 
 	// Create notification sender if not provided
 	if b.notificationSender == nil {
-		b.notificationSender = server.NewNotificationSender("2.0")
+		b.notificationSender = server.NewNotificationSenderWithConfig("2.0", b.notificationConfig)
+	}
+
+	// Create metrics if not provided, shared by the service and the HTTP server
+	if b.metrics == nil {
+		b.metrics = metrics.NewMCPMetrics(metrics.NewRegistry())
 	}
 
 	// Create the server service config
@@ -148,18 +422,104 @@ func (b *ServerBuilder) BuildService() *usecases.ServerService {
 		ToolRepo:           b.toolRepo,
 		PromptRepo:         b.promptRepo,
 		SessionRepo:        b.sessionRepo,
+		SessionStore:       b.sessionStore,
+		SessionLimits:      b.sessionLimits,
+		SessionLabeler:     b.sessionLabeler,
 		NotificationSender: b.notificationSender,
+		Logger:             b.serviceLogger(),
+		ToolPolicy:         b.toolPolicy,
+		RateLimits:         b.rateLimits,
+		Concurrency:        b.concurrency,
+		ToolObserver:       b.metrics,
+		Tracer:             serviceTracer(b.tracer),
+		PageSize:           b.pageSize,
 	}
 
 	// Create and store the server service
 	b.serverService = usecases.NewServerService(config)
+	for name, handler := range b.toolHandlers {
+		b.serverService.RegisterToolHandler(name, handler)
+	}
+	for name, checker := range b.healthChecks {
+		b.serverService.AddHealthCheck(name, checker)
+	}
+	b.toolHandlers, b.healthChecks = nil, nil
 	return b.serverService
 }
 
-// BuildMCPServer builds and returns an MCP server
+// serviceLogger returns the logger of the service, by default one that logs to
+// stderr so that stdio transports keep stdout clean
+func (b *ServerBuilder) serviceLogger() usecases.Logger {
+	if b.logger != nil {
+		return b.logger
+	}
+	logger, err := logging.New(logging.Config{
+		Level:       logging.InfoLevel,
+		OutputPaths: []string{"stderr"},
+		InitialFields: logging.Fields{
+			"component": "server-service",
+		},
+	})
+	if err != nil {
+		return logging.Default()
+	}
+	return logger
+}
+
+// serviceTracer returns tracer as the tracer of the service, which must be nil
+// rather than a nil *tracing.Tracer when tracing is disabled
+func serviceTracer(tracer *tracing.Tracer) usecases.Tracer {
+	if tracer == nil {
+		return nil
+	}
+	return tracer
+}
+
+// BuildMCPServer builds and returns an MCP server. Like the service, the server
+// is built once, so that every caller starts and stops the same instance.
 func (b *ServerBuilder) BuildMCPServer() *rest.MCPServer {
+	// If we already have an MCP server, return it
+	if b.mcpServer != nil {
+		return b.mcpServer
+	}
+
 	service := b.BuildService()
-	return rest.NewMCPServer(service, b.address)
+
+	opts := []rest.MCPServerOption{rest.WithMetrics(b.metrics)}
+	// Deliver the notifications of the service to the SSE streams
+	if notifier, ok := b.notificationSender.(*server.NotificationSender); ok {
+		opts = append(opts, rest.WithNotificationSender(notifier))
+	}
+	if b.logger != nil {
+		opts = append(opts, rest.WithLogger(b.logger))
+	}
+	if b.authenticator != nil {
+		opts = append(opts, rest.WithAuthenticator(b.authenticator))
+	}
+	if b.oauth != nil {
+		opts = append(opts, rest.WithOAuthResourceServer(b.oauth))
+	}
+	if b.cors != nil {
+		opts = append(opts, rest.WithCORS(*b.cors))
+	}
+	if b.localhostOnly {
+		opts = append(opts, rest.WithLocalhostOnly())
+	}
+	if b.tlsConfig != nil {
+		opts = append(opts, rest.WithTLS(b.tlsConfig))
+	}
+	if b.basePath != "" {
+		opts = append(opts, rest.WithBasePath(b.basePath))
+	}
+	if b.baseURL != "" {
+		opts = append(opts, rest.WithBaseURL(b.baseURL))
+	}
+	if len(b.httpTransports) > 0 {
+		opts = append(opts, rest.WithTransports(b.httpTransports...))
+	}
+
+	b.mcpServer = rest.NewMCPServer(service, b.address, opts...)
+	return b.mcpServer
 }
 
 // BuildStdioServer builds a stdio server that uses the MCP server
@@ -170,6 +530,11 @@ func (b *ServerBuilder) BuildStdioServer(opts ...stdio.StdioOption) *stdio.Stdio
 
 // ServeStdio builds and starts serving a stdio server
 func (b *ServerBuilder) ServeStdio(opts ...stdio.StdioOption) error {
+	return b.ServeStdioContext(context.Background(), opts...)
+}
+
+// ServeStdioContext builds and starts serving a stdio server until ctx is canceled
+func (b *ServerBuilder) ServeStdioContext(ctx context.Context, opts ...stdio.StdioOption) error {
 	// Create a default logger for stdio
 	logger, err := logging.New(logging.Config{
 		Level:       logging.InfoLevel,
@@ -188,5 +553,5 @@ func (b *ServerBuilder) ServeStdio(opts ...stdio.StdioOption) error {
 	}
 
 	mcpServer := b.BuildMCPServer()
-	return stdio.ServeStdio(mcpServer, opts...)
+	return stdio.ServeStdioContext(ctx, mcpServer, opts...)
 }
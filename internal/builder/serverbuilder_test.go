@@ -1,14 +1,25 @@
 package builder
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/FreePeak/cortex/internal/domain"
 	"github.com/FreePeak/cortex/internal/infrastructure/logging"
+	"github.com/FreePeak/cortex/internal/infrastructure/server"
+	"github.com/FreePeak/cortex/internal/interfaces/rest"
+	"github.com/FreePeak/cortex/internal/usecases"
 )
 
 // MockResourceRepository is a mock for the ResourceRepository interface
@@ -315,6 +326,281 @@ func TestServerBuilder_BuildMCPServer(t *testing.T) {
 	mcpServer := builder.BuildMCPServer()
 
 	assert.NotNil(t, mcpServer)
+	assert.Equal(t, ":9090", mcpServer.GetAddress())
+
+	// Every caller gets the same instance, so that it can be started and stopped
+	assert.Same(t, mcpServer, builder.BuildMCPServer())
+}
+
+func TestServerBuilder_TransportSettingsAfterBuild(t *testing.T) {
+	settings := map[string]func(b *ServerBuilder){
+		"authenticator": func(b *ServerBuilder) { b.WithAuthenticator(nil) },
+		"oauth":         func(b *ServerBuilder) { b.WithOAuthResourceServer(nil) },
+		"metrics":       func(b *ServerBuilder) { b.WithMetrics(nil) },
+		"cors":          func(b *ServerBuilder) { b.WithCORS(server.CORSConfig{}) },
+		"localhost":     func(b *ServerBuilder) { b.WithLocalhostOnly() },
+		"tls":           func(b *ServerBuilder) { b.WithTLS(nil) },
+	}
+	for name, set := range settings {
+		t.Run(name, func(t *testing.T) {
+			builder := NewServerBuilder().WithAddress(":9090")
+			set(builder)
+			assert.NotEmpty(t, builder.Address())
+			assert.Nil(t, builder.mcpServer, "Address should not build the HTTP server")
+
+			builder.BuildMCPServer()
+			assert.Panics(t, func() { set(builder) })
+		})
+	}
+}
+
+func TestServerBuilder_WithHTTPTransports(t *testing.T) {
+	builder := NewServerBuilder().WithHTTPTransports(domain.TransportSSE)
+	ts := httptest.NewServer(builder.BuildMCPServer().Handler())
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/jsonrpc", "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "Streamable HTTP should not be served")
+
+	// The same transports may be set again, but not others
+	assert.NotPanics(t, func() { builder.WithHTTPTransports(domain.TransportSSE, domain.TransportSSE) })
+	assert.Panics(t, func() { builder.WithHTTPTransports(domain.TransportSSE, domain.TransportHTTP) })
+
+	// Every transport is the default
+	built := NewServerBuilder()
+	built.BuildMCPServer()
+	assert.NotPanics(t, func() { built.WithHTTPTransports(domain.TransportHTTP, domain.TransportSSE) })
+}
+
+func TestServerBuilder_WithBasePath(t *testing.T) {
+	builder := NewServerBuilder().
+		WithBasePath("/api/mcp").
+		WithBaseURL("https://example.com")
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/mcp/", builder.BuildMCPServer().Handler())
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	// Endpoints are served under the base path only
+	resp, err := http.Get(ts.URL + "/api/mcp/status")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(ts.URL + "/status")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// The endpoint event points SSE clients at the message endpoint behind the mount
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/mcp/sse", nil)
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if line == "event: endpoint\n" {
+			break
+		}
+	}
+	data, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(data, "data: https://example.com/api/mcp/message?sessionId="), data)
+}
+
+func TestServerBuilder_HTTPSessions(t *testing.T) {
+	ctx := context.Background()
+	builder := NewServerBuilder().AddTool(ctx, &domain.Tool{Name: "whoami"})
+	service := builder.BuildService()
+
+	var sessions []*domain.ClientSession
+	service.RegisterToolHandler("whoami", func(ctx context.Context, params map[string]interface{}, session *domain.ClientSession) (interface{}, error) {
+		sessions = append(sessions, session)
+		return session.ClientInfo.Name, nil
+	})
+
+	ts := httptest.NewServer(builder.BuildMCPServer().Handler())
+	defer ts.Close()
+
+	post := func(sessionID, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/jsonrpc", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if sessionID != "" {
+			req.Header.Set("Mcp-Session-Id", sessionID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+	const callTool = `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"whoami"}}`
+
+	// Initialize creates the session and returns its ID
+	resp := post("", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","clientInfo":{"name":"test-client","version":"1.0.0"}}}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	sessionID := resp.Header.Get("Mcp-Session-Id")
+	require.NotEmpty(t, sessionID)
+
+	// Tool calls of the session share the initialized session
+	assert.Equal(t, http.StatusOK, post(sessionID, callTool).StatusCode)
+	assert.Equal(t, http.StatusOK, post(sessionID, callTool).StatusCode)
+	require.Len(t, sessions, 2)
+	assert.Same(t, sessions[0], sessions[1])
+	assert.Equal(t, sessionID, sessions[0].ID)
+	assert.Equal(t, "test-client", sessions[0].ClientInfo.Name)
+	assert.Equal(t, "2025-03-26", sessions[0].ProtocolVersion)
+
+	registered, err := service.GetSession(ctx, sessionID)
+	require.NoError(t, err)
+	assert.Same(t, sessions[0], registered)
+
+	// Unknown sessions are rejected
+	assert.Equal(t, http.StatusNotFound, post("unknown", callTool).StatusCode)
+
+	// The client ends the session with DELETE
+	req, err := http.NewRequest(http.MethodDelete, ts.URL+"/jsonrpc", nil)
+	require.NoError(t, err)
+	req.Header.Set("Mcp-Session-Id", sessionID)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	assert.Equal(t, http.StatusNotFound, post(sessionID, callTool).StatusCode)
+}
+
+// openSSEStream opens an SSE stream and returns its reader and session ID.
+func openSSEStream(t *testing.T, ctx context.Context, url string) (*bufio.Reader, string) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if line == "event: endpoint\n" {
+			break
+		}
+	}
+	data, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	_, sessionID, ok := strings.Cut(strings.TrimSpace(data), "sessionId=")
+	require.True(t, ok, data)
+	return reader, sessionID
+}
+
+// readUntilClosed reads the stream until the server closes it, and returns what it read.
+func readUntilClosed(t *testing.T, reader *bufio.Reader) string {
+	t.Helper()
+	done := make(chan string, 1)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		done <- string(data)
+	}()
+	select {
+	case data := <-done:
+		return data
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream was not closed")
+		return ""
+	}
+}
+
+func TestServerBuilder_SessionAdmin(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewServerBuilder()
+	ts := httptest.NewServer(builder.BuildMCPServer().Handler())
+	defer ts.Close()
+	admin := httptest.NewServer(rest.NewSessionAdminHandler(builder.BuildService()))
+	defer admin.Close()
+
+	reader, sessionID := openSSEStream(t, ctx, ts.URL+"/sse")
+
+	// The stream is listed as soon as it opened
+	resp, err := http.Get(admin.URL + "/sessions")
+	require.NoError(t, err)
+	var list struct {
+		Sessions []rest.SessionInfo `json:"sessions"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	resp.Body.Close()
+	require.Len(t, list.Sessions, 1)
+	assert.Equal(t, sessionID, list.Sessions[0].ID)
+	assert.Equal(t, domain.TransportSSE, list.Sessions[0].Transport)
+	assert.False(t, list.Sessions[0].Initialized)
+
+	// Targeted notifications are delivered on the stream
+	resp, err = http.Post(admin.URL+"/sessions/"+sessionID+"/notifications", "application/json",
+		strings.NewReader(`{"method":"notifications/message","params":{"level":"warning","data":"shutting down soon"}}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if strings.HasPrefix(line, "data: ") {
+			assert.Contains(t, line, `"method":"notifications/message"`)
+			break
+		}
+	}
+
+	// Disconnecting the session ends its stream
+	req, err := http.NewRequest(http.MethodDelete, admin.URL+"/sessions/"+sessionID, nil)
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	readUntilClosed(t, reader)
+
+	resp, err = http.Get(admin.URL + "/sessions/" + sessionID)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Post(admin.URL+"/sessions/"+sessionID+"/notifications", "application/json", strings.NewReader(`{"method":"ping"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServerBuilder_WithSessionLimits(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	builder := NewServerBuilder().WithSessionLimits(usecases.SessionLimits{
+		IdleTimeout:   50 * time.Millisecond,
+		CheckInterval: 10 * time.Millisecond,
+	})
+	mcpServer := builder.BuildMCPServer()
+	defer func() { _ = mcpServer.Stop(context.Background()) }()
+	ts := httptest.NewServer(mcpServer.Handler())
+	defer ts.Close()
+
+	// An idle stream is disconnected and its session ended
+	reader, sessionID := openSSEStream(t, ctx, ts.URL+"/sse")
+	readUntilClosed(t, reader)
+
+	_, err := builder.BuildService().LookupSession(ctx, sessionID)
+	assert.Error(t, err)
 }
 
 func TestServerBuilder_BuildStdioServer(t *testing.T) {
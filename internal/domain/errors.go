@@ -1,6 +1,10 @@
 package domain
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 // Common domain errors
 var (
@@ -9,6 +13,9 @@ var (
 	ErrInvalidInput   = NewError("invalid input", 400)
 	ErrInternal       = NewError("internal server error", 500)
 	ErrNotImplemented = NewError("not implemented", 501)
+
+	// ErrNoSessionStore is returned by the methods of a nil SessionStore.
+	ErrNoSessionStore = NewError("session store not configured", 500)
 )
 
 // Error represents a domain error with an associated code.
@@ -41,6 +48,12 @@ func (e *ResourceNotFoundError) Error() string {
 	return e.Err.Error()
 }
 
+// Is reports whether target is ErrNotFound, so that errors.Is(err, ErrNotFound)
+// holds for every missing resource.
+func (e *ResourceNotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
 // NewResourceNotFoundError creates a new ResourceNotFoundError.
 func NewResourceNotFoundError(uri string) *ResourceNotFoundError {
 	return &ResourceNotFoundError{
@@ -63,6 +76,12 @@ func (e *ToolNotFoundError) Error() string {
 	return e.Err.Error()
 }
 
+// Is reports whether target is ErrNotFound, so that errors.Is(err, ErrNotFound)
+// holds for every missing tool.
+func (e *ToolNotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
 // NewToolNotFoundError creates a new ToolNotFoundError.
 func NewToolNotFoundError(name string) *ToolNotFoundError {
 	return &ToolNotFoundError{
@@ -85,6 +104,12 @@ func (e *PromptNotFoundError) Error() string {
 	return e.Err.Error()
 }
 
+// Is reports whether target is ErrNotFound, so that errors.Is(err, ErrNotFound)
+// holds for every missing prompt.
+func (e *PromptNotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
 // NewPromptNotFoundError creates a new PromptNotFoundError.
 func NewPromptNotFoundError(name string) *PromptNotFoundError {
 	return &PromptNotFoundError{
@@ -107,6 +132,12 @@ func (e *SessionNotFoundError) Error() string {
 	return e.Err.Error()
 }
 
+// Is reports whether target is ErrNotFound, so that errors.Is(err, ErrNotFound)
+// holds for every missing session.
+func (e *SessionNotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
 // NewSessionNotFoundError creates a new SessionNotFoundError.
 func NewSessionNotFoundError(id string) *SessionNotFoundError {
 	return &SessionNotFoundError{
@@ -141,3 +172,111 @@ func NewValidationError(field, message string) *ValidationError {
 		),
 	}
 }
+
+// ToolPanicError indicates that a tool handler panicked while executing.
+type ToolPanicError struct {
+	Name  string
+	Value interface{}
+	Stack []byte
+	Err   *Error
+}
+
+// Error returns the error message.
+func (e *ToolPanicError) Error() string {
+	return e.Err.Error()
+}
+
+// NewToolPanicError creates a new ToolPanicError for the recovered panic value.
+func NewToolPanicError(name string, value interface{}, stack []byte) *ToolPanicError {
+	return &ToolPanicError{
+		Name:  name,
+		Value: value,
+		Stack: stack,
+		Err: NewError(
+			fmt.Sprintf("tool %s panicked: %v", name, value),
+			500,
+		),
+	}
+}
+
+// ToolForbiddenError indicates that the caller is not allowed to use a tool.
+type ToolForbiddenError struct {
+	Name string
+	Err  *Error
+}
+
+// Error returns the error message.
+func (e *ToolForbiddenError) Error() string {
+	return e.Err.Error()
+}
+
+// NewToolForbiddenError creates a new ToolForbiddenError.
+func NewToolForbiddenError(name string) *ToolForbiddenError {
+	return &ToolForbiddenError{
+		Name: name,
+		Err: NewError(
+			fmt.Sprintf("access to tool %s is denied", name),
+			403,
+		),
+	}
+}
+
+// RateLimitError indicates that a call was rejected because a rate limit was exceeded.
+type RateLimitError struct {
+	Name       string        // Name of the tool that was called
+	Scope      string        // Limit that was exceeded, e.g. "session" or "tool"
+	RetryAfter time.Duration // Time until the call may be retried
+	Err        *Error
+}
+
+// Error returns the error message.
+func (e *RateLimitError) Error() string {
+	return e.Err.Error()
+}
+
+// NewRateLimitError creates a new RateLimitError.
+func NewRateLimitError(name, scope string, retryAfter time.Duration) *RateLimitError {
+	return &RateLimitError{
+		Name:       name,
+		Scope:      scope,
+		RetryAfter: retryAfter,
+		Err: NewError(
+			fmt.Sprintf("rate limit exceeded for tool %s (%s limit), retry after %s", name, scope, retryAfter.Round(time.Millisecond)),
+			429,
+		),
+	}
+}
+
+// BusyError indicates that a call was rejected because too many calls were already in flight.
+type BusyError struct {
+	Name  string // Name of the tool or provider that is saturated
+	Scope string // Kind of concurrency limit, e.g. "tool" or "provider"
+	Err   *Error
+}
+
+// Error returns the error message.
+func (e *BusyError) Error() string {
+	return e.Err.Error()
+}
+
+// NewBusyError creates a new BusyError.
+func NewBusyError(name, scope string) *BusyError {
+	return &BusyError{
+		Name:  name,
+		Scope: scope,
+		Err: NewError(
+			fmt.Sprintf("%s %s is busy, too many calls in flight", scope, name),
+			503,
+		),
+	}
+}
+
+// IsToolCallRejected reports whether err rejected a tool call before its
+// handler ran, because of the tool policy, a rate limit or a concurrency limit.
+// Such rejections are expected under load and are not server errors.
+func IsToolCallRejected(err error) bool {
+	var forbiddenErr *ToolForbiddenError
+	var rateLimitErr *RateLimitError
+	var busyErr *BusyError
+	return errors.As(err, &forbiddenErr) || errors.As(err, &rateLimitErr) || errors.As(err, &busyErr)
+}
@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 )
 
@@ -116,6 +118,23 @@ func TestSessionNotFoundError(t *testing.T) {
 	}
 }
 
+func TestNotFoundErrorsMatchErrNotFound(t *testing.T) {
+	errs := []error{
+		NewResourceNotFoundError("test/resource"),
+		NewToolNotFoundError("tool"),
+		NewPromptNotFoundError("prompt"),
+		NewSessionNotFoundError("session"),
+	}
+	for _, err := range errs {
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("errors.Is(%T, ErrNotFound) = false, want true", err)
+		}
+		if errors.Is(fmt.Errorf("wrapped: %w", err), ErrInternal) {
+			t.Errorf("errors.Is(%T, ErrInternal) = true, want false", err)
+		}
+	}
+}
+
 func TestValidationError(t *testing.T) {
 	field := "name"
 	message := "must not be empty"
@@ -137,3 +156,24 @@ func TestValidationError(t *testing.T) {
 		t.Error("NewValidationError().Error() should not return empty string")
 	}
 }
+
+func TestToolPanicError(t *testing.T) {
+	name := "test-tool"
+	err := NewToolPanicError(name, "boom", []byte("stack"))
+
+	if err.Name != name {
+		t.Errorf("NewToolPanicError().Name = %v, want %v", err.Name, name)
+	}
+	if err.Value != "boom" {
+		t.Errorf("NewToolPanicError().Value = %v, want boom", err.Value)
+	}
+	if err.Err == nil {
+		t.Error("NewToolPanicError().Err should not be nil")
+	}
+	if err.Err.Code != 500 {
+		t.Errorf("NewToolPanicError().Err.Code = %v, want 500", err.Err.Code)
+	}
+	if err.Error() != "tool test-tool panicked: boom" {
+		t.Errorf("NewToolPanicError().Error() = %v, want %v", err.Error(), "tool test-tool panicked: boom")
+	}
+}
@@ -0,0 +1,48 @@
+package domain
+
+import "context"
+
+// ClientIdentity is the identity of a client, taken from the TLS client
+// certificate it presented and the server verified during a mutual TLS handshake.
+type ClientIdentity struct {
+	Subject        string   // Distinguished name of the certificate subject
+	CommonName     string   // Common name of the certificate subject
+	Issuer         string   // Distinguished name of the certificate issuer
+	SerialNumber   string   // Serial number of the certificate, in decimal
+	DNSNames       []string // DNS subject alternative names
+	URIs           []string // URI subject alternative names, e.g. SPIFFE IDs
+	EmailAddresses []string // Email subject alternative names
+	Fingerprint    string   // Lowercase hex SHA-256 fingerprint of the certificate
+}
+
+// Name returns the most specific name of the client: its first URI, its common
+// name, or its first DNS name, in that order.
+func (c *ClientIdentity) Name() string {
+	if c == nil {
+		return ""
+	}
+	switch {
+	case len(c.URIs) > 0:
+		return c.URIs[0]
+	case c.CommonName != "":
+		return c.CommonName
+	case len(c.DNSNames) > 0:
+		return c.DNSNames[0]
+	}
+	return ""
+}
+
+// clientIdentityContextKey is the context key under which the client identity is stored.
+type clientIdentityContextKey struct{}
+
+// ContextWithClientIdentity returns a copy of ctx that carries the given client identity.
+func ContextWithClientIdentity(ctx context.Context, identity *ClientIdentity) context.Context {
+	return context.WithValue(ctx, clientIdentityContextKey{}, identity)
+}
+
+// ClientIdentityFromContext returns the client identity stored in ctx, or nil if
+// the client did not present a verified certificate.
+func ClientIdentityFromContext(ctx context.Context) *ClientIdentity {
+	identity, _ := ctx.Value(clientIdentityContextKey{}).(*ClientIdentity)
+	return identity
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"context"
+	"testing"
+)
+
+func TestClientIdentity_Name(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity *ClientIdentity
+		want     string
+	}{
+		{"nil identity", nil, ""},
+		{"empty identity", &ClientIdentity{}, ""},
+		{"URI first", &ClientIdentity{CommonName: "agent", URIs: []string{"spiffe://example.org/agent"}}, "spiffe://example.org/agent"},
+		{"common name", &ClientIdentity{CommonName: "agent", DNSNames: []string{"agent.example.org"}}, "agent"},
+		{"DNS name", &ClientIdentity{DNSNames: []string{"agent.example.org"}}, "agent.example.org"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.identity.Name(); got != tt.want {
+				t.Errorf("Name() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIdentityContext(t *testing.T) {
+	ctx := context.Background()
+	if got := ClientIdentityFromContext(ctx); got != nil {
+		t.Errorf("ClientIdentityFromContext() = %v, want nil", got)
+	}
+
+	identity := &ClientIdentity{CommonName: "agent"}
+	if got := ClientIdentityFromContext(ContextWithClientIdentity(ctx, identity)); got != identity {
+		t.Errorf("ClientIdentityFromContext() = %v, want %v", got, identity)
+	}
+}
@@ -1,5 +1,30 @@
 package domain
 
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Standard JSON-RPC error codes.
+const (
+	ParseErrorCode     = -32700
+	InvalidRequestCode = -32600
+	MethodNotFoundCode = -32601
+	InvalidParamsCode  = -32602
+	InternalErrorCode  = -32603
+)
+
+// Server-defined JSON-RPC error codes.
+const (
+	UnauthorizedCode    = -32001
+	ForbiddenCode       = -32003
+	SessionNotFoundCode = -32004
+	BusyCode            = -32005
+	RateLimitedCode     = -32029
+)
+
 // JSONRPCRequest represents a JSON-RPC request in the domain layer.
 type JSONRPCRequest struct {
 	JSONRPC string      `json:"jsonrpc"`
@@ -43,3 +68,86 @@ func CreateErrorResponse(jsonrpcVersion string, id interface{}, code int, messag
 		},
 	}
 }
+
+// CreateErrorResponseFromError creates a new JSONRPCResponse with the given ID and JSON-RPC error.
+func CreateErrorResponseFromError(jsonrpcVersion string, id interface{}, err *JSONRPCError) JSONRPCResponse {
+	return JSONRPCResponse{
+		JSONRPC: jsonrpcVersion,
+		ID:      id,
+		Error:   err,
+	}
+}
+
+// NewJSONRPCErrorFromError maps a structured domain error to a JSON-RPC error.
+// It returns nil when the error has no dedicated JSON-RPC representation, so
+// callers can fall back to their own generic error message.
+func NewJSONRPCErrorFromError(err error) *JSONRPCError {
+	var panicErr *ToolPanicError
+	if errors.As(err, &panicErr) {
+		return &JSONRPCError{
+			Code:    InternalErrorCode,
+			Message: panicErr.Error(),
+			Data: map[string]interface{}{
+				"tool":  panicErr.Name,
+				"panic": fmt.Sprintf("%v", panicErr.Value),
+			},
+		}
+	}
+
+	var forbiddenErr *ToolForbiddenError
+	if errors.As(err, &forbiddenErr) {
+		return &JSONRPCError{
+			Code:    ForbiddenCode,
+			Message: forbiddenErr.Error(),
+			Data: map[string]interface{}{
+				"tool": forbiddenErr.Name,
+			},
+		}
+	}
+
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return &JSONRPCError{
+			Code:    RateLimitedCode,
+			Message: rateLimitErr.Error(),
+			Data: map[string]interface{}{
+				"tool":         rateLimitErr.Name,
+				"scope":        rateLimitErr.Scope,
+				"retryAfter":   int64(math.Ceil(rateLimitErr.RetryAfter.Seconds())),
+				"retryAfterMs": rateLimitErr.RetryAfter.Milliseconds(),
+			},
+		}
+	}
+
+	var busyErr *BusyError
+	if errors.As(err, &busyErr) {
+		return &JSONRPCError{
+			Code:    BusyCode,
+			Message: busyErr.Error(),
+			Data: map[string]interface{}{
+				busyErr.Scope: busyErr.Name,
+			},
+		}
+	}
+
+	return nil
+}
+
+// RetryAfter returns the retry delay carried by a rate limit error, or false if
+// the error is not a rate limit error.
+func (e *JSONRPCError) RetryAfter() (time.Duration, bool) {
+	if e == nil || e.Code != RateLimitedCode {
+		return 0, false
+	}
+	data, ok := e.Data.(map[string]interface{})
+	if !ok {
+		return 0, true
+	}
+	switch ms := data["retryAfterMs"].(type) {
+	case int64:
+		return time.Duration(ms) * time.Millisecond, true
+	case float64:
+		return time.Duration(ms) * time.Millisecond, true
+	}
+	return 0, true
+}
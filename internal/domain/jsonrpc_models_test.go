@@ -1,7 +1,9 @@
 package domain
 
 import (
+	"fmt"
 	"testing"
+	"time"
 )
 
 func TestCreateResponse(t *testing.T) {
@@ -112,3 +114,63 @@ func TestJSONRPCRequest(t *testing.T) {
 		}
 	}
 }
+
+func TestNewJSONRPCErrorFromError(t *testing.T) {
+	// Unstructured errors have no dedicated representation
+	if rpcErr := NewJSONRPCErrorFromError(ErrInternal); rpcErr != nil {
+		t.Errorf("NewJSONRPCErrorFromError(ErrInternal) = %v, want nil", rpcErr)
+	}
+
+	// Tool panics map to an internal error carrying the tool name
+	rpcErr := NewJSONRPCErrorFromError(fmt.Errorf("wrapped: %w", NewToolPanicError("test-tool", "boom", nil)))
+	if rpcErr == nil {
+		t.Fatal("NewJSONRPCErrorFromError() should map ToolPanicError")
+	}
+	if rpcErr.Code != InternalErrorCode {
+		t.Errorf("NewJSONRPCErrorFromError().Code = %v, want %v", rpcErr.Code, InternalErrorCode)
+	}
+	data, ok := rpcErr.Data.(map[string]interface{})
+	if !ok {
+		t.Fatalf("NewJSONRPCErrorFromError().Data should be a map, got %T", rpcErr.Data)
+	}
+	if data["tool"] != "test-tool" {
+		t.Errorf("NewJSONRPCErrorFromError().Data[\"tool\"] = %v, want test-tool", data["tool"])
+	}
+	if data["panic"] != "boom" {
+		t.Errorf("NewJSONRPCErrorFromError().Data[\"panic\"] = %v, want boom", data["panic"])
+	}
+
+	// Tools denied by policy map to a forbidden error
+	rpcErr = NewJSONRPCErrorFromError(NewToolForbiddenError("db.delete"))
+	if rpcErr == nil {
+		t.Fatal("NewJSONRPCErrorFromError() should map ToolForbiddenError")
+	}
+	if rpcErr.Code != ForbiddenCode {
+		t.Errorf("NewJSONRPCErrorFromError().Code = %v, want %v", rpcErr.Code, ForbiddenCode)
+	}
+
+	// Rate limited calls carry a retry hint
+	rpcErr = NewJSONRPCErrorFromError(NewRateLimitError("search", "tool", 1500*time.Millisecond))
+	if rpcErr == nil || rpcErr.Code != RateLimitedCode {
+		t.Fatalf("NewJSONRPCErrorFromError() = %v, want code %v", rpcErr, RateLimitedCode)
+	}
+	data = rpcErr.Data.(map[string]interface{})
+	if data["retryAfter"] != int64(2) {
+		t.Errorf("NewJSONRPCErrorFromError().Data[\"retryAfter\"] = %v, want 2", data["retryAfter"])
+	}
+	if retryAfter, ok := rpcErr.RetryAfter(); !ok || retryAfter != 1500*time.Millisecond {
+		t.Errorf("RetryAfter() = %v, %v, want 1.5s, true", retryAfter, ok)
+	}
+	if _, ok := NewJSONRPCErrorFromError(NewToolForbiddenError("x")).RetryAfter(); ok {
+		t.Error("RetryAfter() should be false for other errors")
+	}
+
+	// Saturated bulkheads map to a busy error naming the provider
+	rpcErr = NewJSONRPCErrorFromError(NewBusyError("database", "provider"))
+	if rpcErr == nil || rpcErr.Code != BusyCode {
+		t.Fatalf("NewJSONRPCErrorFromError() = %v, want code %v", rpcErr, BusyCode)
+	}
+	if data := rpcErr.Data.(map[string]interface{}); data["provider"] != "database" {
+		t.Errorf("NewJSONRPCErrorFromError().Data[\"provider\"] = %v, want database", data["provider"])
+	}
+}
@@ -3,6 +3,7 @@ package domain
 import (
 	"context"
 	"sync"
+	"time"
 )
 
 // MockResourceRepository is a mock implementation of ResourceRepository
@@ -331,3 +332,51 @@ func (m *MockNotificationSender) GetSentNotifications(sessionID string) []*Notif
 
 	return m.sentNotifications[sessionID]
 }
+
+// MockSessionStoreBackend is a mock implementation of SessionStoreBackend
+type MockSessionStoreBackend struct {
+	values map[string]map[string]interface{}
+	mu     sync.Mutex
+}
+
+// NewMockSessionStoreBackend creates a new MockSessionStoreBackend
+func NewMockSessionStoreBackend() *MockSessionStoreBackend {
+	return &MockSessionStoreBackend{
+		values: make(map[string]map[string]interface{}),
+	}
+}
+
+// Get implements SessionStoreBackend.Get
+func (m *MockSessionStoreBackend) Get(ctx context.Context, sessionID, key string) (interface{}, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.values[sessionID][key]
+	return value, ok, nil
+}
+
+// Set implements SessionStoreBackend.Set, ignoring the TTL
+func (m *MockSessionStoreBackend) Set(ctx context.Context, sessionID, key string, value interface{}, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.values[sessionID] == nil {
+		m.values[sessionID] = make(map[string]interface{})
+	}
+	m.values[sessionID][key] = value
+	return nil
+}
+
+// Delete implements SessionStoreBackend.Delete
+func (m *MockSessionStoreBackend) Delete(ctx context.Context, sessionID, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values[sessionID], key)
+	return nil
+}
+
+// Clear implements SessionStoreBackend.Clear
+func (m *MockSessionStoreBackend) Clear(ctx context.Context, sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, sessionID)
+	return nil
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "sort"
+
+// PageAfter returns at most limit of the items whose keys sort after the given
+// one, and whether more follow. Items must be ordered by key. A limit of zero
+// or less returns all of them. It helps implement the paged repositories.
+func PageAfter[T any](items []T, key func(T) string, after string, limit int) ([]T, bool) {
+	start := 0
+	if after != "" {
+		start = sort.Search(len(items), func(i int) bool { return key(items[i]) > after })
+	}
+	items = items[start:]
+	if limit <= 0 || len(items) <= limit {
+		return items, false
+	}
+	return items[:limit], true
+}
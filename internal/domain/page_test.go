@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPageAfter(t *testing.T) {
+	items := []string{"a", "b", "c", "d"}
+	key := func(item string) string { return item }
+
+	tests := []struct {
+		name     string
+		after    string
+		limit    int
+		want     []string
+		wantMore bool
+	}{
+		{"first page", "", 2, []string{"a", "b"}, true},
+		{"next page", "b", 2, []string{"c", "d"}, false},
+		{"after a missing key", "bb", 1, []string{"c"}, true},
+		{"after the last key", "d", 2, []string{}, false},
+		{"no limit", "a", 0, []string{"b", "c", "d"}, false},
+		{"exact fit", "", 4, []string{"a", "b", "c", "d"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, more := PageAfter(items, key, tt.after, tt.limit)
+			if !reflect.DeepEqual(got, tt.want) || more != tt.wantMore {
+				t.Errorf("PageAfter() = %v, %v, want %v, %v", got, more, tt.want, tt.wantMore)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package domain
+
+import "context"
+
+// Principal represents an authenticated caller of the MCP server.
+type Principal struct {
+	ID     string
+	Roles  []string
+	Scopes []string
+	Claims map[string]interface{}
+}
+
+// HasRole reports whether the principal has been granted the given role.
+func (p *Principal) HasRole(role string) bool {
+	if p == nil {
+		return false
+	}
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the principal has been granted the given scope.
+func (p *Principal) HasScope(scope string) bool {
+	if p == nil {
+		return false
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// principalContextKey is the context key under which the authenticated principal is stored.
+type principalContextKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx that carries the given principal.
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal stored in ctx, or nil if the
+// request was not authenticated.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalContextKey{}).(*Principal)
+	return principal
+}
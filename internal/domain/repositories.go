@@ -1,6 +1,9 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // ResourceRepository defines the interface for managing resources.
 type ResourceRepository interface {
@@ -47,6 +50,36 @@ type PromptRepository interface {
 	DeletePrompt(ctx context.Context, name string) error
 }
 
+// PagedResourceRepository is a ResourceRepository that can list its resources
+// a page at a time. Repositories that do not implement it are listed in full
+// and paged by the server.
+type PagedResourceRepository interface {
+	ResourceRepository
+
+	// ListResourcesPage returns at most limit resources whose URIs sort after
+	// the given one, ordered by URI, and whether more follow. An empty after
+	// starts at the first resource, and a limit of zero or less returns all.
+	ListResourcesPage(ctx context.Context, after string, limit int) ([]*Resource, bool, error)
+}
+
+// PagedToolRepository is a ToolRepository that can list its tools a page at a time.
+type PagedToolRepository interface {
+	ToolRepository
+
+	// ListToolsPage returns at most limit tools whose names sort after the
+	// given one, ordered by name, and whether more follow, like ListResourcesPage.
+	ListToolsPage(ctx context.Context, after string, limit int) ([]*Tool, bool, error)
+}
+
+// PagedPromptRepository is a PromptRepository that can list its prompts a page at a time.
+type PagedPromptRepository interface {
+	PromptRepository
+
+	// ListPromptsPage returns at most limit prompts whose names sort after the
+	// given one, ordered by name, and whether more follow, like ListResourcesPage.
+	ListPromptsPage(ctx context.Context, after string, limit int) ([]*Prompt, bool, error)
+}
+
 // SessionRepository defines the interface for managing client sessions.
 type SessionRepository interface {
 	// GetSession retrieves a session by its ID.
@@ -70,3 +103,54 @@ type NotificationSender interface {
 	// BroadcastNotification sends a notification to all connected clients.
 	BroadcastNotification(ctx context.Context, notification *Notification) error
 }
+
+// TargetedNotificationSender is a NotificationSender that can send a
+// notification to the clients whose sessions match a filter.
+type TargetedNotificationSender interface {
+	NotificationSender
+
+	// NotifySessions sends a notification to every connected client whose
+	// session is selected by filter. Clients without a known session are skipped.
+	NotifySessions(ctx context.Context, filter SessionFilter, notification *Notification) error
+}
+
+// NotificationBus relays messages between the instances of a horizontally
+// scaled server, so that notifications and client messages reach the sessions
+// connected to any instance. Implementations deliver every message published
+// to a topic to the handlers subscribed to it on all instances, including the
+// publishing one.
+type NotificationBus interface {
+	// Publish sends payload to the subscribers of topic.
+	Publish(ctx context.Context, topic string, payload []byte) error
+
+	// Subscribe calls handler with the payload of every message published to
+	// topic, until the returned function is called. Handlers may be called
+	// concurrently and must not block for long.
+	Subscribe(topic string, handler func(payload []byte)) (unsubscribe func(), err error)
+}
+
+// SessionStoreBackend persists the key/value state that handlers keep per
+// session, see SessionStore.
+type SessionStoreBackend interface {
+	// Get returns the value stored under key for the session, and false if it is
+	// not set or has expired.
+	Get(ctx context.Context, sessionID, key string) (interface{}, bool, error)
+
+	// Set stores value under key for the session. A positive ttl expires the
+	// value after that duration, otherwise it is kept until the session ends.
+	Set(ctx context.Context, sessionID, key string, value interface{}, ttl time.Duration) error
+
+	// Delete removes the value stored under key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, sessionID, key string) error
+
+	// Clear removes all values of the session.
+	Clear(ctx context.Context, sessionID string) error
+}
+
+// SessionStoreSweeper is implemented by the SessionStoreBackends that keep
+// values across restarts, so that the values of sessions that ended while the
+// server was down, and thus were never cleared, can be removed.
+type SessionStoreSweeper interface {
+	// Retain removes the values of every session but the given ones.
+	Retain(ctx context.Context, sessionIDs []string) error
+}
@@ -0,0 +1,160 @@
+package domain
+
+import "context"
+
+// SessionFilter selects the sessions that a notification is sent to. Filters
+// must be cheap, as they run for every connected session. Only a
+// SessionSelector can be relayed to the sessions of other instances over a
+// NotificationBus.
+type SessionFilter interface {
+	// Match reports whether the filter selects the session, which is never nil.
+	Match(session *ClientSession) bool
+}
+
+// SelectsSession reports whether filter selects the session. A nil filter
+// selects every session, while a nil session is never selected.
+func SelectsSession(filter SessionFilter, session *ClientSession) bool {
+	if session == nil {
+		return false
+	}
+	return filter == nil || filter.Match(session)
+}
+
+// SessionFilterFunc is a SessionFilter that selects the sessions for which it
+// returns true. It cannot be relayed over a NotificationBus.
+type SessionFilterFunc func(session *ClientSession) bool
+
+// Match reports whether the function selects the session.
+func (f SessionFilterFunc) Match(session *ClientSession) bool {
+	return f(session)
+}
+
+// SessionSelector is a SessionFilter that can be serialized, so that other
+// instances apply it to their sessions. It selects the sessions that match all
+// of its set fields, and every session if none is set.
+type SessionSelector struct {
+	PrincipalID     *string           `json:"principalId,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	Subscriptions   []string          `json:"subscriptions,omitempty"`
+	ProtocolVersion *string           `json:"protocolVersion,omitempty"`
+}
+
+// Match reports whether the selector selects the session.
+func (s *SessionSelector) Match(session *ClientSession) bool {
+	if s == nil {
+		return true
+	}
+	if s.PrincipalID != nil && (session.Principal == nil || session.Principal.ID != *s.PrincipalID) {
+		return false
+	}
+	for key, value := range s.Labels {
+		if v, ok := session.Label(key); !ok || v != value {
+			return false
+		}
+	}
+	for _, topic := range s.Subscriptions {
+		if !session.Subscribed(topic) {
+			return false
+		}
+	}
+	return s.ProtocolVersion == nil || session.ProtocolVersion == *s.ProtocolVersion
+}
+
+// merge adds the conditions of other to the selector. It returns false if both
+// set a field to different values.
+func (s *SessionSelector) merge(other *SessionSelector) bool {
+	if other == nil {
+		return true
+	}
+	if !mergeField(&s.PrincipalID, other.PrincipalID) || !mergeField(&s.ProtocolVersion, other.ProtocolVersion) {
+		return false
+	}
+	for key, value := range other.Labels {
+		if v, ok := s.Labels[key]; ok && v != value {
+			return false
+		}
+		if s.Labels == nil {
+			s.Labels = make(map[string]string)
+		}
+		s.Labels[key] = value
+	}
+	s.Subscriptions = append(s.Subscriptions, other.Subscriptions...)
+	return true
+}
+
+// mergeField sets *field to value if it is unset, and returns false if it is
+// already set to a different value.
+func mergeField(field **string, value *string) bool {
+	if value == nil {
+		return true
+	}
+	if *field != nil && **field != *value {
+		return false
+	}
+	*field = value
+	return true
+}
+
+// PrincipalFilter selects the sessions of the principal with the given ID.
+func PrincipalFilter(principalID string) SessionFilter {
+	return &SessionSelector{PrincipalID: &principalID}
+}
+
+// LabelFilter selects the sessions whose label key has the given value.
+func LabelFilter(key, value string) SessionFilter {
+	return &SessionSelector{Labels: map[string]string{key: value}}
+}
+
+// SubscriptionFilter selects the sessions subscribed to a topic.
+func SubscriptionFilter(topic string) SessionFilter {
+	return &SessionSelector{Subscriptions: []string{topic}}
+}
+
+// ProtocolVersionFilter selects the sessions that initialized with the given
+// protocol version.
+func ProtocolVersionFilter(version string) SessionFilter {
+	return &SessionSelector{ProtocolVersion: &version}
+}
+
+// AllFilters selects the sessions selected by every filter. Selectors are
+// combined into a single SessionSelector unless they conflict.
+func AllFilters(filters ...SessionFilter) SessionFilter {
+	selector := &SessionSelector{}
+	for _, filter := range filters {
+		other, ok := filter.(*SessionSelector)
+		if filter != nil && (!ok || !selector.merge(other)) {
+			return allFilters(filters)
+		}
+	}
+	return selector
+}
+
+// allFilters selects the sessions selected by every filter.
+type allFilters []SessionFilter
+
+// Match reports whether every filter selects the session.
+func (f allFilters) Match(session *ClientSession) bool {
+	for _, filter := range f {
+		if !SelectsSession(filter, session) {
+			return false
+		}
+	}
+	return true
+}
+
+// notificationFilterContextKey is the context key under which the notification filter is stored.
+type notificationFilterContextKey struct{}
+
+// ContextWithNotificationFilter returns a copy of ctx that restricts the
+// notifications caused by the operations it is passed to, such as the list
+// changed notifications of adding a tool, to the sessions selected by filter.
+func ContextWithNotificationFilter(ctx context.Context, filter SessionFilter) context.Context {
+	return context.WithValue(ctx, notificationFilterContextKey{}, filter)
+}
+
+// NotificationFilterFromContext returns the notification filter stored in ctx,
+// or nil if there is none.
+func NotificationFilterFromContext(ctx context.Context) SessionFilter {
+	filter, _ := ctx.Value(notificationFilterContextKey{}).(SessionFilter)
+	return filter
+}
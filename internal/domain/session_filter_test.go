@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestSessionFilters(t *testing.T) {
+	session := NewClientSession("test-agent")
+	session.Principal = &Principal{ID: "alice"}
+	session.ProtocolVersion = "2025-03-26"
+	session.SetLabel("tenant", "acme")
+	session.Subscribe("file:///logs")
+
+	tests := []struct {
+		name   string
+		filter SessionFilter
+		want   bool
+	}{
+		{"nil filter", nil, true},
+		{"principal", PrincipalFilter("alice"), true},
+		{"other principal", PrincipalFilter("bob"), false},
+		{"label", LabelFilter("tenant", "acme"), true},
+		{"other label value", LabelFilter("tenant", "globex"), false},
+		{"missing label", LabelFilter("region", ""), false},
+		{"subscription", SubscriptionFilter("file:///logs"), true},
+		{"other subscription", SubscriptionFilter("file:///other"), false},
+		{"protocol version", ProtocolVersionFilter("2025-03-26"), true},
+		{"other protocol version", ProtocolVersionFilter("2024-11-05"), false},
+		{"all match", AllFilters(PrincipalFilter("alice"), LabelFilter("tenant", "acme")), true},
+		{"one does not match", AllFilters(PrincipalFilter("alice"), LabelFilter("tenant", "globex")), false},
+		{"conflicting labels", AllFilters(LabelFilter("tenant", "acme"), LabelFilter("tenant", "globex")), false},
+		{"func", SessionFilterFunc(func(s *ClientSession) bool { return s.UserAgent == "test-agent" }), true},
+		{"func and selector", AllFilters(SessionFilterFunc(func(*ClientSession) bool { return true }), PrincipalFilter("bob")), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SelectsSession(tt.filter, session); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Sessions are never selected without a principal, nor when unknown
+	if PrincipalFilter("").Match(NewClientSession("test-agent")) {
+		t.Error("PrincipalFilter() should not select unauthenticated sessions")
+	}
+	if SelectsSession(nil, nil) {
+		t.Error("SelectsSession() should not select a nil session")
+	}
+
+	session.Unsubscribe("file:///logs")
+	if SubscriptionFilter("file:///logs").Match(session) {
+		t.Error("SubscriptionFilter() should not select a session after Unsubscribe()")
+	}
+}
+
+func TestSessionSelector_JSON(t *testing.T) {
+	session := NewClientSession("test-agent")
+	session.Principal = &Principal{ID: "alice"}
+	session.SetLabel("tenant", "acme")
+
+	// Built-in filters combine into a selector that survives serialization
+	filter := AllFilters(PrincipalFilter("alice"), LabelFilter("tenant", "acme"))
+	if _, ok := filter.(*SessionSelector); !ok {
+		t.Fatalf("AllFilters() = %T, want a *SessionSelector", filter)
+	}
+	data, err := json.Marshal(filter)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var selector SessionSelector
+	if err := json.Unmarshal(data, &selector); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !selector.Match(session) {
+		t.Errorf("decoded selector %s should select the session", data)
+	}
+	session.SetLabel("tenant", "globex")
+	if selector.Match(session) {
+		t.Errorf("decoded selector %s should not select another tenant", data)
+	}
+
+	// Conflicting or custom filters cannot be serialized
+	if _, ok := AllFilters(LabelFilter("tenant", "acme"), LabelFilter("tenant", "globex")).(*SessionSelector); ok {
+		t.Error("AllFilters() of conflicting labels should not return a *SessionSelector")
+	}
+}
+
+func TestNotificationFilterContext(t *testing.T) {
+	ctx := context.Background()
+	if NotificationFilterFromContext(ctx) != nil {
+		t.Error("NotificationFilterFromContext() should return nil without a filter")
+	}
+
+	ctx = ContextWithNotificationFilter(ctx, LabelFilter("tenant", "acme"))
+	filter := NotificationFilterFromContext(ctx)
+	if filter == nil {
+		t.Fatal("NotificationFilterFromContext() = nil, want the filter")
+	}
+
+	session := NewClientSession("test-agent")
+	session.SetLabel("tenant", "acme")
+	if !filter.Match(session) {
+		t.Error("filter from context should select the session")
+	}
+}
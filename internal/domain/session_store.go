@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// SessionStore is the key/value state of one session, kept in a
+// SessionStoreBackend. All methods are safe to call on a nil SessionStore: Get
+// reports missing values and the other methods return ErrNoSessionStore.
+type SessionStore struct {
+	backend   SessionStoreBackend
+	sessionID string
+}
+
+// NewSessionStore returns the store of the session with the given ID in backend.
+func NewSessionStore(backend SessionStoreBackend, sessionID string) *SessionStore {
+	return &SessionStore{backend: backend, sessionID: sessionID}
+}
+
+// Get returns the value stored under key, and false if it is not set or has expired.
+func (s *SessionStore) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	if s == nil {
+		return nil, false, nil
+	}
+	return s.backend.Get(ctx, s.sessionID, key)
+}
+
+// Set stores value under key until the session ends.
+func (s *SessionStore) Set(ctx context.Context, key string, value interface{}) error {
+	return s.SetWithTTL(ctx, key, value, 0)
+}
+
+// SetWithTTL stores value under key for the given duration, or until the
+// session ends if ttl is not positive.
+func (s *SessionStore) SetWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if s == nil {
+		return ErrNoSessionStore
+	}
+	return s.backend.Set(ctx, s.sessionID, key, value, ttl)
+}
+
+// Delete removes the value stored under key.
+func (s *SessionStore) Delete(ctx context.Context, key string) error {
+	if s == nil {
+		return ErrNoSessionStore
+	}
+	return s.backend.Delete(ctx, s.sessionID, key)
+}
+
+// Clear removes all values of the session.
+func (s *SessionStore) Clear(ctx context.Context) error {
+	if s == nil {
+		return ErrNoSessionStore
+	}
+	return s.backend.Clear(ctx, s.sessionID)
+}
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSessionStore(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMockSessionStoreBackend()
+
+	session := NewClientSession("test-agent")
+	if session.Store() != nil {
+		t.Fatal("new session should not have a store")
+	}
+	session.AttachStore(backend)
+	store := session.Store()
+	if store == nil {
+		t.Fatal("AttachStore() should give the session a store")
+	}
+	session.AttachStore(NewMockSessionStoreBackend())
+	if session.Store() != store {
+		t.Error("AttachStore() should keep an attached store")
+	}
+
+	if err := store.Set(ctx, "cursor", 42); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if value, ok, err := store.Get(ctx, "cursor"); err != nil || !ok || value != 42 {
+		t.Errorf("Get() = %v, %v, %v, want 42, true, nil", value, ok, err)
+	}
+	if value, _, _ := backend.Get(ctx, session.ID, "cursor"); value != 42 {
+		t.Errorf("backend value = %v, want 42 under the session ID", value)
+	}
+
+	if err := store.Delete(ctx, "cursor"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, ok, _ := store.Get(ctx, "cursor"); ok {
+		t.Error("Get() after Delete() should report a missing value")
+	}
+
+	if err := store.Set(ctx, "token", "secret"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := store.Clear(ctx); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if _, ok, _ := store.Get(ctx, "token"); ok {
+		t.Error("Get() after Clear() should report a missing value")
+	}
+}
+
+func TestSessionStore_Nil(t *testing.T) {
+	ctx := context.Background()
+
+	// Sessions without an ID cannot have a store
+	session := &ClientSession{State: NewSessionState(time.Now())}
+	session.AttachStore(NewMockSessionStoreBackend())
+	store := session.Store()
+	if store != nil {
+		t.Fatal("session without an ID should not have a store")
+	}
+
+	if _, ok, err := store.Get(ctx, "key"); ok || err != nil {
+		t.Errorf("Get() on nil store = %v, %v, want false, nil", ok, err)
+	}
+	if err := store.Set(ctx, "key", "value"); !errors.Is(err, ErrNoSessionStore) {
+		t.Errorf("Set() on nil store error = %v, want ErrNoSessionStore", err)
+	}
+	if err := store.Delete(ctx, "key"); !errors.Is(err, ErrNoSessionStore) {
+		t.Errorf("Delete() on nil store error = %v, want ErrNoSessionStore", err)
+	}
+	if err := store.Clear(ctx); !errors.Is(err, ErrNoSessionStore) {
+		t.Errorf("Clear() on nil store error = %v, want ErrNoSessionStore", err)
+	}
+}
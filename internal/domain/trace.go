@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+)
+
+// TraceparentHeader is the W3C Trace Context header, and the _meta key, that
+// carries the trace context of a request.
+const TraceparentHeader = "traceparent"
+
+// SpanContext identifies a span within a trace, following the W3C Trace Context format.
+type SpanContext struct {
+	TraceID string // 32 lowercase hex characters
+	SpanID  string // 16 lowercase hex characters
+	Sampled bool
+	Remote  bool // Set if the span context was received from another process
+}
+
+// IsValid reports whether the span context has a valid trace and span ID.
+func (c SpanContext) IsValid() bool {
+	return isHexID(c.TraceID, 32) && isHexID(c.SpanID, 16)
+}
+
+// Traceparent formats the span context as a traceparent header value.
+func (c SpanContext) Traceparent() string {
+	flags := "00"
+	if c.Sampled {
+		flags = "01"
+	}
+	return "00-" + c.TraceID + "-" + c.SpanID + "-" + flags
+}
+
+// ParseTraceparent parses a traceparent header value. It returns false if the
+// value is malformed or carries an all-zero trace or span ID.
+func ParseTraceparent(value string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[3]) != 2 {
+		return SpanContext{}, false
+	}
+
+	// Version ff is invalid; version 00 has exactly four fields
+	version := strings.ToLower(parts[0])
+	if version == "ff" || (version == "00" && len(parts) != 4) {
+		return SpanContext{}, false
+	}
+
+	if _, err := hex.DecodeString(version); err != nil {
+		return SpanContext{}, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return SpanContext{}, false
+	}
+
+	sc := SpanContext{
+		TraceID: parts[1],
+		SpanID:  parts[2],
+		Sampled: flags[0]&0x01 == 0x01,
+		Remote:  true,
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+// SpanContextFromMeta extracts the trace context from the _meta field of MCP
+// request params, e.g. {"_meta": {"traceparent": "00-..."}}.
+func SpanContextFromMeta(params interface{}) (SpanContext, bool) {
+	p, ok := params.(map[string]interface{})
+	if !ok {
+		return SpanContext{}, false
+	}
+	meta, ok := p["_meta"].(map[string]interface{})
+	if !ok {
+		return SpanContext{}, false
+	}
+	value, ok := meta[TraceparentHeader].(string)
+	if !ok {
+		return SpanContext{}, false
+	}
+	return ParseTraceparent(value)
+}
+
+// isHexID reports whether s is a lowercase hex string of the given length that
+// is not all zeros.
+func isHexID(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+	nonZero := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '0':
+		case (c >= '1' && c <= '9') || (c >= 'a' && c <= 'f'):
+			nonZero = true
+		default:
+			return false
+		}
+	}
+	return nonZero
+}
+
+// spanContextKey is the context key under which the current span context is stored.
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a copy of ctx that carries the given span context.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context stored in ctx. The result is
+// not valid if ctx carries no trace context.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc
+}
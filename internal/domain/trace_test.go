@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		want   SpanContext
+		wantOK bool
+	}{
+		{
+			name:   "Sampled",
+			value:  "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			want:   SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true, Remote: true},
+			wantOK: true,
+		},
+		{
+			name:   "Not sampled",
+			value:  "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			want:   SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Remote: true},
+			wantOK: true,
+		},
+		{
+			name:   "Future version with extra fields",
+			value:  "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			want:   SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true, Remote: true},
+			wantOK: true,
+		},
+		{name: "Empty", value: ""},
+		{name: "Invalid version", value: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "Extra fields in version 00", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{name: "Zero trace ID", value: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{name: "Zero span ID", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{name: "Uppercase hex", value: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{name: "Short trace ID", value: "00-4bf92f3577b34da6-00f067aa0ba902b7-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseTraceparent(tt.value)
+			if ok != tt.wantOK {
+				t.Fatalf("ParseTraceparent() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTraceparent() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpanContext_Traceparent(t *testing.T) {
+	value := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, ok := ParseTraceparent(value)
+	if !ok {
+		t.Fatal("ParseTraceparent() failed")
+	}
+	if got := sc.Traceparent(); got != value {
+		t.Errorf("Traceparent() = %v, want %v", got, value)
+	}
+}
+
+func TestSpanContextFromMeta(t *testing.T) {
+	params := map[string]interface{}{
+		"name": "echo",
+		"_meta": map[string]interface{}{
+			"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		},
+	}
+	sc, ok := SpanContextFromMeta(params)
+	if !ok || sc.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("SpanContextFromMeta() = %+v, %v", sc, ok)
+	}
+
+	if _, ok := SpanContextFromMeta(map[string]interface{}{"name": "echo"}); ok {
+		t.Error("SpanContextFromMeta() without _meta should fail")
+	}
+	if _, ok := SpanContextFromMeta(nil); ok {
+		t.Error("SpanContextFromMeta(nil) should fail")
+	}
+}
+
+func TestSpanContextFromContext(t *testing.T) {
+	ctx := context.Background()
+	if SpanContextFromContext(ctx).IsValid() {
+		t.Error("SpanContextFromContext() of an empty context should not be valid")
+	}
+
+	sc := SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"}
+	if got := SpanContextFromContext(ContextWithSpanContext(ctx, sc)); got != sc {
+		t.Errorf("SpanContextFromContext() = %+v, want %+v", got, sc)
+	}
+}
@@ -2,23 +2,395 @@
 package domain
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	"github.com/google/uuid"
 )
 
+// Transports that carry client sessions, see ClientSession.Transport.
+const (
+	TransportStdio = "stdio" // The standard input and output of the process
+	TransportSSE   = "sse"   // An SSE stream and its message endpoint
+	TransportHTTP  = "http"  // Streamable HTTP requests carrying the Mcp-Session-Id header
+)
+
 // ClientSession represents an active client connection to the MCP server.
+// Transports create a session per connection, record what the client reported
+// on initialize, and pass the same session to every tool handler.
 type ClientSession struct {
 	ID        string
 	UserAgent string
 	Connected bool
+	Principal *Principal // Authenticated caller, nil for unauthenticated transports
+	Transport string     // Transport that carries the session, e.g. TransportSSE
+
+	// ClientIdentity is the verified TLS client certificate, nil without mutual TLS
+	ClientIdentity *ClientIdentity
+
+	// RemoteAddr is the host of the client's network address, "" if not known
+	RemoteAddr string
+
+	// Reported by the client on initialize
+	ClientInfo         ClientInfo
+	ClientCapabilities map[string]interface{}
+	ProtocolVersion    string
+
+	CreatedAt time.Time
+
+	// State holds the mutable state of the session, shared by every handler
+	// that receives the session. It is nil for sessions not created by NewClientSession.
+	State *SessionState
+}
+
+// ClientInfo is the name and version of the client implementation.
+type ClientInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
 }
 
 // NewClientSession creates a new ClientSession with a unique ID.
 func NewClientSession(userAgent string) *ClientSession {
+	now := time.Now()
 	return &ClientSession{
 		ID:        uuid.New().String(),
 		UserAgent: userAgent,
 		Connected: true,
+		CreatedAt: now,
+		State:     NewSessionState(now),
+	}
+}
+
+// Initialize records the client info, capabilities and protocol version sent
+// by the client in the params of its initialize request.
+func (s *ClientSession) Initialize(params map[string]interface{}) {
+	if version, ok := params["protocolVersion"].(string); ok {
+		s.ProtocolVersion = version
+	}
+	if capabilities, ok := params["capabilities"].(map[string]interface{}); ok {
+		s.ClientCapabilities = capabilities
+	}
+	if info, ok := params["clientInfo"].(map[string]interface{}); ok {
+		s.ClientInfo.Name, _ = info["name"].(string)
+		s.ClientInfo.Version, _ = info["version"].(string)
+	}
+	s.State.markInitialized()
+}
+
+// Initialized reports whether the client has sent its initialize request.
+func (s *ClientSession) Initialized() bool {
+	return s.State.isInitialized()
+}
+
+// LastSeen returns when the client last sent a request in this session.
+func (s *ClientSession) LastSeen() time.Time {
+	return s.State.LastSeen()
+}
+
+// Touch records that the client sent a request in this session.
+func (s *ClientSession) Touch() {
+	s.State.touch(time.Now())
+}
+
+// Store returns the key/value store in which tool handlers keep state for the
+// session, such as cursors or upstream tokens. It is cleared when the session
+// ends, and nil if the server has no session store.
+func (s *ClientSession) Store() *SessionStore {
+	return s.State.Store()
+}
+
+// OnClose sets the function that disconnects the client when the session is
+// closed, e.g. by ending its stream. Transports set it when they open the session.
+func (s *ClientSession) OnClose(fn func()) {
+	s.State.setCloser(fn)
+}
+
+// Close disconnects the client of the session. Only the first call has an effect.
+func (s *ClientSession) Close() {
+	s.State.close()
+}
+
+// Closed reports whether the session was closed.
+func (s *ClientSession) Closed() bool {
+	return s.State.isClosed()
+}
+
+// Label returns the value of a label of the session, and whether it is set.
+func (s *ClientSession) Label(key string) (string, bool) {
+	return s.State.Label(key)
+}
+
+// Labels returns a copy of the labels of the session.
+func (s *ClientSession) Labels() map[string]string {
+	return s.State.Labels()
+}
+
+// SetLabel sets a label of the session, e.g. its tenant, which notifications
+// can be targeted at with LabelFilter. Labels are set by the server, usually
+// when the session is initialized, never by the client.
+func (s *ClientSession) SetLabel(key, value string) {
+	s.State.SetLabel(key, value)
+}
+
+// Subscribe subscribes the session to a topic, e.g. the URI of a resource,
+// which notifications can be targeted at with SubscriptionFilter.
+func (s *ClientSession) Subscribe(topic string) {
+	s.State.Subscribe(topic)
+}
+
+// Unsubscribe unsubscribes the session from a topic.
+func (s *ClientSession) Unsubscribe(topic string) {
+	s.State.Unsubscribe(topic)
+}
+
+// Subscribed reports whether the session is subscribed to a topic.
+func (s *ClientSession) Subscribed(topic string) bool {
+	return s.State.Subscribed(topic)
+}
+
+// AttachStore gives the session a store in backend, unless it already has one.
+// Sessions without an ID or state cannot have a store.
+func (s *ClientSession) AttachStore(backend SessionStoreBackend) {
+	if s.ID == "" || backend == nil {
+		return
+	}
+	s.State.attachStore(backend, s.ID)
+}
+
+// SessionState is the mutable state of a session. It is safe for concurrent use.
+type SessionState struct {
+	mu          sync.RWMutex
+	lastSeen    time.Time
+	initialized bool
+	values      map[interface{}]interface{}
+	store       *SessionStore
+	closer      func()
+	closed      bool
+	labels      map[string]string
+	topics      map[string]struct{}
+}
+
+// NewSessionState creates the state of a session that was last seen at the given time.
+func NewSessionState(lastSeen time.Time) *SessionState {
+	return &SessionState{lastSeen: lastSeen}
+}
+
+// Value returns the value stored under key, or nil if there is none.
+func (s *SessionState) Value(key interface{}) interface{} {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.values[key]
+}
+
+// SetValue stores a value under key for the lifetime of the session, e.g. a
+// cursor or a per-client cache. Handlers should use keys of their own type
+// to avoid collisions.
+func (s *SessionState) SetValue(key, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values == nil {
+		s.values = make(map[interface{}]interface{})
+	}
+	s.values[key] = value
+}
+
+// DeleteValue removes the value stored under key.
+func (s *SessionState) DeleteValue(key interface{}) {
+	if s == nil {
+		return
 	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+}
+
+// Label returns the value of a label of the session, and whether it is set.
+func (s *SessionState) Label(key string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.labels[key]
+	return value, ok
+}
+
+// Labels returns a copy of the labels of the session.
+func (s *SessionState) Labels() map[string]string {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	labels := make(map[string]string, len(s.labels))
+	for key, value := range s.labels {
+		labels[key] = value
+	}
+	return labels
+}
+
+// SetLabel sets a label of the session.
+func (s *SessionState) SetLabel(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.labels == nil {
+		s.labels = make(map[string]string)
+	}
+	s.labels[key] = value
+}
+
+// Subscribe subscribes the session to a topic.
+func (s *SessionState) Subscribe(topic string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.topics == nil {
+		s.topics = make(map[string]struct{})
+	}
+	s.topics[topic] = struct{}{}
+}
+
+// Unsubscribe unsubscribes the session from a topic.
+func (s *SessionState) Unsubscribe(topic string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.topics, topic)
+}
+
+// Subscribed reports whether the session is subscribed to a topic.
+func (s *SessionState) Subscribed(topic string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.topics[topic]
+	return ok
+}
+
+// LastSeen returns when the client last sent a request in the session.
+func (s *SessionState) LastSeen() time.Time {
+	if s == nil {
+		return time.Time{}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastSeen
+}
+
+func (s *SessionState) touch(now time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSeen = now
+}
+
+func (s *SessionState) isInitialized() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.initialized
+}
+
+func (s *SessionState) markInitialized() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.initialized = true
+}
+
+// Store returns the key/value store of the session, see ClientSession.Store.
+func (s *SessionState) Store() *SessionStore {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.store
+}
+
+func (s *SessionState) setCloser(fn func()) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closer = fn
+}
+
+// close marks the session closed and calls its closer, outside the lock so that
+// the closer may use the session.
+func (s *SessionState) close() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	closer := s.closer
+	s.mu.Unlock()
+
+	if closer != nil {
+		closer()
+	}
+}
+
+func (s *SessionState) isClosed() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.closed
+}
+
+func (s *SessionState) attachStore(backend SessionStoreBackend, sessionID string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.store == nil {
+		s.store = NewSessionStore(backend, sessionID)
+	}
+}
+
+// clientSessionContextKey is the context key under which the current session is stored.
+type clientSessionContextKey struct{}
+
+// ContextWithClientSession returns a copy of ctx that carries the given session.
+func ContextWithClientSession(ctx context.Context, session *ClientSession) context.Context {
+	return context.WithValue(ctx, clientSessionContextKey{}, session)
+}
+
+// ClientSessionFromContext returns the session stored in ctx, or nil if there is none.
+func ClientSessionFromContext(ctx context.Context) *ClientSession {
+	session, _ := ctx.Value(clientSessionContextKey{}).(*ClientSession)
+	return session
 }
 
 // Resource represents a resource that can be requested by clients.
@@ -1,7 +1,9 @@
 package domain
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -129,3 +131,138 @@ func TestNotification(t *testing.T) {
 		})
 	}
 }
+
+func TestPrincipal(t *testing.T) {
+	principal := &Principal{
+		ID:     "alice",
+		Roles:  []string{"admin"},
+		Scopes: []string{"tools:call"},
+	}
+
+	if !principal.HasRole("admin") {
+		t.Error("HasRole(\"admin\") = false, want true")
+	}
+	if principal.HasRole("reader") {
+		t.Error("HasRole(\"reader\") = true, want false")
+	}
+	if !principal.HasScope("tools:call") {
+		t.Error("HasScope(\"tools:call\") = false, want true")
+	}
+
+	var nilPrincipal *Principal
+	if nilPrincipal.HasRole("admin") || nilPrincipal.HasScope("tools:call") {
+		t.Error("nil principal should have no roles or scopes")
+	}
+
+	ctx := ContextWithPrincipal(context.Background(), principal)
+	if got := PrincipalFromContext(ctx); got != principal {
+		t.Errorf("PrincipalFromContext() = %v, want %v", got, principal)
+	}
+	if got := PrincipalFromContext(context.Background()); got != nil {
+		t.Errorf("PrincipalFromContext() = %v, want nil", got)
+	}
+}
+
+func TestClientSession_Initialize(t *testing.T) {
+	session := NewClientSession("test-agent")
+	if session.Initialized() {
+		t.Fatal("new session should not be initialized")
+	}
+	if session.CreatedAt.IsZero() || !session.LastSeen().Equal(session.CreatedAt) {
+		t.Errorf("LastSeen() = %v, want CreatedAt %v", session.LastSeen(), session.CreatedAt)
+	}
+
+	session.Initialize(map[string]interface{}{
+		"protocolVersion": "2025-03-26",
+		"capabilities":    map[string]interface{}{"roots": map[string]interface{}{"listChanged": true}},
+		"clientInfo":      map[string]interface{}{"name": "test-client", "version": "1.2.3"},
+	})
+
+	if !session.Initialized() {
+		t.Error("session should be initialized")
+	}
+	if session.ProtocolVersion != "2025-03-26" {
+		t.Errorf("ProtocolVersion = %q, want %q", session.ProtocolVersion, "2025-03-26")
+	}
+	if session.ClientInfo != (ClientInfo{Name: "test-client", Version: "1.2.3"}) {
+		t.Errorf("ClientInfo = %+v", session.ClientInfo)
+	}
+	if _, ok := session.ClientCapabilities["roots"]; !ok {
+		t.Errorf("ClientCapabilities = %v, want roots", session.ClientCapabilities)
+	}
+
+	before := session.LastSeen()
+	time.Sleep(time.Millisecond)
+	session.Touch()
+	if !session.LastSeen().After(before) {
+		t.Error("Touch() should advance LastSeen()")
+	}
+}
+
+func TestSessionState(t *testing.T) {
+	type key struct{}
+	state := NewSessionState(time.Now())
+
+	if got := state.Value(key{}); got != nil {
+		t.Errorf("Value() = %v, want nil", got)
+	}
+	state.SetValue(key{}, 42)
+	if got := state.Value(key{}); got != 42 {
+		t.Errorf("Value() = %v, want 42", got)
+	}
+	state.DeleteValue(key{})
+	if got := state.Value(key{}); got != nil {
+		t.Errorf("Value() after DeleteValue() = %v, want nil", got)
+	}
+
+	// Sessions not created by NewClientSession have no state
+	var nilState *SessionState
+	nilState.SetValue(key{}, 42)
+	if got := nilState.Value(key{}); got != nil {
+		t.Errorf("Value() on nil state = %v, want nil", got)
+	}
+	session := &ClientSession{ID: "literal"}
+	session.Touch()
+	if session.Initialized() || !session.LastSeen().IsZero() {
+		t.Error("session without state should report zero values")
+	}
+}
+
+func TestClientSessionContext(t *testing.T) {
+	ctx := context.Background()
+	if got := ClientSessionFromContext(ctx); got != nil {
+		t.Errorf("ClientSessionFromContext() = %v, want nil", got)
+	}
+
+	session := NewClientSession("test-agent")
+	if got := ClientSessionFromContext(ContextWithClientSession(ctx, session)); got != session {
+		t.Errorf("ClientSessionFromContext() = %v, want %v", got, session)
+	}
+}
+
+func TestClientSession_Close(t *testing.T) {
+	session := NewClientSession("test-agent")
+	closed := 0
+	session.OnClose(func() {
+		closed++
+		if !session.Closed() {
+			t.Error("Closed() should be true while the closer runs")
+		}
+	})
+
+	if session.Closed() {
+		t.Fatal("new session should not be closed")
+	}
+	session.Close()
+	session.Close()
+	if !session.Closed() || closed != 1 {
+		t.Errorf("Closed() = %v, closer called %d times, want true and 1", session.Closed(), closed)
+	}
+
+	// Sessions without a closer can be closed too
+	other := NewClientSession("test-agent")
+	other.Close()
+	if !other.Closed() {
+		t.Error("Closed() should be true after Close()")
+	}
+}
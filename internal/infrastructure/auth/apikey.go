@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"net/http"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+// DefaultAPIKeyHeader is the header checked for API keys by default.
+const DefaultAPIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator authenticates requests against a static set of API keys.
+// The key may be sent in the API key header or as a bearer token.
+type APIKeyAuthenticator struct {
+	keys   map[[sha256.Size]byte]*domain.Principal
+	header string
+}
+
+// APIKeyOption defines a function type for configuring APIKeyAuthenticator
+type APIKeyOption func(*APIKeyAuthenticator)
+
+// WithAPIKeyHeader sets the header from which the API key is read
+func WithAPIKeyHeader(header string) APIKeyOption {
+	return func(a *APIKeyAuthenticator) {
+		a.header = header
+	}
+}
+
+// NewAPIKeyAuthenticator creates a new APIKeyAuthenticator that maps each key
+// to the principal it authenticates.
+func NewAPIKeyAuthenticator(keys map[string]*domain.Principal, opts ...APIKeyOption) *APIKeyAuthenticator {
+	a := &APIKeyAuthenticator{
+		keys:   make(map[[sha256.Size]byte]*domain.Principal, len(keys)),
+		header: DefaultAPIKeyHeader,
+	}
+
+	// Keys are indexed by their digest so lookups do not leak timing
+	// information about the stored keys.
+	for key, principal := range keys {
+		if key == "" {
+			continue
+		}
+		a.keys[sha256.Sum256([]byte(key))] = principal
+	}
+
+	// Apply all options
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// Authenticate returns the principal for the API key presented by the request.
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*domain.Principal, error) {
+	key := r.Header.Get(a.header)
+	if key == "" {
+		key = bearerToken(r)
+	}
+	if key == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	principal, ok := a.keys[sha256.Sum256([]byte(key))]
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	return principal, nil
+}
@@ -0,0 +1,171 @@
+// Package auth provides authentication for the HTTP and SSE transports of the MCP server.
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/FreePeak/cortex/internal/domain"
+	"github.com/FreePeak/cortex/internal/infrastructure/logging"
+)
+
+// Common authentication errors
+var (
+	// ErrMissingCredentials is returned when a request carries no credentials
+	ErrMissingCredentials = errors.New("missing credentials")
+
+	// ErrInvalidCredentials is returned when the presented credentials are not valid
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Authenticator verifies the credentials carried by an HTTP request.
+type Authenticator interface {
+	// Authenticate returns the principal for the request, or an error if
+	// the request could not be authenticated.
+	Authenticate(r *http.Request) (*domain.Principal, error)
+}
+
+// AuthenticatorFunc is a function type that implements Authenticator
+type AuthenticatorFunc func(r *http.Request) (*domain.Principal, error)
+
+// Authenticate calls the authenticator function
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (*domain.Principal, error) {
+	return f(r)
+}
+
+// Challenger is implemented by authenticators that want to customize the
+// WWW-Authenticate header sent along with a 401 response.
+type Challenger interface {
+	// Challenge returns the WWW-Authenticate header value for the failed request.
+	Challenge(r *http.Request, err error) string
+}
+
+// chainAuthenticator tries several authenticators in order.
+type chainAuthenticator struct {
+	authenticators []Authenticator
+}
+
+// NewChainAuthenticator creates an authenticator that accepts a request if any
+// of the given authenticators accepts it. Authenticators are tried in order.
+func NewChainAuthenticator(authenticators ...Authenticator) Authenticator {
+	return &chainAuthenticator{authenticators: authenticators}
+}
+
+// Authenticate returns the principal from the first authenticator that accepts
+// the request. If none does, it returns the most specific error: a valid token
+// that lacks a scope over invalid credentials, and those over missing ones.
+func (c *chainAuthenticator) Authenticate(r *http.Request) (*domain.Principal, error) {
+	var chainErr error = ErrMissingCredentials
+	for _, a := range c.authenticators {
+		principal, err := a.Authenticate(r)
+		if err == nil {
+			return principal, nil
+		}
+		if authErrorRank(err) > authErrorRank(chainErr) {
+			chainErr = err
+		}
+	}
+	return nil, chainErr
+}
+
+// authErrorRank orders authentication errors by how much they tell the client.
+func authErrorRank(err error) int {
+	switch {
+	case errors.Is(err, ErrInsufficientScope):
+		return 2
+	case errors.Is(err, ErrMissingCredentials):
+		return 0
+	default:
+		return 1
+	}
+}
+
+// Challenge returns the challenge of the first chained authenticator that provides one.
+func (c *chainAuthenticator) Challenge(r *http.Request, err error) string {
+	for _, a := range c.authenticators {
+		if challenger, ok := a.(Challenger); ok {
+			return challenger.Challenge(r, err)
+		}
+	}
+	return defaultChallenge
+}
+
+// defaultChallenge is the WWW-Authenticate value used when the authenticator does not provide one.
+const defaultChallenge = `Bearer realm="mcp"`
+
+// Middleware returns an http.Handler that authenticates every request with the
+// given authenticator before passing it on to next. The authenticated principal
+// is attached to the request context, see domain.PrincipalFromContext.
+// Requests that fail authentication are answered with 401 and a JSON-RPC error.
+func Middleware(authenticator Authenticator, logger *logging.Logger, next http.Handler) http.Handler {
+	if logger == nil {
+		logger = logging.Default()
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// CORS preflight requests never carry credentials
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, err := authenticator.Authenticate(r)
+		if err != nil {
+			logger.Warn("Authentication failed", logging.Fields{
+				"path":       r.URL.Path,
+				"remoteAddr": r.RemoteAddr,
+				"error":      err.Error(),
+			})
+
+			challenge := defaultChallenge
+			if challenger, ok := authenticator.(Challenger); ok {
+				challenge = challenger.Challenge(r, err)
+			}
+
+			// A valid token without the required scopes is forbidden, not unauthenticated
+			if errors.Is(err, ErrInsufficientScope) {
+				WriteForbidden(w, challenge, err.Error())
+				return
+			}
+			WriteUnauthorized(w, challenge, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(domain.ContextWithPrincipal(r.Context(), principal)))
+	})
+}
+
+// WriteUnauthorized writes a 401 response with the given WWW-Authenticate challenge.
+func WriteUnauthorized(w http.ResponseWriter, challenge string, err error) {
+	if challenge != "" {
+		w.Header().Set("WWW-Authenticate", challenge)
+	}
+	writeError(w, http.StatusUnauthorized, domain.UnauthorizedCode, "Unauthorized: "+err.Error())
+}
+
+// WriteForbidden writes a 403 response with a JSON-RPC error and an optional
+// WWW-Authenticate challenge.
+func WriteForbidden(w http.ResponseWriter, challenge string, message string) {
+	if challenge != "" {
+		w.Header().Set("WWW-Authenticate", challenge)
+	}
+	writeError(w, http.StatusForbidden, domain.ForbiddenCode, "Forbidden: "+message)
+}
+
+// writeError writes a JSON-RPC error response with the given HTTP status.
+func writeError(w http.ResponseWriter, status int, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(domain.CreateErrorResponse("2.0", nil, code, message))
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header.
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(header[7:])
+}
@@ -0,0 +1,285 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	alice := &domain.Principal{ID: "alice", Roles: []string{"admin"}}
+	authenticator := NewAPIKeyAuthenticator(map[string]*domain.Principal{"secret-key": alice})
+
+	tests := []struct {
+		name      string
+		header    string
+		value     string
+		principal *domain.Principal
+		err       error
+	}{
+		{name: "API key header", header: DefaultAPIKeyHeader, value: "secret-key", principal: alice},
+		{name: "Bearer token", header: "Authorization", value: "Bearer secret-key", principal: alice},
+		{name: "Wrong key", header: DefaultAPIKeyHeader, value: "other-key", err: ErrInvalidCredentials},
+		{name: "No credentials", err: ErrMissingCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.value)
+			}
+
+			principal, err := authenticator.Authenticate(r)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				assert.Nil(t, principal)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.principal, principal)
+		})
+	}
+}
+
+// testHMACSecret is a shared secret of the minimum length.
+var testHMACSecret = []byte("test-secret-of-at-least-32-bytes")
+
+// newTestHMACAuthenticator creates an HMACTokenAuthenticator with testHMACSecret.
+func newTestHMACAuthenticator(t *testing.T, opts ...HMACOption) *HMACTokenAuthenticator {
+	t.Helper()
+	authenticator, err := NewHMACTokenAuthenticator(testHMACSecret, opts...)
+	require.NoError(t, err)
+	return authenticator
+}
+
+func TestNewHMACTokenAuthenticator_ShortSecret(t *testing.T) {
+	for _, secret := range [][]byte{nil, []byte("short"), testHMACSecret[:MinHMACSecretLength-1]} {
+		_, err := NewHMACTokenAuthenticator(secret)
+		assert.Error(t, err, "secret of %d bytes", len(secret))
+	}
+}
+
+func TestHMACTokenAuthenticator(t *testing.T) {
+	secret := testHMACSecret
+	now := time.Now()
+
+	sign := func(claims Claims) string {
+		token, err := SignHMACToken(secret, claims)
+		require.NoError(t, err)
+		return token
+	}
+
+	authenticator := newTestHMACAuthenticator(t, WithHMACIssuer("cortex"), WithHMACAudience("mcp"))
+	exp := now.Add(time.Hour).Unix()
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{
+			name:  "Valid token",
+			token: sign(Claims{"sub": "bob", "iss": "cortex", "aud": "mcp", "scope": "tools:read tools:call", "roles": []string{"reader"}, "exp": now.Add(time.Hour).Unix()}),
+		},
+		{
+			name:  "Expired token",
+			token: sign(Claims{"sub": "bob", "iss": "cortex", "aud": "mcp", "exp": now.Add(-time.Hour).Unix()}),
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name:  "Wrong issuer",
+			token: sign(Claims{"sub": "bob", "iss": "other", "aud": "mcp", "exp": exp}),
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name:  "Wrong audience",
+			token: sign(Claims{"sub": "bob", "iss": "cortex", "aud": []string{"other"}, "exp": exp}),
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name: "Wrong secret",
+			token: func() string {
+				token, _ := SignHMACToken([]byte("other-secret-of-at-least-32-bytes"), Claims{"sub": "bob", "exp": exp})
+				return token
+			}(),
+			err: ErrInvalidCredentials,
+		},
+		{
+			name:  "Token without expiry",
+			token: sign(Claims{"sub": "bob", "iss": "cortex", "aud": "mcp"}),
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name:  "Token without subject",
+			token: sign(Claims{"iss": "cortex", "aud": "mcp", "exp": exp}),
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name:  "Token with empty subject",
+			token: sign(Claims{"sub": "", "iss": "cortex", "aud": "mcp", "exp": exp}),
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name:  "Malformed token",
+			token: "not-a-token",
+			err:   ErrInvalidCredentials,
+		},
+		{
+			name: "Missing token",
+			err:  ErrMissingCredentials,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			principal, err := authenticator.Authenticate(r)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "bob", principal.ID)
+			assert.Equal(t, []string{"tools:read", "tools:call"}, principal.Scopes)
+			assert.True(t, principal.HasRole("reader"))
+		})
+	}
+}
+
+func TestChainAuthenticator(t *testing.T) {
+	authenticator := NewChainAuthenticator(
+		NewAPIKeyAuthenticator(map[string]*domain.Principal{"key": {ID: "service"}}),
+		newTestHMACAuthenticator(t),
+	)
+
+	// API key is accepted by the first authenticator
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set(DefaultAPIKeyHeader, "key")
+	principal, err := authenticator.Authenticate(r)
+	require.NoError(t, err)
+	assert.Equal(t, "service", principal.ID)
+
+	// Bearer token is accepted by the second authenticator
+	token, err := SignHMACToken(testHMACSecret, Claims{"sub": "user", "exp": time.Now().Add(time.Hour).Unix()})
+	require.NoError(t, err)
+	r = httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	principal, err = authenticator.Authenticate(r)
+	require.NoError(t, err)
+	assert.Equal(t, "user", principal.ID)
+
+	// Invalid credentials are reported over missing ones
+	r = httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("Authorization", "Bearer nope")
+	_, err = authenticator.Authenticate(r)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestChainAuthenticator_InsufficientScope(t *testing.T) {
+	issuer, rs := newTestResourceServer(t)
+	token, err := issuer.Issue("alice", "https://mcp.example.com", []string{"mcp:read"}, time.Minute)
+	require.NoError(t, err)
+
+	// The token is valid for the resource server but lacks its scope, and is
+	// rejected as invalid by the HMAC authenticator tried after it
+	authenticator := NewChainAuthenticator(rs, newTestHMACAuthenticator(t))
+	handler := Middleware(authenticator, nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	_, err = authenticator.Authenticate(r)
+	assert.ErrorIs(t, err, ErrInsufficientScope)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), `error="insufficient_scope"`)
+}
+
+func TestMiddleware(t *testing.T) {
+	authenticator := NewAPIKeyAuthenticator(map[string]*domain.Principal{"key": {ID: "alice"}})
+
+	var seen *domain.Principal
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = domain.PrincipalFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := Middleware(authenticator, nil, next)
+
+	// Unauthenticated requests are rejected with a challenge
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/jsonrpc", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, defaultChallenge, w.Header().Get("WWW-Authenticate"))
+	assert.Contains(t, w.Body.String(), "Unauthorized")
+	assert.Nil(t, seen)
+
+	// Authenticated requests carry the principal in their context
+	r := httptest.NewRequest(http.MethodPost, "/jsonrpc", nil)
+	r.Header.Set(DefaultAPIKeyHeader, "key")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, seen)
+	assert.Equal(t, "alice", seen.ID)
+}
+
+func TestClientCertAuthenticator(t *testing.T) {
+	identity := &domain.ClientIdentity{
+		Subject:     "CN=agent",
+		CommonName:  "agent",
+		URIs:        []string{"spiffe://example.org/agent"},
+		Fingerprint: "ab12",
+	}
+	request := func(identity *domain.ClientIdentity) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		if identity != nil {
+			r = r.WithContext(domain.ContextWithClientIdentity(r.Context(), identity))
+		}
+		return r
+	}
+
+	t.Run("default principal", func(t *testing.T) {
+		authenticator := NewClientCertAuthenticator()
+
+		principal, err := authenticator.Authenticate(request(identity))
+		require.NoError(t, err)
+		assert.Equal(t, "spiffe://example.org/agent", principal.ID)
+		assert.Equal(t, "ab12", principal.Claims["fingerprint"])
+
+		_, err = authenticator.Authenticate(request(nil))
+		assert.ErrorIs(t, err, ErrMissingCredentials)
+
+		_, err = authenticator.Authenticate(request(&domain.ClientIdentity{Fingerprint: "cd34"}))
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("custom principal", func(t *testing.T) {
+		authenticator := NewClientCertAuthenticator(WithClientCertPrincipal(func(identity *domain.ClientIdentity) (*domain.Principal, error) {
+			if identity.CommonName != "agent" {
+				return nil, ErrInvalidCredentials
+			}
+			return &domain.Principal{ID: identity.CommonName, Roles: []string{"agent"}}, nil
+		}))
+
+		principal, err := authenticator.Authenticate(request(identity))
+		require.NoError(t, err)
+		assert.Equal(t, "agent", principal.ID)
+		assert.True(t, principal.HasRole("agent"))
+
+		_, err = authenticator.Authenticate(request(&domain.ClientIdentity{CommonName: "intruder"}))
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+// ClientCertAuthenticator authenticates requests by the TLS client certificate
+// verified during a mutual TLS handshake. It relies on the client identity
+// attached to the request context by the HTTP transport.
+type ClientCertAuthenticator struct {
+	principal func(identity *domain.ClientIdentity) (*domain.Principal, error)
+}
+
+// ClientCertOption defines a function type for configuring ClientCertAuthenticator
+type ClientCertOption func(*ClientCertAuthenticator)
+
+// WithClientCertPrincipal sets the function that maps a verified client identity
+// to a principal, e.g. to grant roles by common name. Returning an error rejects
+// the request.
+func WithClientCertPrincipal(fn func(identity *domain.ClientIdentity) (*domain.Principal, error)) ClientCertOption {
+	return func(a *ClientCertAuthenticator) {
+		a.principal = fn
+	}
+}
+
+// NewClientCertAuthenticator creates a new ClientCertAuthenticator. By default
+// the principal ID is the most specific name of the certificate, see
+// domain.ClientIdentity.Name.
+func NewClientCertAuthenticator(opts ...ClientCertOption) *ClientCertAuthenticator {
+	a := &ClientCertAuthenticator{principal: defaultClientCertPrincipal}
+
+	// Apply all options
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// Authenticate returns the principal for the verified client certificate of the request.
+func (a *ClientCertAuthenticator) Authenticate(r *http.Request) (*domain.Principal, error) {
+	identity := domain.ClientIdentityFromContext(r.Context())
+	if identity == nil {
+		return nil, ErrMissingCredentials
+	}
+
+	principal, err := a.principal(identity)
+	if err != nil {
+		return nil, err
+	}
+	if principal == nil {
+		return nil, ErrInvalidCredentials
+	}
+	return principal, nil
+}
+
+// Challenge returns an empty challenge, as client certificates are not requested
+// through the WWW-Authenticate header.
+func (a *ClientCertAuthenticator) Challenge(r *http.Request, err error) string {
+	return ""
+}
+
+// defaultClientCertPrincipal maps a client identity to a principal named after
+// the certificate.
+func defaultClientCertPrincipal(identity *domain.ClientIdentity) (*domain.Principal, error) {
+	name := identity.Name()
+	if name == "" {
+		return nil, ErrInvalidCredentials
+	}
+	return &domain.Principal{
+		ID: name,
+		Claims: map[string]interface{}{
+			"subject":     identity.Subject,
+			"issuer":      identity.Issuer,
+			"fingerprint": identity.Fingerprint,
+		},
+	}, nil
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"net/http"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+// MinHMACSecretLength is the minimum length of the shared secret of an
+// HMACTokenAuthenticator, the output size of SHA-256.
+const MinHMACSecretLength = 32
+
+// hmacAlgorithms maps the supported JWS HMAC algorithms to their hash functions.
+var hmacAlgorithms = map[string]func() hash.Hash{
+	"HS256": sha256.New,
+	"HS384": sha512.New384,
+	"HS512": sha512.New,
+}
+
+// HMACTokenAuthenticator authenticates bearer tokens that are JSON Web Tokens
+// signed with a shared HMAC secret (HS256, HS384 or HS512).
+// The "sub" claim becomes the principal ID, "roles" its roles and "scope" its
+// scopes. Tokens must carry a "sub" and an "exp" claim.
+type HMACTokenAuthenticator struct {
+	secret   []byte
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+// HMACOption defines a function type for configuring HMACTokenAuthenticator
+type HMACOption func(*HMACTokenAuthenticator)
+
+// WithHMACIssuer requires tokens to carry the given "iss" claim
+func WithHMACIssuer(issuer string) HMACOption {
+	return func(a *HMACTokenAuthenticator) {
+		a.issuer = issuer
+	}
+}
+
+// WithHMACAudience requires tokens to carry the given "aud" claim
+func WithHMACAudience(audience string) HMACOption {
+	return func(a *HMACTokenAuthenticator) {
+		a.audience = audience
+	}
+}
+
+// WithHMACLeeway sets the allowed clock skew when checking "exp" and "nbf"
+func WithHMACLeeway(leeway time.Duration) HMACOption {
+	return func(a *HMACTokenAuthenticator) {
+		a.leeway = leeway
+	}
+}
+
+// NewHMACTokenAuthenticator creates a new HMACTokenAuthenticator using the given
+// shared secret, which must be at least MinHMACSecretLength bytes long.
+func NewHMACTokenAuthenticator(secret []byte, opts ...HMACOption) (*HMACTokenAuthenticator, error) {
+	if len(secret) < MinHMACSecretLength {
+		return nil, fmt.Errorf("HMAC secret must be at least %d bytes long", MinHMACSecretLength)
+	}
+
+	a := &HMACTokenAuthenticator{
+		secret: secret,
+		leeway: 30 * time.Second,
+		now:    time.Now,
+	}
+
+	// Apply all options
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a, nil
+}
+
+// Authenticate verifies the bearer token of the request and returns its principal.
+func (a *HMACTokenAuthenticator) Authenticate(r *http.Request) (*domain.Principal, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	claims, err := a.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+	return claims.Principal(), nil
+}
+
+// Verify checks the signature and registered claims of a token and returns its claims.
+func (a *HMACTokenAuthenticator) Verify(token string) (Claims, error) {
+	parsed, err := parseJWT(token)
+	if err != nil {
+		return nil, err
+	}
+
+	newHash, ok := hmacAlgorithms[parsed.header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported signing algorithm %q", ErrInvalidCredentials, parsed.header.Alg)
+	}
+
+	mac := hmac.New(newHash, a.secret)
+	mac.Write(parsed.signingInput)
+	if !hmac.Equal(mac.Sum(nil), parsed.signature) {
+		return nil, fmt.Errorf("%w: invalid token signature", ErrInvalidCredentials)
+	}
+
+	if err := parsed.claims.validateTimes(a.now(), a.leeway); err != nil {
+		return nil, err
+	}
+	if parsed.claims.Subject() == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+	if a.issuer != "" && parsed.claims.Issuer() != a.issuer {
+		return nil, fmt.Errorf("%w: token issuer does not match", ErrInvalidCredentials)
+	}
+	if err := parsed.claims.validateAudience(a.audience); err != nil {
+		return nil, err
+	}
+
+	return parsed.claims, nil
+}
+
+// SignHMACToken issues an HS256 signed token carrying the given claims.
+// It is intended for trusted token issuers and tests.
+func SignHMACToken(secret []byte, claims Claims) (string, error) {
+	header := jwtHeader{Alg: "HS256", Typ: "JWT"}
+	return signJWT(header, claims, func(signingInput []byte) ([]byte, error) {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signingInput)
+		return mac.Sum(nil), nil
+	})
+}
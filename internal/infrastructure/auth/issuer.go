@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LocalIssuer is a minimal in-process token issuer that signs RS256 access tokens.
+// It is meant for tests and local development against an OAuthResourceServer,
+// not as a replacement for a real authorization server.
+type LocalIssuer struct {
+	issuer string
+	kid    string
+	key    *rsa.PrivateKey
+}
+
+// NewLocalIssuer creates a new LocalIssuer with a freshly generated RSA key.
+func NewLocalIssuer(issuer string) (*LocalIssuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate issuer key: %w", err)
+	}
+
+	return &LocalIssuer{
+		issuer: issuer,
+		kid:    uuid.New().String(),
+		key:    key,
+	}, nil
+}
+
+// Issuer returns the issuer identifier placed in the "iss" claim.
+func (i *LocalIssuer) Issuer() string {
+	return i.issuer
+}
+
+// Issue signs an access token for the subject with the given audience, scopes and lifetime.
+func (i *LocalIssuer) Issue(subject, audience string, scopes []string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		"iss": i.issuer,
+		"sub": subject,
+		"aud": audience,
+		"iat": now.Unix(),
+		"exp": now.Add(ttl).Unix(),
+		"jti": uuid.New().String(),
+	}
+	if len(scopes) > 0 {
+		claims["scope"] = strings.Join(scopes, " ")
+	}
+	return i.Sign(claims)
+}
+
+// Sign signs an access token carrying the given claims as-is.
+func (i *LocalIssuer) Sign(claims Claims) (string, error) {
+	header := jwtHeader{Alg: "RS256", Kid: i.kid, Typ: "at+jwt"}
+	return signJWT(header, claims, func(signingInput []byte) ([]byte, error) {
+		digest := sha256.Sum256(signingInput)
+		return rsa.SignPKCS1v15(rand.Reader, i.key, crypto.SHA256, digest[:])
+	})
+}
+
+// KeySet returns a KeySet holding the issuer's public key.
+func (i *LocalIssuer) KeySet() *KeySet {
+	ks := NewKeySet()
+	ks.AddKeyForAlgorithm(i.kid, "RS256", &i.key.PublicKey)
+	return ks
+}
+
+// JWKS returns the issuer's public key as a JSON Web Key Set document.
+func (i *LocalIssuer) JWKS() ([]byte, error) {
+	return json.Marshal(jsonWebKeySet{
+		Keys: []JSONWebKey{NewRSAJSONWebKey(i.kid, &i.key.PublicKey)},
+	})
+}
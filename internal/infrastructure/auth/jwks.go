@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+)
+
+// JSONWebKey is a public key in JSON Web Key format (RFC 7517).
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// jsonWebKeySet is the JSON representation of a JWKS document.
+type jsonWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// KeySet holds the public keys used to verify access token signatures.
+type KeySet struct {
+	mu   sync.RWMutex
+	keys map[string]keySetEntry
+}
+
+// keySetEntry is a public key and the algorithm it is restricted to, if any.
+type keySetEntry struct {
+	key crypto.PublicKey
+	alg string
+}
+
+// NewKeySet creates an empty KeySet.
+func NewKeySet() *KeySet {
+	return &KeySet{
+		keys: make(map[string]keySetEntry),
+	}
+}
+
+// ParseJWKS parses a JSON Web Key Set document.
+// Keys with unsupported types or that are not meant for signatures are skipped.
+func ParseJWKS(data []byte) (*KeySet, error) {
+	var document jsonWebKeySet
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	ks := NewKeySet()
+	for _, jwk := range document.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q in JWKS: %w", jwk.Kid, err)
+		}
+		ks.AddKeyForAlgorithm(jwk.Kid, jwk.Alg, key)
+	}
+
+	if ks.Len() == 0 {
+		return nil, errors.New("JWKS contains no usable signing keys")
+	}
+	return ks, nil
+}
+
+// LoadJWKSFile reads and parses a JSON Web Key Set from a file.
+func LoadJWKSFile(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+	return ParseJWKS(data)
+}
+
+// AddKey adds or replaces the public key with the given key ID.
+func (ks *KeySet) AddKey(kid string, key crypto.PublicKey) {
+	ks.AddKeyForAlgorithm(kid, "", key)
+}
+
+// AddKeyForAlgorithm adds or replaces the public key with the given key ID,
+// which only verifies tokens signed with alg. An empty alg allows every
+// algorithm that matches the key type.
+func (ks *KeySet) AddKeyForAlgorithm(kid, alg string, key crypto.PublicKey) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys[kid] = keySetEntry{key: key, alg: alg}
+}
+
+// Key returns the public key with the given key ID. If kid is empty and the
+// set holds exactly one key, that key is returned.
+func (ks *KeySet) Key(kid string) (crypto.PublicKey, bool) {
+	entry, ok := ks.entry(kid)
+	return entry.key, ok
+}
+
+// entry returns the key with the given key ID along with its algorithm.
+func (ks *KeySet) entry(kid string) (keySetEntry, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if entry, ok := ks.keys[kid]; ok {
+		return entry, true
+	}
+	if kid == "" && len(ks.keys) == 1 {
+		for _, entry := range ks.keys {
+			return entry, true
+		}
+	}
+	return keySetEntry{}, false
+}
+
+// Len returns the number of keys in the set.
+func (ks *KeySet) Len() int {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return len(ks.keys)
+}
+
+// PublicKey decodes the JSON Web Key into an RSA or ECDSA public key.
+func (k JSONWebKey) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %w", err)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x coordinate: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y coordinate: %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// NewRSAJSONWebKey encodes an RSA public key as a JSON Web Key.
+func NewRSAJSONWebKey(kid string, key *rsa.PublicKey) JSONWebKey {
+	return JSONWebKey{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// decodeBigInt decodes a base64url encoded big-endian integer.
+func decodeBigInt(value string) (*big.Int, error) {
+	if value == "" {
+		return nil, errors.New("missing value")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// verifySignature checks a JWS signature made with an asymmetric algorithm.
+func verifySignature(alg string, key crypto.PublicKey, signingInput, signature []byte) error {
+	var hashFunc crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hashFunc = crypto.SHA256
+	case "RS384", "ES384":
+		hashFunc = crypto.SHA384
+	case "RS512":
+		hashFunc = crypto.SHA512
+	default:
+		return fmt.Errorf("%w: unsupported signing algorithm %q", ErrInvalidCredentials, alg)
+	}
+
+	digest := hashSum(hashFunc, signingInput)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if alg[0] != 'R' {
+			return fmt.Errorf("%w: algorithm %s does not match RSA key", ErrInvalidCredentials, alg)
+		}
+		if err := rsa.VerifyPKCS1v15(k, hashFunc, digest, signature); err != nil {
+			return fmt.Errorf("%w: invalid token signature", ErrInvalidCredentials)
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if alg[0] != 'E' {
+			return fmt.Errorf("%w: algorithm %s does not match EC key", ErrInvalidCredentials, alg)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("%w: invalid token signature", ErrInvalidCredentials)
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return fmt.Errorf("%w: invalid token signature", ErrInvalidCredentials)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unsupported key type %T", ErrInvalidCredentials, key)
+	}
+}
+
+// hashSum returns the digest of data using the given hash function.
+func hashSum(hashFunc crypto.Hash, data []byte) []byte {
+	switch hashFunc {
+	case crypto.SHA384:
+		sum := sha512.Sum384(data)
+		return sum[:]
+	case crypto.SHA512:
+		sum := sha512.Sum512(data)
+		return sum[:]
+	default:
+		sum := sha256.Sum256(data)
+		return sum[:]
+	}
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+// Claims holds the decoded payload of a JSON Web Token.
+type Claims map[string]interface{}
+
+// Subject returns the "sub" claim.
+func (c Claims) Subject() string {
+	sub, _ := c["sub"].(string)
+	return sub
+}
+
+// Issuer returns the "iss" claim.
+func (c Claims) Issuer() string {
+	iss, _ := c["iss"].(string)
+	return iss
+}
+
+// Audience returns the "aud" claim, which may be a single string or a list.
+func (c Claims) Audience() []string {
+	return stringList(c["aud"])
+}
+
+// Scopes returns the granted scopes from the space separated "scope" claim
+// or, failing that, from the "scp" list claim.
+func (c Claims) Scopes() []string {
+	if scope, ok := c["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+	return stringList(c["scp"])
+}
+
+// Roles returns the "roles" claim.
+func (c Claims) Roles() []string {
+	return stringList(c["roles"])
+}
+
+// Principal converts the claims into a domain principal.
+func (c Claims) Principal() *domain.Principal {
+	return &domain.Principal{
+		ID:     c.Subject(),
+		Roles:  c.Roles(),
+		Scopes: c.Scopes(),
+		Claims: c,
+	}
+}
+
+// validateTimes checks the "exp" and "nbf" claims against now, allowing for
+// clock skew. Tokens without an expiry would be valid forever, so "exp" is required.
+func (c Claims) validateTimes(now time.Time, leeway time.Duration) error {
+	exp, ok := c.numericDate("exp")
+	if !ok {
+		return fmt.Errorf("%w: token has no expiry", ErrInvalidCredentials)
+	}
+	if now.After(exp.Add(leeway)) {
+		return fmt.Errorf("%w: token expired", ErrInvalidCredentials)
+	}
+	if nbf, ok := c.numericDate("nbf"); ok && now.Add(leeway).Before(nbf) {
+		return fmt.Errorf("%w: token not yet valid", ErrInvalidCredentials)
+	}
+	return nil
+}
+
+// validateAudience checks that the token was issued for the expected audience.
+func (c Claims) validateAudience(audience string) error {
+	if audience == "" {
+		return nil
+	}
+	for _, aud := range c.Audience() {
+		if aud == audience {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: token audience does not match", ErrInvalidCredentials)
+}
+
+// numericDate returns a claim holding seconds since the epoch as a time.
+func (c Claims) numericDate(key string) (time.Time, bool) {
+	switch v := c[key].(type) {
+	case float64:
+		return time.Unix(int64(v), 0), true
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		return time.Unix(n, 0), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// stringList converts a claim that may be a string or a list of strings.
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	default:
+		return nil
+	}
+}
+
+// jwtHeader is the JOSE header of a signed JSON Web Token.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// parsedJWT holds the parts of a compact serialized JSON Web Token.
+type parsedJWT struct {
+	header       jwtHeader
+	claims       Claims
+	signingInput []byte
+	signature    []byte
+}
+
+// parseJWT splits and decodes a compact serialized token without verifying it.
+func parseJWT(token string) (*parsedJWT, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidCredentials)
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed token header", ErrInvalidCredentials)
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed token header", ErrInvalidCredentials)
+	}
+
+	claimBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed token claims", ErrInvalidCredentials)
+	}
+	var claims Claims
+	if err := json.Unmarshal(claimBytes, &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed token claims", ErrInvalidCredentials)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed token signature", ErrInvalidCredentials)
+	}
+
+	return &parsedJWT{
+		header:       header,
+		claims:       claims,
+		signingInput: []byte(parts[0] + "." + parts[1]),
+		signature:    signature,
+	}, nil
+}
+
+// signJWT serializes and signs a token with the given signing function.
+func signJWT(header jwtHeader, claims Claims, sign func(signingInput []byte) ([]byte, error)) (string, error) {
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal token header: %w", err)
+	}
+	claimBytes, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal token claims: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerBytes) + "." +
+		base64.RawURLEncoding.EncodeToString(claimBytes)
+
+	signature, err := sign([]byte(signingInput))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+// ProtectedResourceMetadataPath is the well-known path of the OAuth 2.0
+// protected resource metadata document (RFC 9728).
+const ProtectedResourceMetadataPath = "/.well-known/oauth-protected-resource"
+
+// MetadataPath returns the path of the protected resource metadata document of
+// a resource served under resourcePath: the well-known path is inserted before
+// it (RFC 9728, section 3.1), e.g. /.well-known/oauth-protected-resource/api/mcp
+// for /api/mcp.
+func MetadataPath(resourcePath string) string {
+	return ProtectedResourceMetadataPath + strings.TrimSuffix(resourcePath, "/")
+}
+
+// ErrInsufficientScope is returned when a valid token lacks a required scope.
+var ErrInsufficientScope = errors.New("insufficient scope")
+
+// OAuthConfig contains configuration for an OAuth 2.1 protected resource.
+type OAuthConfig struct {
+	// Resource is the canonical URI of this MCP server, e.g. "https://mcp.example.com".
+	// Tokens must carry it in their "aud" claim unless Audience is set.
+	Resource string
+
+	// AuthorizationServers lists the issuer URLs of the trusted authorization servers.
+	AuthorizationServers []string
+
+	// Issuer, if set, must match the "iss" claim of every token.
+	Issuer string
+
+	// Audience overrides the expected "aud" claim. Defaults to Resource.
+	Audience string
+
+	// RequiredScopes must all be granted to the token.
+	RequiredScopes []string
+
+	// ScopesSupported is advertised in the metadata document. Defaults to RequiredScopes.
+	ScopesSupported []string
+
+	// Keys verifies token signatures, see LoadJWKSFile and LocalIssuer.KeySet.
+	Keys *KeySet
+
+	// Leeway is the allowed clock skew when checking "exp" and "nbf". Defaults to 30 seconds.
+	Leeway time.Duration
+}
+
+// OAuthResourceServer authenticates requests carrying OAuth 2.1 JWT access tokens
+// and serves the protected resource metadata that MCP clients use to discover
+// the authorization server.
+type OAuthResourceServer struct {
+	config OAuthConfig
+	now    func() time.Time
+}
+
+// NewOAuthResourceServer creates a new OAuthResourceServer with the given configuration.
+func NewOAuthResourceServer(config OAuthConfig) (*OAuthResourceServer, error) {
+	if config.Keys == nil || config.Keys.Len() == 0 {
+		return nil, errors.New("oauth: at least one verification key is required")
+	}
+	if config.Audience == "" {
+		config.Audience = config.Resource
+	}
+	if config.Audience == "" {
+		return nil, errors.New("oauth: resource or audience is required")
+	}
+	if config.ScopesSupported == nil {
+		config.ScopesSupported = config.RequiredScopes
+	}
+	if config.Leeway == 0 {
+		config.Leeway = 30 * time.Second
+	}
+
+	return &OAuthResourceServer{
+		config: config,
+		now:    time.Now,
+	}, nil
+}
+
+// Authenticate validates the bearer access token of the request and returns its principal.
+func (s *OAuthResourceServer) Authenticate(r *http.Request) (*domain.Principal, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	claims, err := s.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+	return claims.Principal(), nil
+}
+
+// Verify checks the signature, lifetime, issuer, audience and scopes of an access token.
+func (s *OAuthResourceServer) Verify(token string) (Claims, error) {
+	parsed, err := parseJWT(token)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := s.config.Keys.entry(parsed.header.Kid)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidCredentials, parsed.header.Kid)
+	}
+	// A key published for one algorithm must not verify tokens of another
+	if key.alg != "" && key.alg != parsed.header.Alg {
+		return nil, fmt.Errorf("%w: algorithm %s does not match key %q", ErrInvalidCredentials, parsed.header.Alg, parsed.header.Kid)
+	}
+	if err := verifySignature(parsed.header.Alg, key.key, parsed.signingInput, parsed.signature); err != nil {
+		return nil, err
+	}
+
+	claims := parsed.claims
+	if err := claims.validateTimes(s.now(), s.config.Leeway); err != nil {
+		return nil, err
+	}
+	if s.config.Issuer != "" && claims.Issuer() != s.config.Issuer {
+		return nil, fmt.Errorf("%w: token issuer does not match", ErrInvalidCredentials)
+	}
+	if err := claims.validateAudience(s.config.Audience); err != nil {
+		return nil, err
+	}
+
+	granted := make(map[string]bool)
+	for _, scope := range claims.Scopes() {
+		granted[scope] = true
+	}
+	for _, scope := range s.config.RequiredScopes {
+		if !granted[scope] {
+			return nil, fmt.Errorf("%w: missing scope %q", ErrInsufficientScope, scope)
+		}
+	}
+
+	return claims, nil
+}
+
+// Challenge returns the WWW-Authenticate header value for a failed request,
+// pointing the client at the protected resource metadata.
+func (s *OAuthResourceServer) Challenge(r *http.Request, err error) string {
+	params := []string{fmt.Sprintf("resource_metadata=%q", s.MetadataURL(r))}
+
+	switch {
+	case errors.Is(err, ErrInsufficientScope):
+		params = append(params,
+			`error="insufficient_scope"`,
+			fmt.Sprintf("scope=%q", strings.Join(s.config.RequiredScopes, " ")),
+		)
+	case errors.Is(err, ErrInvalidCredentials):
+		params = append(params,
+			`error="invalid_token"`,
+			fmt.Sprintf("error_description=%q", err.Error()),
+		)
+	}
+
+	return "Bearer " + strings.Join(params, ", ")
+}
+
+// MetadataPath returns the path of the protected resource metadata document,
+// derived from the path of the resource URI.
+func (s *OAuthResourceServer) MetadataPath() string {
+	u, err := url.Parse(s.config.Resource)
+	if err != nil {
+		return ProtectedResourceMetadataPath
+	}
+	return MetadataPath(u.Path)
+}
+
+// MetadataURL returns the absolute URL of the protected resource metadata
+// document, on the host of the resource URI or else of the request.
+func (s *OAuthResourceServer) MetadataURL(r *http.Request) string {
+	if u, err := url.Parse(s.config.Resource); err == nil && u.Scheme != "" && u.Host != "" {
+		return u.Scheme + "://" + u.Host + s.MetadataPath()
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + s.MetadataPath()
+}
+
+// MetadataHandler returns an http.Handler serving the protected resource metadata document.
+func (s *OAuthResourceServer) MetadataHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		metadata := map[string]interface{}{
+			"resource":                 s.config.Resource,
+			"bearer_methods_supported": []string{"header"},
+		}
+		if len(s.config.AuthorizationServers) > 0 {
+			metadata["authorization_servers"] = s.config.AuthorizationServers
+		}
+		if len(s.config.ScopesSupported) > 0 {
+			metadata["scopes_supported"] = s.config.ScopesSupported
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(metadata)
+	})
+}
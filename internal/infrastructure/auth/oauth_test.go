@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestResourceServer(t *testing.T) (*LocalIssuer, *OAuthResourceServer) {
+	t.Helper()
+
+	issuer, err := NewLocalIssuer("https://auth.example.com")
+	require.NoError(t, err)
+
+	// Load the keys through a JWKS file, as a deployment would
+	jwks, err := issuer.JWKS()
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwks, 0o600))
+	keys, err := LoadJWKSFile(path)
+	require.NoError(t, err)
+
+	rs, err := NewOAuthResourceServer(OAuthConfig{
+		Resource:             "https://mcp.example.com",
+		AuthorizationServers: []string{issuer.Issuer()},
+		Issuer:               issuer.Issuer(),
+		RequiredScopes:       []string{"mcp:tools"},
+		Keys:                 keys,
+	})
+	require.NoError(t, err)
+
+	return issuer, rs
+}
+
+func TestNewOAuthResourceServer_Validation(t *testing.T) {
+	_, err := NewOAuthResourceServer(OAuthConfig{Resource: "https://mcp.example.com"})
+	assert.Error(t, err)
+
+	issuer, err := NewLocalIssuer("https://auth.example.com")
+	require.NoError(t, err)
+	_, err = NewOAuthResourceServer(OAuthConfig{Keys: issuer.KeySet()})
+	assert.Error(t, err)
+}
+
+func TestOAuthResourceServer_Authenticate(t *testing.T) {
+	issuer, rs := newTestResourceServer(t)
+
+	issue := func(audience string, scopes []string, ttl time.Duration) string {
+		token, err := issuer.Issue("carol", audience, scopes, ttl)
+		require.NoError(t, err)
+		return token
+	}
+
+	otherIssuer, err := NewLocalIssuer(issuer.Issuer())
+	require.NoError(t, err)
+	foreignToken, err := otherIssuer.Issue("carol", "https://mcp.example.com", []string{"mcp:tools"}, time.Hour)
+	require.NoError(t, err)
+	unboundToken, err := issuer.Sign(Claims{"iss": issuer.Issuer(), "sub": "carol", "aud": "https://mcp.example.com", "scope": "mcp:tools"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{name: "Valid token", token: issue("https://mcp.example.com", []string{"mcp:tools", "mcp:admin"}, time.Hour)},
+		{name: "Wrong audience", token: issue("https://other.example.com", []string{"mcp:tools"}, time.Hour), err: ErrInvalidCredentials},
+		{name: "Expired token", token: issue("https://mcp.example.com", []string{"mcp:tools"}, -time.Hour), err: ErrInvalidCredentials},
+		{name: "Missing scope", token: issue("https://mcp.example.com", []string{"mcp:read"}, time.Hour), err: ErrInsufficientScope},
+		{name: "Unknown signing key", token: foreignToken, err: ErrInvalidCredentials},
+		{name: "Token without expiry", token: unboundToken, err: ErrInvalidCredentials},
+		{name: "Missing token", err: ErrMissingCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			principal, err := rs.Authenticate(r)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "carol", principal.ID)
+			assert.True(t, principal.HasScope("mcp:admin"))
+		})
+	}
+}
+
+func TestOAuthResourceServer_KeyAlgorithm(t *testing.T) {
+	issuer, err := NewLocalIssuer("https://auth.example.com")
+	require.NoError(t, err)
+	token, err := issuer.Issue("carol", "https://mcp.example.com", nil, time.Hour)
+	require.NoError(t, err)
+
+	verify := func(alg string) error {
+		var document jsonWebKeySet
+		jwks, err := issuer.JWKS()
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(jwks, &document))
+		document.Keys[0].Alg = alg
+		jwks, err = json.Marshal(document)
+		require.NoError(t, err)
+
+		keys, err := ParseJWKS(jwks)
+		require.NoError(t, err)
+		rs, err := NewOAuthResourceServer(OAuthConfig{Resource: "https://mcp.example.com", Keys: keys})
+		require.NoError(t, err)
+		_, err = rs.Verify(token)
+		return err
+	}
+
+	// The issuer signs with RS256
+	assert.NoError(t, verify("RS256"))
+	assert.NoError(t, verify(""))
+	assert.ErrorIs(t, verify("RS512"), ErrInvalidCredentials)
+}
+
+func TestOAuthResourceServer_Challenge(t *testing.T) {
+	_, rs := newTestResourceServer(t)
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	challenge := rs.Challenge(r, ErrMissingCredentials)
+	assert.Equal(t, `Bearer resource_metadata="https://mcp.example.com/.well-known/oauth-protected-resource"`, challenge)
+
+	challenge = rs.Challenge(r, ErrInvalidCredentials)
+	assert.Contains(t, challenge, `error="invalid_token"`)
+
+	challenge = rs.Challenge(r, ErrInsufficientScope)
+	assert.Contains(t, challenge, `error="insufficient_scope"`)
+	assert.Contains(t, challenge, `scope="mcp:tools"`)
+}
+
+func TestOAuthResourceServer_MetadataURL(t *testing.T) {
+	issuer, _ := newTestResourceServer(t)
+
+	tests := []struct {
+		resource string
+		want     string
+	}{
+		{"https://mcp.example.com", "https://mcp.example.com/.well-known/oauth-protected-resource"},
+		{"https://mcp.example.com/", "https://mcp.example.com/.well-known/oauth-protected-resource"},
+		{"https://example.com/api/mcp", "https://example.com/.well-known/oauth-protected-resource/api/mcp"},
+		{"/api/mcp", "http://example.org/.well-known/oauth-protected-resource/api/mcp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.resource, func(t *testing.T) {
+			rs, err := NewOAuthResourceServer(OAuthConfig{Resource: tt.resource, Keys: issuer.KeySet()})
+			require.NoError(t, err)
+			r := httptest.NewRequest(http.MethodPost, "http://example.org/api/mcp", nil)
+			assert.Equal(t, tt.want, rs.MetadataURL(r))
+		})
+	}
+}
+
+func TestOAuthResourceServer_MetadataHandler(t *testing.T) {
+	_, rs := newTestResourceServer(t)
+
+	w := httptest.NewRecorder()
+	rs.MetadataHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, ProtectedResourceMetadataPath, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var metadata map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &metadata))
+	assert.Equal(t, "https://mcp.example.com", metadata["resource"])
+	assert.Equal(t, []interface{}{"https://auth.example.com"}, metadata["authorization_servers"])
+	assert.Equal(t, []interface{}{"mcp:tools"}, metadata["scopes_supported"])
+}
+
+func TestMiddleware_OAuth(t *testing.T) {
+	issuer, rs := newTestResourceServer(t)
+	handler := Middleware(rs, nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Missing token is answered with 401 and a metadata pointer
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/sse", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), "resource_metadata=")
+
+	// Token without the required scope is answered with 403
+	token, err := issuer.Issue("carol", "https://mcp.example.com", []string{"mcp:read"}, time.Hour)
+	require.NoError(t, err)
+	r := httptest.NewRequest(http.MethodPost, "/sse", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), `error="insufficient_scope"`)
+}
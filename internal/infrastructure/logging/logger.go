@@ -19,7 +19,7 @@ type Logger struct {
 }
 
 // Fields is a type alias for key-value pairs
-type Fields map[string]interface{}
+type Fields = map[string]interface{}
 
 // LogLevel represents the log severity level
 type LogLevel string
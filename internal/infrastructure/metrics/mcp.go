@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+// MCPMetrics holds the request and tool metrics of an MCP server.
+type MCPMetrics struct {
+	registry        *Registry
+	requests        *Counter
+	requestDuration *Histogram
+	toolCalls       *Counter
+	toolErrors      *Counter
+	toolDuration    *Histogram
+}
+
+// NewMCPMetrics registers the MCP server metrics with the registry.
+func NewMCPMetrics(registry *Registry) *MCPMetrics {
+	return &MCPMetrics{
+		registry: registry,
+		requests: registry.NewCounter(
+			"mcp_requests_total",
+			"Total number of JSON-RPC requests by method and status.",
+			"method", "status",
+		),
+		requestDuration: registry.NewHistogram(
+			"mcp_request_duration_seconds",
+			"Latency of JSON-RPC requests by method.",
+			nil, "method",
+		),
+		toolCalls: registry.NewCounter(
+			"mcp_tool_calls_total",
+			"Total number of tool calls by tool and status.",
+			"tool", "status",
+		),
+		toolErrors: registry.NewCounter(
+			"mcp_tool_errors_total",
+			"Total number of failed tool calls by tool and reason.",
+			"tool", "reason",
+		),
+		toolDuration: registry.NewHistogram(
+			"mcp_tool_call_duration_seconds",
+			"Latency of tool calls by tool.",
+			nil, "tool",
+		),
+	}
+}
+
+// Registry returns the registry the metrics are registered with.
+func (m *MCPMetrics) Registry() *Registry {
+	return m.registry
+}
+
+// ObserveRequest records a JSON-RPC request.
+func (m *MCPMetrics) ObserveRequest(method string, duration time.Duration, failed bool) {
+	status := "ok"
+	if failed {
+		status = "error"
+	}
+	m.requests.Inc(method, status)
+	m.requestDuration.Observe(duration.Seconds(), method)
+}
+
+// ObserveToolCall records a tool call and, if it failed, the reason.
+func (m *MCPMetrics) ObserveToolCall(tool string, duration time.Duration, err error) {
+	if err != nil {
+		m.toolCalls.Inc(tool, "error")
+		m.toolErrors.Inc(tool, errorReason(err))
+	} else {
+		m.toolCalls.Inc(tool, "ok")
+	}
+	m.toolDuration.Observe(duration.Seconds(), tool)
+}
+
+// errorReason classifies a tool error for the reason label.
+func errorReason(err error) string {
+	var (
+		panicErr     *domain.ToolPanicError
+		forbiddenErr *domain.ToolForbiddenError
+		rateLimitErr *domain.RateLimitError
+		busyErr      *domain.BusyError
+	)
+	switch {
+	case errors.As(err, &panicErr):
+		return "panic"
+	case errors.As(err, &forbiddenErr):
+		return "forbidden"
+	case errors.As(err, &rateLimitErr):
+		return "rate_limited"
+	case errors.As(err, &busyErr):
+		return "busy"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	return "error"
+}
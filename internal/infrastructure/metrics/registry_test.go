@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func render(t *testing.T, registry *Registry) string {
+	t.Helper()
+	var b strings.Builder
+	_, err := registry.WriteTo(&b)
+	require.NoError(t, err)
+	return b.String()
+}
+
+func TestRegistry_Counter(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounter("test_total", "A test counter.", "method")
+
+	counter.Inc("tools/call")
+	counter.Add(2, "tools/call")
+	counter.Inc(`say "hi"`)
+	counter.Add(-1, "tools/call") // Counters never decrease
+
+	assert.Equal(t, float64(3), counter.Value("tools/call"))
+	assert.Equal(t, float64(0), counter.Value("missing"))
+
+	expected := `# HELP test_total A test counter.
+# TYPE test_total counter
+test_total{method="say \"hi\""} 1
+test_total{method="tools/call"} 3
+`
+	assert.Equal(t, expected, render(t, registry))
+}
+
+func TestRegistry_Histogram(t *testing.T) {
+	registry := NewRegistry()
+	histogram := registry.NewHistogram("latency_seconds", "A test histogram.", []float64{1, 0.1}, "tool")
+
+	histogram.Observe(0.05, "echo")
+	histogram.Observe(0.5, "echo")
+	histogram.Observe(5, "echo")
+
+	assert.Equal(t, uint64(3), histogram.Count("echo"))
+
+	expected := `# HELP latency_seconds A test histogram.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{tool="echo",le="0.1"} 1
+latency_seconds_bucket{tool="echo",le="1"} 2
+latency_seconds_bucket{tool="echo",le="+Inf"} 3
+latency_seconds_sum{tool="echo"} 5.55
+latency_seconds_count{tool="echo"} 3
+`
+	assert.Equal(t, expected, render(t, registry))
+}
+
+func TestRegistry_FuncMetrics(t *testing.T) {
+	registry := NewRegistry()
+	sessions := 2
+	registry.NewGaugeFunc("sessions_active", "Active sessions.", func() float64 { return float64(sessions) })
+	registry.NewCounterFunc("drops_total", "Dropped events.", func() float64 { return 7 })
+
+	sessions = 3
+	output := render(t, registry)
+	assert.Contains(t, output, "# TYPE sessions_active gauge\nsessions_active 3\n")
+	assert.Contains(t, output, "# TYPE drops_total counter\ndrops_total 7\n")
+
+	// Families are rendered in name order
+	assert.Less(t, strings.Index(output, "drops_total"), strings.Index(output, "sessions_active"))
+
+	// Registering a metric again replaces it
+	registry.NewGaugeFunc("sessions_active", "Active sessions.", func() float64 { return 0 })
+	assert.Contains(t, render(t, registry), "sessions_active 0\n")
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounter("requests_total", "Requests.").Inc()
+
+	w := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "requests_total 1\n")
+
+	w = httptest.NewRecorder()
+	registry.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestMCPMetrics(t *testing.T) {
+	m := NewMCPMetrics(NewRegistry())
+
+	m.ObserveRequest("tools/call", 10*time.Millisecond, false)
+	m.ObserveRequest("tools/call", 10*time.Millisecond, true)
+	m.ObserveToolCall("echo", time.Millisecond, nil)
+	m.ObserveToolCall("echo", time.Millisecond, domain.NewRateLimitError("echo", "session", time.Second))
+	m.ObserveToolCall("db.delete", time.Millisecond, domain.NewToolForbiddenError("db.delete"))
+
+	assert.Equal(t, float64(1), m.requests.Value("tools/call", "ok"))
+	assert.Equal(t, float64(1), m.requests.Value("tools/call", "error"))
+	assert.Equal(t, uint64(2), m.requestDuration.Count("tools/call"))
+	assert.Equal(t, float64(1), m.toolCalls.Value("echo", "ok"))
+	assert.Equal(t, float64(1), m.toolErrors.Value("echo", "rate_limited"))
+	assert.Equal(t, float64(1), m.toolErrors.Value("db.delete", "forbidden"))
+
+	output := render(t, m.Registry())
+	assert.Contains(t, output, `mcp_tool_calls_total{tool="echo",status="error"} 1`)
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrBusClosed is returned when publishing to or subscribing on a closed bus.
+var ErrBusClosed = errors.New("notification bus is closed")
+
+// busSubscription is a handler subscribed to a topic.
+type busSubscription struct {
+	handler func(payload []byte)
+}
+
+// busHandlers holds the handlers subscribed to each topic. It is safe for concurrent use.
+type busHandlers struct {
+	mu     sync.RWMutex
+	topics map[string]map[*busSubscription]struct{}
+}
+
+// add subscribes handler to topic, and reports whether it is the first handler of the topic.
+func (h *busHandlers) add(topic string, handler func(payload []byte)) (*busSubscription, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.topics == nil {
+		h.topics = make(map[string]map[*busSubscription]struct{})
+	}
+	subs, ok := h.topics[topic]
+	if !ok {
+		subs = make(map[*busSubscription]struct{})
+		h.topics[topic] = subs
+	}
+	sub := &busSubscription{handler: handler}
+	subs[sub] = struct{}{}
+	return sub, !ok
+}
+
+// remove unsubscribes a handler from topic, and reports whether it was the last handler of the topic.
+func (h *busHandlers) remove(topic string, sub *busSubscription) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs, ok := h.topics[topic]
+	if !ok {
+		return false
+	}
+	if _, ok := subs[sub]; !ok {
+		return false
+	}
+	delete(subs, sub)
+	if len(subs) > 0 {
+		return false
+	}
+	delete(h.topics, topic)
+	return true
+}
+
+// dispatch calls the handlers subscribed to topic, outside the lock so that
+// handlers may publish and subscribe.
+func (h *busHandlers) dispatch(topic string, payload []byte) {
+	h.mu.RLock()
+	handlers := make([]func(payload []byte), 0, len(h.topics[topic]))
+	for sub := range h.topics[topic] {
+		handlers = append(handlers, sub.handler)
+	}
+	h.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(payload)
+	}
+}
+
+// InMemoryNotificationBus implements a NotificationBus within a process. It
+// delivers messages synchronously, so it suits a single instance, or tests that
+// run several senders side by side.
+type InMemoryNotificationBus struct {
+	handlers busHandlers
+}
+
+// NewInMemoryNotificationBus creates a new InMemoryNotificationBus.
+func NewInMemoryNotificationBus() *InMemoryNotificationBus {
+	return &InMemoryNotificationBus{}
+}
+
+// Publish calls the handlers subscribed to topic with payload before returning.
+func (b *InMemoryNotificationBus) Publish(ctx context.Context, topic string, payload []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.handlers.dispatch(topic, payload)
+	return nil
+}
+
+// Subscribe calls handler with the payload of every message published to topic.
+func (b *InMemoryNotificationBus) Subscribe(topic string, handler func(payload []byte)) (func(), error) {
+	sub, _ := b.handlers.add(topic, handler)
+	var once sync.Once
+	return func() {
+		once.Do(func() { b.handlers.remove(topic, sub) })
+	}, nil
+}
@@ -0,0 +1,534 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Operations of the frames exchanged with a NotificationBroker.
+const (
+	brokerOpChallenge     = "challenge"     // Broker sends a nonce to a new connection
+	brokerOpAuth          = "auth"          // Client answers the challenge with the HMAC of the nonce
+	brokerOpAuthenticated = "authenticated" // Broker accepts the answer
+	brokerOpSubscribe     = "subscribe"     // Client subscribes its connection to a topic
+	brokerOpSubscribed    = "subscribed"    // Broker acknowledges a subscription
+	brokerOpUnsubscribe   = "unsubscribe"   // Client unsubscribes its connection from a topic
+	brokerOpPublish       = "publish"       // Client publishes a message to a topic
+	brokerOpMessage       = "message"       // Broker delivers a message to a subscribed connection
+)
+
+// brokerQueueSize is the number of frames queued for a connection. The broker
+// disconnects connections that let their queue fill up, and clients stop
+// reading while their dispatch queue is full.
+const brokerQueueSize = 1024
+
+// brokerSubscribeTimeout is how long a client waits for the broker to
+// acknowledge a subscription.
+const brokerSubscribeTimeout = 5 * time.Second
+
+// brokerAuthTimeout is how long the broker waits for a new connection to
+// answer its challenge.
+const brokerAuthTimeout = 5 * time.Second
+
+// errBrokerSecretRequired is returned when a broker or a connection to it is
+// created without a shared secret.
+var errBrokerSecretRequired = errors.New("notification broker requires a shared secret")
+
+// brokerProof returns the answer to a challenge of the broker.
+func brokerProof(secret, nonce []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(nonce)
+	return mac.Sum(nil)
+}
+
+// brokerFrame is a frame exchanged with a NotificationBroker. Frames are
+// encoded as JSON, one per line.
+type brokerFrame struct {
+	Op      string `json:"op"`
+	Topic   string `json:"topic"`
+	Payload []byte `json:"payload,omitempty"`
+}
+
+// NotificationBroker is a reference message broker for the instances of a
+// server, which relays messages published by one connection to every
+// connection subscribed to their topic over TCP or a Unix socket. It keeps no
+// messages, so instances miss what is published while they are disconnected.
+// Production deployments may prefer an existing broker behind their own
+// NotificationBus.
+//
+// Whoever can publish on the bus can post messages to any session and read
+// every notification, so connections must prove that they know the shared
+// secret of the broker before they may subscribe or publish. The secret is
+// never sent, but frames are not encrypted: keep the broker on a private
+// network or a Unix socket, and set NotificationConfig.BusSecret so that
+// instances reject messages that were not signed by another instance.
+type NotificationBroker struct {
+	secret   []byte
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[*brokerConn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// brokerConn is a connection to the broker and the topics it subscribed to.
+type brokerConn struct {
+	conn      net.Conn
+	out       chan brokerFrame
+	topics    map[string]struct{} // Guarded by the mutex of the broker
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// close closes the connection. Only the first call has an effect.
+func (c *brokerConn) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		_ = c.conn.Close()
+	})
+}
+
+// NewNotificationBroker creates a broker that serves no listener yet, see
+// Serve. Connections must authenticate with secret, which must not be empty.
+func NewNotificationBroker(secret []byte) (*NotificationBroker, error) {
+	if len(secret) == 0 {
+		return nil, errBrokerSecretRequired
+	}
+	return &NotificationBroker{secret: secret, conns: make(map[*brokerConn]struct{})}, nil
+}
+
+// ListenNotificationBroker creates a broker listening on the given network and
+// address, e.g. "tcp" and "127.0.0.1:0" or "unix" and a socket path, and
+// serves it in the background until it is closed. Connections must
+// authenticate with secret, see DialNotificationBus.
+func ListenNotificationBroker(network, address string, secret []byte) (*NotificationBroker, error) {
+	broker, err := NewNotificationBroker(secret)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for notification broker: %w", err)
+	}
+	broker.mu.Lock()
+	broker.listener = listener
+	broker.mu.Unlock()
+	go func() { _ = broker.Serve(listener) }()
+	return broker, nil
+}
+
+// Addr returns the address the broker listens on, or nil if it serves no listener.
+func (b *NotificationBroker) Addr() net.Addr {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.listener == nil {
+		return nil
+	}
+	return b.listener.Addr()
+}
+
+// Serve accepts connections on listener until the broker is closed. It
+// returns nil once the broker is closed.
+func (b *NotificationBroker) Serve(listener net.Listener) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		_ = listener.Close()
+		return nil
+	}
+	b.listener = listener
+	b.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			b.mu.Lock()
+			closed := b.closed
+			b.mu.Unlock()
+			if closed || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		b.serveConn(conn)
+	}
+}
+
+// serveConn registers a connection and starts reading and writing its frames.
+func (b *NotificationBroker) serveConn(conn net.Conn) {
+	c := &brokerConn{
+		conn:   conn,
+		out:    make(chan brokerFrame, brokerQueueSize),
+		topics: make(map[string]struct{}),
+		done:   make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		_ = conn.Close()
+		return
+	}
+	b.conns[c] = struct{}{}
+	b.wg.Add(2)
+	b.mu.Unlock()
+
+	go b.writeLoop(c)
+	go b.readLoop(c)
+}
+
+// readLoop handles the frames sent by a connection until it is closed.
+func (b *NotificationBroker) readLoop(c *brokerConn) {
+	defer b.wg.Done()
+	defer b.removeConn(c)
+
+	decoder := json.NewDecoder(c.conn)
+	if !b.authenticate(c, decoder) {
+		return
+	}
+	for {
+		var frame brokerFrame
+		if err := decoder.Decode(&frame); err != nil {
+			return
+		}
+
+		switch frame.Op {
+		case brokerOpSubscribe:
+			b.mu.Lock()
+			c.topics[frame.Topic] = struct{}{}
+			b.mu.Unlock()
+			b.enqueue(c, brokerFrame{Op: brokerOpSubscribed, Topic: frame.Topic})
+		case brokerOpUnsubscribe:
+			b.mu.Lock()
+			delete(c.topics, frame.Topic)
+			b.mu.Unlock()
+		case brokerOpPublish:
+			b.publish(frame.Topic, frame.Payload)
+		default:
+			return
+		}
+	}
+}
+
+// authenticate challenges a new connection to prove that it knows the secret
+// of the broker, and reports whether it did in time.
+func (b *NotificationBroker) authenticate(c *brokerConn, decoder *json.Decoder) bool {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return false
+	}
+	b.enqueue(c, brokerFrame{Op: brokerOpChallenge, Payload: nonce})
+
+	_ = c.conn.SetReadDeadline(time.Now().Add(brokerAuthTimeout))
+	var frame brokerFrame
+	if err := decoder.Decode(&frame); err != nil {
+		return false
+	}
+	if frame.Op != brokerOpAuth || !hmac.Equal(frame.Payload, brokerProof(b.secret, nonce)) {
+		return false
+	}
+	_ = c.conn.SetReadDeadline(time.Time{})
+	b.enqueue(c, brokerFrame{Op: brokerOpAuthenticated})
+	return true
+}
+
+// writeLoop writes the frames queued for a connection until it is closed.
+func (b *NotificationBroker) writeLoop(c *brokerConn) {
+	defer b.wg.Done()
+
+	encoder := json.NewEncoder(c.conn)
+	for {
+		select {
+		case frame := <-c.out:
+			if err := encoder.Encode(frame); err != nil {
+				c.close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// publish queues a message on every connection subscribed to topic.
+func (b *NotificationBroker) publish(topic string, payload []byte) {
+	b.mu.Lock()
+	subscribers := make([]*brokerConn, 0, len(b.conns))
+	for c := range b.conns {
+		if _, ok := c.topics[topic]; ok {
+			subscribers = append(subscribers, c)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, c := range subscribers {
+		b.enqueue(c, brokerFrame{Op: brokerOpMessage, Topic: topic, Payload: payload})
+	}
+}
+
+// enqueue queues a frame for a connection, disconnecting it if its queue is full.
+func (b *NotificationBroker) enqueue(c *brokerConn, frame brokerFrame) {
+	select {
+	case c.out <- frame:
+	case <-c.done:
+	default:
+		// A slow consumer would hold up every publisher
+		c.close()
+	}
+}
+
+// removeConn closes a connection and forgets its subscriptions.
+func (b *NotificationBroker) removeConn(c *brokerConn) {
+	c.close()
+	b.mu.Lock()
+	delete(b.conns, c)
+	b.mu.Unlock()
+}
+
+// Close stops accepting connections, closes the open ones and waits for them to end.
+func (b *NotificationBroker) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	listener := b.listener
+	conns := make([]*brokerConn, 0, len(b.conns))
+	for c := range b.conns {
+		conns = append(conns, c)
+	}
+	b.mu.Unlock()
+
+	var err error
+	if listener != nil {
+		err = listener.Close()
+	}
+	for _, c := range conns {
+		c.close()
+	}
+	b.wg.Wait()
+	return err
+}
+
+// BrokerNotificationBus implements a NotificationBus connected to a
+// NotificationBroker. It does not reconnect: once the connection is lost,
+// Publish and Subscribe return ErrBusClosed and Done is closed.
+type BrokerNotificationBus struct {
+	conn      net.Conn
+	writeMu   sync.Mutex
+	encoder   *json.Encoder
+	subMu     sync.Mutex // Serializes subscriptions, so that acknowledgements arrive in order
+	handlers  busHandlers
+	acks      chan string
+	messages  chan brokerFrame
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// DialNotificationBus connects to the NotificationBroker at the given network
+// and address, and authenticates with the shared secret of the broker.
+func DialNotificationBus(ctx context.Context, network, address string, secret []byte) (*BrokerNotificationBus, error) {
+	if len(secret) == 0 {
+		return nil, errBrokerSecretRequired
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to notification broker: %w", err)
+	}
+	if err := authenticateBroker(ctx, conn, secret); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	b := &BrokerNotificationBus{
+		conn:     conn,
+		encoder:  json.NewEncoder(conn),
+		acks:     make(chan string, 1),
+		messages: make(chan brokerFrame, brokerQueueSize),
+		done:     make(chan struct{}),
+	}
+	go b.readLoop()
+	go b.dispatchLoop()
+	return b, nil
+}
+
+// authenticateBroker answers the challenge of the broker on a new connection.
+func authenticateBroker(ctx context.Context, conn net.Conn, secret []byte) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(brokerAuthTimeout)
+	}
+	_ = conn.SetDeadline(deadline)
+	defer func() { _ = conn.SetDeadline(time.Time{}) }()
+
+	// Frames are read one at a time, so that nothing the broker sends after
+	// authenticating is buffered here
+	var challenge brokerFrame
+	if err := readBrokerFrame(conn, &challenge); err != nil || challenge.Op != brokerOpChallenge {
+		return fmt.Errorf("notification broker did not send a challenge: %v", err)
+	}
+	if err := json.NewEncoder(conn).Encode(brokerFrame{Op: brokerOpAuth, Payload: brokerProof(secret, challenge.Payload)}); err != nil {
+		return fmt.Errorf("failed to authenticate with notification broker: %w", err)
+	}
+	var answer brokerFrame
+	if err := readBrokerFrame(conn, &answer); err != nil || answer.Op != brokerOpAuthenticated {
+		return errors.New("notification broker rejected the shared secret")
+	}
+	return nil
+}
+
+// readBrokerFrame reads one frame, byte by byte up to its newline.
+func readBrokerFrame(conn net.Conn, frame *brokerFrame) error {
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		if _, err := conn.Read(buf); err != nil {
+			return err
+		}
+		if buf[0] == '\n' {
+			return json.Unmarshal(line, frame)
+		}
+		line = append(line, buf[0])
+	}
+}
+
+// Publish sends payload to the subscribers of topic on every connected instance.
+func (b *BrokerNotificationBus) Publish(ctx context.Context, topic string, payload []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.write(ctx, brokerFrame{Op: brokerOpPublish, Topic: topic, Payload: payload})
+}
+
+// Subscribe calls handler with the payload of every message published to
+// topic. It returns once the broker acknowledged the subscription, so that
+// messages published afterwards are delivered.
+func (b *BrokerNotificationBus) Subscribe(topic string, handler func(payload []byte)) (func(), error) {
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+
+	sub, first := b.handlers.add(topic, handler)
+	if first {
+		if err := b.subscribe(topic); err != nil {
+			b.handlers.remove(topic, sub)
+			return nil, err
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.subMu.Lock()
+			defer b.subMu.Unlock()
+			if b.handlers.remove(topic, sub) {
+				_ = b.write(context.Background(), brokerFrame{Op: brokerOpUnsubscribe, Topic: topic})
+			}
+		})
+	}, nil
+}
+
+// subscribe subscribes the connection to topic and waits for the acknowledgement.
+func (b *BrokerNotificationBus) subscribe(topic string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), brokerSubscribeTimeout)
+	defer cancel()
+
+	if err := b.write(ctx, brokerFrame{Op: brokerOpSubscribe, Topic: topic}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case acked := <-b.acks:
+			if acked == topic {
+				return nil
+			}
+		case <-b.done:
+			return ErrBusClosed
+		case <-ctx.Done():
+			return fmt.Errorf("notification broker did not acknowledge subscription to %s: %w", topic, ctx.Err())
+		}
+	}
+}
+
+// write sends a frame to the broker.
+func (b *BrokerNotificationBus) write(ctx context.Context, frame brokerFrame) error {
+	select {
+	case <-b.done:
+		return ErrBusClosed
+	default:
+	}
+
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+
+	deadline, _ := ctx.Deadline()
+	_ = b.conn.SetWriteDeadline(deadline)
+	if err := b.encoder.Encode(frame); err != nil {
+		b.Close()
+		return fmt.Errorf("%w: %v", ErrBusClosed, err)
+	}
+	return nil
+}
+
+// readLoop reads the frames sent by the broker until the connection is closed.
+// Messages are handed to dispatchLoop, so that handlers may subscribe.
+func (b *BrokerNotificationBus) readLoop() {
+	defer b.Close()
+
+	decoder := json.NewDecoder(b.conn)
+	for {
+		var frame brokerFrame
+		if err := decoder.Decode(&frame); err != nil {
+			return
+		}
+
+		switch frame.Op {
+		case brokerOpSubscribed:
+			select {
+			case b.acks <- frame.Topic:
+			case <-b.done:
+				return
+			}
+		case brokerOpMessage:
+			select {
+			case b.messages <- frame:
+			case <-b.done:
+				return
+			}
+		}
+	}
+}
+
+// dispatchLoop calls the handlers of the messages received from the broker.
+func (b *BrokerNotificationBus) dispatchLoop() {
+	for {
+		select {
+		case frame := <-b.messages:
+			b.handlers.dispatch(frame.Topic, frame.Payload)
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// Done returns a channel that is closed once the bus is disconnected from the broker.
+func (b *BrokerNotificationBus) Done() <-chan struct{} {
+	return b.done
+}
+
+// Close disconnects from the broker. Only the first call has an effect.
+func (b *BrokerNotificationBus) Close() {
+	b.closeOnce.Do(func() {
+		close(b.done)
+		_ = b.conn.Close()
+	})
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+// signedMessageMaxAge is how old a signed message may be, or how far in the
+// future, before subscribers drop it. It bounds how long a captured message
+// can be replayed, and must exceed the clock skew between instances.
+const signedMessageMaxAge = time.Minute
+
+// signedMessage is the envelope of a message on a SignedNotificationBus.
+type signedMessage struct {
+	Time      int64  `json:"t"`
+	Payload   []byte `json:"p"`
+	Signature []byte `json:"s"`
+}
+
+// SignedNotificationBus signs the messages published on another bus with a
+// secret shared by the instances, and drops the messages it receives that are
+// unsigned, signed with another secret, or too old. Messages routed to a
+// session carry the principal of the request, so whoever can publish on an
+// unsigned bus can call tools on behalf of any session.
+type SignedNotificationBus struct {
+	bus    domain.NotificationBus
+	secret []byte
+}
+
+// NewSignedNotificationBus creates a bus that signs the messages published on bus with secret.
+func NewSignedNotificationBus(bus domain.NotificationBus, secret []byte) *SignedNotificationBus {
+	return &SignedNotificationBus{bus: bus, secret: secret}
+}
+
+// sign returns the signature of a message published to topic at t.
+func (b *SignedNotificationBus) sign(topic string, t int64, payload []byte) []byte {
+	mac := hmac.New(sha256.New, b.secret)
+	mac.Write([]byte(topic))
+	mac.Write([]byte{0})
+	_ = binary.Write(mac, binary.BigEndian, t)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// Publish signs payload and publishes it to topic.
+func (b *SignedNotificationBus) Publish(ctx context.Context, topic string, payload []byte) error {
+	t := time.Now().UnixNano()
+	data, err := json.Marshal(signedMessage{Time: t, Payload: payload, Signature: b.sign(topic, t, payload)})
+	if err != nil {
+		return err
+	}
+	return b.bus.Publish(ctx, topic, data)
+}
+
+// Subscribe calls handler with the payload of every message published to
+// topic whose signature is valid.
+func (b *SignedNotificationBus) Subscribe(topic string, handler func(payload []byte)) (func(), error) {
+	return b.bus.Subscribe(topic, func(data []byte) {
+		var message signedMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			return
+		}
+		age := time.Since(time.Unix(0, message.Time))
+		if age > signedMessageMaxAge || age < -signedMessageMaxAge {
+			return
+		}
+		if !hmac.Equal(message.Signature, b.sign(topic, message.Time, message.Payload)) {
+			return
+		}
+		handler(message.Payload)
+	})
+}
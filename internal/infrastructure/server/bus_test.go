@@ -0,0 +1,312 @@
+package server_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+	"github.com/FreePeak/cortex/internal/infrastructure/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBrokerSecret is the shared secret of the brokers in tests.
+var testBrokerSecret = []byte("broker-secret")
+
+// testBuses runs a test against the in-memory bus and against buses connected
+// to a broker over TCP and a Unix socket. Each test gets two buses, one per
+// instance.
+func testBuses(t *testing.T, test func(t *testing.T, first, second domain.NotificationBus)) {
+	t.Run("in-memory", func(t *testing.T) {
+		bus := server.NewInMemoryNotificationBus()
+		test(t, bus, bus)
+	})
+
+	dir, err := os.MkdirTemp("", "bus")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, listen := range []struct{ network, address string }{
+		{"tcp", "127.0.0.1:0"},
+		{"unix", filepath.Join(dir, "broker.sock")},
+	} {
+		t.Run("broker-"+listen.network, func(t *testing.T) {
+			broker, err := server.ListenNotificationBroker(listen.network, listen.address, testBrokerSecret)
+			require.NoError(t, err)
+			defer broker.Close()
+
+			ctx := context.Background()
+			first, err := server.DialNotificationBus(ctx, listen.network, broker.Addr().String(), testBrokerSecret)
+			require.NoError(t, err)
+			defer first.Close()
+			second, err := server.DialNotificationBus(ctx, listen.network, broker.Addr().String(), testBrokerSecret)
+			require.NoError(t, err)
+			defer second.Close()
+
+			test(t, first, second)
+		})
+	}
+}
+
+// receive returns the next payload received on ch.
+func receive(t *testing.T, ch <-chan string) string {
+	t.Helper()
+	select {
+	case payload := <-ch:
+		return payload
+	case <-time.After(time.Second):
+		t.Fatal("No message received")
+		return ""
+	}
+}
+
+func TestNotificationBus_PublishSubscribe(t *testing.T) {
+	testBuses(t, func(t *testing.T, first, second domain.NotificationBus) {
+		ctx := context.Background()
+		received := make(chan string, 10)
+		unsubscribe, err := second.Subscribe("topic", func(payload []byte) {
+			received <- string(payload)
+		})
+		require.NoError(t, err)
+
+		// Messages of other topics are not delivered
+		require.NoError(t, first.Publish(ctx, "other", []byte("ignored")))
+		require.NoError(t, first.Publish(ctx, "topic", []byte("hello")))
+		assert.Equal(t, "hello", receive(t, received))
+
+		// Unsubscribed handlers no longer receive messages
+		unsubscribe()
+		again := make(chan string, 1)
+		unsubscribe, err = second.Subscribe("topic", func(payload []byte) {
+			again <- string(payload)
+		})
+		require.NoError(t, err)
+		defer unsubscribe()
+		require.NoError(t, first.Publish(ctx, "topic", []byte("again")))
+		assert.Equal(t, "again", receive(t, again))
+		assert.Empty(t, received)
+	})
+}
+
+func TestBrokerNotificationBus_Closed(t *testing.T) {
+	broker, err := server.ListenNotificationBroker("tcp", "127.0.0.1:0", testBrokerSecret)
+	require.NoError(t, err)
+
+	bus, err := server.DialNotificationBus(context.Background(), "tcp", broker.Addr().String(), testBrokerSecret)
+	require.NoError(t, err)
+
+	require.NoError(t, broker.Close())
+	select {
+	case <-bus.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Bus was not disconnected when the broker closed")
+	}
+
+	assert.ErrorIs(t, bus.Publish(context.Background(), "topic", nil), server.ErrBusClosed)
+	_, err = bus.Subscribe("topic", func([]byte) {})
+	assert.ErrorIs(t, err, server.ErrBusClosed)
+}
+
+func TestNotificationBroker_Authentication(t *testing.T) {
+	_, err := server.ListenNotificationBroker("tcp", "127.0.0.1:0", nil)
+	assert.Error(t, err, "brokers require a secret")
+
+	broker, err := server.ListenNotificationBroker("tcp", "127.0.0.1:0", testBrokerSecret)
+	require.NoError(t, err)
+	defer broker.Close()
+	ctx := context.Background()
+
+	_, err = server.DialNotificationBus(ctx, "tcp", broker.Addr().String(), []byte("wrong-secret"))
+	assert.Error(t, err, "a wrong secret is rejected")
+
+	subscriber, err := server.DialNotificationBus(ctx, "tcp", broker.Addr().String(), testBrokerSecret)
+	require.NoError(t, err)
+	defer subscriber.Close()
+	received := make(chan string, 1)
+	_, err = subscriber.Subscribe("topic", func(payload []byte) { received <- string(payload) })
+	require.NoError(t, err)
+
+	// A connection that publishes without answering the challenge is dropped
+	conn, err := net.Dial("tcp", broker.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte(`{"op":"publish","topic":"topic","payload":"Zm9yZ2Vk"}` + "\n"))
+	require.NoError(t, err)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = io.ReadAll(conn)
+	assert.NoError(t, err, "the broker closes the connection")
+	select {
+	case payload := <-received:
+		t.Fatalf("Unauthenticated message delivered: %s", payload)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSignedNotificationBus(t *testing.T) {
+	ctx := context.Background()
+	bus := server.NewInMemoryNotificationBus()
+	signed := server.NewSignedNotificationBus(bus, []byte("secret"))
+	received := make(chan string, 10)
+	unsubscribe, err := signed.Subscribe("topic", func(payload []byte) { received <- string(payload) })
+	require.NoError(t, err)
+	defer unsubscribe()
+
+	// Unsigned messages and messages signed with another secret are dropped
+	require.NoError(t, bus.Publish(ctx, "topic", []byte(`{"p":"Zm9yZ2Vk"}`)))
+	require.NoError(t, server.NewSignedNotificationBus(bus, []byte("other")).Publish(ctx, "topic", []byte("forged")))
+	assert.Empty(t, received)
+
+	require.NoError(t, signed.Publish(ctx, "topic", []byte("hello")))
+	assert.Equal(t, "hello", receive(t, received))
+
+	// Signatures cover the topic, so signed messages cannot be replayed to another one
+	var replayed []byte
+	unsubscribeRaw, err := bus.Subscribe("other", func(payload []byte) { replayed = payload })
+	require.NoError(t, err)
+	defer unsubscribeRaw()
+	require.NoError(t, signed.Publish(ctx, "other", []byte("elsewhere")))
+	require.NoError(t, bus.Publish(ctx, "topic", replayed))
+	assert.Empty(t, received)
+}
+
+func TestNotificationSender_Bus(t *testing.T) {
+	testBuses(t, func(t *testing.T, first, second domain.NotificationBus) {
+		ctx := context.Background()
+		senderA := server.NewNotificationSenderWithConfig("2.0", server.NotificationConfig{Bus: first})
+		senderB := server.NewNotificationSenderWithConfig("2.0", server.NotificationConfig{Bus: second})
+		defer senderA.Close()
+		defer senderB.Close()
+		assert.NotEqual(t, senderA.InstanceID(), senderB.InstanceID())
+
+		sessionA := server.NewMCPSession("session-a", "agent", 10)
+		sessionB := server.NewMCPSession("session-b", "agent", 10)
+		senderA.RegisterSession(sessionA)
+		senderB.RegisterSession(sessionB)
+		defer senderA.UnregisterSession("session-a")
+		defer senderB.UnregisterSession("session-b")
+
+		// Broadcasts reach the sessions of every instance once
+		require.NoError(t, senderA.BroadcastNotification(ctx, &domain.Notification{Method: "broadcast"}))
+		select {
+		case notification := <-sessionB.NotificationChannel():
+			assert.Equal(t, "broadcast", notification.Method)
+		case <-time.After(time.Second):
+			t.Fatal("Broadcast did not reach the session of the other instance")
+		}
+		assert.Equal(t, "broadcast", (<-sessionA.NotificationChannel()).Method)
+
+		// Notifications for a session of another instance are relayed to it
+		require.NoError(t, senderA.SendNotification(ctx, "session-b", &domain.Notification{Method: "direct"}))
+		select {
+		case notification := <-sessionB.NotificationChannel():
+			assert.Equal(t, "direct", notification.Method)
+		case <-time.After(time.Second):
+			t.Fatal("Notification did not reach the session of the other instance")
+		}
+
+		assert.Empty(t, sessionA.NotificationChannel())
+		assert.Empty(t, sessionB.NotificationChannel())
+	})
+}
+
+func TestNotificationSender_BusNotifySessions(t *testing.T) {
+	testBuses(t, func(t *testing.T, first, second domain.NotificationBus) {
+		ctx := context.Background()
+		senderA := server.NewNotificationSenderWithConfig("2.0", server.NotificationConfig{Bus: first})
+		senderB := server.NewNotificationSenderWithConfig("2.0", server.NotificationConfig{Bus: second})
+		defer senderA.Close()
+		defer senderB.Close()
+
+		sessions := make(map[string]*server.MCPSession)
+		for _, tenant := range []string{"a", "b"} {
+			client := domain.NewClientSession("agent")
+			client.SetLabel("tenant", tenant)
+			sessions[tenant] = server.NewMCPSession(client.ID, "agent", 10)
+			sessions[tenant].SetClientSession(client)
+			senderB.RegisterSession(sessions[tenant])
+			defer senderB.UnregisterSession(client.ID)
+		}
+
+		// Selectors are applied to the sessions of the other instances
+		require.NoError(t, senderA.NotifySessions(ctx, domain.LabelFilter("tenant", "a"), &domain.Notification{Method: "tenant/changed"}))
+		select {
+		case notification := <-sessions["a"].NotificationChannel():
+			assert.Equal(t, "tenant/changed", notification.Method)
+		case <-time.After(time.Second):
+			t.Fatal("Filtered notification did not reach the session of the other instance")
+		}
+		assert.Empty(t, sessions["b"].NotificationChannel())
+
+		// Custom filters cannot be relayed
+		custom := domain.SessionFilterFunc(func(*domain.ClientSession) bool { return true })
+		err := senderA.NotifySessions(ctx, custom, &domain.Notification{Method: "custom"})
+		assert.ErrorIs(t, err, server.ErrFilterNotRelayable)
+		assert.Empty(t, sessions["a"].NotificationChannel())
+		assert.Empty(t, sessions["b"].NotificationChannel())
+	})
+}
+
+func TestSSEServer_RoutesMessagesAcrossInstances(t *testing.T) {
+	bus := server.NewInMemoryNotificationBus()
+	newInstance := func() *httptest.Server {
+		notifier := server.NewNotificationSenderWithConfig("2.0", server.NotificationConfig{Bus: bus, BusSecret: []byte("secret")})
+		return httptest.NewServer(server.NewSSEServer(notifier, mockMCPHandler))
+	}
+	streamInstance := newInstance()
+	defer streamInstance.Close()
+	otherInstance := newInstance()
+	defer otherInstance.Close()
+
+	// Open a stream on one instance and read the session ID from the connected event
+	resp, err := http.Get(streamInstance.URL + "/sse")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+
+	var sessionID string
+	for sessionID == "" {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if strings.HasPrefix(line, "data: {\"sessionId\"") {
+			var data struct {
+				SessionID string `json:"sessionId"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &data))
+			sessionID = data.SessionID
+		}
+	}
+
+	// Post a message for the session to the other instance
+	posted, err := http.Post(otherInstance.URL+"/message?sessionId="+sessionID, "application/json",
+		strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"ping"}`))
+	require.NoError(t, err)
+	defer posted.Body.Close()
+	assert.Equal(t, http.StatusOK, posted.StatusCode)
+	assert.Equal(t, "application/json", posted.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(posted.Body)
+	require.NoError(t, err)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &response))
+	assert.Equal(t, float64(7), response["id"])
+	assert.Equal(t, "success", response["result"])
+
+	// The response is also sent on the stream, by the instance holding it
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if strings.HasPrefix(line, "data: ") && strings.Contains(line, `"id":7`) {
+			break
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+// CORSConfig contains the cross-origin policy of the HTTP and SSE endpoints.
+//
+// Requests that carry an Origin header are only served if the origin is
+// allowed, or is the host the request was sent to and that host is listed in
+// AllowedHosts. Requests without an Origin header come from non-browser
+// clients. Requests of either kind are rejected if their Host header is not
+// allowed, which protects servers on local and private networks against DNS
+// rebinding attacks.
+type CORSConfig struct {
+	// AllowedOrigins lists the origins, other than the server itself, that may
+	// access the server, e.g. "https://app.example.com". "*" allows every
+	// origin and a "*" in a pattern matches any port or host label, e.g.
+	// "http://localhost:*". By default cross-origin requests are rejected.
+	AllowedOrigins []string
+
+	// AllowedHosts lists the host names, without port, that requests may be
+	// sent to, e.g. "mcp.example.com". A "*" in a pattern matches any host
+	// label, e.g. "*.example.com", and "*" alone allows every host. The HTTP
+	// transport defaults to DefaultAllowedHosts, so servers reached by other
+	// names must list them.
+	AllowedHosts []string
+
+	// AllowedMethods are returned in preflight responses. Defaults to GET, POST, DELETE and OPTIONS.
+	AllowedMethods []string
+
+	// AllowedHeaders are returned in preflight responses. Defaults to the
+	// headers used by MCP clients.
+	AllowedHeaders []string
+
+	// ExposedHeaders lists the response headers that browsers may read.
+	ExposedHeaders []string
+
+	// AllowCredentials allows cookies and authorization headers on cross-origin requests.
+	AllowCredentials bool
+
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration
+}
+
+// Default CORS values
+var (
+	defaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodDelete, http.MethodOptions}
+	defaultCORSHeaders = []string{"Content-Type", "Authorization", "X-API-Key", "Mcp-Session-Id", "Last-Event-ID"}
+)
+
+// LocalhostOrigins are the origins of pages served from the local machine.
+var LocalhostOrigins = []string{
+	"http://localhost", "http://localhost:*",
+	"https://localhost", "https://localhost:*",
+	"http://127.0.0.1", "http://127.0.0.1:*",
+	"https://127.0.0.1", "https://127.0.0.1:*",
+	"http://[::1]", "http://[::1]:*",
+	"https://[::1]", "https://[::1]:*",
+}
+
+// LocalhostHosts are the host names of the local machine.
+var LocalhostHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// DefaultAllowedHosts returns the hosts that requests may be sent to when no
+// AllowedHosts are configured: the names of the local machine, the host of
+// the listen address and the host of the base URL sent to clients, if any.
+func DefaultAllowedHosts(addr, baseURL string) []string {
+	hosts := append([]string(nil), LocalhostHosts...)
+	if host, _, err := net.SplitHostPort(addr); err == nil && host != "" && !net.ParseIP(host).IsUnspecified() {
+		hosts = append(hosts, host)
+	}
+	if u, err := url.Parse(baseURL); err == nil && u.Hostname() != "" {
+		hosts = append(hosts, u.Hostname())
+	}
+	return hosts
+}
+
+// DefaultCORSConfig returns a CORS configuration that rejects cross-origin
+// requests.
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{}
+}
+
+// LocalhostCORSConfig returns a CORS configuration that only allows pages
+// served from the local machine, and requests sent to it by name.
+func LocalhostCORSConfig() CORSConfig {
+	return CORSConfig{AllowedOrigins: LocalhostOrigins, AllowedHosts: LocalhostHosts}
+}
+
+// CORSPolicy enforces a CORSConfig on HTTP requests.
+type CORSPolicy struct {
+	config CORSConfig
+}
+
+// NewCORSPolicy creates a new CORSPolicy, filling in defaults for unset fields.
+func NewCORSPolicy(config CORSConfig) *CORSPolicy {
+	if len(config.AllowedMethods) == 0 {
+		config.AllowedMethods = defaultCORSMethods
+	}
+	if len(config.AllowedHeaders) == 0 {
+		config.AllowedHeaders = defaultCORSHeaders
+	}
+	return &CORSPolicy{config: config}
+}
+
+// AllowOrigin reports whether the origin may access the server.
+func (p *CORSPolicy) AllowOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range p.config.AllowedOrigins {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" || matchOrigin(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowHost reports whether requests may be sent to the host of a Host header,
+// which may include a port. Every host is allowed without AllowedHosts.
+func (p *CORSPolicy) AllowHost(host string) bool {
+	return len(p.config.AllowedHosts) == 0 || p.listsHost(host)
+}
+
+// listsHost reports whether the host of a Host header is listed in AllowedHosts.
+func (p *CORSPolicy) listsHost(host string) bool {
+	host = strings.ToLower(hostName(host))
+	for _, allowed := range p.config.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" || matchOrigin(allowed, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler returns an http.Handler that validates the Host and Origin headers,
+// answers preflight requests and adds CORS headers before passing requests on
+// to next.
+func (p *CORSPolicy) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !p.AllowHost(r.Host) {
+			forbidden(w, "Forbidden: host not allowed")
+			return
+		}
+
+		origin := r.Header.Get("Origin")
+		if origin != "" {
+			// A page served from a rebound name sends its own host as well, so
+			// only the listed hosts count as the server itself
+			if !(sameOrigin(origin, r.Host) && p.listsHost(r.Host)) && !p.AllowOrigin(origin) {
+				forbidden(w, "Forbidden: origin not allowed")
+				return
+			}
+			p.setOriginHeaders(w, origin)
+		}
+
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Allow", strings.Join(p.config.AllowedMethods, ", "))
+			if origin != "" && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", strings.Join(p.config.AllowedMethods, ", "))
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(p.config.AllowedHeaders, ", "))
+				if p.config.MaxAge > 0 {
+					w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(p.config.MaxAge.Seconds())))
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forbidden rejects a request with a JSON-RPC error.
+func forbidden(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	_ = json.NewEncoder(w).Encode(domain.CreateErrorResponse("2.0", nil, domain.ForbiddenCode, message))
+}
+
+// sameOrigin reports whether a page of origin sent the request to its own
+// host, i.e. the request is not cross-origin.
+func sameOrigin(origin, host string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, host) {
+		return true
+	}
+	// The Host header leaves out the default port of the scheme
+	return u.Port() == "" && strings.EqualFold(hostName(u.Host), hostName(host)) &&
+		((u.Scheme == "http" && strings.HasSuffix(host, ":80")) || (u.Scheme == "https" && strings.HasSuffix(host, ":443")))
+}
+
+// hostName returns the host of a Host header without its port and brackets.
+func hostName(host string) string {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		return name
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+}
+
+// setOriginHeaders adds the CORS response headers for an allowed origin.
+func (p *CORSPolicy) setOriginHeaders(w http.ResponseWriter, origin string) {
+	header := w.Header()
+	header.Add("Vary", "Origin")
+
+	// A wildcard cannot be combined with credentials, so echo the origin instead
+	if p.allowsAnyOrigin() && !p.config.AllowCredentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.config.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(p.config.ExposedHeaders) > 0 {
+		header.Set("Access-Control-Expose-Headers", strings.Join(p.config.ExposedHeaders, ", "))
+	}
+}
+
+// allowsAnyOrigin reports whether the policy allows every origin.
+func (p *CORSPolicy) allowsAnyOrigin() bool {
+	for _, allowed := range p.config.AllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOrigin matches an origin against a pattern in which each "*" stands for
+// a single port or host label, i.e. a run of characters other than "." and "/".
+func matchOrigin(pattern, origin string) bool {
+	star := strings.IndexByte(pattern, '*')
+	if star < 0 {
+		return pattern == origin
+	}
+	if !strings.HasPrefix(origin, pattern[:star]) {
+		return false
+	}
+	pattern, origin = pattern[star+1:], origin[star:]
+
+	// Let the wildcard consume as little as possible while the rest matches
+	for i := 0; ; i++ {
+		if matchOrigin(pattern, origin[i:]) {
+			return true
+		}
+		if i == len(origin) || origin[i] == '.' || origin[i] == '/' {
+			return false
+		}
+	}
+}
+
+// IsLoopbackAddr reports whether a listen address only binds to the loopback
+// interface, e.g. "127.0.0.1:8080" or "localhost:8080".
+func IsLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// LocalhostAddr rewrites a listen address so that it only binds to the loopback
+// interface, e.g. ":8080" becomes "127.0.0.1:8080".
+func LocalhostAddr(addr string) string {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		// Treat a bare port as the port to listen on
+		port = strings.TrimPrefix(addr, ":")
+	}
+	return net.JoinHostPort("127.0.0.1", port)
+}
@@ -0,0 +1,206 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/infrastructure/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORSPolicy_AllowOrigin(t *testing.T) {
+	policy := server.NewCORSPolicy(server.CORSConfig{
+		AllowedOrigins: append([]string{"https://app.example.com"}, server.LocalhostOrigins...),
+	})
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{origin: "https://app.example.com", want: true},
+		{origin: "HTTPS://APP.EXAMPLE.COM", want: true},
+		{origin: "http://localhost:3000", want: true},
+		{origin: "http://127.0.0.1", want: true},
+		{origin: "http://[::1]:8080", want: true},
+		{origin: "https://evil.example.com", want: false},
+		{origin: "http://localhost.evil.com", want: false},
+		{origin: "null", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			assert.Equal(t, tt.want, policy.AllowOrigin(tt.origin))
+		})
+	}
+}
+
+func TestCORSPolicy_Handler(t *testing.T) {
+	policy := server.NewCORSPolicy(server.CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+	handler := policy.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(method, origin string, header map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/message", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("Allowed origin", func(t *testing.T) {
+		w := serve(http.MethodPost, "https://app.example.com", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "Origin", w.Header().Get("Vary"))
+	})
+
+	t.Run("Rejected origin", func(t *testing.T) {
+		w := serve(http.MethodPost, "https://evil.example.com", nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Contains(t, w.Body.String(), "origin not allowed")
+	})
+
+	t.Run("No origin", func(t *testing.T) {
+		w := serve(http.MethodPost, "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("Preflight", func(t *testing.T) {
+		w := serve(http.MethodOptions, "https://app.example.com", map[string]string{
+			"Access-Control-Request-Method": http.MethodPost,
+		})
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "GET, POST, DELETE, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
+		assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+		assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+	})
+}
+
+func TestSSEServer_CORS(t *testing.T) {
+	notifier := server.NewNotificationSender("2.0")
+	srv := server.NewSSEServer(notifier, mockMCPHandler, server.WithLocalhostOnly())
+
+	// Preflight requests for the message endpoint are answered
+	r := httptest.NewRequest(http.MethodOptions, "http://localhost:8080/message?sessionId=abc", nil)
+	r.Header.Set("Origin", "http://localhost:5173")
+	r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "http://localhost:5173", w.Header().Get("Access-Control-Allow-Origin"))
+
+	// Pages from other origins cannot reach a localhost-only server
+	r = httptest.NewRequest(http.MethodPost, "http://localhost:8080/message?sessionId=abc", strings.NewReader(`{}`))
+	r.Header.Set("Origin", "http://attacker.example.com")
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Nor can they by resolving their own name to the loopback address
+	r = httptest.NewRequest(http.MethodPost, "http://attacker.example.com:8080/message?sessionId=abc", strings.NewReader(`{}`))
+	r.Header.Set("Origin", "http://attacker.example.com:8080")
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "host not allowed")
+}
+
+func TestCORSPolicy_Default(t *testing.T) {
+	config := server.DefaultCORSConfig()
+	config.AllowedHosts = server.DefaultAllowedHosts("mcp.example.com:8080", "")
+	handler := server.NewCORSPolicy(config).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		url    string
+		origin string
+		want   int
+	}{
+		{"no origin", "http://mcp.example.com/", "", http.StatusOK},
+		{"same origin", "http://mcp.example.com:8080/", "http://mcp.example.com:8080", http.StatusOK},
+		{"same origin on the default port", "https://mcp.example.com/", "https://mcp.example.com", http.StatusOK},
+		{"cross origin", "http://mcp.example.com:8080/", "https://app.example.com", http.StatusForbidden},
+		{"other port", "http://localhost:8080/", "http://localhost:3000", http.StatusForbidden},
+		{"opaque origin", "http://localhost:8080/", "null", http.StatusForbidden},
+		{"rebound name", "http://attacker.example:8080/", "http://attacker.example:8080", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tt.url, nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestCORSPolicy_AllowHost(t *testing.T) {
+	local := server.NewCORSPolicy(server.LocalhostCORSConfig())
+	assert.True(t, local.AllowHost("localhost:8080"))
+	assert.True(t, local.AllowHost("127.0.0.1"))
+	assert.True(t, local.AllowHost("[::1]:8080"))
+	assert.False(t, local.AllowHost("attacker.example.com:8080"))
+	assert.False(t, local.AllowHost("localhost.attacker.example.com"))
+
+	policy := server.NewCORSPolicy(server.CORSConfig{AllowedHosts: []string{"*.example.com"}})
+	assert.True(t, policy.AllowHost("mcp.example.com:443"))
+	assert.False(t, policy.AllowHost("example.org"))
+
+	// Every host is allowed without AllowedHosts
+	assert.True(t, server.NewCORSPolicy(server.DefaultCORSConfig()).AllowHost("anything.example.org"))
+}
+
+func TestCORSPolicy_SameOriginRequiresListedHost(t *testing.T) {
+	// A page on a rebound name sends its own host as the origin
+	handler := server.NewCORSPolicy(server.DefaultCORSConfig()).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	r := httptest.NewRequest(http.MethodPost, "http://attacker.example/", nil)
+	r.Header.Set("Origin", "http://attacker.example")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "origin not allowed")
+}
+
+func TestDefaultAllowedHosts(t *testing.T) {
+	assert.Equal(t, server.LocalhostHosts, server.DefaultAllowedHosts(":8080", ""))
+	assert.Equal(t, server.LocalhostHosts, server.DefaultAllowedHosts("0.0.0.0:8080", ""))
+	assert.Equal(t, append(append([]string(nil), server.LocalhostHosts...), "mcp.internal", "mcp.example.com"),
+		server.DefaultAllowedHosts("mcp.internal:8080", "https://mcp.example.com"))
+}
+
+func TestIsLoopbackAddr(t *testing.T) {
+	assert.True(t, server.IsLoopbackAddr("127.0.0.1:8080"))
+	assert.True(t, server.IsLoopbackAddr("localhost:8080"))
+	assert.True(t, server.IsLoopbackAddr("[::1]:8080"))
+	assert.False(t, server.IsLoopbackAddr(":8080"))
+	assert.False(t, server.IsLoopbackAddr("0.0.0.0:8080"))
+}
+
+func TestLocalhostAddr(t *testing.T) {
+	assert.Equal(t, "127.0.0.1:8080", server.LocalhostAddr(":8080"))
+	assert.Equal(t, "127.0.0.1:8080", server.LocalhostAddr("0.0.0.0:8080"))
+	assert.Equal(t, "127.0.0.1:9000", server.LocalhostAddr("9000"))
+}
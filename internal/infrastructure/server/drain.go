@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultDrainTimeout is how long a server waits for in-flight requests to
+// complete during shutdown if the caller's context has no deadline.
+const DefaultDrainTimeout = 30 * time.Second
+
+// ShutdownEvent is the SSE event sent to open streams before they are closed
+// during shutdown, so that clients can reconnect elsewhere.
+const ShutdownEvent = "event: shutdown\ndata: {\"reason\":\"server shutting down\"}\n\n"
+
+// Drainer tracks in-flight requests, so that a server can stop accepting new
+// requests and wait for the running ones to complete before it shuts down.
+type Drainer struct {
+	mu       sync.Mutex
+	active   int
+	draining bool
+	idle     chan struct{} // Closed once draining and no request is in flight
+}
+
+// NewDrainer creates a new Drainer.
+func NewDrainer() *Drainer {
+	return &Drainer{idle: make(chan struct{})}
+}
+
+// Begin registers a new request. It returns false if the server is draining,
+// in which case the request must be rejected. Otherwise End must be called once
+// the request is complete.
+func (d *Drainer) Begin() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	d.active++
+	return true
+}
+
+// End marks a request registered with Begin as complete.
+func (d *Drainer) End() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.active--
+	if d.draining && d.active == 0 {
+		close(d.idle)
+	}
+}
+
+// Drain stops accepting new requests and waits until all in-flight requests are
+// complete. It returns the context's error if ctx is done first. Drain may be
+// called more than once.
+func (d *Drainer) Drain(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.draining {
+		d.draining = true
+		if d.active == 0 {
+			close(d.idle)
+		}
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-d.idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Draining reports whether the server has stopped accepting new requests.
+func (d *Drainer) Draining() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draining
+}
+
+// Active returns the number of requests in flight.
+func (d *Drainer) Active() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.active
+}
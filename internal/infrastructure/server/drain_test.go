@@ -0,0 +1,135 @@
+package server_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/infrastructure/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrainer(t *testing.T) {
+	drainer := server.NewDrainer()
+
+	require.True(t, drainer.Begin())
+	assert.Equal(t, 1, drainer.Active())
+
+	// Draining times out while a request is in flight
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, drainer.Drain(ctx), context.DeadlineExceeded)
+	assert.True(t, drainer.Draining())
+
+	// New requests are rejected while draining
+	assert.False(t, drainer.Begin())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- drainer.Drain(context.Background())
+	}()
+
+	drainer.End()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Drain did not return after the last request ended")
+	}
+	assert.Equal(t, 0, drainer.Active())
+}
+
+func TestSSEServer_Shutdown(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	handler := func(ctx context.Context, rawMessage json.RawMessage) interface{} {
+		close(entered)
+		<-release
+		return map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": "done"}
+	}
+
+	sseServer := server.NewSSEServer(server.NewNotificationSender("2.0"), handler)
+	testServer := httptest.NewServer(sseServer)
+	defer testServer.Close()
+
+	// Open a stream and read the session ID from the connected event
+	resp, err := http.Get(testServer.URL + "/sse")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+
+	var sessionID string
+	for sessionID == "" {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if strings.HasPrefix(line, "data: {\"sessionId\"") {
+			var data struct {
+				SessionID string `json:"sessionId"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &data))
+			sessionID = data.SessionID
+		}
+	}
+	messageURL := testServer.URL + "/message?sessionId=" + sessionID
+
+	// Start a message that stays in flight until released
+	inFlight := make(chan int, 1)
+	go func() {
+		resp, err := http.Post(messageURL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call"}`))
+		if err != nil {
+			inFlight <- 0
+			return
+		}
+		resp.Body.Close()
+		inFlight <- resp.StatusCode
+	}()
+	<-entered
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- sseServer.Shutdown(ctx)
+	}()
+
+	// Shutdown waits for the message in flight
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown returned while a message was in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// New messages and streams are rejected while draining
+	rejected, err := http.Post(messageURL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":2,"method":"ping"}`))
+	require.NoError(t, err)
+	rejected.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, rejected.StatusCode)
+
+	rejected, err = http.Get(testServer.URL + "/sse")
+	require.NoError(t, err)
+	rejected.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, rejected.StatusCode)
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-inFlight)
+	require.NoError(t, <-shutdown)
+
+	// The stream receives the response and then the shutdown event before it closes
+	var events []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		if strings.HasPrefix(line, "event: ") {
+			events = append(events, strings.TrimSpace(strings.TrimPrefix(line, "event: ")))
+		}
+	}
+	assert.Equal(t, []string{"endpoint", "message", "shutdown"}, events)
+}
@@ -15,4 +15,8 @@ var (
 
 	// ErrChannelFull is returned when a notification channel is full
 	ErrChannelFull = errors.New("notification channel is full")
+
+	// ErrFilterNotRelayable is returned when a notification is sent with a
+	// session filter that is not a domain.SessionSelector over a bus
+	ErrFilterNotRelayable = errors.New("session filter cannot be relayed over the bus")
 )
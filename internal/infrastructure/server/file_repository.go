@@ -0,0 +1,547 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+// Subdirectories of the directory passed to OpenFileRepositories.
+const (
+	fileToolsDir     = "tools"
+	fileResourcesDir = "resources"
+	filePromptsDir   = "prompts"
+	fileSessionsDir  = "sessions"
+)
+
+// FileRepositories are the file repositories of a server, kept in one
+// directory with a subdirectory per repository.
+type FileRepositories struct {
+	Tools     *FileToolRepository
+	Resources *FileResourceRepository
+	Prompts   *FilePromptRepository
+	Sessions  *FileSessionRepository
+}
+
+// OpenFileRepositories opens the tool, resource, prompt and session
+// repositories kept in dir, creating the directories that do not exist.
+func OpenFileRepositories(dir string) (*FileRepositories, error) {
+	tools, err := NewFileToolRepository(filepath.Join(dir, fileToolsDir))
+	if err != nil {
+		return nil, err
+	}
+	resources, err := NewFileResourceRepository(filepath.Join(dir, fileResourcesDir))
+	if err != nil {
+		return nil, err
+	}
+	prompts, err := NewFilePromptRepository(filepath.Join(dir, filePromptsDir))
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := NewFileSessionRepository(filepath.Join(dir, fileSessionsDir))
+	if err != nil {
+		return nil, err
+	}
+	return &FileRepositories{Tools: tools, Resources: resources, Prompts: prompts, Sessions: sessions}, nil
+}
+
+// fileRecords is a directory of JSON files, one per record. Files are named
+// after the hash of the key of their record, so that any key maps to a valid
+// file name inside the directory.
+type fileRecords struct {
+	dir string
+}
+
+// openFileRecords opens the records in dir, creating the directory if it does not exist.
+func openFileRecords(dir string) (*fileRecords, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create repository directory: %w", err)
+	}
+	return &fileRecords{dir: dir}, nil
+}
+
+// path returns the file of the record with the given key.
+func (f *fileRecords) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(f.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// load decodes every record in the directory with decode.
+func (f *fileRecords) load(decode func(data []byte) error) error {
+	paths, err := filepath.Glob(filepath.Join(f.dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list repository directory: %w", err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read repository file: %w", err)
+		}
+		if err := decode(data); err != nil {
+			return fmt.Errorf("failed to decode repository file %s: %w", filepath.Base(path), err)
+		}
+	}
+	return nil
+}
+
+// write stores record under key, replacing the file atomically.
+func (f *fileRecords) write(key string, record interface{}) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode repository record %q: %w", key, err)
+	}
+	if err := writeFileAtomic(f.dir, f.path(key), data); err != nil {
+		return fmt.Errorf("failed to write repository record %q: %w", key, err)
+	}
+	return nil
+}
+
+// remove deletes the record stored under key.
+func (f *fileRecords) remove(key string) error {
+	if err := os.Remove(f.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove repository record %q: %w", key, err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to path through a temporary file in dir, which
+// is renamed over path once it is complete, so that a crash never leaves the
+// file half written.
+func writeFileAtomic(dir, path string, data []byte) error {
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// FileToolRepository implements a ToolRepository that keeps each tool in a
+// JSON file in a directory, so that tools added at runtime survive restarts.
+// Tools are loaded when the repository is created and served from memory;
+// every change is written to disk before it takes effect. Tools are listed
+// by name.
+type FileToolRepository struct {
+	mu      sync.RWMutex
+	records *fileRecords
+	tools   map[string]*domain.Tool
+}
+
+// NewFileToolRepository creates a FileToolRepository in dir, loading the
+// tools it contains and creating the directory if it does not exist.
+func NewFileToolRepository(dir string) (*FileToolRepository, error) {
+	records, err := openFileRecords(dir)
+	if err != nil {
+		return nil, err
+	}
+	r := &FileToolRepository{records: records, tools: make(map[string]*domain.Tool)}
+	err = records.load(func(data []byte) error {
+		var tool domain.Tool
+		if err := json.Unmarshal(data, &tool); err != nil {
+			return err
+		}
+		r.tools[tool.Name] = &tool
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetTool retrieves a tool by its name.
+func (r *FileToolRepository) GetTool(ctx context.Context, name string) (*domain.Tool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if tool, ok := r.tools[name]; ok {
+		return tool, nil
+	}
+	return nil, domain.NewToolNotFoundError(name)
+}
+
+// ListTools returns all available tools, ordered by name.
+func (r *FileToolRepository) ListTools(ctx context.Context) ([]*domain.Tool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tools := make([]*domain.Tool, 0, len(r.tools))
+	for _, tool := range r.tools {
+		tools = append(tools, tool)
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools, nil
+}
+
+// ListToolsPage returns at most limit tools whose names sort after the given one,
+// ordered by name, and whether more follow.
+func (r *FileToolRepository) ListToolsPage(ctx context.Context, after string, limit int) ([]*domain.Tool, bool, error) {
+	tools, err := r.ListTools(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	page, more := domain.PageAfter(tools, toolName, after, limit)
+	return page, more, nil
+}
+
+// AddTool adds a new tool to the repository, replacing any tool with the same name.
+func (r *FileToolRepository) AddTool(ctx context.Context, tool *domain.Tool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.records.write(tool.Name, tool); err != nil {
+		return err
+	}
+	r.tools[tool.Name] = tool
+	return nil
+}
+
+// DeleteTool removes a tool from the repository.
+func (r *FileToolRepository) DeleteTool(ctx context.Context, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tools[name]; !ok {
+		return domain.NewToolNotFoundError(name)
+	}
+	if err := r.records.remove(name); err != nil {
+		return err
+	}
+	delete(r.tools, name)
+	return nil
+}
+
+// FileResourceRepository implements a ResourceRepository that keeps each
+// resource in a JSON file in a directory, like FileToolRepository. Resources
+// are listed by URI.
+type FileResourceRepository struct {
+	mu        sync.RWMutex
+	records   *fileRecords
+	resources map[string]*domain.Resource
+}
+
+// NewFileResourceRepository creates a FileResourceRepository in dir, loading
+// the resources it contains and creating the directory if it does not exist.
+func NewFileResourceRepository(dir string) (*FileResourceRepository, error) {
+	records, err := openFileRecords(dir)
+	if err != nil {
+		return nil, err
+	}
+	r := &FileResourceRepository{records: records, resources: make(map[string]*domain.Resource)}
+	err = records.load(func(data []byte) error {
+		var resource domain.Resource
+		if err := json.Unmarshal(data, &resource); err != nil {
+			return err
+		}
+		r.resources[resource.URI] = &resource
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetResource retrieves a resource by its URI.
+func (r *FileResourceRepository) GetResource(ctx context.Context, uri string) (*domain.Resource, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if resource, ok := r.resources[uri]; ok {
+		return resource, nil
+	}
+	return nil, domain.NewResourceNotFoundError(uri)
+}
+
+// ListResources returns all available resources, ordered by URI.
+func (r *FileResourceRepository) ListResources(ctx context.Context) ([]*domain.Resource, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	resources := make([]*domain.Resource, 0, len(r.resources))
+	for _, resource := range r.resources {
+		resources = append(resources, resource)
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].URI < resources[j].URI })
+	return resources, nil
+}
+
+// ListResourcesPage returns at most limit resources whose URIs sort after the given one,
+// ordered by URI, and whether more follow.
+func (r *FileResourceRepository) ListResourcesPage(ctx context.Context, after string, limit int) ([]*domain.Resource, bool, error) {
+	resources, err := r.ListResources(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	page, more := domain.PageAfter(resources, resourceURI, after, limit)
+	return page, more, nil
+}
+
+// AddResource adds a new resource to the repository, replacing any resource with the same URI.
+func (r *FileResourceRepository) AddResource(ctx context.Context, resource *domain.Resource) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.records.write(resource.URI, resource); err != nil {
+		return err
+	}
+	r.resources[resource.URI] = resource
+	return nil
+}
+
+// DeleteResource removes a resource from the repository.
+func (r *FileResourceRepository) DeleteResource(ctx context.Context, uri string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.resources[uri]; !ok {
+		return domain.NewResourceNotFoundError(uri)
+	}
+	if err := r.records.remove(uri); err != nil {
+		return err
+	}
+	delete(r.resources, uri)
+	return nil
+}
+
+// FilePromptRepository implements a PromptRepository that keeps each prompt
+// in a JSON file in a directory, like FileToolRepository. Prompts are listed
+// by name.
+type FilePromptRepository struct {
+	mu      sync.RWMutex
+	records *fileRecords
+	prompts map[string]*domain.Prompt
+}
+
+// NewFilePromptRepository creates a FilePromptRepository in dir, loading the
+// prompts it contains and creating the directory if it does not exist.
+func NewFilePromptRepository(dir string) (*FilePromptRepository, error) {
+	records, err := openFileRecords(dir)
+	if err != nil {
+		return nil, err
+	}
+	r := &FilePromptRepository{records: records, prompts: make(map[string]*domain.Prompt)}
+	err = records.load(func(data []byte) error {
+		var prompt domain.Prompt
+		if err := json.Unmarshal(data, &prompt); err != nil {
+			return err
+		}
+		r.prompts[prompt.Name] = &prompt
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetPrompt retrieves a prompt by its name.
+func (r *FilePromptRepository) GetPrompt(ctx context.Context, name string) (*domain.Prompt, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if prompt, ok := r.prompts[name]; ok {
+		return prompt, nil
+	}
+	return nil, domain.NewPromptNotFoundError(name)
+}
+
+// ListPrompts returns all available prompts, ordered by name.
+func (r *FilePromptRepository) ListPrompts(ctx context.Context) ([]*domain.Prompt, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	prompts := make([]*domain.Prompt, 0, len(r.prompts))
+	for _, prompt := range r.prompts {
+		prompts = append(prompts, prompt)
+	}
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
+	return prompts, nil
+}
+
+// ListPromptsPage returns at most limit prompts whose names sort after the given one,
+// ordered by name, and whether more follow.
+func (r *FilePromptRepository) ListPromptsPage(ctx context.Context, after string, limit int) ([]*domain.Prompt, bool, error) {
+	prompts, err := r.ListPrompts(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	page, more := domain.PageAfter(prompts, promptName, after, limit)
+	return page, more, nil
+}
+
+// AddPrompt adds a new prompt to the repository, replacing any prompt with the same name.
+func (r *FilePromptRepository) AddPrompt(ctx context.Context, prompt *domain.Prompt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.records.write(prompt.Name, prompt); err != nil {
+		return err
+	}
+	r.prompts[prompt.Name] = prompt
+	return nil
+}
+
+// DeletePrompt removes a prompt from the repository.
+func (r *FilePromptRepository) DeletePrompt(ctx context.Context, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.prompts[name]; !ok {
+		return domain.NewPromptNotFoundError(name)
+	}
+	if err := r.records.remove(name); err != nil {
+		return err
+	}
+	delete(r.prompts, name)
+	return nil
+}
+
+// fileSessionRecord is the JSON representation of a session in a FileSessionRepository.
+type fileSessionRecord struct {
+	ID                 string                 `json:"id"`
+	UserAgent          string                 `json:"userAgent,omitempty"`
+	Transport          string                 `json:"transport,omitempty"`
+	Principal          *domain.Principal      `json:"principal,omitempty"`
+	ClientIdentity     *domain.ClientIdentity `json:"clientIdentity,omitempty"`
+	Initialized        bool                   `json:"initialized,omitempty"`
+	ClientInfo         domain.ClientInfo      `json:"clientInfo"`
+	ClientCapabilities map[string]interface{} `json:"clientCapabilities,omitempty"`
+	ProtocolVersion    string                 `json:"protocolVersion,omitempty"`
+	Labels             map[string]string      `json:"labels,omitempty"`
+	CreatedAt          time.Time              `json:"createdAt"`
+	LastSeen           time.Time              `json:"lastSeen"`
+}
+
+// newFileSessionRecord returns the record of a session as it is now.
+func newFileSessionRecord(session *domain.ClientSession) fileSessionRecord {
+	return fileSessionRecord{
+		ID:                 session.ID,
+		UserAgent:          session.UserAgent,
+		Transport:          session.Transport,
+		Principal:          session.Principal,
+		ClientIdentity:     session.ClientIdentity,
+		Initialized:        session.Initialized(),
+		ClientInfo:         session.ClientInfo,
+		ClientCapabilities: session.ClientCapabilities,
+		ProtocolVersion:    session.ProtocolVersion,
+		Labels:             session.Labels(),
+		CreatedAt:          session.CreatedAt,
+		LastSeen:           session.LastSeen(),
+	}
+}
+
+// session restores the session of a record. Its client is not connected.
+func (r fileSessionRecord) session() *domain.ClientSession {
+	session := &domain.ClientSession{
+		ID:             r.ID,
+		UserAgent:      r.UserAgent,
+		Transport:      r.Transport,
+		Principal:      r.Principal,
+		ClientIdentity: r.ClientIdentity,
+		CreatedAt:      r.CreatedAt,
+		State:          domain.NewSessionState(r.LastSeen),
+	}
+	if r.Initialized {
+		session.Initialize(map[string]interface{}{
+			"protocolVersion": r.ProtocolVersion,
+			"capabilities":    r.ClientCapabilities,
+			"clientInfo":      map[string]interface{}{"name": r.ClientInfo.Name, "version": r.ClientInfo.Version},
+		})
+	}
+	for key, value := range r.Labels {
+		session.SetLabel(key, value)
+	}
+	return session
+}
+
+// FileSessionRepository implements a SessionRepository that keeps each
+// session in a JSON file in a directory, so that clients of the streamable
+// HTTP transport can resume their sessions after a restart. A session is
+// written as it is when it is added, i.e. when it starts and when it is
+// initialized; later changes to its state, such as when it was last seen,
+// are kept in memory. Restored sessions are not connected, and are expired
+// by the session limits like any other. Sessions are listed by ID.
+type FileSessionRepository struct {
+	mu       sync.RWMutex
+	records  *fileRecords
+	sessions map[string]*domain.ClientSession
+}
+
+// NewFileSessionRepository creates a FileSessionRepository in dir, restoring
+// the sessions it contains and creating the directory if it does not exist.
+func NewFileSessionRepository(dir string) (*FileSessionRepository, error) {
+	records, err := openFileRecords(dir)
+	if err != nil {
+		return nil, err
+	}
+	r := &FileSessionRepository{records: records, sessions: make(map[string]*domain.ClientSession)}
+	err = records.load(func(data []byte) error {
+		var record fileSessionRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return err
+		}
+		r.sessions[record.ID] = record.session()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetSession retrieves a session by its ID.
+func (r *FileSessionRepository) GetSession(ctx context.Context, id string) (*domain.ClientSession, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if session, ok := r.sessions[id]; ok {
+		return session, nil
+	}
+	return nil, domain.NewSessionNotFoundError(id)
+}
+
+// ListSessions returns all active sessions, ordered by ID.
+func (r *FileSessionRepository) ListSessions(ctx context.Context) ([]*domain.ClientSession, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sessions := make([]*domain.ClientSession, 0, len(r.sessions))
+	for _, session := range r.sessions {
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].ID < sessions[j].ID })
+	return sessions, nil
+}
+
+// AddSession adds a new session to the repository, replacing any session with the same ID.
+func (r *FileSessionRepository) AddSession(ctx context.Context, session *domain.ClientSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.records.write(session.ID, newFileSessionRecord(session)); err != nil {
+		return err
+	}
+	r.sessions[session.ID] = session
+	return nil
+}
+
+// DeleteSession removes a session from the repository.
+func (r *FileSessionRepository) DeleteSession(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.sessions[id]; !ok {
+		return domain.NewSessionNotFoundError(id)
+	}
+	if err := r.records.remove(id); err != nil {
+		return err
+	}
+	delete(r.sessions, id)
+	return nil
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileToolRepository(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	repo, err := NewFileToolRepository(dir)
+	require.NoError(t, err)
+
+	tool := &domain.Tool{
+		Name:        "search",
+		Description: "Searches documents",
+		Parameters: []domain.ToolParameter{
+			{Name: "query", Type: "string", Required: true},
+			{Name: "tags", Type: "array", Items: map[string]interface{}{"type": "string"}},
+		},
+	}
+	require.NoError(t, repo.AddTool(ctx, tool))
+	require.NoError(t, repo.AddTool(ctx, &domain.Tool{Name: "echo"}))
+	require.NoError(t, repo.AddTool(ctx, &domain.Tool{Name: "removed"}))
+	require.NoError(t, repo.DeleteTool(ctx, "removed"))
+
+	_, err = repo.GetTool(ctx, "missing")
+	var notFound *domain.ToolNotFoundError
+	assert.ErrorAs(t, err, &notFound)
+	assert.ErrorAs(t, repo.DeleteTool(ctx, "removed"), &notFound)
+
+	// Tools are restored when the repository is opened again
+	reopened, err := NewFileToolRepository(dir)
+	require.NoError(t, err)
+	restored, err := reopened.GetTool(ctx, "search")
+	require.NoError(t, err)
+	assert.Equal(t, tool, restored)
+
+	tools, err := reopened.ListTools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools, 2)
+	assert.Equal(t, "echo", tools[0].Name)
+	assert.Equal(t, "search", tools[1].Name)
+}
+
+func TestFileResourceRepository(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	repo, err := NewFileResourceRepository(dir)
+	require.NoError(t, err)
+
+	resource := &domain.Resource{URI: "file:///docs/readme.md", Name: "Readme", MIMEType: "text/markdown"}
+	require.NoError(t, repo.AddResource(ctx, resource))
+	require.NoError(t, repo.AddResource(ctx, &domain.Resource{URI: "file:///docs/changelog.md"}))
+	require.NoError(t, repo.DeleteResource(ctx, "file:///docs/changelog.md"))
+
+	var notFound *domain.ResourceNotFoundError
+	assert.ErrorAs(t, repo.DeleteResource(ctx, "file:///docs/changelog.md"), &notFound)
+
+	reopened, err := NewFileResourceRepository(dir)
+	require.NoError(t, err)
+	resources, err := reopened.ListResources(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*domain.Resource{resource}, resources)
+}
+
+func TestFilePromptRepository(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	repo, err := NewFilePromptRepository(dir)
+	require.NoError(t, err)
+
+	prompt := &domain.Prompt{
+		Name:       "greet",
+		Template:   "Hello {{name}}",
+		Parameters: []domain.PromptParameter{{Name: "name", Type: "string", Required: true}},
+	}
+	require.NoError(t, repo.AddPrompt(ctx, prompt))
+
+	// Adding a prompt with the same name replaces it
+	replaced := &domain.Prompt{Name: "greet", Template: "Hi {{name}}", Parameters: prompt.Parameters}
+	require.NoError(t, repo.AddPrompt(ctx, replaced))
+
+	reopened, err := NewFilePromptRepository(dir)
+	require.NoError(t, err)
+	restored, err := reopened.GetPrompt(ctx, "greet")
+	require.NoError(t, err)
+	assert.Equal(t, replaced, restored)
+
+	_, err = reopened.GetPrompt(ctx, "missing")
+	var notFound *domain.PromptNotFoundError
+	assert.ErrorAs(t, err, &notFound)
+}
+
+func TestFileSessionRepository(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	repo, err := NewFileSessionRepository(dir)
+	require.NoError(t, err)
+
+	session := domain.NewClientSession("agent")
+	session.Transport = domain.TransportHTTP
+	session.Principal = &domain.Principal{ID: "alice", Roles: []string{"admin"}}
+	session.Initialize(map[string]interface{}{
+		"protocolVersion": "2025-03-26",
+		"capabilities":    map[string]interface{}{"roots": map[string]interface{}{}},
+		"clientInfo":      map[string]interface{}{"name": "client", "version": "1.0"},
+	})
+	session.SetLabel("tenant", "a")
+	require.NoError(t, repo.AddSession(ctx, session))
+
+	// The repository returns the session it was given, whose state stays live
+	got, err := repo.GetSession(ctx, session.ID)
+	require.NoError(t, err)
+	assert.Same(t, session, got)
+
+	reopened, err := NewFileSessionRepository(dir)
+	require.NoError(t, err)
+	restored, err := reopened.GetSession(ctx, session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, session.UserAgent, restored.UserAgent)
+	assert.Equal(t, domain.TransportHTTP, restored.Transport)
+	assert.Equal(t, "alice", restored.Principal.ID)
+	assert.True(t, restored.Initialized())
+	assert.False(t, restored.Connected)
+	assert.Equal(t, session.ClientInfo, restored.ClientInfo)
+	assert.Equal(t, "2025-03-26", restored.ProtocolVersion)
+	assert.Equal(t, map[string]string{"tenant": "a"}, restored.Labels())
+	assert.WithinDuration(t, session.CreatedAt, restored.CreatedAt, time.Millisecond)
+	assert.WithinDuration(t, session.LastSeen(), restored.LastSeen(), time.Millisecond)
+
+	require.NoError(t, reopened.DeleteSession(ctx, session.ID))
+	var notFound *domain.SessionNotFoundError
+	assert.ErrorAs(t, reopened.DeleteSession(ctx, session.ID), &notFound)
+
+	sessions, err := reopened.ListSessions(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestOpenFileRepositories(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	repos, err := OpenFileRepositories(dir)
+	require.NoError(t, err)
+	require.NoError(t, repos.Tools.AddTool(ctx, &domain.Tool{Name: "echo"}))
+	require.NoError(t, repos.Resources.AddResource(ctx, &domain.Resource{URI: "test://echo"}))
+	require.NoError(t, repos.Prompts.AddPrompt(ctx, &domain.Prompt{Name: "echo"}))
+	require.NoError(t, repos.Sessions.AddSession(ctx, domain.NewClientSession("agent")))
+
+	// Each repository keeps its records in its own directory, without temporary files
+	for _, sub := range []string{fileToolsDir, fileResourcesDir, filePromptsDir, fileSessionsDir} {
+		entries, err := os.ReadDir(filepath.Join(dir, sub))
+		require.NoError(t, err)
+		require.Len(t, entries, 1, sub)
+		assert.Equal(t, ".json", filepath.Ext(entries[0].Name()), sub)
+	}
+
+	// Files that cannot be decoded are reported when the repository is opened
+	require.NoError(t, os.WriteFile(filepath.Join(dir, fileToolsDir, "broken.json"), []byte("{"), 0o600))
+	_, err = OpenFileRepositories(dir)
+	assert.Error(t, err)
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"sort"
 	"sync"
 
 	"github.com/FreePeak/cortex/internal/domain"
@@ -29,7 +30,7 @@ func (r *InMemoryResourceRepository) GetResource(ctx context.Context, uri string
 	return nil, domain.NewResourceNotFoundError(uri)
 }
 
-// ListResources returns all available resources.
+// ListResources returns all available resources, ordered by URI.
 func (r *InMemoryResourceRepository) ListResources(ctx context.Context) ([]*domain.Resource, error) {
 	var resources []*domain.Resource
 	r.resources.Range(func(_, value interface{}) bool {
@@ -41,9 +42,21 @@ func (r *InMemoryResourceRepository) ListResources(ctx context.Context) ([]*doma
 		resources = append(resources, res)
 		return true
 	})
+	sort.Slice(resources, func(i, j int) bool { return resources[i].URI < resources[j].URI })
 	return resources, nil
 }
 
+// ListResourcesPage returns at most limit resources whose URIs sort after the given one,
+// ordered by URI, and whether more follow.
+func (r *InMemoryResourceRepository) ListResourcesPage(ctx context.Context, after string, limit int) ([]*domain.Resource, bool, error) {
+	resources, err := r.ListResources(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	page, more := domain.PageAfter(resources, resourceURI, after, limit)
+	return page, more, nil
+}
+
 // AddResource adds a new resource to the repository.
 func (r *InMemoryResourceRepository) AddResource(ctx context.Context, resource *domain.Resource) error {
 	r.resources.Store(resource.URI, resource)
@@ -83,7 +96,7 @@ func (r *InMemoryToolRepository) GetTool(ctx context.Context, name string) (*dom
 	return nil, domain.NewToolNotFoundError(name)
 }
 
-// ListTools returns all available tools.
+// ListTools returns all available tools, ordered by name.
 func (r *InMemoryToolRepository) ListTools(ctx context.Context) ([]*domain.Tool, error) {
 	var tools []*domain.Tool
 	r.tools.Range(func(_, value interface{}) bool {
@@ -95,9 +108,21 @@ func (r *InMemoryToolRepository) ListTools(ctx context.Context) ([]*domain.Tool,
 		tools = append(tools, t)
 		return true
 	})
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
 	return tools, nil
 }
 
+// ListToolsPage returns at most limit tools whose names sort after the given one,
+// ordered by name, and whether more follow.
+func (r *InMemoryToolRepository) ListToolsPage(ctx context.Context, after string, limit int) ([]*domain.Tool, bool, error) {
+	tools, err := r.ListTools(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	page, more := domain.PageAfter(tools, toolName, after, limit)
+	return page, more, nil
+}
+
 // AddTool adds a new tool to the repository.
 func (r *InMemoryToolRepository) AddTool(ctx context.Context, tool *domain.Tool) error {
 	// Store the tool with its original name
@@ -137,7 +162,7 @@ func (r *InMemoryPromptRepository) GetPrompt(ctx context.Context, name string) (
 	return nil, domain.NewPromptNotFoundError(name)
 }
 
-// ListPrompts returns all available prompts.
+// ListPrompts returns all available prompts, ordered by name.
 func (r *InMemoryPromptRepository) ListPrompts(ctx context.Context) ([]*domain.Prompt, error) {
 	var prompts []*domain.Prompt
 	r.prompts.Range(func(_, value interface{}) bool {
@@ -149,9 +174,21 @@ func (r *InMemoryPromptRepository) ListPrompts(ctx context.Context) ([]*domain.P
 		prompts = append(prompts, p)
 		return true
 	})
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
 	return prompts, nil
 }
 
+// ListPromptsPage returns at most limit prompts whose names sort after the given one,
+// ordered by name, and whether more follow.
+func (r *InMemoryPromptRepository) ListPromptsPage(ctx context.Context, after string, limit int) ([]*domain.Prompt, bool, error) {
+	prompts, err := r.ListPrompts(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	page, more := domain.PageAfter(prompts, promptName, after, limit)
+	return page, more, nil
+}
+
 // AddPrompt adds a new prompt to the repository.
 func (r *InMemoryPromptRepository) AddPrompt(ctx context.Context, prompt *domain.Prompt) error {
 	r.prompts.Store(prompt.Name, prompt)
@@ -189,7 +226,7 @@ func (r *InMemorySessionRepository) GetSession(ctx context.Context, id string) (
 	return nil, domain.NewSessionNotFoundError(id)
 }
 
-// ListSessions returns all active sessions.
+// ListSessions returns all active sessions, ordered by ID.
 func (r *InMemorySessionRepository) ListSessions(ctx context.Context) ([]*domain.ClientSession, error) {
 	var sessions []*domain.ClientSession
 	r.sessions.Range(func(_, value interface{}) bool {
@@ -201,6 +238,7 @@ func (r *InMemorySessionRepository) ListSessions(ctx context.Context) ([]*domain
 		sessions = append(sessions, s)
 		return true
 	})
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].ID < sessions[j].ID })
 	return sessions, nil
 }
 
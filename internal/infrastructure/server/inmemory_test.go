@@ -147,6 +147,27 @@ func TestInMemoryToolRepository_ListTools(t *testing.T) {
 	assert.Contains(t, names, "tool2")
 }
 
+func TestInMemoryToolRepository_ListToolsPage(t *testing.T) {
+	repo := NewInMemoryToolRepository()
+	ctx := context.Background()
+	for _, name := range []string{"c", "a", "d", "b"} {
+		require.NoError(t, repo.AddTool(ctx, &domain.Tool{Name: name}))
+	}
+
+	tools, more, err := repo.ListToolsPage(ctx, "", 3)
+	require.NoError(t, err)
+	assert.True(t, more)
+	require.Len(t, tools, 3)
+	assert.Equal(t, "a", tools[0].Name)
+	assert.Equal(t, "c", tools[2].Name)
+
+	tools, more, err = repo.ListToolsPage(ctx, "c", 3)
+	require.NoError(t, err)
+	assert.False(t, more)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "d", tools[0].Name)
+}
+
 func TestInMemoryToolRepository_DeleteTool(t *testing.T) {
 	repo := NewInMemoryToolRepository()
 	ctx := context.Background()
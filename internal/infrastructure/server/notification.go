@@ -2,10 +2,15 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/FreePeak/cortex/internal/domain"
+	"github.com/google/uuid"
 )
 
 // JSONRPCNotification represents a notification sent to clients via JSON-RPC.
@@ -18,19 +23,142 @@ type JSONRPCNotification struct {
 // NotificationChannel is a channel for sending notifications.
 type NotificationChannel chan JSONRPCNotification
 
+// DefaultNotificationBufferSize is the number of notifications queued per
+// session when no buffer size is configured.
+const DefaultNotificationBufferSize = 100
+
+// defaultBroadcastWorkers is the number of goroutines that wait on full
+// session queues during a broadcast with the OverflowBlock policy.
+const defaultBroadcastWorkers = 16
+
+// defaultBusDeliveryTimeout is how long OverflowBlock waits for room in the
+// queues of local sessions for a notification received over the bus, when no
+// BlockTimeout is configured. Deliveries run on the goroutine of the bus, so
+// they must not wait forever.
+const defaultBusDeliveryTimeout = 5 * time.Second
+
+// OverflowPolicy decides what happens to a notification when the queue of a
+// session is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDropNewest drops the notification being sent.
+	OverflowDropNewest OverflowPolicy = iota
+
+	// OverflowDropOldest drops the oldest queued notification to make room
+	// for the one being sent.
+	OverflowDropOldest
+
+	// OverflowBlock waits up to NotificationConfig.BlockTimeout for room in the
+	// queue, then drops the notification being sent.
+	OverflowBlock
+
+	// OverflowDisconnect drops the notification and disconnects the session,
+	// so that a slow consumer does not silently miss notifications.
+	OverflowDisconnect
+)
+
+// String returns the name of the policy.
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowDropNewest:
+		return "drop-newest"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowBlock:
+		return "block"
+	case OverflowDisconnect:
+		return "disconnect"
+	default:
+		return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+	}
+}
+
+// NotificationConfig configures how notifications are queued for sessions.
+// The zero value queues DefaultNotificationBufferSize notifications per
+// session and drops new notifications when a queue is full.
+type NotificationConfig struct {
+	// BufferSize is the number of notifications queued per session.
+	BufferSize int
+
+	// Overflow decides what happens when the queue of a session is full.
+	Overflow OverflowPolicy
+
+	// BlockTimeout is how long OverflowBlock waits for room in a queue. A
+	// broadcast waits at most this long in total, not per session. Zero waits
+	// until the context of the send is done, or 5 seconds for notifications
+	// received over the bus.
+	BlockTimeout time.Duration
+
+	// BroadcastWorkers is the number of goroutines that wait on full queues
+	// during a broadcast with OverflowBlock. Zero uses a default of 16.
+	BroadcastWorkers int
+
+	// Bus relays notifications between the instances of a horizontally scaled
+	// server, so that broadcasts and notifications sent to a session reach
+	// clients connected to any instance. Nil keeps notifications local.
+	Bus domain.NotificationBus
+
+	// InstanceID identifies this instance on the bus. It defaults to a random ID.
+	InstanceID string
+
+	// BusSecret is shared by the instances to sign the messages they publish on
+	// the bus, see SignedNotificationBus. Messages routed to a session carry
+	// the principal of the request, so it should be set unless only trusted
+	// instances can reach the bus. Nil sends unsigned messages.
+	BusSecret []byte
+}
+
+// Topics of the messages exchanged over a NotificationBus.
+const (
+	busTopicBroadcast            = "cortex.notifications.broadcast"
+	busTopicSessionNotifications = "cortex.notifications.session." // Followed by the session ID
+	busTopicSessionMessages      = "cortex.messages.session."      // Followed by the session ID
+	busTopicReplies              = "cortex.replies."               // Followed by a request ID
+)
+
+// busNotification is a notification relayed to other instances over the bus.
+type busNotification struct {
+	Origin       string                  `json:"origin"` // Instance that published the notification
+	Notification JSONRPCNotification     `json:"notification"`
+	Selector     *domain.SessionSelector `json:"selector,omitempty"` // Sessions notified, all if nil
+}
+
+// DeliveryStats counts the notifications sent to a session.
+type DeliveryStats struct {
+	Delivered int64 // Notifications queued for the session
+	Dropped   int64 // Notifications dropped, including those dropped from the queue
+}
+
 // MCPSession represents a connected client session.
 type MCPSession struct {
 	id        string
 	userAgent string
 	notifChan NotificationChannel
+	client    *domain.ClientSession // Session of the client, matched against the filters of targeted notifications
+
+	mu        sync.RWMutex // Held for reading while sending, for writing while closing the channel
+	closed    bool
+	done      chan struct{} // Closed when the session is closed, to release blocked senders
+	closeOnce sync.Once
+	onClose   []func()
+
+	delivered atomic.Int64
+	dropped   atomic.Int64
 }
 
 // NewMCPSession creates a new MCPSession.
 func NewMCPSession(id, userAgent string, bufferSize int) *MCPSession {
+	return newMCPSession(id, userAgent, make(NotificationChannel, bufferSize))
+}
+
+// newMCPSession creates a session that queues notifications on notifChan.
+func newMCPSession(id, userAgent string, notifChan NotificationChannel) *MCPSession {
 	return &MCPSession{
 		id:        id,
 		userAgent: userAgent,
-		notifChan: make(NotificationChannel, bufferSize),
+		notifChan: notifChan,
+		done:      make(chan struct{}),
 	}
 }
 
@@ -44,22 +172,130 @@ func (s *MCPSession) NotificationChannel() NotificationChannel {
 	return s.notifChan
 }
 
+// ClientSession returns the session of the client, or nil if it is not known.
+func (s *MCPSession) ClientSession() *domain.ClientSession {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.client
+}
+
+// SetClientSession sets the session of the client, which targeted
+// notifications are matched against. Sessions without one only receive
+// notifications sent to their ID or broadcast to everyone.
+func (s *MCPSession) SetClientSession(client *domain.ClientSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.client = client
+}
+
+// OnClose registers a function called once when the session is closed, for
+// example to end the stream of a session disconnected as a slow consumer.
+func (s *MCPSession) OnClose(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onClose = append(s.onClose, fn)
+}
+
 // Close closes the notification channel.
 func (s *MCPSession) Close() {
-	close(s.notifChan)
+	s.closeOnce.Do(func() {
+		// Release the senders blocked on a full queue before taking the lock
+		close(s.done)
+
+		s.mu.Lock()
+		s.closed = true
+		close(s.notifChan)
+		onClose := s.onClose
+		s.mu.Unlock()
+
+		for _, fn := range onClose {
+			fn()
+		}
+	})
+}
+
+// Stats returns the delivery counters of the session.
+func (s *MCPSession) Stats() DeliveryStats {
+	return DeliveryStats{
+		Delivered: s.delivered.Load(),
+		Dropped:   s.dropped.Load(),
+	}
 }
 
 // NotificationSender handles sending notifications to clients.
 type NotificationSender struct {
 	sessions       sync.Map
 	jsonrpcVersion string
+	config         NotificationConfig
+	delivered      atomic.Int64 // Number of notifications queued for a session
+	dropped        atomic.Int64 // Number of notifications dropped because a session channel was full
+	disconnected   atomic.Int64 // Number of sessions disconnected as slow consumers
+
+	bus                  domain.NotificationBus
+	instanceID           string
+	busSessions          sync.Map // Session ID to the function that unsubscribes it from the bus
+	unsubscribeBroadcast func()
 }
 
+// NotificationSender can target the sessions of a principal, tenant or topic.
+var _ domain.TargetedNotificationSender = (*NotificationSender)(nil)
+
 // NewNotificationSender creates a new NotificationSender.
 func NewNotificationSender(jsonrpcVersion string) *NotificationSender {
-	return &NotificationSender{
+	return NewNotificationSenderWithConfig(jsonrpcVersion, NotificationConfig{})
+}
+
+// NewNotificationSenderWithConfig creates a NotificationSender that queues
+// and drops notifications as configured.
+func NewNotificationSenderWithConfig(jsonrpcVersion string, config NotificationConfig) *NotificationSender {
+	if config.BufferSize <= 0 {
+		config.BufferSize = DefaultNotificationBufferSize
+	}
+	if config.BroadcastWorkers <= 0 {
+		config.BroadcastWorkers = defaultBroadcastWorkers
+	}
+	if config.Bus != nil && config.InstanceID == "" {
+		config.InstanceID = uuid.New().String()
+	}
+
+	if config.Bus != nil && len(config.BusSecret) > 0 {
+		config.Bus = NewSignedNotificationBus(config.Bus, config.BusSecret)
+	}
+
+	n := &NotificationSender{
 		jsonrpcVersion: jsonrpcVersion,
+		config:         config,
+		bus:            config.Bus,
+		instanceID:     config.InstanceID,
 	}
+	if n.bus != nil {
+		// Subscriptions only fail on a disconnected bus, which then carries nothing anyway
+		n.unsubscribeBroadcast, _ = n.bus.Subscribe(busTopicBroadcast, n.receiveBroadcast)
+	}
+	return n
+}
+
+// Bus returns the bus that relays notifications between instances, which
+// signs its messages if NotificationConfig.BusSecret is set, or nil.
+func (n *NotificationSender) Bus() domain.NotificationBus {
+	return n.bus
+}
+
+// InstanceID returns the ID of this instance on the bus, or "" without a bus.
+func (n *NotificationSender) InstanceID() string {
+	return n.instanceID
+}
+
+// Close stops receiving broadcasts from other instances.
+func (n *NotificationSender) Close() {
+	if n.unsubscribeBroadcast != nil {
+		n.unsubscribeBroadcast()
+	}
+}
+
+// BufferSize returns the number of notifications queued per session.
+func (n *NotificationSender) BufferSize() int {
+	return n.config.BufferSize
 }
 
 // NotificationRegistrar is an interface for registering and unregistering sessions.
@@ -71,13 +307,26 @@ type NotificationRegistrar interface {
 	UnregisterSession(sessionID string)
 }
 
-// RegisterSession registers a session for notifications.
+// RegisterSession registers a session for notifications. With a bus, the
+// session also receives the notifications sent to it on other instances.
 func (n *NotificationSender) RegisterSession(session *MCPSession) {
 	n.sessions.Store(session.ID(), session)
+
+	if n.bus != nil {
+		unsubscribe, err := n.bus.Subscribe(busTopicSessionNotifications+session.ID(), func(payload []byte) {
+			n.receiveSessionNotification(session, payload)
+		})
+		if err == nil {
+			n.busSessions.Store(session.ID(), unsubscribe)
+		}
+	}
 }
 
 // UnregisterSession unregisters a session.
 func (n *NotificationSender) UnregisterSession(sessionID string) {
+	if unsubscribe, ok := n.busSessions.LoadAndDelete(sessionID); ok {
+		unsubscribe.(func())()
+	}
 	if session, ok := n.sessions.LoadAndDelete(sessionID); ok {
 		if s, ok := session.(*MCPSession); ok {
 			s.Close()
@@ -85,11 +334,29 @@ func (n *NotificationSender) UnregisterSession(sessionID string) {
 	}
 }
 
-// SendNotification sends a notification to a specific client.
+// SessionStats returns the delivery counters of a registered session.
+func (n *NotificationSender) SessionStats(sessionID string) (DeliveryStats, bool) {
+	value, ok := n.sessions.Load(sessionID)
+	if !ok {
+		return DeliveryStats{}, false
+	}
+	session, ok := value.(*MCPSession)
+	if !ok {
+		return DeliveryStats{}, false
+	}
+	return session.Stats(), true
+}
+
+// SendNotification sends a notification to a specific client. With a bus,
+// notifications for sessions of other instances are published to the bus, and
+// whether such a session exists is not known.
 func (n *NotificationSender) SendNotification(ctx context.Context, sessionID string, notification *domain.Notification) error {
 	value, ok := n.sessions.Load(sessionID)
 	if !ok {
-		return fmt.Errorf("session %s not found", sessionID)
+		if n.bus != nil {
+			return n.publish(ctx, busTopicSessionNotifications+sessionID, busNotification{Origin: n.instanceID, Notification: n.toJSONRPC(notification)})
+		}
+		return domain.NewSessionNotFoundError(sessionID)
 	}
 
 	session, ok := value.(*MCPSession)
@@ -97,79 +364,343 @@ func (n *NotificationSender) SendNotification(ctx context.Context, sessionID str
 		return domain.ErrInternal
 	}
 
-	jsonRPC := JSONRPCNotification{
-		JSONRPC: n.jsonrpcVersion,
-		Method:  notification.Method,
-		Params:  notification.Params,
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	select {
-	case session.NotificationChannel() <- jsonRPC:
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
-	default:
-		return fmt.Errorf("notification channel for session %s is full or closed", sessionID)
+	return n.send(ctx, session, n.toJSONRPC(notification))
+}
+
+// send queues a notification on a local session, waiting at most BlockTimeout
+// with the OverflowBlock policy.
+func (n *NotificationSender) send(ctx context.Context, session *MCPSession, jsonRPC JSONRPCNotification) error {
+	if n.config.Overflow == OverflowBlock && n.config.BlockTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.config.BlockTimeout)
+		defer cancel()
 	}
+
+	return n.deliver(ctx, session, jsonRPC)
 }
 
-// BroadcastNotification sends a notification to all connected clients.
+// BroadcastNotification sends a notification to all connected clients. It
+// queues the notification on every session from the calling goroutine, and
+// only waits on full queues with the OverflowBlock policy, using a bounded
+// number of workers. The returned error joins the errors of all sessions that
+// missed the notification. With a bus, the notification is also published to
+// the other instances, which deliver it to their sessions.
 func (n *NotificationSender) BroadcastNotification(ctx context.Context, notification *domain.Notification) error {
-	jsonRPC := JSONRPCNotification{
-		JSONRPC: n.jsonrpcVersion,
-		Method:  notification.Method,
-		Params:  notification.Params,
+	jsonRPC := n.toJSONRPC(notification)
+	err := n.notify(ctx, nil, jsonRPC)
+	if n.bus == nil || ctx.Err() != nil {
+		return err
 	}
+	return errors.Join(err, n.publish(ctx, busTopicBroadcast, busNotification{Origin: n.instanceID, Notification: jsonRPC}))
+}
 
-	var wg sync.WaitGroup
-	var errsMu sync.Mutex
-	var errs []error
+// NotifySessions sends a notification to the connected clients whose session
+// is selected by filter, like BroadcastNotification. Sessions registered
+// without a client session are never selected. With a bus, the filter must be
+// a domain.SessionSelector, such as the filters of the domain package, so that
+// the other instances can apply it to their sessions. Other filters only
+// notify the sessions of this instance and return ErrFilterNotRelayable.
+func (n *NotificationSender) NotifySessions(ctx context.Context, filter domain.SessionFilter, notification *domain.Notification) error {
+	if filter == nil {
+		filter = &domain.SessionSelector{}
+	}
+	jsonRPC := n.toJSONRPC(notification)
+	err := n.notify(ctx, filter, jsonRPC)
+	if n.bus == nil || ctx.Err() != nil {
+		return err
+	}
+
+	selector, ok := filter.(*domain.SessionSelector)
+	if !ok {
+		return errors.Join(err, ErrFilterNotRelayable)
+	}
+	if selector == nil {
+		selector = &domain.SessionSelector{}
+	}
+	return errors.Join(err, n.publish(ctx, busTopicBroadcast, busNotification{Origin: n.instanceID, Notification: jsonRPC, Selector: selector}))
+}
 
+// notify sends a notification to the local sessions selected by filter, or to
+// every local session if filter is nil.
+func (n *NotificationSender) notify(ctx context.Context, filter domain.SessionFilter, jsonRPC JSONRPCNotification) error {
 	// First check if the context is already canceled before starting
-	if ctx.Err() != nil {
-		return ctx.Err()
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	// Function to process a session
-	processSession := func(key, value interface{}) bool {
+	var errs []error
+	var full []*MCPSession
+	n.sessions.Range(func(key, value interface{}) bool {
+		session, ok := value.(*MCPSession)
+		if !ok {
+			errs = append(errs, fmt.Errorf("invalid session type"))
+			return true
+		}
+		if filter != nil && !domain.SelectsSession(filter, session.ClientSession()) {
+			return true
+		}
+
+		// Sessions with room in their queue are served without waiting
+		if n.config.Overflow == OverflowBlock {
+			if err := n.tryDeliver(session, jsonRPC); errors.Is(err, ErrChannelFull) {
+				full = append(full, session)
+			} else if err != nil {
+				errs = append(errs, err)
+			}
+			return true
+		}
+
+		if err := n.deliver(ctx, session, jsonRPC); err != nil {
+			errs = append(errs, err)
+		}
+		return true
+	})
+
+	if len(full) > 0 {
+		errs = append(errs, n.broadcastBlocking(ctx, full, jsonRPC)...)
+	}
+
+	return errors.Join(errs...)
+}
+
+// broadcastBlocking waits for room in the queues of the given sessions, using
+// at most BroadcastWorkers goroutines and a single deadline for all of them.
+func (n *NotificationSender) broadcastBlocking(ctx context.Context, sessions []*MCPSession, jsonRPC JSONRPCNotification) []error {
+	if n.config.BlockTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.config.BlockTimeout)
+		defer cancel()
+	}
+
+	workers := min(n.config.BroadcastWorkers, len(sessions))
+	next := make(chan *MCPSession)
+
+	var wg sync.WaitGroup
+	var errsMu sync.Mutex
+	var errs []error
+	for i := 0; i < workers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			session, ok := value.(*MCPSession)
-			if !ok {
-				errsMu.Lock()
-				errs = append(errs, fmt.Errorf("invalid session type"))
-				errsMu.Unlock()
-				return
+			for session := range next {
+				if err := n.deliver(ctx, session, jsonRPC); err != nil {
+					errsMu.Lock()
+					errs = append(errs, err)
+					errsMu.Unlock()
+				}
 			}
+		}()
+	}
+
+	for _, session := range sessions {
+		next <- session
+	}
+	close(next)
+	wg.Wait()
+
+	return errs
+}
+
+// publish publishes a notification to a topic of the bus.
+func (n *NotificationSender) publish(ctx context.Context, topic string, message busNotification) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	if err := n.bus.Publish(ctx, topic, payload); err != nil {
+		return fmt.Errorf("failed to publish notification: %w", err)
+	}
+	return nil
+}
+
+// receiveBroadcast delivers a notification broadcast by another instance to
+// the local sessions, or to those selected by its selector.
+func (n *NotificationSender) receiveBroadcast(payload []byte) {
+	var message busNotification
+	if err := json.Unmarshal(payload, &message); err != nil || message.Origin == n.instanceID {
+		return
+	}
+	var filter domain.SessionFilter
+	if message.Selector != nil {
+		filter = message.Selector
+	}
+	ctx, cancel := n.busDeliveryContext()
+	defer cancel()
+	_ = n.notify(ctx, filter, message.Notification)
+}
+
+// receiveSessionNotification delivers a notification sent to a local session by
+// another instance.
+func (n *NotificationSender) receiveSessionNotification(session *MCPSession, payload []byte) {
+	var message busNotification
+	if err := json.Unmarshal(payload, &message); err != nil {
+		return
+	}
+	ctx, cancel := n.busDeliveryContext()
+	defer cancel()
+	_ = n.send(ctx, session, message.Notification)
+}
+
+// busDeliveryContext returns the context of the delivery of a notification
+// received over the bus, which bounds how long the bus is held up by full
+// queues.
+func (n *NotificationSender) busDeliveryContext() (context.Context, context.CancelFunc) {
+	timeout := n.config.BlockTimeout
+	if timeout <= 0 {
+		timeout = defaultBusDeliveryTimeout
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// toJSONRPC converts a notification to its JSON-RPC form.
+func (n *NotificationSender) toJSONRPC(notification *domain.Notification) JSONRPCNotification {
+	return JSONRPCNotification{
+		JSONRPC: n.jsonrpcVersion,
+		Method:  notification.Method,
+		Params:  notification.Params,
+	}
+}
 
-			// Try to send notification with timeout from context
-			select {
-			case session.NotificationChannel() <- jsonRPC:
-				// Successfully sent
-			case <-ctx.Done():
-				errsMu.Lock()
-				errs = append(errs, fmt.Errorf("context canceled for session %s: %w", session.ID(), ctx.Err()))
-				errsMu.Unlock()
-			default:
-				errsMu.Lock()
-				errs = append(errs, fmt.Errorf("notification channel for session %s is full or closed", session.ID()))
-				errsMu.Unlock()
+// deliver queues a notification on a session, applying the overflow policy if
+// its queue is full.
+func (n *NotificationSender) deliver(ctx context.Context, session *MCPSession, jsonRPC JSONRPCNotification) error {
+	err := n.tryDeliver(session, jsonRPC)
+	if !errors.Is(err, ErrChannelFull) {
+		return err
+	}
+
+	switch n.config.Overflow {
+	case OverflowDropOldest:
+		err = n.deliverDropOldest(session, jsonRPC)
+	case OverflowBlock:
+		err = n.deliverBlocking(ctx, session, jsonRPC)
+		if err != nil {
+			if !errors.Is(err, ErrSessionClosed) {
+				n.drop(session)
 			}
-		}()
-		return true
+			err = fmt.Errorf("notification channel for session %s is full or closed: %w", session.ID(), err)
+		}
+	case OverflowDisconnect:
+		n.drop(session)
+		n.disconnected.Add(1)
+		n.UnregisterSession(session.ID())
+		err = fmt.Errorf("session %s disconnected as a slow consumer: %w", session.ID(), ErrChannelFull)
+	default:
+		n.drop(session)
 	}
+	return err
+}
 
-	// Process all sessions
-	n.sessions.Range(processSession)
+// tryDeliver queues a notification on a session without waiting. It returns
+// an error wrapping ErrChannelFull if the queue is full.
+func (n *NotificationSender) tryDeliver(session *MCPSession, jsonRPC JSONRPCNotification) error {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
 
-	// Wait for all goroutines to complete
-	wg.Wait()
+	if session.closed {
+		return fmt.Errorf("notification channel for session %s is full or closed: %w", session.ID(), ErrSessionClosed)
+	}
 
-	// Return first error if any occurred
-	if len(errs) > 0 {
-		return errs[0]
+	select {
+	case session.notifChan <- jsonRPC:
+		n.deliveredTo(session)
+		return nil
+	default:
+		return fmt.Errorf("notification channel for session %s is full or closed: %w", session.ID(), ErrChannelFull)
 	}
+}
 
-	return nil
+// deliverDropOldest makes room for a notification by dropping the oldest
+// queued ones. Unbuffered channels have no oldest notification, so the new
+// notification is dropped instead.
+func (n *NotificationSender) deliverDropOldest(session *MCPSession, jsonRPC JSONRPCNotification) error {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
+	if session.closed {
+		return fmt.Errorf("notification channel for session %s is full or closed: %w", session.ID(), ErrSessionClosed)
+	}
+
+	// Other senders may fill the freed slot, so retry a bounded number of times
+	for attempt := 0; attempt < 3 && cap(session.notifChan) > 0; attempt++ {
+		select {
+		case <-session.notifChan:
+			n.drop(session)
+		default:
+		}
+
+		select {
+		case session.notifChan <- jsonRPC:
+			n.deliveredTo(session)
+			return nil
+		default:
+		}
+	}
+
+	n.drop(session)
+	return fmt.Errorf("notification channel for session %s is full or closed: %w", session.ID(), ErrChannelFull)
+}
+
+// deliverBlocking waits for room in the queue of a session until ctx is done
+// or the session is closed.
+func (n *NotificationSender) deliverBlocking(ctx context.Context, session *MCPSession, jsonRPC JSONRPCNotification) error {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
+	if session.closed {
+		return ErrSessionClosed
+	}
+
+	select {
+	case session.notifChan <- jsonRPC:
+		n.deliveredTo(session)
+		return nil
+	case <-session.done:
+		return ErrSessionClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deliveredTo counts a notification queued for a session.
+func (n *NotificationSender) deliveredTo(session *MCPSession) {
+	session.delivered.Add(1)
+	n.delivered.Add(1)
+}
+
+// drop counts a notification dropped for a session.
+func (n *NotificationSender) drop(session *MCPSession) {
+	session.dropped.Add(1)
+	n.dropped.Add(1)
+}
+
+// Delivered returns the number of notifications queued for a session.
+func (n *NotificationSender) Delivered() int64 {
+	return n.delivered.Load()
+}
+
+// Dropped returns the number of notifications dropped because a session channel was full.
+func (n *NotificationSender) Dropped() int64 {
+	return n.dropped.Load()
+}
+
+// Disconnected returns the number of sessions disconnected as slow consumers.
+func (n *NotificationSender) Disconnected() int64 {
+	return n.disconnected.Load()
+}
+
+// QueueDepth returns the number of notifications waiting to be delivered, across all sessions.
+func (n *NotificationSender) QueueDepth() int {
+	depth := 0
+	n.sessions.Range(func(key, value interface{}) bool {
+		if session, ok := value.(*MCPSession); ok {
+			depth += len(session.notifChan)
+		}
+		return true
+	})
+	return depth
 }
@@ -121,7 +121,8 @@ func TestNotificationSender_SendNotification_SessionNotFound(t *testing.T) {
 	ctx := context.Background()
 	err := sender.SendNotification(ctx, "non-existent-session", notification)
 	require.Error(t, err)
-	assert.Contains(t, err.Error(), "session non-existent-session not found")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.Contains(t, err.Error(), "non-existent-session")
 }
 
 // Test SendNotification - Channel full or closed
@@ -302,3 +303,226 @@ func TestNotificationSender_BroadcastNotification_NoSessions(t *testing.T) {
 	err := sender.BroadcastNotification(ctx, notification)
 	require.NoError(t, err) // Should not error if there are no sessions
 }
+
+// Test BroadcastNotification - Every failed session is reported
+func TestNotificationSender_BroadcastNotification_JoinsErrors(t *testing.T) {
+	sender := NewNotificationSender(testJsonrpcVersion)
+	for _, id := range []string{"full1", "full2"} {
+		sender.RegisterSession(NewMCPSession(id, "agent", 0))
+		defer sender.UnregisterSession(id)
+	}
+
+	err := sender.BroadcastNotification(context.Background(), &domain.Notification{Method: "broadcast/full"})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrChannelFull)
+	assert.Contains(t, err.Error(), "session full1")
+	assert.Contains(t, err.Error(), "session full2")
+	assert.Equal(t, int64(2), sender.Dropped())
+}
+
+// Test BroadcastNotification - Many sessions are served without a goroutine each
+func TestNotificationSender_BroadcastNotification_ManySessions(t *testing.T) {
+	sender := NewNotificationSender(testJsonrpcVersion)
+	sessions := make([]*MCPSession, 2000)
+	for i := range sessions {
+		sessions[i] = NewMCPSession(fmt.Sprintf("s%d", i), "agent", 1)
+		sender.RegisterSession(sessions[i])
+	}
+
+	err := sender.BroadcastNotification(context.Background(), &domain.Notification{Method: "broadcast/many"})
+	require.NoError(t, err)
+
+	for _, session := range sessions {
+		assert.Len(t, session.NotificationChannel(), 1)
+	}
+	assert.Equal(t, int64(len(sessions)), sender.Delivered())
+}
+
+// Test the delivery counters of a session
+func TestNotificationSender_SessionStats(t *testing.T) {
+	sender := NewNotificationSender(testJsonrpcVersion)
+	sender.RegisterSession(NewMCPSession("s1", "agent", 1))
+	defer sender.UnregisterSession("s1")
+
+	ctx := context.Background()
+	require.NoError(t, sender.SendNotification(ctx, "s1", &domain.Notification{Method: "first"}))
+	require.ErrorIs(t, sender.SendNotification(ctx, "s1", &domain.Notification{Method: "second"}), ErrChannelFull)
+
+	stats, ok := sender.SessionStats("s1")
+	require.True(t, ok)
+	assert.Equal(t, DeliveryStats{Delivered: 1, Dropped: 1}, stats)
+
+	_, ok = sender.SessionStats("non-existent")
+	assert.False(t, ok)
+}
+
+// Test the buffer size and defaults of the configuration
+func TestNewNotificationSenderWithConfig(t *testing.T) {
+	sender := NewNotificationSenderWithConfig(testJsonrpcVersion, NotificationConfig{BufferSize: 5})
+	assert.Equal(t, 5, sender.BufferSize())
+
+	sender = NewNotificationSender(testJsonrpcVersion)
+	assert.Equal(t, DefaultNotificationBufferSize, sender.BufferSize())
+}
+
+// Test the overflow policies
+func TestNotificationSender_OverflowPolicies(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("DropNewest", func(t *testing.T) {
+		sender := NewNotificationSenderWithConfig(testJsonrpcVersion, NotificationConfig{Overflow: OverflowDropNewest})
+		session := NewMCPSession("s1", "agent", 1)
+		sender.RegisterSession(session)
+		defer sender.UnregisterSession("s1")
+
+		require.NoError(t, sender.SendNotification(ctx, "s1", &domain.Notification{Method: "first"}))
+		require.ErrorIs(t, sender.SendNotification(ctx, "s1", &domain.Notification{Method: "second"}), ErrChannelFull)
+
+		assert.Equal(t, "first", (<-session.NotificationChannel()).Method)
+	})
+
+	t.Run("DropOldest", func(t *testing.T) {
+		sender := NewNotificationSenderWithConfig(testJsonrpcVersion, NotificationConfig{Overflow: OverflowDropOldest})
+		session := NewMCPSession("s1", "agent", 1)
+		sender.RegisterSession(session)
+		defer sender.UnregisterSession("s1")
+
+		require.NoError(t, sender.SendNotification(ctx, "s1", &domain.Notification{Method: "first"}))
+		require.NoError(t, sender.SendNotification(ctx, "s1", &domain.Notification{Method: "second"}))
+
+		assert.Equal(t, "second", (<-session.NotificationChannel()).Method)
+		assert.Equal(t, DeliveryStats{Delivered: 2, Dropped: 1}, session.Stats())
+	})
+
+	t.Run("BlockUntilRoom", func(t *testing.T) {
+		sender := NewNotificationSenderWithConfig(testJsonrpcVersion, NotificationConfig{
+			Overflow:     OverflowBlock,
+			BlockTimeout: time.Second,
+		})
+		session := NewMCPSession("s1", "agent", 1)
+		sender.RegisterSession(session)
+		defer sender.UnregisterSession("s1")
+
+		require.NoError(t, sender.SendNotification(ctx, "s1", &domain.Notification{Method: "first"}))
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			<-session.NotificationChannel()
+		}()
+		require.NoError(t, sender.SendNotification(ctx, "s1", &domain.Notification{Method: "second"}))
+
+		assert.Equal(t, "second", (<-session.NotificationChannel()).Method)
+	})
+
+	t.Run("BlockTimeout", func(t *testing.T) {
+		sender := NewNotificationSenderWithConfig(testJsonrpcVersion, NotificationConfig{
+			Overflow:     OverflowBlock,
+			BlockTimeout: 20 * time.Millisecond,
+		})
+		sender.RegisterSession(NewMCPSession("s1", "agent", 0))
+		sender.RegisterSession(NewMCPSession("s2", "agent", 0))
+		defer sender.UnregisterSession("s1")
+		defer sender.UnregisterSession("s2")
+
+		err := sender.SendNotification(ctx, "s1", &domain.Notification{Method: "test"})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		// The timeout applies to the whole broadcast, not to each session
+		start := time.Now()
+		err = sender.BroadcastNotification(ctx, &domain.Notification{Method: "broadcast"})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		assert.Equal(t, int64(3), sender.Dropped())
+	})
+
+	t.Run("BlockReleasedByClose", func(t *testing.T) {
+		sender := NewNotificationSenderWithConfig(testJsonrpcVersion, NotificationConfig{Overflow: OverflowBlock})
+		sender.RegisterSession(NewMCPSession("s1", "agent", 0))
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			sender.UnregisterSession("s1")
+		}()
+
+		err := sender.SendNotification(ctx, "s1", &domain.Notification{Method: "test"})
+		require.ErrorIs(t, err, ErrSessionClosed)
+	})
+
+	t.Run("Disconnect", func(t *testing.T) {
+		sender := NewNotificationSenderWithConfig(testJsonrpcVersion, NotificationConfig{Overflow: OverflowDisconnect})
+		session := NewMCPSession("s1", "agent", 1)
+		disconnected := make(chan struct{})
+		session.OnClose(func() { close(disconnected) })
+		sender.RegisterSession(session)
+
+		require.NoError(t, sender.SendNotification(ctx, "s1", &domain.Notification{Method: "first"}))
+		require.ErrorIs(t, sender.SendNotification(ctx, "s1", &domain.Notification{Method: "second"}), ErrChannelFull)
+
+		select {
+		case <-disconnected:
+		case <-time.After(100 * time.Millisecond):
+			t.Fatal("Slow consumer was not disconnected")
+		}
+		_, ok := sender.SessionStats("s1")
+		assert.False(t, ok, "Slow consumer should be unregistered")
+		assert.Equal(t, int64(1), sender.Disconnected())
+	})
+}
+
+// Test bus deliveries - Full queues never hold up the bus forever
+func TestNotificationSender_BusDeliveryTimeout(t *testing.T) {
+	t.Run("DefaultTimeout", func(t *testing.T) {
+		sender := NewNotificationSenderWithConfig(testJsonrpcVersion, NotificationConfig{Overflow: OverflowBlock})
+		ctx, cancel := sender.busDeliveryContext()
+		defer cancel()
+
+		deadline, ok := ctx.Deadline()
+		require.True(t, ok, "Bus deliveries should have a deadline without a BlockTimeout")
+		assert.WithinDuration(t, time.Now().Add(defaultBusDeliveryTimeout), deadline, time.Second)
+	})
+
+	t.Run("FullQueues", func(t *testing.T) {
+		bus := NewInMemoryNotificationBus()
+		sender := NewNotificationSenderWithConfig(testJsonrpcVersion, NotificationConfig{
+			Overflow:     OverflowBlock,
+			BlockTimeout: 50 * time.Millisecond,
+			Bus:          bus,
+		})
+		defer sender.Close()
+		// Nobody reads the unbuffered queue of the session
+		sender.RegisterSession(NewMCPSession("s1", "agent", 0))
+		defer sender.UnregisterSession("s1")
+
+		payload := []byte(`{"origin":"other","notification":{"jsonrpc":"2.0","method":"test"}}`)
+		for _, topic := range []string{busTopicBroadcast, busTopicSessionNotifications + "s1"} {
+			start := time.Now()
+			require.NoError(t, bus.Publish(context.Background(), topic, payload))
+			assert.Less(t, time.Since(start), 500*time.Millisecond, "Delivery on %s held up the bus", topic)
+		}
+		stats, _ := sender.SessionStats("s1")
+		assert.Equal(t, int64(2), stats.Dropped)
+	})
+}
+
+// Test NotifySessions - Only the selected sessions are notified
+func TestNotificationSender_NotifySessions(t *testing.T) {
+	sender := NewNotificationSender(testJsonrpcVersion)
+
+	sessions := make(map[string]*MCPSession)
+	for _, tenant := range []string{"a", "b"} {
+		client := domain.NewClientSession("agent")
+		client.SetLabel("tenant", tenant)
+		sessions[tenant] = NewMCPSession(client.ID, "agent", 1)
+		sessions[tenant].SetClientSession(client)
+		sender.RegisterSession(sessions[tenant])
+	}
+	// Sessions of unknown clients are never selected
+	unknown := NewMCPSession("unknown", "agent", 1)
+	sender.RegisterSession(unknown)
+
+	err := sender.NotifySessions(context.Background(), domain.LabelFilter("tenant", "a"), &domain.Notification{Method: "tenant/changed"})
+	require.NoError(t, err)
+
+	assert.Len(t, sessions["a"].NotificationChannel(), 1)
+	assert.Empty(t, sessions["b"].NotificationChannel())
+	assert.Empty(t, unknown.NotificationChannel())
+}
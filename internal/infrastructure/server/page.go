@@ -0,0 +1,18 @@
+package server
+
+import "github.com/FreePeak/cortex/internal/domain"
+
+// Keys of the entries of the repositories, for domain.PageAfter
+func toolName(tool *domain.Tool) string            { return tool.Name }
+func resourceURI(resource *domain.Resource) string { return resource.URI }
+func promptName(prompt *domain.Prompt) string      { return prompt.Name }
+
+// The built-in repositories list their entries a page at a time
+var (
+	_ domain.PagedToolRepository     = (*InMemoryToolRepository)(nil)
+	_ domain.PagedResourceRepository = (*InMemoryResourceRepository)(nil)
+	_ domain.PagedPromptRepository   = (*InMemoryPromptRepository)(nil)
+	_ domain.PagedToolRepository     = (*FileToolRepository)(nil)
+	_ domain.PagedResourceRepository = (*FileResourceRepository)(nil)
+	_ domain.PagedPromptRepository   = (*FilePromptRepository)(nil)
+)
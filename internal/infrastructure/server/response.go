@@ -0,0 +1,42 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+// ResponseStatus returns the HTTP status code for a JSON-RPC response. Most
+// responses, including errors, are sent with 200 OK; calls rejected by a
+// saturated bulkhead are answered with 503 Service Unavailable, and rate
+// limited calls with 429 Too Many Requests and a Retry-After header, which is
+// set on w.
+func ResponseStatus(w http.ResponseWriter, response interface{}) int {
+	var rpcErr *domain.JSONRPCError
+	switch r := response.(type) {
+	case domain.JSONRPCResponse:
+		rpcErr = r.Error
+	case *domain.JSONRPCResponse:
+		if r != nil {
+			rpcErr = r.Error
+		}
+	}
+
+	if rpcErr != nil && rpcErr.Code == domain.BusyCode {
+		return http.StatusServiceUnavailable
+	}
+
+	retryAfter, ok := rpcErr.RetryAfter()
+	if !ok {
+		return http.StatusOK
+	}
+
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	return http.StatusTooManyRequests
+}
@@ -0,0 +1,29 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+	"github.com/FreePeak/cortex/internal/infrastructure/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseStatus(t *testing.T) {
+	w := httptest.NewRecorder()
+	response := domain.CreateResponse("2.0", 1, "ok")
+	assert.Equal(t, http.StatusOK, server.ResponseStatus(w, response))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+
+	w = httptest.NewRecorder()
+	rpcErr := domain.NewJSONRPCErrorFromError(domain.NewRateLimitError("search", "session", 2500*time.Millisecond))
+	response = domain.CreateErrorResponseFromError("2.0", 1, rpcErr)
+	assert.Equal(t, http.StatusTooManyRequests, server.ResponseStatus(w, response))
+	assert.Equal(t, "3", w.Header().Get("Retry-After"))
+
+	w = httptest.NewRecorder()
+	response = domain.CreateErrorResponseFromError("2.0", 1, domain.NewJSONRPCErrorFromError(domain.NewBusyError("db.query", "tool")))
+	assert.Equal(t, http.StatusServiceUnavailable, server.ResponseStatus(w, response))
+}
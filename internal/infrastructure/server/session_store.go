@@ -0,0 +1,289 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// sessionEntry is a value in a session store.
+type sessionEntry struct {
+	value     interface{}
+	expiresAt time.Time // Zero if the value does not expire
+}
+
+// expired reports whether the entry expired at now.
+func (e sessionEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// expiresAt returns when a value stored at now with the given ttl expires.
+func expiresAt(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
+
+// InMemorySessionStore implements a SessionStoreBackend using in-memory storage.
+// Values are stored as they are, so they may be anything, e.g. a database cursor.
+// Expired values are removed when they are read or when the session is written.
+type InMemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]map[string]sessionEntry
+}
+
+// NewInMemorySessionStore creates a new InMemorySessionStore.
+func NewInMemorySessionStore() *InMemorySessionStore {
+	return &InMemorySessionStore{sessions: make(map[string]map[string]sessionEntry)}
+}
+
+// Get returns the value stored under key for the session.
+func (s *InMemorySessionStore) Get(ctx context.Context, sessionID, key string) (interface{}, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.sessions[sessionID][key]
+	if !ok {
+		return nil, false, nil
+	}
+	if entry.expired(time.Now()) {
+		s.deleteLocked(sessionID, key)
+		return nil, false, nil
+	}
+	return entry.value, true, nil
+}
+
+// Set stores value under key for the session.
+func (s *InMemorySessionStore) Set(ctx context.Context, sessionID, key string, value interface{}, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	values, ok := s.sessions[sessionID]
+	if !ok {
+		values = make(map[string]sessionEntry)
+		s.sessions[sessionID] = values
+	}
+	for k, entry := range values {
+		if entry.expired(now) {
+			delete(values, k)
+		}
+	}
+	values[key] = sessionEntry{value: value, expiresAt: expiresAt(now, ttl)}
+	return nil
+}
+
+// Delete removes the value stored under key for the session.
+func (s *InMemorySessionStore) Delete(ctx context.Context, sessionID, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleteLocked(sessionID, key)
+	return nil
+}
+
+// Clear removes all values of the session.
+func (s *InMemorySessionStore) Clear(ctx context.Context, sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, sessionID)
+	return nil
+}
+
+// deleteLocked removes a value, and the session once it has none. The caller must hold s.mu.
+func (s *InMemorySessionStore) deleteLocked(sessionID, key string) {
+	values := s.sessions[sessionID]
+	delete(values, key)
+	if len(values) == 0 {
+		delete(s.sessions, sessionID)
+	}
+}
+
+// FileSessionStore implements a SessionStoreBackend that keeps the values of
+// each session in a JSON file in a directory, so that they survive restarts.
+// Values must be serializable with encoding/json, and Get returns them decoded
+// into an interface{}, i.e. objects as map[string]interface{} and numbers as float64.
+//
+// Values are only found again after a restart if the session is, which requires
+// a persistent SessionRepository such as FileSessionRepository. Sessions do not
+// end when the server stops, so the files of sessions that are unknown when the
+// server starts are removed, see Retain.
+type FileSessionStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// fileSessionEntry is the JSON representation of a value in a FileSessionStore.
+type fileSessionEntry struct {
+	Value     json.RawMessage `json:"value"`
+	ExpiresAt *time.Time      `json:"expiresAt,omitempty"`
+}
+
+// NewFileSessionStore creates a FileSessionStore in dir, creating the directory
+// if it does not exist.
+func NewFileSessionStore(dir string) (*FileSessionStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create session store directory: %w", err)
+	}
+	return &FileSessionStore{dir: dir}, nil
+}
+
+// Get returns the value stored under key for the session.
+func (s *FileSessionStore) Get(ctx context.Context, sessionID, key string) (interface{}, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.load(sessionID)
+	if err != nil {
+		return nil, false, err
+	}
+	entry, ok := values[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if entry.ExpiresAt != nil && !time.Now().Before(*entry.ExpiresAt) {
+		delete(values, key)
+		return nil, false, s.save(sessionID, values)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(entry.Value, &value); err != nil {
+		return nil, false, fmt.Errorf("failed to decode session value %q: %w", key, err)
+	}
+	return value, true, nil
+}
+
+// Set stores value under key for the session.
+func (s *FileSessionStore) Set(ctx context.Context, sessionID, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode session value %q: %w", key, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.load(sessionID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for k, entry := range values {
+		if entry.ExpiresAt != nil && !now.Before(*entry.ExpiresAt) {
+			delete(values, k)
+		}
+	}
+	entry := fileSessionEntry{Value: data}
+	if ttl > 0 {
+		expires := now.Add(ttl)
+		entry.ExpiresAt = &expires
+	}
+	values[key] = entry
+	return s.save(sessionID, values)
+}
+
+// Delete removes the value stored under key for the session.
+func (s *FileSessionStore) Delete(ctx context.Context, sessionID, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.load(sessionID)
+	if err != nil {
+		return err
+	}
+	if _, ok := values[key]; !ok {
+		return nil
+	}
+	delete(values, key)
+	return s.save(sessionID, values)
+}
+
+// Clear removes all values of the session.
+func (s *FileSessionStore) Clear(ctx context.Context, sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.path(sessionID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove session store file: %w", err)
+	}
+	return nil
+}
+
+// Retain removes the files of every session but the given ones.
+func (s *FileSessionStore) Retain(ctx context.Context, sessionIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keep := make(map[string]bool, len(sessionIDs))
+	for _, id := range sessionIDs {
+		keep[s.path(id)] = true
+	}
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read session store directory: %w", err)
+	}
+	var errs []error
+	for _, entry := range entries {
+		path := filepath.Join(s.dir, entry.Name())
+		if entry.IsDir() || filepath.Ext(path) != ".json" || keep[path] {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("failed to remove session store file: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// path returns the file of a session. Session IDs are hashed, so that any ID
+// maps to a valid file name inside the directory.
+func (s *FileSessionStore) path(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// load reads the values of a session. The caller must hold s.mu.
+func (s *FileSessionStore) load(sessionID string) (map[string]fileSessionEntry, error) {
+	values := make(map[string]fileSessionEntry)
+	data, err := os.ReadFile(s.path(sessionID))
+	if errors.Is(err, os.ErrNotExist) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session store file: %w", err)
+	}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to decode session store file: %w", err)
+	}
+	return values, nil
+}
+
+// save writes the values of a session, removing its file once it has none.
+// The file is replaced atomically, so that a crash never leaves it half written.
+// The caller must hold s.mu.
+func (s *FileSessionStore) save(sessionID string, values map[string]fileSessionEntry) error {
+	path := s.path(sessionID)
+	if len(values) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove session store file: %w", err)
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("failed to encode session store file: %w", err)
+	}
+	if err := writeFileAtomic(s.dir, path, data); err != nil {
+		return fmt.Errorf("failed to write session store file: %w", err)
+	}
+	return nil
+}
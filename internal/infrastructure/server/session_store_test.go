@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) domain.SessionStoreBackend{
+		"in-memory": func(t *testing.T) domain.SessionStoreBackend {
+			return NewInMemorySessionStore()
+		},
+		"file": func(t *testing.T) domain.SessionStoreBackend {
+			store, err := NewFileSessionStore(t.TempDir())
+			require.NoError(t, err)
+			return store
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			t.Run("get, set and delete", func(t *testing.T) {
+				store := newStore(t)
+
+				_, ok, err := store.Get(ctx, "session-1", "cursor")
+				require.NoError(t, err)
+				assert.False(t, ok)
+
+				require.NoError(t, store.Set(ctx, "session-1", "cursor", "abc", 0))
+				require.NoError(t, store.Set(ctx, "session-2", "cursor", "xyz", 0))
+
+				value, ok, err := store.Get(ctx, "session-1", "cursor")
+				require.NoError(t, err)
+				assert.True(t, ok)
+				assert.Equal(t, "abc", value)
+
+				require.NoError(t, store.Set(ctx, "session-1", "cursor", "def", 0))
+				value, _, err = store.Get(ctx, "session-1", "cursor")
+				require.NoError(t, err)
+				assert.Equal(t, "def", value)
+
+				require.NoError(t, store.Delete(ctx, "session-1", "cursor"))
+				require.NoError(t, store.Delete(ctx, "session-1", "missing"))
+				_, ok, err = store.Get(ctx, "session-1", "cursor")
+				require.NoError(t, err)
+				assert.False(t, ok)
+
+				// Sessions are isolated from each other
+				value, ok, err = store.Get(ctx, "session-2", "cursor")
+				require.NoError(t, err)
+				assert.True(t, ok)
+				assert.Equal(t, "xyz", value)
+			})
+
+			t.Run("ttl", func(t *testing.T) {
+				store := newStore(t)
+
+				require.NoError(t, store.Set(ctx, "session-1", "short", "value", time.Millisecond))
+				require.NoError(t, store.Set(ctx, "session-1", "long", "value", time.Hour))
+				time.Sleep(5 * time.Millisecond)
+
+				_, ok, err := store.Get(ctx, "session-1", "short")
+				require.NoError(t, err)
+				assert.False(t, ok)
+				_, ok, err = store.Get(ctx, "session-1", "long")
+				require.NoError(t, err)
+				assert.True(t, ok)
+			})
+
+			t.Run("clear", func(t *testing.T) {
+				store := newStore(t)
+
+				require.NoError(t, store.Set(ctx, "session-1", "a", "1", 0))
+				require.NoError(t, store.Set(ctx, "session-1", "b", "2", 0))
+				require.NoError(t, store.Set(ctx, "session-2", "a", "1", 0))
+				require.NoError(t, store.Clear(ctx, "session-1"))
+				require.NoError(t, store.Clear(ctx, "unknown"))
+
+				_, ok, err := store.Get(ctx, "session-1", "a")
+				require.NoError(t, err)
+				assert.False(t, ok)
+				_, ok, err = store.Get(ctx, "session-2", "a")
+				require.NoError(t, err)
+				assert.True(t, ok)
+			})
+		})
+	}
+}
+
+func TestInMemorySessionStore_KeepsValues(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemorySessionStore()
+
+	// Values that cannot be serialized are stored as they are
+	ch := make(chan int)
+	require.NoError(t, store.Set(ctx, "session-1", "channel", ch, 0))
+	value, ok, err := store.Get(ctx, "session-1", "channel")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, ch, value)
+}
+
+func TestFileSessionStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := NewFileSessionStore(dir)
+	require.NoError(t, err)
+
+	// Values survive a restart, decoded as JSON
+	require.NoError(t, store.Set(ctx, "../session/1", "token", map[string]interface{}{"expires": 3600}, 0))
+	restarted, err := NewFileSessionStore(dir)
+	require.NoError(t, err)
+	value, ok, err := restarted.Get(ctx, "../session/1", "token")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"expires": float64(3600)}, value)
+
+	// Session IDs never escape the directory
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.False(t, entries[0].IsDir())
+
+	// Values must be serializable
+	assert.Error(t, store.Set(ctx, "session-2", "channel", make(chan int), 0))
+
+	// The file is removed with the last value
+	require.NoError(t, store.Delete(ctx, "../session/1", "token"))
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestFileSessionStore_Retain(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileSessionStore(t.TempDir())
+	require.NoError(t, err)
+
+	for _, id := range []string{"known", "orphan-1", "orphan-2"} {
+		require.NoError(t, store.Set(ctx, id, "cursor", id, 0))
+	}
+
+	// Only the files of the given sessions are kept
+	require.NoError(t, store.Retain(ctx, []string{"known", "unknown"}))
+	value, ok, err := store.Get(ctx, "known", "cursor")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "known", value)
+	for _, id := range []string{"orphan-1", "orphan-2"} {
+		_, ok, err := store.Get(ctx, id, "cursor")
+		require.NoError(t, err)
+		assert.False(t, ok, "Values of %s should be removed", id)
+	}
+
+	// No session is kept without IDs
+	require.NoError(t, store.Retain(ctx, nil))
+	entries, err := os.ReadDir(store.dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
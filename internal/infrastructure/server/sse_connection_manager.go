@@ -4,87 +4,137 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/FreePeak/cortex/internal/domain"
 )
 
-// sseConnectionManager implements the domain.ConnectionManager interface
-// for managing SSE connections.
-type sseConnectionManager struct {
+// ConnectionPool manages active SSE sessions and implements the domain.ConnectionManager interface.
+// Every event it delivers is queued on the notification channel of a session,
+// without blocking the sender.
+type ConnectionPool struct {
 	mu       sync.RWMutex
 	sessions map[string]domain.SSESession
+	dropped  atomic.Int64 // Number of events dropped because a session queue was full
 }
 
-// NewSSEConnectionManager creates a new connection manager for SSE sessions.
-func NewSSEConnectionManager() domain.ConnectionManager {
-	return &sseConnectionManager{
+// NewConnectionPool creates a new connection pool.
+func NewConnectionPool() *ConnectionPool {
+	return &ConnectionPool{
 		sessions: make(map[string]domain.SSESession),
 	}
 }
 
-// AddSession adds a session to the connection manager.
-func (m *sseConnectionManager) AddSession(session domain.SSESession) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.sessions[session.ID()] = session
+// AddSession adds a session to the pool.
+func (p *ConnectionPool) AddSession(session domain.SSESession) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sessions[session.ID()] = session
 }
 
-// RemoveSession removes a session from the connection manager.
-func (m *sseConnectionManager) RemoveSession(sessionID string) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	delete(m.sessions, sessionID)
+// RemoveSession removes a session from the pool.
+func (p *ConnectionPool) RemoveSession(sessionID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.sessions, sessionID)
 }
 
-// GetSession retrieves a session by its ID.
-func (m *sseConnectionManager) GetSession(sessionID string) (domain.SSESession, bool) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	session, ok := m.sessions[sessionID]
+// GetSession returns a session by ID.
+func (p *ConnectionPool) GetSession(sessionID string) (domain.SSESession, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	session, ok := p.sessions[sessionID]
 	return session, ok
 }
 
-// Broadcast sends an event to all connected sessions.
-func (m *sseConnectionManager) Broadcast(event interface{}) error {
-	// Format the event as a SSE message
+// Send queues an event on the session with the given ID. It returns
+// ErrSessionNotFound, ErrSessionClosed, or ErrChannelFull if the queue of the
+// session is full and the event was dropped.
+func (p *ConnectionPool) Send(sessionID string, event interface{}) error {
+	session, ok := p.GetSession(sessionID)
+	if !ok {
+		return ErrSessionNotFound
+	}
+
 	eventData, err := json.Marshal(event)
 	if err != nil {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
+	return p.send(session, formatMessageEvent(eventData))
+}
 
-	eventStr := fmt.Sprintf("event: message\ndata: %s\n\n", eventData)
+// Broadcast sends an event to all active sessions. Sessions whose queue is full miss the event.
+func (p *ConnectionPool) Broadcast(event interface{}) error {
+	eventData, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
 
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	p.broadcastRaw(formatMessageEvent(eventData))
+	return nil
+}
 
-	for _, session := range m.sessions {
-		select {
-		case session.NotificationChannel() <- eventStr:
-			// Event sent successfully
-		default:
-			// Queue is full or closed, we continue anyway to avoid blocking
-		}
+// broadcastRaw queues a formatted SSE event on all active sessions.
+func (p *ConnectionPool) broadcastRaw(eventStr string) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, session := range p.sessions {
+		_ = p.send(session, eventStr)
 	}
+}
 
-	return nil
+// send queues a formatted SSE event on a session. It is the only way events
+// reach the queue of a session.
+func (p *ConnectionPool) send(session domain.SSESession, eventStr string) error {
+	select {
+	case <-session.Context().Done():
+		return ErrSessionClosed
+	default:
+	}
+
+	select {
+	case session.NotificationChannel() <- eventStr:
+		return nil
+	default:
+		p.dropped.Add(1)
+		return ErrChannelFull
+	}
 }
 
-// CloseAll closes all active sessions.
-func (m *sseConnectionManager) CloseAll() {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// CloseAll closes all active sessions, after writing the events already queued.
+func (p *ConnectionPool) CloseAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	for _, session := range m.sessions {
+	for _, session := range p.sessions {
 		session.Close()
 	}
 
 	// Clear the map
-	m.sessions = make(map[string]domain.SSESession)
+	p.sessions = make(map[string]domain.SSESession)
 }
 
 // Count returns the number of active sessions.
-func (m *sseConnectionManager) Count() int {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	return len(m.sessions)
+func (p *ConnectionPool) Count() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.sessions)
+}
+
+// Dropped returns the number of events dropped because a session queue was full.
+func (p *ConnectionPool) Dropped() int64 {
+	return p.dropped.Load()
+}
+
+// QueueDepth returns the number of events waiting to be written, across all sessions.
+func (p *ConnectionPool) QueueDepth() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	depth := 0
+	for _, session := range p.sessions {
+		depth += len(session.NotificationChannel())
+	}
+	return depth
 }
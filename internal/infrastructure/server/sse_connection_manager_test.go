@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // MockSSESession is a mock implementation of domain.SSESession for testing
@@ -59,14 +60,14 @@ func (m *MockSSESession) IsClosed() bool {
 	return m.closed
 }
 
-func TestNewSSEConnectionManager(t *testing.T) {
-	mgr := NewSSEConnectionManager()
+func TestNewConnectionPool(t *testing.T) {
+	mgr := NewConnectionPool()
 	assert.NotNil(t, mgr)
 	assert.Equal(t, 0, mgr.Count())
 }
 
-func TestSSEConnectionManager_AddAndGetSession(t *testing.T) {
-	mgr := NewSSEConnectionManager()
+func TestConnectionPool_AddAndGetSession(t *testing.T) {
+	mgr := NewConnectionPool()
 
 	// Add a session
 	session1 := NewMockSSESession("session1")
@@ -90,8 +91,8 @@ func TestSSEConnectionManager_AddAndGetSession(t *testing.T) {
 	assert.Nil(t, s)
 }
 
-func TestSSEConnectionManager_RemoveSession(t *testing.T) {
-	mgr := NewSSEConnectionManager()
+func TestConnectionPool_RemoveSession(t *testing.T) {
+	mgr := NewConnectionPool()
 
 	// Add a session
 	session := NewMockSSESession("test-session")
@@ -109,8 +110,8 @@ func TestSSEConnectionManager_RemoveSession(t *testing.T) {
 	mgr.RemoveSession("non-existent")
 }
 
-func TestSSEConnectionManager_CloseAll(t *testing.T) {
-	mgr := NewSSEConnectionManager()
+func TestConnectionPool_CloseAll(t *testing.T) {
+	mgr := NewConnectionPool()
 
 	// Add multiple sessions
 	sessions := []*MockSSESession{
@@ -137,8 +138,8 @@ func TestSSEConnectionManager_CloseAll(t *testing.T) {
 	}
 }
 
-func TestSSEConnectionManager_Broadcast(t *testing.T) {
-	mgr := NewSSEConnectionManager()
+func TestConnectionPool_Broadcast(t *testing.T) {
+	mgr := NewConnectionPool()
 
 	// Add multiple sessions
 	session1 := NewMockSSESession("session1")
@@ -169,8 +170,8 @@ func TestSSEConnectionManager_Broadcast(t *testing.T) {
 	}
 }
 
-func TestSSEConnectionManager_BroadcastToFullQueue(t *testing.T) {
-	mgr := NewSSEConnectionManager()
+func TestConnectionPool_BroadcastToFullQueue(t *testing.T) {
+	mgr := NewConnectionPool()
 
 	// Create a session with a small channel buffer
 	session := &MockSSESession{
@@ -194,8 +195,8 @@ func TestSSEConnectionManager_BroadcastToFullQueue(t *testing.T) {
 	session.Close()
 }
 
-func TestSSEConnectionManager_BroadcastToClosedSession(t *testing.T) {
-	mgr := NewSSEConnectionManager()
+func TestConnectionPool_BroadcastToClosedSession(t *testing.T) {
+	mgr := NewConnectionPool()
 
 	// Add a session and then close it
 	session := NewMockSSESession("closed-session")
@@ -214,12 +215,12 @@ func TestSSEConnectionManager_BroadcastToClosedSession(t *testing.T) {
 	})
 }
 
-func TestSSEConnectionManager_ConcurrentAccess(t *testing.T) {
+func TestConnectionPool_ConcurrentAccess(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping concurrent test in short mode")
 	}
 
-	mgr := NewSSEConnectionManager()
+	mgr := NewConnectionPool()
 	var wg sync.WaitGroup
 
 	// Define a fixed number of goroutines and sessions
@@ -278,3 +279,48 @@ func TestSSEConnectionManager_ConcurrentAccess(t *testing.T) {
 	// Clean up
 	mgr.CloseAll()
 }
+
+func TestConnectionPool_Send(t *testing.T) {
+	pool := NewConnectionPool()
+
+	session := NewMockSSESession("session1")
+	pool.AddSession(session)
+
+	err := pool.Send("session1", map[string]string{"data": "test-data"})
+	require.NoError(t, err)
+
+	msg := <-session.notifChan
+	assert.Equal(t, "event: message\ndata: {\"data\":\"test-data\"}\n\n", msg)
+}
+
+func TestConnectionPool_SendErrors(t *testing.T) {
+	pool := NewConnectionPool()
+
+	t.Run("UnknownSession", func(t *testing.T) {
+		err := pool.Send("non-existent", "event")
+		assert.ErrorIs(t, err, ErrSessionNotFound)
+	})
+
+	t.Run("ClosedSession", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		session := &MockSSESession{id: "closed", notifChan: make(chan string, 1), ctx: ctx}
+		pool.AddSession(session)
+
+		err := pool.Send("closed", "event")
+		assert.ErrorIs(t, err, ErrSessionClosed)
+		assert.Empty(t, session.notifChan)
+	})
+
+	t.Run("FullQueue", func(t *testing.T) {
+		session := &MockSSESession{id: "full", notifChan: make(chan string, 1), ctx: context.Background()}
+		pool.AddSession(session)
+		session.notifChan <- "queued"
+
+		dropped := pool.Dropped()
+		err := pool.Send("full", "event")
+		assert.ErrorIs(t, err, ErrChannelFull)
+		assert.Equal(t, dropped+1, pool.Dropped())
+		assert.Equal(t, 1, pool.QueueDepth())
+	})
+}
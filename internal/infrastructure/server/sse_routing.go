@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+	"github.com/FreePeak/cortex/internal/infrastructure/logging"
+	"github.com/google/uuid"
+)
+
+// routeAcceptTimeout is how long a message posted for a session of another
+// instance waits for that instance to accept it, before the session is
+// considered unknown.
+const routeAcceptTimeout = 2 * time.Second
+
+// routedMessage is a message posted to an instance that does not hold the
+// stream of its session, relayed over the bus to the instance that does.
+type routedMessage struct {
+	ReplyTo        string                 `json:"replyTo"` // Topic of the replies
+	Principal      *domain.Principal      `json:"principal,omitempty"`
+	ClientIdentity *domain.ClientIdentity `json:"clientIdentity,omitempty"`
+	Message        json.RawMessage        `json:"message"`
+}
+
+// routedReply answers a routed message. The instance holding the stream first
+// accepts the message, then replies with the HTTP response to the post.
+type routedReply struct {
+	Accepted bool            `json:"accepted,omitempty"`
+	Status   int             `json:"status,omitempty"`
+	Header   http.Header     `json:"header,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// routeMessage relays a message posted for a session of another instance over
+// the bus, and writes the response of that instance.
+func (s *SSEServer) routeMessage(w http.ResponseWriter, r *http.Request, bus domain.NotificationBus, sessionID string) {
+	var rawMessage json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&rawMessage); err != nil {
+		s.writeJSONRPCError(w, nil, -32700, "Parse error")
+		return
+	}
+
+	replies := make(chan routedReply, 2)
+	replyTo := busTopicReplies + uuid.New().String()
+	unsubscribe, err := bus.Subscribe(replyTo, func(payload []byte) {
+		var reply routedReply
+		if json.Unmarshal(payload, &reply) != nil {
+			return
+		}
+		select {
+		case replies <- reply:
+		default:
+		}
+	})
+	if err != nil {
+		s.logger.Warn("Failed to route message to another instance", logging.Fields{"sessionID": sessionID, "error": err.Error()})
+		s.writeJSONRPCError(w, nil, -32603, "Failed to route message")
+		return
+	}
+	defer unsubscribe()
+
+	payload, err := json.Marshal(routedMessage{
+		ReplyTo:        replyTo,
+		Principal:      domain.PrincipalFromContext(r.Context()),
+		ClientIdentity: domain.ClientIdentityFromContext(r.Context()),
+		Message:        rawMessage,
+	})
+	if err == nil {
+		err = bus.Publish(r.Context(), busTopicSessionMessages+sessionID, payload)
+	}
+	if err != nil {
+		s.logger.Warn("Failed to route message to another instance", logging.Fields{"sessionID": sessionID, "error": err.Error()})
+		s.writeJSONRPCError(w, nil, -32603, "Failed to route message")
+		return
+	}
+
+	// No instance accepts messages for unknown sessions
+	accept := time.NewTimer(routeAcceptTimeout)
+	defer accept.Stop()
+	var reply routedReply
+	select {
+	case reply = <-replies:
+	case <-accept.C:
+		s.writeJSONRPCError(w, nil, -32602, "Invalid session ID")
+		return
+	case <-r.Context().Done():
+		return
+	}
+
+	// Messages rejected by the other instance are answered at once
+	if reply.Accepted {
+		select {
+		case reply = <-replies:
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	for key, values := range reply.Header {
+		w.Header()[key] = values
+	}
+	if reply.Response != nil {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(reply.Status)
+	_, _ = w.Write(reply.Response)
+}
+
+// receiveRoutedMessage handles a message posted for a session of this instance
+// on another instance, and replies with its response.
+func (s *SSEServer) receiveRoutedMessage(bus domain.NotificationBus, session *sseSession, payload []byte) {
+	var message routedMessage
+	if err := json.Unmarshal(payload, &message); err != nil || message.ReplyTo == "" {
+		return
+	}
+	reply := func(r routedReply) {
+		data, err := json.Marshal(r)
+		if err == nil {
+			_ = bus.Publish(context.Background(), message.ReplyTo, data)
+		}
+	}
+	replyError := func(status, code int, text string) {
+		response, _ := json.Marshal(domain.CreateErrorResponse("2.0", nil, code, text))
+		reply(routedReply{Status: status, Response: response})
+	}
+
+	if !s.drainer.Begin() {
+		replyError(http.StatusServiceUnavailable, domain.BusyCode, "Server is shutting down")
+		return
+	}
+	defer s.drainer.End()
+
+	// The same principal and client certificate checks apply as to local posts
+	if !samePrincipal(session.principal, message.Principal) {
+		s.logger.Warn("Rejected routed message for session owned by another principal", logging.Fields{"sessionID": session.id})
+		replyError(http.StatusForbidden, domain.ForbiddenCode, "Session belongs to a different principal")
+		return
+	}
+	if !sameClientIdentity(session.identity, message.ClientIdentity) {
+		s.logger.Warn("Rejected routed message for session owned by another client certificate", logging.Fields{"sessionID": session.id})
+		replyError(http.StatusForbidden, domain.ForbiddenCode, "Session belongs to a different client certificate")
+		return
+	}
+	reply(routedReply{Accepted: true})
+
+	// The message is handled as long as the stream is open
+	ctx := domain.ContextWithPrincipal(session.ctx, session.principal)
+	ctx = domain.ContextWithClientIdentity(ctx, session.identity)
+	ctx = context.WithValue(ctx, SessionIDContextKey, session.id)
+	ctx = domain.ContextWithClientSession(ctx, session.client)
+	session.client.Touch()
+
+	response := s.mcpHandler(ctx, message.Message)
+	if response == nil {
+		reply(routedReply{Status: http.StatusOK})
+		return
+	}
+
+	eventData, _ := json.Marshal(response)
+	if err := s.connectionPool.send(session, formatMessageEvent(eventData)); errors.Is(err, ErrChannelFull) {
+		s.logger.Warn("Dropped response event, session queue is full", logging.Fields{"sessionID": session.id})
+	}
+
+	recorder := httptest.NewRecorder()
+	status := ResponseStatus(recorder, response)
+	reply(routedReply{Status: status, Header: recorder.Header(), Response: eventData})
+}
@@ -3,130 +3,23 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
-	"sync"
 
+	"github.com/FreePeak/cortex/internal/domain"
 	"github.com/FreePeak/cortex/internal/infrastructure/logging"
 	"github.com/google/uuid"
 )
 
-// sseSession represents an active SSE connection.
-type sseSession struct {
-	writer     http.ResponseWriter
-	flusher    http.Flusher
-	done       chan struct{}
-	eventQueue chan string // Channel for queuing events
-	id         string
-	notifChan  NotificationChannel
-	ctx        context.Context
-	cancel     context.CancelFunc
-}
-
-// SessionID returns the session ID.
-func (s *sseSession) ID() string {
-	return s.id
-}
-
-// NotificationChannel returns the channel for sending notifications.
-func (s *sseSession) NotificationChannel() NotificationChannel {
-	return s.notifChan
-}
-
-// Close closes the notification channel and cancels the context.
-func (s *sseSession) Close() {
-	s.cancel()
-	close(s.notifChan)
-	close(s.done)
-}
-
 // SSEContextFunc is a function that takes an existing context and the current
 // request and returns a potentially modified context based on the request
 // content. This can be used to inject context values from headers, for example.
 type SSEContextFunc func(ctx context.Context, r *http.Request) context.Context
 
-// ConnectionPool manages active SSE sessions.
-type ConnectionPool struct {
-	mu       sync.RWMutex
-	sessions map[string]*sseSession
-}
-
-// NewConnectionPool creates a new connection pool.
-func NewConnectionPool() *ConnectionPool {
-	return &ConnectionPool{
-		sessions: make(map[string]*sseSession),
-	}
-}
-
-// Add adds a session to the pool.
-func (p *ConnectionPool) Add(session *sseSession) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	p.sessions[session.id] = session
-}
-
-// Remove removes a session from the pool.
-func (p *ConnectionPool) Remove(sessionID string) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	delete(p.sessions, sessionID)
-}
-
-// Get returns a session by ID.
-func (p *ConnectionPool) Get(sessionID string) (*sseSession, bool) {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-	session, ok := p.sessions[sessionID]
-	return session, ok
-}
-
-// Broadcast sends an event to all active sessions.
-func (p *ConnectionPool) Broadcast(event interface{}) {
-	eventData, err := json.Marshal(event)
-	if err != nil {
-		return
-	}
-
-	eventStr := fmt.Sprintf("event: message\ndata: %s\n\n", eventData)
-
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-
-	for _, session := range p.sessions {
-		select {
-		case session.eventQueue <- eventStr:
-			// Event queued successfully
-		case <-session.done:
-			// Session is closed
-		default:
-			// Queue is full
-		}
-	}
-}
-
-// CloseAll closes all active sessions.
-func (p *ConnectionPool) CloseAll() {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	for _, session := range p.sessions {
-		session.Close()
-	}
-
-	// Clear the map
-	p.sessions = make(map[string]*sseSession)
-}
-
-// Count returns the number of active sessions.
-func (p *ConnectionPool) Count() int {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-	return len(p.sessions)
-}
-
 // package-level constant for context key
 type ContextKey string
 
@@ -144,11 +37,24 @@ type SSEServer struct {
 	srv             *http.Server
 	contextFunc     SSEContextFunc
 	mcpHandler      func(ctx context.Context, rawMessage json.RawMessage) interface{}
+	onSessionStart  func(ctx context.Context, session *domain.ClientSession)
+	onSessionEnd    func(ctx context.Context, session *domain.ClientSession)
 	logger          *logging.Logger
+	cors            *CORSPolicy
+	corsSet         bool // Whether the CORS policy was configured explicitly
+	localhostOnly   bool
+	drainer         *Drainer // Tracks in-flight messages during shutdown
 	ctx             context.Context
 	cancel          context.CancelFunc
 }
 
+// SSEServer is the SSE transport, and ConnectionPool the connection manager it uses.
+var (
+	_ domain.SSEHandler        = (*SSEServer)(nil)
+	_ domain.ConnectionManager = (*ConnectionPool)(nil)
+	_ domain.SSESession        = (*sseSession)(nil)
+)
+
 // SSEOption defines a function type for configuring SSEServer
 type SSEOption func(*SSEServer)
 
@@ -159,6 +65,32 @@ func WithLogger(logger *logging.Logger) SSEOption {
 	}
 }
 
+// WithCORS sets the cross-origin policy of the SSE and message endpoints
+func WithCORS(config CORSConfig) SSEOption {
+	return func(s *SSEServer) {
+		s.cors = NewCORSPolicy(config)
+		s.corsSet = true
+	}
+}
+
+// WithCORSPolicy sets the cross-origin policy of the SSE and message endpoints.
+// A nil policy disables CORS handling, for servers mounted behind a handler
+// that already applies one.
+func WithCORSPolicy(policy *CORSPolicy) SSEOption {
+	return func(s *SSEServer) {
+		s.cors = policy
+		s.corsSet = true
+	}
+}
+
+// WithLocalhostOnly makes Start bind to the loopback interface only and, unless
+// a CORS policy is set explicitly, restricts browser access to local origins.
+func WithLocalhostOnly() SSEOption {
+	return func(s *SSEServer) {
+		s.localhostOnly = true
+	}
+}
+
 // WithBaseURL sets the base URL for the SSE server
 func WithBaseURL(baseURL string) SSEOption {
 	return func(s *SSEServer) {
@@ -222,15 +154,33 @@ func WithSSEContextFunc(fn SSEContextFunc) SSEOption {
 	}
 }
 
+// WithSessionStartFunc sets a function that is called once the stream of a
+// session opened, e.g. to register the session. Closing the session, see
+// domain.ClientSession.Close, ends its stream.
+func WithSessionStartFunc(fn func(ctx context.Context, session *domain.ClientSession)) SSEOption {
+	return func(s *SSEServer) {
+		s.onSessionStart = fn
+	}
+}
+
+// WithSessionEndFunc sets a function that is called once the stream of a
+// session closed, e.g. to unregister the session.
+func WithSessionEndFunc(fn func(ctx context.Context, session *domain.ClientSession)) SSEOption {
+	return func(s *SSEServer) {
+		s.onSessionEnd = fn
+	}
+}
+
 // NewSSEServer creates a new SSE server instance with the given notification sender and options.
 func NewSSEServer(notifier *NotificationSender, mcpHandler func(ctx context.Context, rawMessage json.RawMessage) interface{}, opts ...SSEOption) *SSEServer {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// Create default logger
+	// Log to stderr by default, as stdout is reserved for JSON-RPC messages
+	// when the stdio transport is served by the same process
 	defaultLogger, err := logging.New(logging.Config{
 		Level:       logging.InfoLevel,
 		Development: true,
-		OutputPaths: []string{"stdout"},
+		OutputPaths: []string{"stderr"},
 		InitialFields: logging.Fields{
 			"component": "sse-server",
 		},
@@ -245,6 +195,7 @@ func NewSSEServer(notifier *NotificationSender, mcpHandler func(ctx context.Cont
 		sseEndpoint:     "/sse",
 		messageEndpoint: "/message",
 		mcpHandler:      mcpHandler,
+		drainer:         NewDrainer(),
 		connectionPool:  NewConnectionPool(),
 		logger:          defaultLogger,
 		ctx:             ctx,
@@ -256,6 +207,15 @@ func NewSSEServer(notifier *NotificationSender, mcpHandler func(ctx context.Cont
 		opt(s)
 	}
 
+	// Apply the default policy unless one was configured or disabled explicitly
+	if !s.corsSet {
+		if s.localhostOnly {
+			s.cors = NewCORSPolicy(LocalhostCORSConfig())
+		} else {
+			s.cors = NewCORSPolicy(DefaultCORSConfig())
+		}
+	}
+
 	return s
 }
 
@@ -274,6 +234,10 @@ func NewTestServer(notifier *NotificationSender, mcpHandler func(ctx context.Con
 // Start begins serving SSE connections on the specified address.
 // It sets up HTTP handlers for SSE and message endpoints.
 func (s *SSEServer) Start(addr string) error {
+	if s.localhostOnly {
+		addr = LocalhostAddr(addr)
+	}
+
 	s.srv = &http.Server{
 		Addr:    addr,
 		Handler: s,
@@ -282,19 +246,33 @@ func (s *SSEServer) Start(addr string) error {
 	return s.srv.ListenAndServe()
 }
 
-// Shutdown gracefully stops the SSE server, closing all active sessions
-// and shutting down the HTTP server.
+// Shutdown gracefully stops the SSE server. It stops accepting new streams and
+// messages, waits for in-flight messages to complete until ctx is done, sends
+// a final shutdown event to every open stream and closes it, and finally shuts
+// down the HTTP server. If ctx has no deadline, DefaultDrainTimeout applies.
 func (s *SSEServer) Shutdown(ctx context.Context) error {
-	// Cancel the server context first to stop accepting new connections
-	s.cancel()
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultDrainTimeout)
+		defer cancel()
+	}
 
-	// Close all active sessions
+	err := s.drainer.Drain(ctx)
+	if err != nil {
+		s.logger.Warn("Shutdown deadline reached with messages in flight", logging.Fields{"inFlight": s.drainer.Active()})
+	}
+
+	// Tell clients the server is going away before their streams are closed
+	s.connectionPool.broadcastRaw(ShutdownEvent)
 	s.connectionPool.CloseAll()
+	s.cancel()
 
 	if s.srv != nil {
-		return s.srv.Shutdown(ctx)
+		if shutdownErr := s.srv.Shutdown(ctx); shutdownErr != nil {
+			return shutdownErr
+		}
 	}
-	return nil
+	return err
 }
 
 // handleSSE handles incoming SSE connection requests.
@@ -305,100 +283,76 @@ func (s *SSEServer) handleSSE(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if s.drainer.Draining() {
+		http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	// The ID keys the session everywhere, so it is never taken from the client,
+	// which could otherwise take over the session of another client
+	sessionID := uuid.New().String()
 
-	flusher, ok := w.(http.Flusher)
-	if !ok {
+	// The stream lives until the server shuts down, the session is closed or
+	// the client goes away
+	session, err := newSSESession(s.ctx, w, sessionID, s.notifier.BufferSize())
+	if err != nil {
 		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
 		return
 	}
+	defer session.Close()
+	stop := context.AfterFunc(r.Context(), session.Close)
+	defer stop()
 
-	sessionID := r.URL.Query().Get("session")
-	if sessionID == "" {
-		sessionID = uuid.New().String()
-	}
+	// The session lives as long as the stream, and is shared by its messages
+	client := domain.NewClientSession(r.UserAgent())
+	client.ID = sessionID
+	client.Transport = domain.TransportSSE
+	client.Principal = domain.PrincipalFromContext(r.Context())
+	client.ClientIdentity = domain.ClientIdentityFromContext(r.Context())
+	session.principal = client.Principal
+	session.identity = client.ClientIdentity
+	session.client = client
 
-	// Create a context for this session that is a child of the server context
-	// and can be canceled when the session ends
-	sessionCtx, sessionCancel := context.WithCancel(s.ctx)
-
-	session := &sseSession{
-		writer:     w,
-		flusher:    flusher,
-		done:       make(chan struct{}),
-		eventQueue: make(chan string, 100), // Buffer for events
-		id:         sessionID,
-		notifChan:  make(NotificationChannel, 100),
-		ctx:        sessionCtx,
-		cancel:     sessionCancel,
-	}
+	// Closing the session ends the stream
+	client.OnClose(session.Close)
 
 	// Add the session to the connection pool
-	s.connectionPool.Add(session)
-	defer s.connectionPool.Remove(sessionID)
+	s.connectionPool.AddSession(session)
+	defer s.connectionPool.RemoveSession(sessionID)
 
-	s.notifier.RegisterSession(&MCPSession{
-		id:        sessionID,
-		userAgent: r.UserAgent(),
-		notifChan: session.notifChan,
-	})
+	if s.onSessionStart != nil {
+		s.onSessionStart(r.Context(), client)
+	}
+	if s.onSessionEnd != nil {
+		defer s.onSessionEnd(context.WithoutCancel(r.Context()), client)
+	}
+
+	// A session disconnected as a slow consumer ends the stream
+	notifSession := newMCPSession(sessionID, r.UserAgent(), session.notifChan)
+	notifSession.OnClose(session.Close)
+	notifSession.SetClientSession(client)
+	s.notifier.RegisterSession(notifSession)
 	defer s.notifier.UnregisterSession(sessionID)
 
-	// Start notification handler for this session
-	go func() {
-		for {
-			select {
-			case notification := <-session.notifChan:
-				eventData, err := json.Marshal(notification)
-				if err == nil {
-					select {
-					case session.eventQueue <- fmt.Sprintf("event: message\ndata: %s\n\n", eventData):
-						// Event queued successfully
-					case <-session.done:
-						return
-					case <-session.ctx.Done():
-						return
-					}
-				}
-			case <-session.done:
-				return
-			case <-session.ctx.Done():
-				return
-			case <-r.Context().Done():
-				return
-			}
+	// Messages posted for this session on other instances are relayed here
+	if bus := s.notifier.Bus(); bus != nil {
+		unsubscribe, err := bus.Subscribe(busTopicSessionMessages+sessionID, func(payload []byte) {
+			go s.receiveRoutedMessage(bus, session, payload)
+		})
+		if err != nil {
+			s.logger.Warn("Failed to receive routed messages for session", logging.Fields{"sessionID": sessionID, "error": err.Error()})
+		} else {
+			defer unsubscribe()
 		}
-	}()
+	}
 
+	// The connected and endpoint events are the first events of the stream
 	messageEndpoint := fmt.Sprintf("%s?sessionId=%s", s.CompleteMessageEndpoint(), sessionID)
+	_ = s.connectionPool.send(session, fmt.Sprintf("event: connected\ndata: {\"sessionId\": \"%s\"}\n\n", sessionID))
+	_ = s.connectionPool.send(session, fmt.Sprintf("event: endpoint\ndata: %s\n\n", messageEndpoint))
 
-	// Send the initial connected event
-	fmt.Fprintf(w, "event: connected\ndata: {\"sessionId\": \"%s\"}\n\n", sessionID)
-	flusher.Flush()
-
-	// Send the endpoint event
-	fmt.Fprintf(w, "event: endpoint\ndata: %s\n\n", messageEndpoint)
-	flusher.Flush()
-
-	// Main event loop - this runs in the HTTP handler goroutine
-	for {
-		select {
-		case event := <-session.eventQueue:
-			// Write the event to the response
-			fmt.Fprint(w, event)
-			flusher.Flush()
-		case <-r.Context().Done():
-			sessionCancel()
-			close(session.done)
-			return
-		case <-session.ctx.Done():
-			close(session.done)
-			return
-		}
-	}
+	// Write events in the HTTP handler goroutine until the stream ends
+	session.Start()
 }
 
 // handleMessage processes incoming JSON-RPC messages from clients and sends responses
@@ -409,22 +363,57 @@ func (s *SSEServer) handleMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reject new messages while draining, but let in-flight ones complete
+	if !s.drainer.Begin() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(domain.CreateErrorResponse("2.0", nil, domain.BusyCode, "Server is shutting down"))
+		return
+	}
+	defer s.drainer.End()
+
 	sessionID := r.URL.Query().Get("sessionId")
 	if sessionID == "" {
 		s.writeJSONRPCError(w, nil, -32602, "Missing sessionId")
 		return
 	}
 
-	session, ok := s.connectionPool.Get(sessionID)
-	if !ok {
+	pooled, ok := s.connectionPool.GetSession(sessionID)
+	session, isSSE := pooled.(*sseSession)
+	if !ok || !isSSE {
+		// The stream of the session may be held by another instance
+		if bus := s.notifier.Bus(); bus != nil {
+			s.routeMessage(w, r, bus, sessionID)
+			return
+		}
 		s.writeJSONRPCError(w, nil, -32602, "Invalid session ID")
 		return
 	}
 
+	// Only the principal that opened the stream may post messages to it
+	if !samePrincipal(session.principal, domain.PrincipalFromContext(r.Context())) {
+		s.logger.Warn("Rejected message for session owned by another principal", logging.Fields{"sessionID": sessionID})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(domain.CreateErrorResponse("2.0", nil, domain.ForbiddenCode, "Session belongs to a different principal"))
+		return
+	}
+
+	// Only the client certificate that opened the stream may post messages to it
+	if !sameClientIdentity(session.identity, domain.ClientIdentityFromContext(r.Context())) {
+		s.logger.Warn("Rejected message for session owned by another client certificate", logging.Fields{"sessionID": sessionID})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(domain.CreateErrorResponse("2.0", nil, domain.ForbiddenCode, "Session belongs to a different client certificate"))
+		return
+	}
+
 	// Create context for the message handler
 	ctx := r.Context()
-	// Add the session ID to the context so tool handlers can access it
+	// Add the session to the context so tool handlers can access it
 	ctx = context.WithValue(ctx, SessionIDContextKey, sessionID)
+	ctx = domain.ContextWithClientSession(ctx, session.client)
+	session.client.Touch()
 
 	if s.contextFunc != nil {
 		ctx = s.contextFunc(ctx, r)
@@ -445,20 +434,13 @@ func (s *SSEServer) handleMessage(w http.ResponseWriter, r *http.Request) {
 		eventData, _ := json.Marshal(response)
 
 		// Queue the event for sending via SSE
-		select {
-		case session.eventQueue <- fmt.Sprintf("event: message\ndata: %s\n\n", eventData):
-			// Event queued successfully
-		case <-session.done:
-			// Session is closed, don't try to queue
-		case <-session.ctx.Done():
-			// Session context was canceled
-		default:
-			// Queue is full, could log this
+		if err := s.connectionPool.send(session, formatMessageEvent(eventData)); errors.Is(err, ErrChannelFull) {
+			s.logger.Warn("Dropped response event, session queue is full", logging.Fields{"sessionID": sessionID})
 		}
 
 		// Send HTTP response
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
+		w.WriteHeader(ResponseStatus(w, response))
 		_ = json.NewEncoder(w).Encode(response)
 	} else {
 		// For notifications, just send 200 OK with no body
@@ -466,6 +448,22 @@ func (s *SSEServer) handleMessage(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// samePrincipal reports whether two principals identify the same caller.
+func samePrincipal(a, b *domain.Principal) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.ID == b.ID
+}
+
+// sameClientIdentity reports whether two client identities belong to the same certificate.
+func sameClientIdentity(a, b *domain.ClientIdentity) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Fingerprint == b.Fingerprint
+}
+
 // writeJSONRPCError writes a JSON-RPC error response with the given error details.
 func (s *SSEServer) writeJSONRPCError(
 	w http.ResponseWriter,
@@ -487,37 +485,23 @@ func (s *SSEServer) writeJSONRPCError(
 }
 
 // SendEventToSession sends an event to a specific SSE session identified by sessionID.
-// Returns an error if the session is not found or closed.
+// Returns an error if the session is not found or closed, or its queue is full.
 func (s *SSEServer) SendEventToSession(
 	sessionID string,
 	event interface{},
 ) error {
-	session, ok := s.connectionPool.Get(sessionID)
-	if !ok {
-		return fmt.Errorf("session not found: %s", sessionID)
-	}
-
-	eventData, err := json.Marshal(event)
-	if err != nil {
-		return err
-	}
+	return s.connectionPool.Send(sessionID, event)
+}
 
-	// Queue the event for sending via SSE
-	select {
-	case session.eventQueue <- fmt.Sprintf("event: message\ndata: %s\n\n", eventData):
-		return nil
-	case <-session.done:
-		return fmt.Errorf("session closed")
-	case <-session.ctx.Done():
-		return fmt.Errorf("session context canceled")
-	default:
-		return fmt.Errorf("event queue full")
-	}
+// ConnectionPool returns the pool of active SSE sessions.
+func (s *SSEServer) ConnectionPool() *ConnectionPool {
+	return s.connectionPool
 }
 
 // BroadcastEvent sends an event to all active SSE sessions.
-func (s *SSEServer) BroadcastEvent(event interface{}) {
-	s.connectionPool.Broadcast(event)
+// Sessions whose queue is full miss the event.
+func (s *SSEServer) BroadcastEvent(event interface{}) error {
+	return s.connectionPool.Broadcast(event)
 }
 
 func (s *SSEServer) GetUrlPath(input string) (string, error) {
@@ -554,6 +538,15 @@ func (s *SSEServer) CompleteMessagePath() string {
 
 // ServeHTTP implements the http.Handler interface.
 func (s *SSEServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.cors != nil {
+		s.cors.Handler(http.HandlerFunc(s.route)).ServeHTTP(w, r)
+		return
+	}
+	s.route(w, r)
+}
+
+// route dispatches a request to the SSE or message endpoint.
+func (s *SSEServer) route(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	// Use exact path matching rather than Contains
 	ssePath := s.CompleteSsePath()
@@ -1,6 +1,7 @@
 package server_test
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"io"
@@ -354,3 +355,42 @@ func TestSSEServer_SendEvent(t *testing.T) {
 func TestSSEServer_BroadcastEvent(t *testing.T) {
 	t.Skip("Skipping test that requires internal structure access")
 }
+
+// readSessionID reads the session ID from the connected event of a stream.
+func readSessionID(t *testing.T, reader *bufio.Reader) string {
+	t.Helper()
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if strings.HasPrefix(line, "data: {\"sessionId\"") {
+			var data struct {
+				SessionID string `json:"sessionId"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &data))
+			return data.SessionID
+		}
+	}
+}
+
+func TestSSEServer_IgnoresClientSessionID(t *testing.T) {
+	sseServer := server.NewSSEServer(server.NewNotificationSender("2.0"), mockMCPHandler)
+	testServer := httptest.NewServer(sseServer)
+	defer testServer.Close()
+
+	// Two streams asking for the same session ID get sessions of their own
+	var sessionIDs []string
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(testServer.URL + "/sse?session=victim")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		sessionIDs = append(sessionIDs, readSessionID(t, bufio.NewReader(resp.Body)))
+	}
+	assert.NotEqual(t, "victim", sessionIDs[0])
+	assert.NotEqual(t, sessionIDs[0], sessionIDs[1])
+
+	// The first client still owns its session
+	resp, err := http.Post(testServer.URL+"/message?sessionId="+sessionIDs[0], "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -7,108 +7,113 @@ import (
 	"net/http"
 
 	"github.com/FreePeak/cortex/internal/domain"
-	"github.com/google/uuid"
 )
 
 // sse_session.go defines the implementation of the domain.SSESession interface.
 
-// sseSession2 represents an active SSE connection and implements the domain.SSESession interface.
-type sseSession2 struct {
+// sseSession represents an active SSE connection and implements the domain.SSESession interface.
+// Every event of the stream is queued on eventQueue and written by Start, the only
+// goroutine that writes to the response.
+type sseSession struct {
 	writer     http.ResponseWriter
 	flusher    http.Flusher
-	done       chan struct{}
-	eventQueue chan string // Channel for queuing events
+	done       chan struct{} // Closed once Start returned
+	eventQueue chan string   // Channel for queuing formatted events
 	id         string
-	notifChan  NotificationChannel
+	notifChan  NotificationChannel // Notifications sent by the NotificationSender
 	ctx        context.Context
 	cancel     context.CancelFunc
+	principal  *domain.Principal      // Principal that opened the stream, nil if unauthenticated
+	identity   *domain.ClientIdentity // Client certificate that opened the stream, nil without mutual TLS
+	client     *domain.ClientSession  // Session passed to the handlers of every message of the stream
 }
 
-// NewSSESession creates a new SSE session.
-func NewSSESession(w http.ResponseWriter, userAgent string, bufferSize int) (domain.SSESession, error) {
+// newSSESession creates a session with the given ID that writes to w until ctx is
+// done or the session is closed. Queues hold up to bufferSize events each.
+func newSSESession(ctx context.Context, w http.ResponseWriter, id string, bufferSize int) (*sseSession, error) {
 	// Check if the ResponseWriter supports flushing
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		return nil, ErrResponseWriterNotFlusher
 	}
 
-	// Create a context with cancellation for this session
-	ctx, cancel := context.WithCancel(context.Background())
-
-	// Generate a unique ID for this session
-	sessionID := uuid.New().String()
-
-	session := &sseSession2{
+	ctx, cancel := context.WithCancel(ctx)
+	return &sseSession{
 		writer:     w,
 		flusher:    flusher,
 		done:       make(chan struct{}),
 		eventQueue: make(chan string, bufferSize),
-		id:         sessionID,
+		id:         id,
 		notifChan:  make(NotificationChannel, bufferSize),
 		ctx:        ctx,
 		cancel:     cancel,
-	}
-
-	return session, nil
+	}, nil
 }
 
 // ID returns the session ID.
-func (s *sseSession2) ID() string {
+func (s *sseSession) ID() string {
 	return s.id
 }
 
-// NotificationChannel returns the channel for sending notifications.
-func (s *sseSession2) NotificationChannel() chan<- string {
+// NotificationChannel returns the channel on which formatted events are queued
+// for the stream.
+func (s *sseSession) NotificationChannel() chan<- string {
 	return s.eventQueue
 }
 
-// Close closes the session.
-func (s *sseSession2) Close() {
+// Context returns the session's context, which is done once the session is closed.
+func (s *sseSession) Context() context.Context {
+	return s.ctx
+}
+
+// Close ends the stream. Start writes the events that are still queued, then returns.
+func (s *sseSession) Close() {
 	s.cancel()
-	close(s.done)
-	close(s.notifChan)
-	// We intentionally don't close eventQueue here to avoid panic
-	// when writing to a closed channel. It will be garbage collected
-	// when the session object is no longer referenced.
 }
 
-// Start begins processing events for this session.
-// This method should be called in a separate goroutine.
-func (s *sseSession2) Start() {
-	// Set headers for SSE
+// Start writes the SSE headers, then queued events and notifications until the
+// session is closed. It blocks, and is called by the handler of the stream.
+func (s *sseSession) Start() {
+	defer close(s.done)
+
 	s.writer.Header().Set("Content-Type", "text/event-stream")
 	s.writer.Header().Set("Cache-Control", "no-cache")
 	s.writer.Header().Set("Connection", "keep-alive")
-	s.writer.Header().Set("Access-Control-Allow-Origin", "*")
 	s.flusher.Flush()
 
-	// Main event loop - processing events from the eventQueue
+	notifications := s.notifChan
 	for {
 		select {
-		case <-s.ctx.Done():
-			// Context canceled, stop processing
-			return
-		case <-s.done:
-			// Session closed, stop processing
-			return
 		case event := <-s.eventQueue:
-			// Write the event directly to the response
-			_, _ = s.writer.Write([]byte(event))
-			s.flusher.Flush()
-		case notification := <-s.notifChan:
-			// Handle notifications from the MCP server
-			// This is important to maintain compatibility with the original implementation
-			// Converting notification to SSE event format
-			event, err := json.Marshal(notification)
+			s.write(event)
+		case notification, ok := <-notifications:
+			if !ok {
+				// The notification sender unregistered the session
+				notifications = nil
+				continue
+			}
+			eventData, err := json.Marshal(notification)
 			if err == nil {
-				_, _ = s.writer.Write([]byte(fmt.Sprintf("event: message\ndata: %s\n\n", event)))
-				s.flusher.Flush()
+				s.write(formatMessageEvent(eventData))
 			}
+		case <-s.ctx.Done():
+			// Write what is still queued, such as the shutdown event
+			for len(s.eventQueue) > 0 {
+				fmt.Fprint(s.writer, <-s.eventQueue)
+			}
+			s.flusher.Flush()
+			return
 		}
 	}
 }
 
-// Context returns the session's context.
-func (s *sseSession2) Context() context.Context {
-	return s.ctx
+// write writes an event to the stream and flushes it.
+func (s *sseSession) write(event string) {
+	fmt.Fprint(s.writer, event)
+	s.flusher.Flush()
+}
+
+// formatMessageEvent formats JSON data as an SSE message event.
+func formatMessageEvent(data []byte) string {
+	return fmt.Sprintf("event: message\ndata: %s\n\n", data)
 }
@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"sync"
@@ -82,18 +83,18 @@ func (w *strictNonFlusherWriter) WriteHeader(statusCode int) {
 func TestNewSSESession(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		w := newMockResponseWriterFlusher()
-		session, err := NewSSESession(w, "test-agent", 10)
+		session, err := newSSESession(context.Background(), w, "test-session", 10)
 
 		require.NoError(t, err)
 		assert.NotNil(t, session)
-		assert.NotEmpty(t, session.ID())
+		assert.Equal(t, "test-session", session.ID())
 	})
 
 	t.Run("Error_NonFlusher", func(t *testing.T) {
 		// Use our custom implementation that doesn't implement http.Flusher
 		w := &strictNonFlusherWriter{}
 
-		session, err := NewSSESession(w, "test-agent", 10)
+		session, err := newSSESession(context.Background(), w, "test-session", 10)
 
 		assert.Error(t, err)
 		assert.Equal(t, ErrResponseWriterNotFlusher, err)
@@ -103,7 +104,7 @@ func TestNewSSESession(t *testing.T) {
 
 func TestSSESession_ID(t *testing.T) {
 	w := newMockResponseWriterFlusher()
-	session, err := NewSSESession(w, "test-agent", 10)
+	session, err := newSSESession(context.Background(), w, "test-session", 10)
 	require.NoError(t, err)
 
 	id := session.ID()
@@ -112,7 +113,7 @@ func TestSSESession_ID(t *testing.T) {
 
 func TestSSESession_NotificationChannel(t *testing.T) {
 	w := newMockResponseWriterFlusher()
-	session, err := NewSSESession(w, "test-agent", 10)
+	session, err := newSSESession(context.Background(), w, "test-session", 10)
 	require.NoError(t, err)
 
 	ch := session.NotificationChannel()
@@ -121,7 +122,7 @@ func TestSSESession_NotificationChannel(t *testing.T) {
 
 func TestSSESession_Close(t *testing.T) {
 	w := newMockResponseWriterFlusher()
-	session, err := NewSSESession(w, "test-agent", 10)
+	session, err := newSSESession(context.Background(), w, "test-session", 10)
 	require.NoError(t, err)
 
 	// Verify that Close doesn't panic
@@ -141,7 +142,7 @@ func TestSSESession_Close(t *testing.T) {
 
 func TestSSESession_Context(t *testing.T) {
 	w := newMockResponseWriterFlusher()
-	session, err := NewSSESession(w, "test-agent", 10)
+	session, err := newSSESession(context.Background(), w, "test-session", 10)
 	require.NoError(t, err)
 
 	ctx := session.Context()
@@ -167,7 +168,7 @@ func TestSSESession_Context(t *testing.T) {
 
 func TestSSESession_Start(t *testing.T) {
 	w := newMockResponseWriterFlusher()
-	session, err := NewSSESession(w, "test-agent", 10)
+	session, err := newSSESession(context.Background(), w, "test-session", 10)
 	require.NoError(t, err)
 
 	// Start session in a goroutine
@@ -183,7 +184,8 @@ func TestSSESession_Start(t *testing.T) {
 	assert.Equal(t, "text/event-stream", headers.Get("Content-Type"))
 	assert.Equal(t, "no-cache", headers.Get("Cache-Control"))
 	assert.Equal(t, "keep-alive", headers.Get("Connection"))
-	assert.Equal(t, "*", headers.Get("Access-Control-Allow-Origin"))
+	// CORS headers are the responsibility of the HTTP handler, not the session
+	assert.Empty(t, headers.Get("Access-Control-Allow-Origin"))
 	assert.True(t, w.Flushed(), "The response should be flushed")
 
 	// Send an event to the session
@@ -204,7 +206,7 @@ func TestSSESession_Start(t *testing.T) {
 
 func TestSSESession_StartWithNotification(t *testing.T) {
 	w := newMockResponseWriterFlusher()
-	session, err := NewSSESession(w, "test-agent", 10)
+	session, err := newSSESession(context.Background(), w, "test-session", 10)
 	require.NoError(t, err)
 
 	// Start session in a goroutine
@@ -212,10 +214,6 @@ func TestSSESession_StartWithNotification(t *testing.T) {
 		session.Start()
 	}()
 
-	// Get the internal session to access notifChan
-	sseSession, ok := session.(*sseSession2)
-	require.True(t, ok)
-
 	// Give it a moment to set headers
 	time.Sleep(50 * time.Millisecond)
 
@@ -226,7 +224,7 @@ func TestSSESession_StartWithNotification(t *testing.T) {
 		Params:  map[string]interface{}{"key": "value"},
 	}
 
-	sseSession.notifChan <- notification
+	session.notifChan <- notification
 
 	// Give it a moment to process the notification
 	time.Sleep(50 * time.Millisecond)
@@ -240,3 +238,52 @@ func TestSSESession_StartWithNotification(t *testing.T) {
 	// Clean up
 	session.Close()
 }
+
+func TestSSESession_CloseWritesQueuedEvents(t *testing.T) {
+	w := newMockResponseWriterFlusher()
+	session, err := newSSESession(context.Background(), w, "test-session", 10)
+	require.NoError(t, err)
+
+	// Queue events and close the session before the stream starts
+	session.NotificationChannel() <- "event: message\ndata: first\n\n"
+	session.NotificationChannel() <- ShutdownEvent
+	session.Close()
+
+	done := make(chan struct{})
+	go func() {
+		session.Start()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Start did not return after the session was closed")
+	}
+
+	responseBody := w.Body().String()
+	assert.Contains(t, responseBody, "data: first")
+	assert.Contains(t, responseBody, "event: shutdown")
+}
+
+func TestSSESession_ParentContextEndsStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := newMockResponseWriterFlusher()
+	session, err := newSSESession(ctx, w, "test-session", 10)
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		session.Start()
+		close(done)
+	}()
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Start did not return after the parent context was canceled")
+	}
+	assert.Error(t, session.Context().Err())
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+// DefaultCertReloadInterval is how often the certificate files are checked for
+// changes if TLSConfig.ReloadInterval is not set.
+const DefaultCertReloadInterval = 10 * time.Second
+
+// TLSConfig contains the TLS configuration of the HTTP transport.
+type TLSConfig struct {
+	// CertFile and KeyFile are the PEM encoded certificate chain and private key
+	// of the server. The files are reloaded when they change, so that renewed
+	// certificates are picked up without a restart.
+	CertFile string
+	KeyFile  string
+
+	// Config is the base configuration, e.g. to set cipher suites. If no
+	// certificate files are given, it must provide the server certificate.
+	Config *tls.Config
+
+	// ClientCAFile is the PEM encoded bundle of CAs that client certificates are
+	// verified against. Setting it enables mutual TLS.
+	ClientCAFile string
+
+	// ClientAuth is the client certificate policy. It defaults to
+	// tls.RequireAndVerifyClientCert if ClientCAFile is set.
+	ClientAuth tls.ClientAuthType
+
+	// ReloadInterval is how often the certificate files are checked for changes.
+	// It defaults to DefaultCertReloadInterval.
+	ReloadInterval time.Duration
+}
+
+// NewTLSConfig creates the tls.Config of the HTTP transport from config.
+func NewTLSConfig(config TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.Config != nil {
+		tlsConfig = config.Config.Clone()
+	}
+
+	if config.CertFile != "" || config.KeyFile != "" {
+		reloader, err := NewCertReloader(config.CertFile, config.KeyFile, config.ReloadInterval)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = nil
+		tlsConfig.GetCertificate = reloader.GetCertificate
+	} else if len(tlsConfig.Certificates) == 0 && tlsConfig.GetCertificate == nil && tlsConfig.GetConfigForClient == nil {
+		return nil, errors.New("TLS requires a certificate and key file or a tls.Config with certificates")
+	}
+
+	if config.ClientCAFile != "" {
+		pem, err := os.ReadFile(config.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", config.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	if config.ClientAuth != tls.NoClientCert {
+		tlsConfig.ClientAuth = config.ClientAuth
+	}
+
+	return tlsConfig, nil
+}
+
+// CertReloader serves a certificate loaded from files, reloading it when the
+// files change. The files are checked at most once per interval, during a TLS
+// handshake, so that no background goroutine is needed.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	checkedAt time.Time
+}
+
+// NewCertReloader loads the certificate and key from the given files. The files
+// are checked for changes at most once per interval, which defaults to
+// DefaultCertReloadInterval.
+func NewCertReloader(certFile, keyFile string, interval time.Duration) (*CertReloader, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("both a certificate and a key file are required")
+	}
+	if interval <= 0 {
+		interval = DefaultCertReloadInterval
+	}
+
+	r := &CertReloader{certFile: certFile, keyFile: keyFile, interval: interval}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate returns the current certificate. It implements the
+// GetCertificate callback of tls.Config. If reloading changed files fails, e.g.
+// because only one of them has been replaced yet, the previous certificate is
+// kept until the next check.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checkedAt) >= r.interval {
+		r.checkedAt = time.Now()
+		if r.modified() {
+			_ = r.reloadLocked()
+		}
+	}
+	return r.cert, nil
+}
+
+// Reload loads the certificate and key from the files, regardless of whether
+// they changed.
+func (r *CertReloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reloadLocked()
+}
+
+// reloadLocked loads the certificate. The caller must hold r.mu.
+func (r *CertReloader) reloadLocked() error {
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	r.cert = &cert
+	r.certMod = certMod
+	r.keyMod = keyMod
+	r.checkedAt = time.Now()
+	return nil
+}
+
+// modified reports whether either file changed since the certificate was loaded.
+// The caller must hold r.mu.
+func (r *CertReloader) modified() bool {
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		return false
+	}
+	return !certMod.Equal(r.certMod) || !keyMod.Equal(r.keyMod)
+}
+
+// modTimes returns the modification times of the certificate and key files.
+func (r *CertReloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to stat certificate file: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to stat key file: %w", err)
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+// ClientIdentityFromCertificate returns the identity described by a client certificate.
+func ClientIdentityFromCertificate(cert *x509.Certificate) *domain.ClientIdentity {
+	fingerprint := sha256.Sum256(cert.Raw)
+	identity := &domain.ClientIdentity{
+		Subject:        cert.Subject.String(),
+		CommonName:     cert.Subject.CommonName,
+		Issuer:         cert.Issuer.String(),
+		SerialNumber:   cert.SerialNumber.String(),
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		Fingerprint:    hex.EncodeToString(fingerprint[:]),
+	}
+	for _, uri := range cert.URIs {
+		identity.URIs = append(identity.URIs, uri.String())
+	}
+	return identity
+}
+
+// ClientIdentityMiddleware attaches the identity of the verified TLS client
+// certificate to the request context, see domain.ClientIdentityFromContext.
+// Requests without a verified certificate are passed on unchanged.
+func ClientIdentityMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+			identity := ClientIdentityFromCertificate(r.TLS.VerifiedChains[0][0])
+			r = r.WithContext(domain.ContextWithClientIdentity(r.Context(), identity))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,299 @@
+package server_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+	"github.com/FreePeak/cortex/internal/infrastructure/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCert is a generated certificate and its key.
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert generates a certificate from the template, signed by parent, or
+// self-signed if parent is nil.
+func newTestCert(t *testing.T, template *x509.Certificate, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func newTestCA(t *testing.T) *testCert {
+	return newTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+}
+
+func newServerCert(t *testing.T, ca *testCert, commonName string) *testCert {
+	return newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: commonName},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+	}, ca)
+}
+
+func newClientCert(t *testing.T, ca *testCert) *testCert {
+	spiffeID, err := url.Parse("spiffe://example.org/agent")
+	require.NoError(t, err)
+	return newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "agent", Organization: []string{"Example"}},
+		URIs:        []*url.URL{spiffeID},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+	}, ca)
+}
+
+// writeFile writes data to a file in dir and returns its path.
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	cert := newServerCert(t, ca, "server")
+	certFile := writeFile(t, dir, "server.crt", cert.certPEM)
+	keyFile := writeFile(t, dir, "server.key", cert.keyPEM)
+
+	t.Run("requires a certificate", func(t *testing.T) {
+		_, err := server.NewTLSConfig(server.TLSConfig{})
+		assert.Error(t, err)
+
+		_, err = server.NewTLSConfig(server.TLSConfig{CertFile: certFile})
+		assert.Error(t, err)
+	})
+
+	t.Run("certificate files", func(t *testing.T) {
+		config, err := server.NewTLSConfig(server.TLSConfig{CertFile: certFile, KeyFile: keyFile})
+		require.NoError(t, err)
+		assert.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+		assert.Equal(t, tls.NoClientCert, config.ClientAuth)
+
+		served, err := config.GetCertificate(&tls.ClientHelloInfo{})
+		require.NoError(t, err)
+		assert.Equal(t, cert.cert.Raw, served.Certificate[0])
+	})
+
+	t.Run("base config", func(t *testing.T) {
+		pair, err := tls.X509KeyPair(cert.certPEM, cert.keyPEM)
+		require.NoError(t, err)
+
+		config, err := server.NewTLSConfig(server.TLSConfig{
+			Config: &tls.Config{Certificates: []tls.Certificate{pair}, MinVersion: tls.VersionTLS13},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, uint16(tls.VersionTLS13), config.MinVersion)
+		assert.Len(t, config.Certificates, 1)
+	})
+
+	t.Run("client CA enables mutual TLS", func(t *testing.T) {
+		caFile := writeFile(t, dir, "ca.crt", ca.certPEM)
+		config, err := server.NewTLSConfig(server.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile})
+		require.NoError(t, err)
+		assert.Equal(t, tls.RequireAndVerifyClientCert, config.ClientAuth)
+		assert.NotNil(t, config.ClientCAs)
+
+		config, err = server.NewTLSConfig(server.TLSConfig{
+			CertFile:     certFile,
+			KeyFile:      keyFile,
+			ClientCAFile: caFile,
+			ClientAuth:   tls.VerifyClientCertIfGiven,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, tls.VerifyClientCertIfGiven, config.ClientAuth)
+	})
+
+	t.Run("invalid client CA file", func(t *testing.T) {
+		badFile := writeFile(t, dir, "bad.crt", []byte("not a certificate"))
+		_, err := server.NewTLSConfig(server.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: badFile})
+		assert.Error(t, err)
+
+		_, err = server.NewTLSConfig(server.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: filepath.Join(dir, "missing.crt")})
+		assert.Error(t, err)
+	})
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	first := newServerCert(t, ca, "first")
+	certFile := writeFile(t, dir, "server.crt", first.certPEM)
+	keyFile := writeFile(t, dir, "server.key", first.keyPEM)
+
+	reloader, err := server.NewCertReloader(certFile, keyFile, time.Millisecond)
+	require.NoError(t, err)
+
+	served, err := reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, first.cert.Raw, served.Certificate[0])
+
+	// Replace the files with a renewed certificate
+	second := newServerCert(t, ca, "second")
+	writeFile(t, dir, "server.crt", second.certPEM)
+	writeFile(t, dir, "server.key", second.keyPEM)
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	require.NoError(t, os.Chtimes(keyFile, later, later))
+	time.Sleep(2 * time.Millisecond)
+
+	served, err = reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, second.cert.Raw, served.Certificate[0])
+
+	// A half-written update keeps the previous certificate
+	writeFile(t, dir, "server.key", []byte("garbage"))
+	evenLater := later.Add(time.Minute)
+	require.NoError(t, os.Chtimes(keyFile, evenLater, evenLater))
+	time.Sleep(2 * time.Millisecond)
+
+	served, err = reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, second.cert.Raw, served.Certificate[0])
+	assert.Error(t, reloader.Reload())
+
+	_, err = server.NewCertReloader(certFile, filepath.Join(dir, "missing.key"), 0)
+	assert.Error(t, err)
+}
+
+func TestClientIdentityMiddleware_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	serverCert := newServerCert(t, ca, "server")
+	clientCert := newClientCert(t, ca)
+
+	tlsConfig, err := server.NewTLSConfig(server.TLSConfig{
+		CertFile:     writeFile(t, dir, "server.crt", serverCert.certPEM),
+		KeyFile:      writeFile(t, dir, "server.key", serverCert.keyPEM),
+		ClientCAFile: writeFile(t, dir, "ca.crt", ca.certPEM),
+	})
+	require.NoError(t, err)
+
+	// Serve like the HTTP transport does, with the certificate from the TLS configuration
+	var identity *domain.ClientIdentity
+	srv := &http.Server{
+		Handler: server.ClientIdentityMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity = domain.ClientIdentityFromContext(r.Context())
+			_, _ = io.WriteString(w, identity.Name())
+		})),
+		TLSConfig: tlsConfig,
+		ErrorLog:  log.New(io.Discard, "", 0),
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.ServeTLS(listener, "", "") }()
+	defer srv.Close()
+	serverURL := "https://" + listener.Addr().String()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	t.Run("verified client certificate", func(t *testing.T) {
+		pair, err := tls.X509KeyPair(clientCert.certPEM, clientCert.keyPEM)
+		require.NoError(t, err)
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			Certificates: []tls.Certificate{pair},
+			MinVersion:   tls.VersionTLS12,
+		}}}
+
+		resp, err := client.Get(serverURL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, "spiffe://example.org/agent", string(body))
+		require.NotNil(t, identity)
+		assert.Equal(t, "agent", identity.CommonName)
+		assert.Equal(t, "CN=agent,O=Example", identity.Subject)
+		assert.Equal(t, "CN=Test CA", identity.Issuer)
+		assert.Equal(t, clientCert.cert.SerialNumber.String(), identity.SerialNumber)
+		assert.Len(t, identity.Fingerprint, 64)
+	})
+
+	t.Run("missing client certificate", func(t *testing.T) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:    roots,
+			MinVersion: tls.VersionTLS12,
+		}}}
+
+		resp, err := client.Get(serverURL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		assert.Error(t, err)
+	})
+
+	t.Run("certificate from another CA", func(t *testing.T) {
+		otherCert := newClientCert(t, newTestCA(t))
+		pair, err := tls.X509KeyPair(otherCert.certPEM, otherCert.keyPEM)
+		require.NoError(t, err)
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			Certificates: []tls.Certificate{pair},
+			MinVersion:   tls.VersionTLS12,
+		}}}
+
+		resp, err := client.Get(serverURL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		assert.Error(t, err)
+	})
+}
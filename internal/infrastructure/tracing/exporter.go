@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// InMemoryExporter keeps exported spans in memory. It is meant for tests.
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// NewInMemoryExporter creates a new, empty InMemoryExporter.
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// ExportSpan stores the span.
+func (e *InMemoryExporter) ExportSpan(span SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Spans returns the exported spans in the order in which they ended.
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+// SpansByName returns the exported spans with the given name.
+func (e *InMemoryExporter) SpansByName(name string) []SpanData {
+	var result []SpanData
+	for _, span := range e.Spans() {
+		if span.Name == name {
+			result = append(result, span)
+		}
+	}
+	return result
+}
+
+// Reset removes all stored spans.
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+// WriterExporter writes each span as a line of JSON, e.g. to stderr or a file,
+// from where it can be shipped to a tracing backend.
+type WriterExporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewWriterExporter creates a new WriterExporter that writes to w.
+func NewWriterExporter(w io.Writer) *WriterExporter {
+	return &WriterExporter{enc: json.NewEncoder(w)}
+}
+
+// jsonSpan is the JSON representation of a span, using OpenTelemetry field names.
+type jsonSpan struct {
+	TraceID           string                 `json:"traceId"`
+	SpanID            string                 `json:"spanId"`
+	ParentSpanID      string                 `json:"parentSpanId,omitempty"`
+	Name              string                 `json:"name"`
+	Kind              string                 `json:"kind"`
+	StartTimeUnixNano int64                  `json:"startTimeUnixNano"`
+	EndTimeUnixNano   int64                  `json:"endTimeUnixNano"`
+	Attributes        map[string]interface{} `json:"attributes,omitempty"`
+	Status            jsonStatus             `json:"status"`
+}
+
+type jsonStatus struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// ExportSpan writes the span. Write errors are dropped, as tracing must not
+// interfere with request handling.
+func (e *WriterExporter) ExportSpan(span SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_ = e.enc.Encode(jsonSpan{
+		TraceID:           span.SpanContext.TraceID,
+		SpanID:            span.SpanContext.SpanID,
+		ParentSpanID:      span.ParentSpanID,
+		Name:              span.Name,
+		Kind:              span.Kind.String(),
+		StartTimeUnixNano: span.StartTime.UnixNano(),
+		EndTimeUnixNano:   span.EndTime.UnixNano(),
+		Attributes:        span.Attributes,
+		Status:            jsonStatus{Code: span.StatusCode.String(), Message: span.StatusMessage},
+	})
+}
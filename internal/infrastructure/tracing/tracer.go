@@ -0,0 +1,349 @@
+// Package tracing records spans that follow the OpenTelemetry data model and
+// the W3C Trace Context format, without any external dependency. Spans are
+// handed to a pluggable Exporter once they end.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+// SpanKind describes the relationship of a span to its parent, as in OpenTelemetry.
+type SpanKind int
+
+// Available span kinds
+const (
+	SpanKindInternal SpanKind = iota
+	SpanKindServer
+	SpanKindClient
+)
+
+// String returns the OpenTelemetry name of the span kind.
+func (k SpanKind) String() string {
+	switch k {
+	case SpanKindServer:
+		return "server"
+	case SpanKindClient:
+		return "client"
+	default:
+		return "internal"
+	}
+}
+
+// StatusCode is the status of a span, as in OpenTelemetry.
+type StatusCode int
+
+// Available status codes
+const (
+	StatusUnset StatusCode = iota
+	StatusOK
+	StatusError
+)
+
+// String returns the OpenTelemetry name of the status code.
+func (c StatusCode) String() string {
+	switch c {
+	case StatusOK:
+		return "ok"
+	case StatusError:
+		return "error"
+	default:
+		return "unset"
+	}
+}
+
+// Common span attribute keys
+const (
+	AttrRPCSystem    = "rpc.system"
+	AttrRPCMethod    = "rpc.method"
+	AttrRPCErrorCode = "rpc.jsonrpc.error_code"
+	AttrMCPMethod    = "mcp.method.name"
+	AttrToolName     = "mcp.tool.name"
+	AttrSessionID    = "mcp.session.id"
+	AttrErrorType    = "error.type"
+)
+
+// SpanData is the immutable record of an ended span that is passed to exporters.
+type SpanData struct {
+	Name          string
+	SpanContext   domain.SpanContext
+	ParentSpanID  string // Empty for root spans
+	Kind          SpanKind
+	StartTime     time.Time
+	EndTime       time.Time
+	Attributes    map[string]interface{}
+	StatusCode    StatusCode
+	StatusMessage string
+}
+
+// Duration returns how long the span took.
+func (d SpanData) Duration() time.Duration {
+	return d.EndTime.Sub(d.StartTime)
+}
+
+// Exporter receives spans once they end. Implementations must be safe for
+// concurrent use and should not block.
+type Exporter interface {
+	ExportSpan(span SpanData)
+}
+
+// ExporterFunc is a function type that implements Exporter
+type ExporterFunc func(span SpanData)
+
+// ExportSpan calls the exporter function
+func (f ExporterFunc) ExportSpan(span SpanData) {
+	f(span)
+}
+
+// Tracer creates spans and exports them once they end. A nil *Tracer is valid
+// and creates no spans, so tracing can be left unconfigured.
+type Tracer struct {
+	exporter Exporter
+}
+
+// NewTracer creates a new Tracer that exports spans to the given exporter.
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+// Start starts a span as a child of the span context carried by ctx, or as the
+// root of a new trace if there is none. It returns a copy of ctx that carries
+// the new span, which must be ended by calling End.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	parent := domain.SpanContextFromContext(ctx)
+	sc := domain.SpanContext{SpanID: newID(8), Sampled: true}
+	data := SpanData{
+		Name:       name,
+		Kind:       kind,
+		StartTime:  time.Now(),
+		Attributes: make(map[string]interface{}),
+	}
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.Sampled = parent.Sampled
+		data.ParentSpanID = parent.SpanID
+	} else {
+		sc.TraceID = newID(16)
+	}
+	data.SpanContext = sc
+
+	span := &Span{tracer: t, data: data}
+	return ContextWithSpan(ctx, span), span
+}
+
+// StartRequest starts a server span for a JSON-RPC request, and returns the
+// function that ends it with the error of the response. A trace context in the
+// request's _meta takes precedence over the one carried by ctx, e.g. from a
+// traceparent header. It implements the tracer of the server service.
+func (t *Tracer) StartRequest(ctx context.Context, method string, params interface{}, sessionID string) (context.Context, func(rpcErr *domain.JSONRPCError)) {
+	ctx, span := t.startRequest(ctx, method, params, sessionID)
+	return ctx, span.EndRequest
+}
+
+// startRequest starts the server span of StartRequest.
+func (t *Tracer) startRequest(ctx context.Context, method string, params interface{}, sessionID string) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	if sc, ok := domain.SpanContextFromMeta(params); ok {
+		ctx = domain.ContextWithSpanContext(ctx, sc)
+	}
+
+	// Name tool calls after their target, as in the MCP semantic conventions
+	name := method
+	toolName := ""
+	if method == "tools/call" {
+		if p, ok := params.(map[string]interface{}); ok {
+			toolName, _ = p["name"].(string)
+		}
+	}
+	if toolName != "" {
+		name += " " + toolName
+	}
+
+	ctx, span := t.Start(ctx, name, SpanKindServer)
+	span.SetAttribute(AttrRPCSystem, "jsonrpc")
+	span.SetAttribute(AttrRPCMethod, method)
+	span.SetAttribute(AttrMCPMethod, method)
+	if toolName != "" {
+		span.SetAttribute(AttrToolName, toolName)
+	}
+	if sessionID != "" {
+		span.SetAttribute(AttrSessionID, sessionID)
+	}
+	return ctx, span
+}
+
+// StartToolCall starts an internal span for the execution of a tool, and
+// returns the function that ends it with the error of the call. It implements
+// the tracer of the server service.
+func (t *Tracer) StartToolCall(ctx context.Context, tool, sessionID string) (context.Context, func(err error)) {
+	ctx, span := t.Start(ctx, "execute_tool "+tool, SpanKindInternal)
+	span.SetAttribute(AttrToolName, tool)
+	if sessionID != "" {
+		span.SetAttribute(AttrSessionID, sessionID)
+	}
+	return ctx, func(err error) {
+		span.RecordError(err)
+		span.End()
+	}
+}
+
+// export hands an ended span to the exporter.
+func (t *Tracer) export(data SpanData) {
+	if t.exporter != nil && data.SpanContext.Sampled {
+		t.exporter.ExportSpan(data)
+	}
+}
+
+// Span is an operation within a trace. All methods are safe for concurrent use
+// and do nothing on a nil *Span.
+type Span struct {
+	tracer *Tracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+// SpanContext returns the span context of the span.
+func (s *Span) SpanContext() domain.SpanContext {
+	if s == nil {
+		return domain.SpanContext{}
+	}
+	return s.data.SpanContext
+}
+
+// SetAttribute sets an attribute on the span.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.Attributes[key] = value
+	}
+}
+
+// SetStatus sets the status of the span.
+func (s *Span) SetStatus(code StatusCode, message string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.data.StatusCode = code
+		s.data.StatusMessage = message
+	}
+}
+
+// RecordError marks the span as failed with the given error. A nil error is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.SetAttribute(AttrErrorType, errorType(err))
+	s.SetStatus(StatusError, err.Error())
+}
+
+// End ends the span and exports it. Subsequent calls have no effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	s.tracer.export(data)
+}
+
+// EndRequest ends a span started by StartRequest, recording the JSON-RPC error
+// of the response, if any.
+func (s *Span) EndRequest(rpcErr *domain.JSONRPCError) {
+	if s == nil {
+		return
+	}
+	if rpcErr != nil {
+		s.SetAttribute(AttrRPCErrorCode, rpcErr.Code)
+		s.SetStatus(StatusError, rpcErr.Message)
+	}
+	s.End()
+}
+
+// spanKey is the context key under which the current span is stored.
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx that carries the span and its span context.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	ctx = context.WithValue(ctx, spanKey{}, span)
+	return domain.ContextWithSpanContext(ctx, span.SpanContext())
+}
+
+// SpanFromContext returns the current span stored in ctx, or nil if there is none.
+// Tool handlers can use it to add attributes to the span of their tool call.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Extract returns a copy of ctx that carries the trace context of the request's
+// traceparent header, if it has a valid one.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	if sc, ok := domain.ParseTraceparent(header.Get(domain.TraceparentHeader)); ok {
+		return domain.ContextWithSpanContext(ctx, sc)
+	}
+	return ctx
+}
+
+// Inject sets the traceparent header from the span context carried by ctx.
+func Inject(ctx context.Context, header http.Header) {
+	if sc := domain.SpanContextFromContext(ctx); sc.IsValid() {
+		header.Set(domain.TraceparentHeader, sc.Traceparent())
+	}
+}
+
+// Middleware extracts the trace context of incoming HTTP requests, so that the
+// spans created while handling them join the caller's trace.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(Extract(r.Context(), r.Header)))
+	})
+}
+
+// errorType returns the low-cardinality error.type attribute of an error, which
+// is the type of the error as recommended by the OpenTelemetry conventions.
+func errorType(err error) string {
+	return fmt.Sprintf("%T", err)
+}
+
+// newID returns a random, non-zero hex ID of n bytes.
+func newID(n int) string {
+	b := make([]byte, n)
+	for {
+		_, _ = rand.Read(b)
+		for _, c := range b {
+			if c != 0 {
+				return hex.EncodeToString(b)
+			}
+		}
+	}
+}
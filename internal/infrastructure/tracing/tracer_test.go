@@ -0,0 +1,183 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FreePeak/cortex/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestTracer_Start(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer(exporter)
+
+	ctx, parent := tracer.Start(context.Background(), "parent", SpanKindServer)
+	assert.Same(t, parent, SpanFromContext(ctx))
+	assert.Equal(t, parent.SpanContext(), domain.SpanContextFromContext(ctx))
+
+	_, child := tracer.Start(ctx, "child", SpanKindInternal)
+	child.SetAttribute("key", "value")
+	child.RecordError(errors.New("boom"))
+	child.End()
+	child.End() // Ending twice exports once
+	parent.End()
+
+	spans := exporter.Spans()
+	require.Len(t, spans, 2)
+
+	childData, parentData := spans[0], spans[1]
+	assert.Equal(t, "child", childData.Name)
+	assert.True(t, childData.SpanContext.IsValid())
+	assert.Equal(t, parentData.SpanContext.TraceID, childData.SpanContext.TraceID)
+	assert.Equal(t, parentData.SpanContext.SpanID, childData.ParentSpanID)
+	assert.Empty(t, parentData.ParentSpanID)
+	assert.Equal(t, "value", childData.Attributes["key"])
+	assert.Equal(t, "*errors.errorString", childData.Attributes[AttrErrorType])
+	assert.Equal(t, StatusError, childData.StatusCode)
+	assert.Equal(t, "boom", childData.StatusMessage)
+	assert.False(t, childData.EndTime.Before(childData.StartTime))
+}
+
+func TestTracer_RemoteParent(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer(exporter)
+
+	header := http.Header{}
+	header.Set("traceparent", testTraceparent)
+	ctx := Extract(context.Background(), header)
+
+	_, span := tracer.Start(ctx, "request", SpanKindServer)
+	span.End()
+
+	spans := exporter.Spans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].ParentSpanID)
+
+	// Spans of an unsampled trace are not exported
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, span = tracer.Start(Extract(context.Background(), header), "request", SpanKindServer)
+	span.End()
+	assert.Len(t, exporter.Spans(), 1)
+}
+
+func TestTracer_StartRequest(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer(exporter)
+
+	params := map[string]interface{}{
+		"name":  "echo",
+		"_meta": map[string]interface{}{"traceparent": testTraceparent},
+	}
+
+	// The trace context in _meta wins over the one from the HTTP header
+	header := http.Header{}
+	header.Set("traceparent", "00-11111111111111111111111111111111-2222222222222222-01")
+	ctx := Extract(context.Background(), header)
+
+	_, end := tracer.StartRequest(ctx, "tools/call", params, "session-1")
+	end(&domain.JSONRPCError{Code: domain.RateLimitedCode, Message: "rate limited"})
+
+	spans := exporter.SpansByName("tools/call echo")
+	require.Len(t, spans, 1)
+	data := spans[0]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", data.SpanContext.TraceID)
+	assert.Equal(t, SpanKindServer, data.Kind)
+	assert.Equal(t, "jsonrpc", data.Attributes[AttrRPCSystem])
+	assert.Equal(t, "tools/call", data.Attributes[AttrMCPMethod])
+	assert.Equal(t, "echo", data.Attributes[AttrToolName])
+	assert.Equal(t, "session-1", data.Attributes[AttrSessionID])
+	assert.Equal(t, domain.RateLimitedCode, data.Attributes[AttrRPCErrorCode])
+	assert.Equal(t, StatusError, data.StatusCode)
+
+	// A nil tracer returns a function that does nothing
+	var nilTracer *Tracer
+	gotCtx, end := nilTracer.StartRequest(ctx, "ping", nil, "")
+	assert.Equal(t, ctx, gotCtx)
+	end(nil)
+}
+
+func TestTracer_StartToolCall(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer(exporter)
+
+	ctx, parent := tracer.Start(context.Background(), "tools/call fail", SpanKindServer)
+	toolCtx, end := tracer.StartToolCall(ctx, "fail", "session-1")
+	end(errors.New("failed"))
+	parent.End()
+
+	spans := exporter.SpansByName("execute_tool fail")
+	require.Len(t, spans, 1)
+	data := spans[0]
+	assert.Equal(t, data.SpanContext, domain.SpanContextFromContext(toolCtx))
+	assert.Equal(t, parent.SpanContext().SpanID, data.ParentSpanID)
+	assert.Equal(t, SpanKindInternal, data.Kind)
+	assert.Equal(t, "fail", data.Attributes[AttrToolName])
+	assert.Equal(t, "session-1", data.Attributes[AttrSessionID])
+	assert.Equal(t, StatusError, data.StatusCode)
+	assert.Equal(t, "failed", data.StatusMessage)
+
+	// A nil tracer returns a function that does nothing
+	var nilTracer *Tracer
+	gotCtx, end := nilTracer.StartToolCall(ctx, "fail", "")
+	assert.Equal(t, ctx, gotCtx)
+	end(nil)
+}
+
+func TestTracer_Nil(t *testing.T) {
+	var tracer *Tracer
+	ctx := context.Background()
+
+	gotCtx, span := tracer.Start(ctx, "noop", SpanKindInternal)
+	assert.Equal(t, ctx, gotCtx)
+	assert.Nil(t, span)
+
+	// A nil span is safe to use
+	span.SetAttribute("key", "value")
+	span.RecordError(errors.New("boom"))
+	span.EndRequest(nil)
+	assert.False(t, span.SpanContext().IsValid())
+}
+
+func TestMiddleware(t *testing.T) {
+	var got domain.SpanContext
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = domain.SpanContextFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/message", nil)
+	req.Header.Set("traceparent", testTraceparent)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", got.TraceID)
+	assert.True(t, got.Remote)
+
+	// Inject writes the trace context back into outgoing headers
+	header := http.Header{}
+	Inject(domain.ContextWithSpanContext(context.Background(), got), header)
+	assert.Equal(t, testTraceparent, header.Get("traceparent"))
+}
+
+func TestWriterExporter(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer(NewWriterExporter(&buf))
+
+	_, span := tracer.Start(context.Background(), "execute_tool echo", SpanKindInternal)
+	span.SetAttribute(AttrToolName, "echo")
+	span.End()
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, "execute_tool echo", decoded["name"])
+	assert.Equal(t, "internal", decoded["kind"])
+	assert.Equal(t, span.SpanContext().TraceID, decoded["traceId"])
+	assert.Equal(t, map[string]interface{}{"code": "unset"}, decoded["status"])
+}
@@ -0,0 +1,140 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+	"github.com/FreePeak/cortex/internal/usecases"
+)
+
+// SessionInfo is the representation of a session in the session admin API.
+type SessionInfo struct {
+	ID              string             `json:"id"`
+	Transport       string             `json:"transport"`
+	UserAgent       string             `json:"userAgent,omitempty"`
+	Principal       string             `json:"principal,omitempty"`
+	ClientIdentity  string             `json:"clientIdentity,omitempty"`
+	ClientInfo      *domain.ClientInfo `json:"clientInfo,omitempty"`
+	ProtocolVersion string             `json:"protocolVersion,omitempty"`
+	Labels          map[string]string  `json:"labels,omitempty"`
+	Initialized     bool               `json:"initialized"`
+	CreatedAt       time.Time          `json:"createdAt"`
+	LastSeen        time.Time          `json:"lastSeen"`
+}
+
+// NewSessionInfo returns the admin representation of a session.
+func NewSessionInfo(session *domain.ClientSession) SessionInfo {
+	info := SessionInfo{
+		ID:              session.ID,
+		Transport:       session.Transport,
+		UserAgent:       session.UserAgent,
+		ClientIdentity:  session.ClientIdentity.Name(),
+		ProtocolVersion: session.ProtocolVersion,
+		Labels:          session.Labels(),
+		Initialized:     session.Initialized(),
+		CreatedAt:       session.CreatedAt,
+		LastSeen:        session.LastSeen(),
+	}
+	if session.Principal != nil {
+		info.Principal = session.Principal.ID
+	}
+	if session.ClientInfo != (domain.ClientInfo{}) {
+		clientInfo := session.ClientInfo
+		info.ClientInfo = &clientInfo
+	}
+	return info
+}
+
+// sessionAdmin serves the session admin API.
+type sessionAdmin struct {
+	service *usecases.ServerService
+}
+
+// NewSessionAdminHandler returns the handler of the session admin API, which
+// lets operators list sessions, disconnect them and notify their clients:
+//
+//	GET    /sessions                     lists the sessions
+//	GET    /sessions/{id}                returns a session
+//	DELETE /sessions/{id}                disconnects a session
+//	POST   /sessions/{id}/notifications  sends {"method": ..., "params": {...}} to its client
+//
+// The handler performs no authorization. Mount it behind your own, e.g.
+// mux.Handle("/admin/", requireAdmin(http.StripPrefix("/admin", handler))).
+func NewSessionAdminHandler(service *usecases.ServerService) http.Handler {
+	a := &sessionAdmin{service: service}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sessions", a.handleList)
+	mux.HandleFunc("GET /sessions/{id}", a.handleGet)
+	mux.HandleFunc("DELETE /sessions/{id}", a.handleDisconnect)
+	mux.HandleFunc("POST /sessions/{id}/notifications", a.handleNotify)
+	return mux
+}
+
+func (a *sessionAdmin) handleList(w http.ResponseWriter, r *http.Request) {
+	sessions, err := a.service.ListSessions(r.Context())
+	if err != nil {
+		writeAdminError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	infos := make([]SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		infos = append(infos, NewSessionInfo(session))
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].CreatedAt.Before(infos[j].CreatedAt) })
+	writeAdminJSON(w, http.StatusOK, map[string]interface{}{"sessions": infos})
+}
+
+func (a *sessionAdmin) handleGet(w http.ResponseWriter, r *http.Request) {
+	session, err := a.service.LookupSession(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeAdminError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, NewSessionInfo(session))
+}
+
+func (a *sessionAdmin) handleDisconnect(w http.ResponseWriter, r *http.Request) {
+	if err := a.service.DisconnectSession(r.Context(), r.PathValue("id")); err != nil {
+		writeAdminError(w, err, http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *sessionAdmin) handleNotify(w http.ResponseWriter, r *http.Request) {
+	var notification domain.Notification
+	if err := json.NewDecoder(r.Body).Decode(&notification); err != nil || notification.Method == "" {
+		writeAdminJSON(w, http.StatusBadRequest, map[string]string{"error": "body must be a notification with a method"})
+		return
+	}
+
+	// Sessions without an open stream cannot receive notifications
+	if err := a.service.NotifySession(r.Context(), r.PathValue("id"), &notification); err != nil {
+		writeAdminError(w, err, http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// writeAdminError writes err with 404 Not Found if the session does not exist,
+// and with the given status otherwise.
+func writeAdminError(w http.ResponseWriter, err error, status int) {
+	var notFound *domain.SessionNotFoundError
+	if errors.As(err, &notFound) {
+		status = http.StatusNotFound
+	}
+	writeAdminJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// writeAdminJSON writes v as a JSON response with the given status.
+func writeAdminJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
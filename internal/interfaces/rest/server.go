@@ -3,19 +3,29 @@ package rest
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/FreePeak/cortex/internal/domain"
+	"github.com/FreePeak/cortex/internal/infrastructure/auth"
 	"github.com/FreePeak/cortex/internal/infrastructure/logging"
+	"github.com/FreePeak/cortex/internal/infrastructure/metrics"
 	"github.com/FreePeak/cortex/internal/infrastructure/server"
+	"github.com/FreePeak/cortex/internal/infrastructure/tracing"
 	"github.com/FreePeak/cortex/internal/usecases"
 )
 
+// SessionIDHeader carries the ID of the session that an HTTP request belongs to.
+// The server returns it in the response to initialize.
+const SessionIDHeader = "Mcp-Session-Id"
+
 const (
 	// JSON-RPC version used by the MCP protocol
 	jsonRPCVersion = "2.0"
@@ -26,13 +36,23 @@ const (
 
 // MCPServer represents the HTTP server for the MCP protocol.
 type MCPServer struct {
-	service    *usecases.ServerService
-	httpServer *http.Server
-	sseServer  *server.SSEServer
-	notifier   *server.NotificationSender
-	logger     *logging.Logger
-	ctx        context.Context
-	cancel     context.CancelFunc
+	service       *usecases.ServerService
+	httpServer    *http.Server
+	sseServer     *server.SSEServer
+	notifier      *server.NotificationSender
+	logger        *logging.Logger
+	authenticator auth.Authenticator
+	oauth         *auth.OAuthResourceServer
+	cors          *server.CORSConfig
+	metrics       *metrics.MCPMetrics
+	localhostOnly bool
+	tlsConfig     *tls.Config     // Serves HTTPS if set
+	basePath      string          // Path prefix of every endpoint, without trailing slash
+	baseURL       string          // Scheme and host advertised in the SSE endpoint event
+	drainer       *server.Drainer // Tracks in-flight requests during shutdown
+	transports    []string        // HTTP transports served, every one if empty
+	ctx           context.Context
+	cancel        context.CancelFunc
 }
 
 // MCPServerOption is a function option for MCPServer
@@ -45,16 +65,109 @@ func WithLogger(logger *logging.Logger) MCPServerOption {
 	}
 }
 
+// WithAuthenticator requires every request to be authenticated by the given authenticator.
+// The authenticated principal is attached to the ClientSession passed to tool handlers.
+func WithAuthenticator(authenticator auth.Authenticator) MCPServerOption {
+	return func(s *MCPServer) {
+		s.authenticator = authenticator
+	}
+}
+
+// WithOAuthResourceServer protects every MCP endpoint with OAuth 2.1 access tokens
+// and serves the protected resource metadata at /.well-known/oauth-protected-resource
+// followed by the path of the resource, e.g. /api/mcp (RFC 9728).
+// If an authenticator is also configured, either one may accept a request.
+func WithOAuthResourceServer(rs *auth.OAuthResourceServer) MCPServerOption {
+	return func(s *MCPServer) {
+		s.oauth = rs
+	}
+}
+
+// WithCORS sets the cross-origin policy of every endpoint. Requests whose Origin
+// or Host header is not allowed are rejected, protecting the server against DNS
+// rebinding. By default cross-origin requests are rejected, and servers that
+// listen on the loopback interface allow local origins and hosts only.
+func WithCORS(config server.CORSConfig) MCPServerOption {
+	return func(s *MCPServer) {
+		s.cors = &config
+	}
+}
+
+// WithLocalhostOnly binds the server to the loopback interface only and, unless
+// a CORS policy is set explicitly, restricts browser access to local origins.
+func WithLocalhostOnly() MCPServerOption {
+	return func(s *MCPServer) {
+		s.localhostOnly = true
+	}
+}
+
+// WithTLS serves HTTPS with the given configuration, see server.NewTLSConfig.
+// If the configuration verifies client certificates, the identity of the client
+// is attached to the ClientSession passed to tool handlers.
+func WithTLS(config *tls.Config) MCPServerOption {
+	return func(s *MCPServer) {
+		s.tlsConfig = config
+	}
+}
+
+// WithBasePath serves every endpoint under the given path prefix, e.g. /api/mcp,
+// so that the handler can be mounted in an existing server's router. Requests
+// are expected to carry the full path, including the prefix.
+func WithBasePath(basePath string) MCPServerOption {
+	return func(s *MCPServer) {
+		basePath = strings.TrimSuffix(basePath, "/")
+		if basePath != "" && !strings.HasPrefix(basePath, "/") {
+			basePath = "/" + basePath
+		}
+		s.basePath = basePath
+	}
+}
+
+// WithBaseURL sets the scheme and host, e.g. https://example.com, of the message
+// endpoint URL sent to SSE clients. By default the URL is relative to the host
+// the client connected to.
+func WithBaseURL(baseURL string) MCPServerOption {
+	return func(s *MCPServer) {
+		s.baseURL = baseURL
+	}
+}
+
+// WithTransports serves only the given HTTP transports, domain.TransportSSE and
+// domain.TransportHTTP for streamable HTTP. By default both are served.
+func WithTransports(transports ...string) MCPServerOption {
+	return func(s *MCPServer) {
+		s.transports = transports
+	}
+}
+
+// WithMetrics records request metrics in the given MCPMetrics and serves its
+// registry at /metrics. By default the server uses a registry of its own.
+func WithMetrics(m *metrics.MCPMetrics) MCPServerOption {
+	return func(s *MCPServer) {
+		s.metrics = m
+	}
+}
+
+// WithNotificationSender registers SSE sessions with the given sender, so that
+// notifications sent through the service reach the SSE streams. By default the
+// server uses a sender of its own.
+func WithNotificationSender(notifier *server.NotificationSender) MCPServerOption {
+	return func(s *MCPServer) {
+		s.notifier = notifier
+	}
+}
+
 // NewMCPServer creates a new MCP server.
 func NewMCPServer(service *usecases.ServerService, addr string, opts ...MCPServerOption) *MCPServer {
 	// Create root context for the server
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// Create default logger
+	// Log to stderr by default, as stdout is reserved for JSON-RPC messages
+	// when the stdio transport is served by the same process
 	defaultLogger, err := logging.New(logging.Config{
 		Level:       logging.InfoLevel,
 		Development: true,
-		OutputPaths: []string{"stdout"},
+		OutputPaths: []string{"stderr"},
 		InitialFields: logging.Fields{
 			"component": "mcp-server",
 		},
@@ -64,14 +177,12 @@ func NewMCPServer(service *usecases.ServerService, addr string, opts ...MCPServe
 		defaultLogger = logging.Default()
 	}
 
-	notifier := server.NewNotificationSender(jsonRPCVersion)
-
 	s := &MCPServer{
-		service:  service,
-		notifier: notifier,
-		logger:   defaultLogger,
-		ctx:      ctx,
-		cancel:   cancel,
+		service: service,
+		logger:  defaultLogger,
+		drainer: server.NewDrainer(),
+		ctx:     ctx,
+		cancel:  cancel,
 	}
 
 	// Apply all options
@@ -79,6 +190,10 @@ func NewMCPServer(service *usecases.ServerService, addr string, opts ...MCPServe
 		opt(s)
 	}
 
+	if s.notifier == nil {
+		s.notifier = server.NewNotificationSender(jsonRPCVersion)
+	}
+
 	// Create message handler function for the SSE server
 	mcpHandler := func(ctx context.Context, rawMessage json.RawMessage) interface{} {
 		// Create a child context from the server context
@@ -98,8 +213,14 @@ func NewMCPServer(service *usecases.ServerService, addr string, opts ...MCPServe
 	sseOptions := []server.SSEOption{
 		server.WithMessageEndpoint("/message"),
 		server.WithSSEEndpoint("/sse"),
-		server.WithBasePath(""),
+		server.WithBasePath(s.basePath),
+		server.WithBaseURL(s.baseURL),
 		server.WithSSEContextFunc(contextFunc),
+		// An SSE session lives as long as its stream
+		server.WithSessionStartFunc(service.StartSession),
+		server.WithSessionEndFunc(service.EndSession),
+		// CORS is applied to every endpoint below, not just the SSE ones
+		server.WithCORSPolicy(nil),
 	}
 
 	// If we have a logger, pass it to the SSE server
@@ -107,74 +228,274 @@ func NewMCPServer(service *usecases.ServerService, addr string, opts ...MCPServe
 		sseOptions = append(sseOptions, server.WithLogger(s.logger))
 	}
 
-	sseServer := server.NewSSEServer(notifier, mcpHandler, sseOptions...)
+	sseServer := server.NewSSEServer(s.notifier, mcpHandler, sseOptions...)
 
 	s.sseServer = sseServer
 
+	if s.metrics == nil {
+		s.metrics = metrics.NewMCPMetrics(metrics.NewRegistry())
+	}
+	s.registerMetrics()
+
 	// Create HTTP server
 	mux := http.NewServeMux()
 
 	// Standard MCP endpoints
-	mux.HandleFunc("/", s.handleJSONRPC)        // Default endpoint for JSON-RPC
-	mux.HandleFunc("/jsonrpc", s.handleJSONRPC) // Alternative endpoint for JSON-RPC
-	mux.HandleFunc("/events", s.redirectToSSE)  // Redirect to SSE endpoint
+	if s.serves(domain.TransportHTTP) {
+		mux.HandleFunc(s.path("/"), s.handleJSONRPC)        // Default endpoint for JSON-RPC
+		mux.HandleFunc(s.path("/jsonrpc"), s.handleJSONRPC) // Alternative endpoint for JSON-RPC
+	}
 
 	// Add SSE server handler
-	mux.Handle("/sse", sseServer)
-	mux.Handle("/message", sseServer)
+	if s.serves(domain.TransportSSE) {
+		mux.HandleFunc(s.path("/events"), s.redirectToSSE) // Redirect to SSE endpoint
+		mux.Handle(s.path("/sse"), sseServer)
+		mux.Handle(s.path("/message"), sseServer)
+	}
+
+	// Prometheus metrics endpoint
+	mux.Handle(s.path("/metrics"), s.metrics.Registry().Handler())
 
 	// Add a simple status endpoint
-	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc(s.path("/status"), func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		name, version, _ := service.ServerInfo()
+		status := usecases.HealthStatusOK
+		if service.Draining() {
+			status = usecases.HealthStatusDraining
+		}
 		_ = json.NewEncoder(w).Encode(map[string]interface{}{
-			"status":   "ok",
+			"status":   status,
 			"name":     name,
 			"version":  version,
 			"protocol": mcpProtocolVersion,
 		})
 	})
 
+	// Require authentication on every endpoint if an authenticator is configured
+	var handler http.Handler = mux
+	authenticator := s.authenticator
+	if s.oauth != nil {
+		if authenticator != nil {
+			authenticator = auth.NewChainAuthenticator(s.oauth, authenticator)
+		} else {
+			authenticator = s.oauth
+		}
+	}
+	if authenticator != nil {
+		handler = auth.Middleware(authenticator, s.logger, mux)
+	}
+
+	// Probes and the protected resource metadata must be reachable without a token
+	root := http.NewServeMux()
+	root.HandleFunc(s.path("/healthz"), s.handleHealthz)
+	root.HandleFunc(s.path("/readyz"), s.handleReadyz)
+	if s.oauth != nil {
+		// Clients look for it on the root of the host, not under the base path
+		root.Handle(s.oauth.MetadataPath(), s.oauth.MetadataHandler())
+	}
+	root.Handle(s.path("/"), handler)
+	handler = root
+
+	// Attach the identity of a verified TLS client certificate
+	handler = server.ClientIdentityMiddleware(handler)
+
+	// Join the caller's trace if the request carries a traceparent header
+	handler = tracing.Middleware(handler)
+
+	if s.localhostOnly {
+		addr = server.LocalhostAddr(addr)
+	}
+
+	// Validate the Host and Origin headers and answer preflight requests before
+	// anything else. Unless other hosts are configured, the server is only
+	// reachable by local names and the names it is configured with, so DNS
+	// rebinding cannot reach it.
+	corsConfig := server.DefaultCORSConfig()
+	if s.cors != nil {
+		corsConfig = *s.cors
+	} else if s.localhostOnly || server.IsLoopbackAddr(addr) {
+		corsConfig = server.LocalhostCORSConfig()
+	}
+	if len(corsConfig.AllowedHosts) == 0 {
+		corsConfig.AllowedHosts = server.DefaultAllowedHosts(addr, s.baseURL)
+	}
+	handler = server.NewCORSPolicy(corsConfig).Handler(handler)
+
 	s.httpServer = &http.Server{
-		Addr:    addr,
-		Handler: mux,
+		Addr:      addr,
+		Handler:   handler,
+		TLSConfig: s.tlsConfig,
 	}
 
+	// Disconnect sessions that exceed the session limits until the server stops
+	go service.RunSessionReaper(s.ctx)
+
 	return s
 }
 
+// registerMetrics registers the gauges and counters maintained by the
+// transport and the service with the metrics registry.
+func (s *MCPServer) registerMetrics() {
+	registry := s.metrics.Registry()
+	pool := s.sseServer.ConnectionPool()
+
+	registry.NewGaugeFunc("mcp_sse_sessions_active", "Number of active SSE sessions.", func() float64 {
+		return float64(pool.Count())
+	})
+	registry.NewGaugeFunc("mcp_sse_event_queue_depth", "Number of SSE events waiting to be written, across all sessions.", func() float64 {
+		return float64(pool.QueueDepth())
+	})
+	registry.NewGaugeFunc("mcp_notification_queue_depth", "Number of notifications waiting to be delivered, across all sessions.", func() float64 {
+		return float64(s.notifier.QueueDepth())
+	})
+	registry.NewCounterFunc("mcp_notifications_delivered_total", "Total number of notifications queued for a session.", func() float64 {
+		return float64(s.notifier.Delivered())
+	})
+	registry.NewCounterFunc("mcp_notification_consumers_disconnected_total", "Total number of sessions disconnected because they did not keep up with notifications.", func() float64 {
+		return float64(s.notifier.Disconnected())
+	})
+	registry.NewCounterFunc("mcp_notifications_dropped_total", "Total number of notifications and events dropped because a session queue was full.", func() float64 {
+		return float64(pool.Dropped() + s.notifier.Dropped())
+	})
+	registry.NewCounterFunc("mcp_tool_panics_total", "Total number of panics recovered from tool handlers.", func() float64 {
+		return float64(s.service.ToolPanicCount())
+	})
+	registry.NewCounterFunc("mcp_tool_rate_limited_total", "Total number of tool calls rejected by rate limits.", func() float64 {
+		return float64(s.service.RateLimitedCount())
+	})
+}
+
+// path returns the path of an endpoint under the base path.
+func (s *MCPServer) path(endpoint string) string {
+	return s.basePath + endpoint
+}
+
+// endpoints returns the paths the server serves, for logging.
+func (s *MCPServer) endpoints() []string {
+	var endpoints []string
+	if s.serves(domain.TransportHTTP) {
+		endpoints = append(endpoints, "/", "/jsonrpc")
+	}
+	if s.serves(domain.TransportSSE) {
+		endpoints = append(endpoints, "/sse", "/message", "/events")
+	}
+	return append(endpoints, "/status", "/metrics", "/healthz", "/readyz")
+}
+
+// serves reports whether the server serves the given HTTP transport.
+func (s *MCPServer) serves(transport string) bool {
+	if len(s.transports) == 0 {
+		return true
+	}
+	for _, t := range s.transports {
+		if t == transport {
+			return true
+		}
+	}
+	return false
+}
+
 // redirectToSSE redirects clients to the SSE endpoint
 func (s *MCPServer) redirectToSSE(w http.ResponseWriter, r *http.Request) {
-	http.Redirect(w, r, "/sse", http.StatusFound)
+	http.Redirect(w, r, s.path("/sse"), http.StatusFound)
+}
+
+// Handler returns the handler that serves every MCP endpoint, for mounting in
+// an existing server instead of calling Start. Mount it at the base path set
+// with WithBasePath, e.g. mux.Handle("/api/mcp/", s.Handler()), and call Stop
+// to drain it before shutting down that server.
+func (s *MCPServer) Handler() http.Handler {
+	return s.httpServer.Handler
 }
 
-// Start starts the MCP server.
+// Start starts the MCP server. It serves HTTPS if a TLS configuration is set.
 func (s *MCPServer) Start() error {
-	s.logger.Info("Starting MCP server", logging.Fields{"address": s.httpServer.Addr})
-	s.logger.Info("Available endpoints", logging.Fields{"endpoints": "/, /jsonrpc, /sse, /message, /events, /status"})
+	s.logger.Info("Starting MCP server", logging.Fields{"address": s.httpServer.Addr, "tls": s.tlsConfig != nil})
+	s.logger.Info("Available endpoints", logging.Fields{"endpoints": strings.Join(s.endpoints(), ", ")})
+	if s.tlsConfig != nil {
+		// The certificate is provided by the TLS configuration
+		return s.httpServer.ListenAndServeTLS("", "")
+	}
 	return s.httpServer.ListenAndServe()
 }
 
-// Stop stops the MCP server.
+// Stop gracefully stops the MCP server. It fails readiness probes, rejects new
+// requests, waits for in-flight requests until ctx is done, sends a final event
+// to open SSE streams and closes them. If ctx has no deadline,
+// server.DefaultDrainTimeout applies.
 func (s *MCPServer) Stop(ctx context.Context) error {
-	// Cancel our internal context first to signal all ongoing operations to stop
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, server.DefaultDrainTimeout)
+		defer cancel()
+	}
+
+	// Fail readiness probes so that no new traffic is routed to this server
+	s.service.SetDraining(true)
+
+	// Reject new requests and wait for in-flight ones, such as tool calls, to complete
+	drainErr := s.drainer.Drain(ctx)
+	if drainErr != nil {
+		s.logger.Warn("Shutdown deadline reached with requests in flight", logging.Fields{"inFlight": s.drainer.Active()})
+	}
+
+	// Send the final event to open SSE streams and close them
+	if err := s.sseServer.Shutdown(ctx); err != nil && drainErr == nil {
+		drainErr = err
+	}
+
+	// Cancel our internal context to signal all remaining operations to stop
 	s.cancel()
 
-	// Shutdown the HTTP server
-	err := s.httpServer.Shutdown(ctx)
+	// Shutdown the HTTP server, cutting off requests that outlived the deadline
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		_ = s.httpServer.Close()
+		return err
+	}
+	return drainErr
+}
+
+// handleHealthz reports whether the process is alive. It succeeds as long as the
+// server can handle HTTP requests, including while it is draining.
+func (s *MCPServer) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": usecases.HealthStatusOK})
+}
 
-	// Return any error from shutting down the HTTP server
-	return err
+// handleReadyz reports whether the server should receive traffic. It runs the
+// registered health checks and fails while the server is draining.
+func (s *MCPServer) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	report := s.service.CheckReadiness(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if !report.Ready() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(report)
 }
 
 // handleJSONRPC handles JSON-RPC requests over HTTP directly.
 func (s *MCPServer) handleJSONRPC(w http.ResponseWriter, r *http.Request) {
+	// A client ends its session with a DELETE request
+	if r.Method == http.MethodDelete {
+		s.handleDeleteSession(w, r)
+		return
+	}
+
 	// Only accept POST requests
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
+	// Continue the session named by the request, or start a new one
+	session, ok := s.requestSession(w, r)
+	if !ok {
+		return
+	}
+
 	// Read request body
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -186,23 +507,99 @@ func (s *MCPServer) handleJSONRPC(w http.ResponseWriter, r *http.Request) {
 	// This ensures the context is canceled if either the request ends or the server is stopped
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
+	ctx = domain.ContextWithClientSession(ctx, session)
 
 	// Process the message
 	response := s.processMessage(ctx, body)
 
+	// Tell the client the ID of the session started by its initialize request
+	if r.Header.Get(SessionIDHeader) == "" && session.Initialized() {
+		w.Header().Set(SessionIDHeader, session.ID)
+	}
+
 	// Send response
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(server.ResponseStatus(w, response))
 	_ = json.NewEncoder(w).Encode(response)
 }
 
+// requestSession returns the session named by the Mcp-Session-Id header of the
+// request, or a new session that is kept if the request initializes it. If the
+// named session does not exist, or belongs to another caller, it answers 404 so
+// that the client starts a new session.
+func (s *MCPServer) requestSession(w http.ResponseWriter, r *http.Request) (*domain.ClientSession, bool) {
+	principal := domain.PrincipalFromContext(r.Context())
+	identity := domain.ClientIdentityFromContext(r.Context())
+
+	id := r.Header.Get(SessionIDHeader)
+	if id == "" {
+		session := domain.NewClientSession(r.UserAgent())
+		session.Transport = domain.TransportHTTP
+		session.Principal = principal
+		session.ClientIdentity = identity
+		session.RemoteAddr = remoteHost(r)
+		return session, true
+	}
+
+	// Sessions of other transports cannot be continued over HTTP
+	session, err := s.service.GetSession(r.Context(), id)
+	if err != nil || session.Transport != domain.TransportHTTP || !sameCaller(session, principal, identity) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(domain.CreateErrorResponse(jsonRPCVersion, nil, domain.SessionNotFoundCode, "Session not found"))
+		return nil, false
+	}
+	return session, true
+}
+
+// remoteHost returns the host of the client address of a request.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// handleDeleteSession ends the session named by the Mcp-Session-Id header.
+func (s *MCPServer) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(SessionIDHeader) == "" {
+		http.Error(w, "Missing "+SessionIDHeader+" header", http.StatusBadRequest)
+		return
+	}
+
+	session, ok := s.requestSession(w, r)
+	if !ok {
+		return
+	}
+	s.service.EndSession(r.Context(), session)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sameCaller reports whether a request of the given principal and client
+// certificate may continue the session.
+func sameCaller(session *domain.ClientSession, principal *domain.Principal, identity *domain.ClientIdentity) bool {
+	if (session.Principal == nil) != (principal == nil) || (session.Principal != nil && session.Principal.ID != principal.ID) {
+		return false
+	}
+	if (session.ClientIdentity == nil) != (identity == nil) || (identity != nil && session.ClientIdentity.Fingerprint != identity.Fingerprint) {
+		return false
+	}
+	return true
+}
+
 // Helper methods for processing specific JSON-RPC methods
 
 func (s *MCPServer) processInitialize(ctx context.Context, request domain.JSONRPCRequest) interface{} {
 	// Log initialization request
 	s.logger.Info("Processing initialize request")
 
-	// Parse initialization parameters if needed
-	// ...
+	// Record what the client reported, and keep the session for its later requests
+	if session := domain.ClientSessionFromContext(ctx); session != nil {
+		if err := s.service.InitializeSession(ctx, session, request.Params); err != nil {
+			return domain.CreateErrorResponse(jsonRPCVersion, request.ID, -32603, fmt.Sprintf("Failed to initialize session: %v", err))
+		}
+	}
 
 	// Get server info
 	name, version, instructions := s.service.ServerInfo()
@@ -251,10 +648,9 @@ func (s *MCPServer) processResourcesList(ctx context.Context, request domain.JSO
 	// Debug logging to verify service access
 	s.logger.Debug("Service access", logging.Fields{"servicePtr": fmt.Sprintf("%p", s.service)})
 
-	resources, err := s.service.ListResources(ctx)
+	resources, nextCursor, err := s.service.ListResourcesPage(ctx, listCursor(request))
 	if err != nil {
-		s.logger.Error("Error listing resources", logging.Fields{"error": err})
-		return domain.CreateErrorResponse(jsonRPCVersion, request.ID, -32603, fmt.Sprintf("Internal error: %v", err))
+		return s.listErrorResponse(request, "resources", err)
 	}
 
 	s.logger.Info("Found resources", logging.Fields{"count": len(resources)})
@@ -273,11 +669,32 @@ func (s *MCPServer) processResourcesList(ctx context.Context, request domain.JSO
 	result := map[string]interface{}{
 		"resources": resourceList,
 	}
+	if nextCursor != "" {
+		result["nextCursor"] = nextCursor
+	}
 
 	s.logger.Info("Processed resources/list response", logging.Fields{"resourceCount": len(resources)})
 	return domain.CreateResponse(jsonRPCVersion, request.ID, result)
 }
 
+// listCursor returns the cursor of the page requested by a list request, or ""
+// for the first page.
+func listCursor(request domain.JSONRPCRequest) string {
+	params, _ := request.Params.(map[string]interface{})
+	cursor, _ := params["cursor"].(string)
+	return cursor
+}
+
+// listErrorResponse returns the error response of a list request that failed.
+func (s *MCPServer) listErrorResponse(request domain.JSONRPCRequest, kind string, err error) interface{} {
+	var invalid *domain.ValidationError
+	if errors.As(err, &invalid) {
+		return domain.CreateErrorResponse(jsonRPCVersion, request.ID, -32602, fmt.Sprintf("Invalid params: %v", err))
+	}
+	s.logger.Error("Error listing "+kind, logging.Fields{"error": err})
+	return domain.CreateErrorResponse(jsonRPCVersion, request.ID, -32603, fmt.Sprintf("Internal error: %v", err))
+}
+
 func (s *MCPServer) processResourcesRead(ctx context.Context, request domain.JSONRPCRequest) interface{} {
 	s.logger.Info("Processing resources/read request")
 
@@ -329,10 +746,9 @@ func (s *MCPServer) processToolsList(ctx context.Context, request domain.JSONRPC
 	// Debug logging to verify service access
 	s.logger.Debug("Service access", logging.Fields{"servicePtr": fmt.Sprintf("%p", s.service)})
 
-	tools, err := s.service.ListTools(ctx)
+	tools, nextCursor, err := s.service.ListToolsPage(ctx, listCursor(request))
 	if err != nil {
-		s.logger.Error("Error listing tools", logging.Fields{"error": err})
-		return domain.CreateErrorResponse(jsonRPCVersion, request.ID, -32603, fmt.Sprintf("Internal error: %v", err))
+		return s.listErrorResponse(request, "tools", err)
 	}
 
 	s.logger.Info("Found tools", logging.Fields{"count": len(tools)})
@@ -387,6 +803,9 @@ func (s *MCPServer) processToolsList(ctx context.Context, request domain.JSONRPC
 	result := map[string]interface{}{
 		"tools": toolList,
 	}
+	if nextCursor != "" {
+		result["nextCursor"] = nextCursor
+	}
 
 	s.logger.Info("Processed tools/list response", logging.Fields{"toolCount": len(tools)})
 	return domain.CreateResponse(jsonRPCVersion, request.ID, result)
@@ -444,30 +863,35 @@ func (s *MCPServer) processToolsCall(ctx context.Context, request domain.JSONRPC
 		"paramCount":  len(tool.Parameters),
 	})
 
-	// Get the client session (if available)
-	// Try to get session ID from context
-	clientID := ""
-	if ctx.Value(server.SessionIDContextKey) != nil {
+	// Every tool call of a session shares the same session object
+	clientSession := domain.ClientSessionFromContext(ctx)
+	if clientSession == nil {
+		clientSession = &domain.ClientSession{
+			Connected:      true,
+			Principal:      domain.PrincipalFromContext(ctx),
+			ClientIdentity: domain.ClientIdentityFromContext(ctx),
+		}
 		if id, ok := ctx.Value(server.SessionIDContextKey).(string); ok {
-			clientID = id
-			s.logger.Debug("Using sessionID from context", logging.Fields{"sessionID": clientID})
+			clientSession.ID = id
 		}
 	}
 
-	// Create a client session for the tool handler
-	clientSession := &domain.ClientSession{
-		ID:        clientID,
-		Connected: true,
-	}
-
 	// Try to get a registered handler for this tool
 	handler := s.service.GetToolHandler(toolName)
 	if handler != nil {
 		// We have a registered handler, use it
 		s.logger.Info("Using registered handler for tool", logging.Fields{"tool": toolName})
-		result, err := handler(ctx, toolParams, clientSession)
+		result, err := s.service.InvokeToolHandler(ctx, toolName, handler, toolParams, clientSession)
 		if err != nil {
-			s.logger.Error("Error executing tool handler", logging.Fields{"tool": toolName, "error": err})
+			if domain.IsToolCallRejected(err) {
+				// Already logged by the service, and expected under load
+				s.logger.Debug("Tool call rejected", logging.Fields{"tool": toolName, "error": err.Error()})
+			} else {
+				s.logger.Error("Error executing tool handler", logging.Fields{"tool": toolName, "error": err})
+			}
+			if rpcErr := domain.NewJSONRPCErrorFromError(err); rpcErr != nil {
+				return domain.CreateErrorResponseFromError(jsonRPCVersion, request.ID, rpcErr)
+			}
 			return domain.CreateErrorResponse(jsonRPCVersion, request.ID, -32603, fmt.Sprintf("Error executing tool: %v", err))
 		}
 
@@ -492,10 +916,9 @@ func (s *MCPServer) processToolsCall(ctx context.Context, request domain.JSONRPC
 
 func (s *MCPServer) processPromptsList(ctx context.Context, request domain.JSONRPCRequest) interface{} {
 	s.logger.Info("Processing prompts/list request")
-	prompts, err := s.service.ListPrompts(ctx)
+	prompts, nextCursor, err := s.service.ListPromptsPage(ctx, listCursor(request))
 	if err != nil {
-		s.logger.Error("Error listing prompts", logging.Fields{"error": err})
-		return domain.CreateErrorResponse(jsonRPCVersion, request.ID, -32603, fmt.Sprintf("Internal error: %v", err))
+		return s.listErrorResponse(request, "prompts", err)
 	}
 
 	// Convert domain prompts to response format
@@ -521,6 +944,9 @@ func (s *MCPServer) processPromptsList(ctx context.Context, request domain.JSONR
 	result := map[string]interface{}{
 		"prompts": promptList,
 	}
+	if nextCursor != "" {
+		result["nextCursor"] = nextCursor
+	}
 
 	s.logger.Info("Processed prompts/list response", logging.Fields{"promptCount": len(prompts)})
 	return domain.CreateResponse(jsonRPCVersion, request.ID, result)
@@ -554,10 +980,40 @@ func (s *MCPServer) GetAddress() string {
 
 // processMessage processes a JSON-RPC message and returns a response.
 func (s *MCPServer) processMessage(ctx context.Context, rawMessage json.RawMessage) interface{} {
+	start := time.Now()
+	method, response := s.dispatchMessage(ctx, rawMessage)
+
+	failed := false
+	if r, ok := response.(domain.JSONRPCResponse); ok {
+		failed = r.Error != nil
+	}
+	s.metrics.ObserveRequest(method, time.Since(start), failed)
+
+	return response
+}
+
+// knownMethods are the JSON-RPC methods recorded in metrics under their own name.
+// Other methods are recorded as "unknown" to bound the number of series.
+var knownMethods = map[string]bool{
+	"initialize":     true,
+	"ping":           true,
+	"resources/list": true,
+	"resources/read": true,
+	"tools/list":     true,
+	"tools/call":     true,
+	"prompts/list":   true,
+	"prompts/get":    true,
+}
+
+// dispatchMessage parses a JSON-RPC message and routes it to its handler. It
+// returns the method for metrics along with the response.
+func (s *MCPServer) dispatchMessage(ctx context.Context, rawMessage json.RawMessage) (string, interface{}) {
+	method := "unknown"
+
 	// Check if the passed context is done
 	select {
 	case <-ctx.Done():
-		return domain.CreateErrorResponse(jsonRPCVersion, nil, -32603, "Request context canceled")
+		return method, domain.CreateErrorResponse(jsonRPCVersion, nil, -32603, "Request context canceled")
 	default:
 		// Continue processing
 	}
@@ -565,14 +1021,42 @@ func (s *MCPServer) processMessage(ctx context.Context, rawMessage json.RawMessa
 	// Parse JSON-RPC request
 	var request domain.JSONRPCRequest
 	if err := json.Unmarshal(rawMessage, &request); err != nil {
-		return domain.CreateErrorResponse(jsonRPCVersion, nil, -32700, "Parse error")
+		return method, domain.CreateErrorResponse(jsonRPCVersion, nil, -32700, "Parse error")
+	}
+
+	if knownMethods[request.Method] {
+		method = request.Method
 	}
 
 	// Validate JSON-RPC version
 	if request.JSONRPC != jsonRPCVersion {
-		return domain.CreateErrorResponse(jsonRPCVersion, request.ID, -32600, "Invalid JSON-RPC version")
+		return method, domain.CreateErrorResponse(jsonRPCVersion, request.ID, -32600, "Invalid JSON-RPC version")
+	}
+
+	// Reject new requests while draining, but let in-flight ones complete
+	if !s.drainer.Begin() {
+		return method, domain.CreateErrorResponse(jsonRPCVersion, request.ID, domain.BusyCode, "Server is shutting down")
+	}
+	defer s.drainer.End()
+
+	sessionID, _ := ctx.Value(server.SessionIDContextKey).(string)
+	// The tracer injected into the service records the requests as well
+	tracer := s.service.Tracer()
+	if tracer == nil {
+		return method, s.routeRequest(ctx, request)
 	}
+	ctx, end := tracer.StartRequest(ctx, request.Method, request.Params, sessionID)
+	response := s.routeRequest(ctx, request)
+	var rpcErr *domain.JSONRPCError
+	if r, ok := response.(domain.JSONRPCResponse); ok {
+		rpcErr = r.Error
+	}
+	end(rpcErr)
+	return method, response
+}
 
+// routeRequest routes a parsed JSON-RPC request to the handler of its method.
+func (s *MCPServer) routeRequest(ctx context.Context, request domain.JSONRPCRequest) interface{} {
 	// Handle request based on method
 	switch request.Method {
 	case "initialize":
@@ -0,0 +1,214 @@
+package rest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FreePeak/cortex/internal/builder"
+	"github.com/FreePeak/cortex/internal/domain"
+	"github.com/FreePeak/cortex/internal/infrastructure/auth"
+	"github.com/FreePeak/cortex/internal/interfaces/rest"
+)
+
+// freeAddress returns a loopback address that nothing listens on.
+func freeAddress(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+	return addr
+}
+
+// start starts the server and returns its URL once it answers.
+func start(t *testing.T, s *rest.MCPServer) string {
+	t.Helper()
+	go func() { _ = s.Start() }()
+	url := "http://" + s.GetAddress()
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(url + "/healthz")
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond, "server did not start")
+	return url
+}
+
+// toolCall is the response of a tools/call request.
+type toolCall struct {
+	Result interface{}          `json:"result"`
+	Error  *domain.JSONRPCError `json:"error"`
+}
+
+// callTool calls a tool over HTTP.
+func callTool(url, name string) (toolCall, error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/call",
+		"params":  map[string]interface{}{"name": name, "arguments": map[string]interface{}{}},
+	})
+	var call toolCall
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return call, err
+	}
+	defer resp.Body.Close()
+	err = json.NewDecoder(resp.Body).Decode(&call)
+	return call, err
+}
+
+func TestMCPServer_StopWaitsForToolCall(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	b := builder.NewServerBuilder().WithName("test").WithVersion("1.0.0").WithAddress(freeAddress(t))
+	b.AddTool(context.Background(), &domain.Tool{Name: "slow", Description: "Waits to be released"})
+	b.BuildService().RegisterToolHandler("slow", func(ctx context.Context, params map[string]interface{}, session *domain.ClientSession) (interface{}, error) {
+		close(started)
+		<-release
+		return "done", nil
+	})
+	mcpServer := b.BuildMCPServer()
+	url := start(t, mcpServer)
+
+	type callResult struct {
+		call toolCall
+		err  error
+	}
+	calls := make(chan callResult, 1)
+	go func() {
+		call, err := callTool(url, "slow")
+		calls <- callResult{call, err}
+	}()
+	<-started
+
+	stopped := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stopped <- mcpServer.Stop(ctx)
+	}()
+
+	select {
+	case err := <-stopped:
+		t.Fatalf("Stop returned with a tool call in flight: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// New requests are rejected while draining
+	call, err := callTool(url, "slow")
+	require.NoError(t, err)
+	require.NotNil(t, call.Error, "Tool call while draining should be rejected")
+	assert.Equal(t, domain.BusyCode, call.Error.Code)
+
+	close(release)
+	require.NoError(t, <-stopped)
+	result := <-calls
+	require.NoError(t, result.err)
+	assert.Nil(t, result.call.Error)
+	assert.NotNil(t, result.call.Result, "In-flight tool call should complete")
+}
+
+// requestTracer records the requests it starts spans for.
+type requestTracer struct {
+	methods []string
+	codes   []int
+}
+
+func (f *requestTracer) StartRequest(ctx context.Context, method string, params interface{}, sessionID string) (context.Context, func(rpcErr *domain.JSONRPCError)) {
+	f.methods = append(f.methods, method)
+	return ctx, func(rpcErr *domain.JSONRPCError) {
+		code := 0
+		if rpcErr != nil {
+			code = rpcErr.Code
+		}
+		f.codes = append(f.codes, code)
+	}
+}
+
+func (f *requestTracer) StartToolCall(ctx context.Context, tool, sessionID string) (context.Context, func(err error)) {
+	return ctx, func(error) {}
+}
+
+func TestMCPServer_TracesRequests(t *testing.T) {
+	tracer := &requestTracer{}
+	b := builder.NewServerBuilder().WithName("test").WithVersion("1.0.0")
+	b.BuildService().SetTracer(tracer)
+	ts := httptest.NewServer(b.BuildMCPServer().Handler())
+	defer ts.Close()
+
+	for _, method := range []string{"ping", "unknown/method"} {
+		body := []byte(`{"jsonrpc":"2.0","id":1,"method":"` + method + `"}`)
+		resp, err := http.Post(ts.URL, "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, []string{"ping", "unknown/method"}, tracer.methods)
+	assert.Equal(t, []int{0, -32601}, tracer.codes)
+}
+
+func TestMCPServer_ProbesBypassAuthAndReportDraining(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	b := builder.NewServerBuilder().WithName("test").WithVersion("1.0.0").
+		WithAuthenticator(auth.NewAPIKeyAuthenticator(map[string]*domain.Principal{"key": {ID: "alice"}}))
+	b.AddTool(context.Background(), &domain.Tool{Name: "slow", Description: "Waits to be released"})
+	b.RegisterToolHandler("slow", func(ctx context.Context, params map[string]interface{}, session *domain.ClientSession) (interface{}, error) {
+		close(started)
+		<-release
+		return "done", nil
+	})
+	mcpServer := b.BuildMCPServer()
+	ts := httptest.NewServer(mcpServer.Handler())
+	defer ts.Close()
+
+	get := func(path string) int {
+		resp, err := http.Get(ts.URL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Probes are answered without credentials, unlike the MCP endpoints
+	assert.Equal(t, http.StatusOK, get("/healthz"))
+	assert.Equal(t, http.StatusOK, get("/readyz"))
+	assert.Equal(t, http.StatusUnauthorized, get("/status"))
+
+	// Keep a tool call in flight, so that Stop drains until it is released
+	go func() {
+		body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"slow","arguments":{}}}`
+		r, _ := http.NewRequest(http.MethodPost, ts.URL+"/jsonrpc", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set(auth.DefaultAPIKeyHeader, "key")
+		if resp, err := http.DefaultClient.Do(r); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	stopped := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stopped <- mcpServer.Stop(ctx)
+	}()
+
+	// The server is alive but not ready while draining
+	assert.Eventually(t, func() bool { return get("/readyz") == http.StatusServiceUnavailable }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, http.StatusOK, get("/healthz"))
+
+	close(release)
+	require.NoError(t, <-stopped)
+}
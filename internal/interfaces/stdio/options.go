@@ -46,16 +46,15 @@ func WithToolHandler(toolName string, handler func(ctx context.Context, params m
 				}
 			}
 
-			// Create a dummy session for now
-			session := &domain.ClientSession{
-				ID:        "stdio-session",
-				UserAgent: "stdio-client",
-				Connected: true,
-			}
+			// Every tool call of the connection shares the same session
+			session := s.processor.Session()
 
-			// Call the handler
-			result, err := handler(ctx, toolParams, session)
+			// Call the handler, containing any panic it raises
+			result, err := s.server.GetService().InvokeToolHandler(ctx, toolName, handler, toolParams, session)
 			if err != nil {
+				if rpcErr := domain.NewJSONRPCErrorFromError(err); rpcErr != nil {
+					return nil, rpcErr
+				}
 				return nil, &domain.JSONRPCError{
 					Code:    InternalErrorCode,
 					Message: err.Error(),
@@ -5,6 +5,7 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -17,7 +18,6 @@ import (
 	"github.com/FreePeak/cortex/internal/domain"
 	"github.com/FreePeak/cortex/internal/infrastructure/logging"
 	"github.com/FreePeak/cortex/internal/interfaces/rest"
-	"github.com/google/uuid"
 )
 
 // Constants for JSON-RPC
@@ -29,6 +29,10 @@ const (
 	InvalidParamsCode  = -32602
 	MethodNotFoundCode = -32601
 	InternalErrorCode  = -32603
+
+	// DefaultShutdownTimeout is how long the message in flight may take to
+	// complete once a shutdown has started.
+	DefaultShutdownTimeout = 30 * time.Second
 )
 
 // StdioContextFunc is a function that takes an existing context and returns
@@ -41,10 +45,11 @@ type StdioContextFunc func(ctx context.Context) context.Context
 // It provides a simple way to create command-line MCP servers that
 // communicate via standard input/output streams using JSON-RPC messages.
 type StdioServer struct {
-	server      *rest.MCPServer
-	logger      *logging.Logger
-	contextFunc StdioContextFunc
-	processor   *MessageProcessor
+	server          *rest.MCPServer
+	logger          *logging.Logger
+	contextFunc     StdioContextFunc
+	processor       *MessageProcessor
+	shutdownTimeout time.Duration
 }
 
 // StdioOption defines a function type for configuring StdioServer
@@ -66,6 +71,14 @@ func WithStdioContextFunc(fn StdioContextFunc) StdioOption {
 	}
 }
 
+// WithShutdownTimeout sets how long the message in flight may take to complete
+// once the server's context is canceled, e.g. on SIGTERM.
+func WithShutdownTimeout(timeout time.Duration) StdioOption {
+	return func(s *StdioServer) {
+		s.shutdownTimeout = timeout
+	}
+}
+
 // WithErrorLogger is kept for backwards compatibility
 // It will create a custom logger that wraps the standard log.Logger
 func WithErrorLogger(stdLogger *log.Logger) StdioOption {
@@ -124,8 +137,9 @@ func NewStdioServer(server *rest.MCPServer, opts ...StdioOption) *StdioServer {
 	}
 
 	s := &StdioServer{
-		server: server,
-		logger: defaultLogger,
+		server:          server,
+		logger:          defaultLogger,
+		shutdownTimeout: DefaultShutdownTimeout,
 	}
 
 	// Apply all options
@@ -140,7 +154,9 @@ func NewStdioServer(server *rest.MCPServer, opts ...StdioOption) *StdioServer {
 }
 
 // Listen starts listening for JSON-RPC messages on the provided input and writes responses to the provided output.
-// It runs until the context is canceled or an error occurs.
+// It runs until the context is canceled or an error occurs. Once the context is
+// canceled, no new messages are read, and the message in flight may complete
+// and send its response within the shutdown timeout.
 // Returns an error if there are issues with reading input or writing output.
 func (s *StdioServer) Listen(ctx context.Context, stdin io.Reader, stdout io.Writer) error {
 	// Add in any custom context
@@ -148,27 +164,40 @@ func (s *StdioServer) Listen(ctx context.Context, stdin io.Reader, stdout io.Wri
 		ctx = s.contextFunc(ctx)
 	}
 
-	reader := bufio.NewReader(stdin)
+	// The session ends with the connection
+	defer s.server.GetService().EndSession(context.WithoutCancel(ctx), s.processor.Session())
+
+	// Messages are processed on a context that outlives ctx by the shutdown
+	// timeout, so that a shutdown does not cut off the tool call in flight
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+	go s.drainOnCancel(ctx, workCtx, cancelWork)
+
+	lines := make(chan readResult)
+	go readLines(bufio.NewReader(stdin), lines, workCtx.Done())
 
 	// Process messages serially to avoid concurrent writes to stdout
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		default:
-			// Read a line from stdin
-			line, err := reader.ReadString('\n')
-			if err != nil {
-				if err == io.EOF {
+		case read := <-lines:
+			// Do not start new work if a shutdown began while the line was read
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			if read.err != nil {
+				if read.err == io.EOF {
 					s.logger.Info("Input stream closed")
 					return nil
 				}
-				s.logger.Error("Error reading input", logging.Fields{"error": err})
-				return err
+				s.logger.Error("Error reading input", logging.Fields{"error": read.err})
+				return read.err
 			}
 
 			// Process message and get response
-			response, processErr := s.processor.Process(ctx, line)
+			response, processErr := s.processor.Process(workCtx, read.line)
 
 			// Handle processing errors
 			if processErr != nil {
@@ -205,6 +234,51 @@ func (s *StdioServer) Listen(ctx context.Context, stdin io.Reader, stdout io.Wri
 	}
 }
 
+// drainOnCancel marks the service as draining once ctx is canceled, and cancels
+// the work context if the message in flight does not complete within the
+// shutdown timeout.
+func (s *StdioServer) drainOnCancel(ctx, workCtx context.Context, cancelWork context.CancelFunc) {
+	select {
+	case <-ctx.Done():
+	case <-workCtx.Done():
+		return
+	}
+
+	s.server.GetService().SetDraining(true)
+
+	timer := time.NewTimer(s.shutdownTimeout)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		s.logger.Warn("Shutdown timeout reached, canceling the message in flight")
+		cancelWork()
+	case <-workCtx.Done():
+	}
+}
+
+// readResult is a line read from the input, or the error that ended reading.
+type readResult struct {
+	line string
+	err  error
+}
+
+// readLines reads lines from reader and sends them to lines until reading fails
+// or done is closed. Reading happens in its own goroutine, so that a blocked
+// read does not delay a shutdown.
+func readLines(reader *bufio.Reader, lines chan<- readResult, done <-chan struct{}) {
+	for {
+		line, err := reader.ReadString('\n')
+		select {
+		case lines <- readResult{line: line, err: err}:
+		case <-done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
 // writeResponse marshals and writes a JSON-RPC response message followed by a newline.
 // Returns an error if marshaling or writing fails.
 func (s *StdioServer) writeResponse(response interface{}, writer io.Writer) error {
@@ -232,22 +306,31 @@ func (s *StdioServer) writeResponse(response interface{}, writer io.Writer) erro
 // It sets up signal handling for graceful shutdown on SIGTERM and SIGINT.
 // Returns an error if the server encounters any issues during operation.
 func ServeStdio(server *rest.MCPServer, opts ...StdioOption) error {
+	return ServeStdioContext(context.Background(), server, opts...)
+}
+
+// ServeStdioContext is like ServeStdio, but also shuts down gracefully once ctx is canceled.
+func ServeStdioContext(ctx context.Context, server *rest.MCPServer, opts ...StdioOption) error {
 	s := NewStdioServer(server, opts...)
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	// Set up signal handling
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(sigChan)
 
 	go func() {
-		sig := <-sigChan
-		// Only log if logging is enabled
-		if os.Getenv("MCP_DISABLE_LOGGING") != "true" && os.Getenv("DISABLE_LOGGING") != "true" {
-			s.logger.Info("Received shutdown signal, stopping server...", logging.Fields{"signal": sig.String()})
+		select {
+		case sig := <-sigChan:
+			// Only log if logging is enabled
+			if os.Getenv("MCP_DISABLE_LOGGING") != "true" && os.Getenv("DISABLE_LOGGING") != "true" {
+				s.logger.Info("Received shutdown signal, stopping server...", logging.Fields{"signal": sig.String()})
+			}
+			cancel()
+		case <-ctx.Done():
 		}
-		cancel()
 	}()
 
 	// Only log if logging is enabled
@@ -275,8 +358,12 @@ type MessageProcessor struct {
 	server   *rest.MCPServer
 	logger   *logging.Logger
 	handlers map[string]MethodHandler
+	session  *domain.ClientSession // A stdio connection serves a single client session
 }
 
+// stdioUserAgent is the user agent recorded for stdio sessions, which carry no HTTP headers.
+const stdioUserAgent = "stdio"
+
 // MethodHandler defines the interface for JSON-RPC method handlers
 type MethodHandler interface {
 	Handle(ctx context.Context, params interface{}, id interface{}) (interface{}, *domain.JSONRPCError)
@@ -296,7 +383,9 @@ func NewMessageProcessor(server *rest.MCPServer, logger *logging.Logger) *Messag
 		server:   server,
 		logger:   logger,
 		handlers: make(map[string]MethodHandler),
+		session:  domain.NewClientSession(stdioUserAgent),
 	}
+	p.session.Transport = domain.TransportStdio
 
 	// Register standard handlers
 	p.RegisterHandler("initialize", MethodHandlerFunc(p.handleInitialize))
@@ -336,6 +425,9 @@ func (p *MessageProcessor) Process(ctx context.Context, message string) (interfa
 		return createErrorResponse(nil, ParseErrorCode, "Parse error"), nil
 	}
 
+	// Any message shows that the client is still there
+	p.session.Touch()
+
 	// Check if this is a notification (no ID field)
 	// Notifications don't require responses
 	if baseMessage.ID == nil && strings.HasPrefix(baseMessage.Method, "notifications/") {
@@ -370,8 +462,13 @@ func (p *MessageProcessor) Process(ctx context.Context, message string) (interfa
 		), nil
 	}
 
-	// Execute the method handler
+	// Execute the method handler within a span, joining the trace in _meta if any
+	end := func(*domain.JSONRPCError) {}
+	if tracer := p.server.GetService().Tracer(); tracer != nil {
+		msgCtx, end = tracer.StartRequest(msgCtx, baseMessage.Method, baseMessage.Params, p.session.ID)
+	}
 	result, jsonRpcErr := handler.Handle(msgCtx, baseMessage.Params, baseMessage.ID)
+	end(jsonRpcErr)
 	if jsonRpcErr != nil {
 		return createErrorResponseFromJSONRPCError(baseMessage.ID, jsonRpcErr), nil
 	}
@@ -383,6 +480,14 @@ func (p *MessageProcessor) Process(ctx context.Context, message string) (interfa
 // Method handlers
 
 func (p *MessageProcessor) handleInitialize(ctx context.Context, params interface{}, id interface{}) (interface{}, *domain.JSONRPCError) {
+	// Record what the client reported, and keep the session for its later requests
+	if err := p.server.GetService().InitializeSession(ctx, p.session, params); err != nil {
+		return nil, &domain.JSONRPCError{
+			Code:    InternalErrorCode,
+			Message: fmt.Sprintf("Failed to initialize session: %v", err),
+		}
+	}
+
 	name, version, instructions := p.server.GetServerInfo()
 	result := map[string]interface{}{
 		"protocolVersion": "2024-11-05",
@@ -417,7 +522,16 @@ func (p *MessageProcessor) handlePing(ctx context.Context, params interface{}, i
 
 func (p *MessageProcessor) handleToolsList(ctx context.Context, params interface{}, id interface{}) (interface{}, *domain.JSONRPCError) {
 	// Access the service through the server to get tools
-	tools, err := p.server.GetService().ListTools(ctx)
+	paramsMap, _ := params.(map[string]interface{})
+	cursor, _ := paramsMap["cursor"].(string)
+	tools, nextCursor, err := p.server.GetService().ListToolsPage(ctx, cursor)
+	var invalid *domain.ValidationError
+	if errors.As(err, &invalid) {
+		return nil, &domain.JSONRPCError{
+			Code:    InvalidParamsCode,
+			Message: fmt.Sprintf("Invalid params: %v", err),
+		}
+	}
 	if err != nil {
 		return nil, &domain.JSONRPCError{
 			Code:    InternalErrorCode,
@@ -466,9 +580,13 @@ func (p *MessageProcessor) handleToolsList(ctx context.Context, params interface
 		}
 	}
 
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"tools": toolList,
-	}, nil
+	}
+	if nextCursor != "" {
+		result["nextCursor"] = nextCursor
+	}
+	return result, nil
 }
 
 func (p *MessageProcessor) handleToolsCall(ctx context.Context, params interface{}, id interface{}) (interface{}, *domain.JSONRPCError) {
@@ -500,11 +618,8 @@ func (p *MessageProcessor) handleToolsCall(ctx context.Context, params interface
 		}
 	}
 
-	// Create a client session for the tool handler
-	clientSession := &domain.ClientSession{
-		ID:        generateSessionID(),
-		Connected: true,
-	}
+	// Every tool call of the connection shares the same session
+	clientSession := p.Session()
 
 	// Access the service to get the tool handler
 	service := p.server.GetService()
@@ -514,9 +629,17 @@ func (p *MessageProcessor) handleToolsCall(ctx context.Context, params interface
 	if handler != nil {
 		// We have a registered handler, use it
 		p.logger.Info("Using registered handler for tool", logging.Fields{"tool": toolName})
-		result, err := handler(ctx, toolParams, clientSession)
+		result, err := service.InvokeToolHandler(ctx, toolName, handler, toolParams, clientSession)
 		if err != nil {
-			p.logger.Error("Error executing tool handler", logging.Fields{"tool": toolName, "error": err})
+			if domain.IsToolCallRejected(err) {
+				// Already logged by the service
+				p.logger.Debug("Tool call rejected", logging.Fields{"tool": toolName, "error": err.Error()})
+			} else {
+				p.logger.Error("Error executing tool handler", logging.Fields{"tool": toolName, "error": err})
+			}
+			if rpcErr := domain.NewJSONRPCErrorFromError(err); rpcErr != nil {
+				return nil, rpcErr
+			}
 			return nil, &domain.JSONRPCError{
 				Code:    InternalErrorCode,
 				Message: fmt.Sprintf("Error executing tool: %v", err),
@@ -540,9 +663,9 @@ func (p *MessageProcessor) handleToolsCall(ctx context.Context, params interface
 	}
 }
 
-// generateSessionID creates a unique session ID
-func generateSessionID() string {
-	return uuid.New().String()
+// Session returns the session of the stdio connection.
+func (p *MessageProcessor) Session() *domain.ClientSession {
+	return p.session
 }
 
 // isTerminalError determines if an error should cause the server to shut down
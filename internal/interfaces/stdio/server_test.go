@@ -0,0 +1,126 @@
+package stdio_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FreePeak/cortex/internal/builder"
+	"github.com/FreePeak/cortex/internal/domain"
+	"github.com/FreePeak/cortex/internal/interfaces/stdio"
+)
+
+const slowToolCall = `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"slow","arguments":{}}}` + "\n"
+
+// newSlowServer returns a stdio server with a slow tool, which reports on
+// started when it is called and completes once release is closed or its
+// context is done.
+func newSlowServer(shutdownTimeout time.Duration, started, release chan struct{}) *stdio.StdioServer {
+	b := builder.NewServerBuilder().WithName("test").WithVersion("1.0.0")
+	b.AddTool(context.Background(), &domain.Tool{Name: "slow", Description: "Waits to be released"})
+	b.BuildService().RegisterToolHandler("slow", func(ctx context.Context, params map[string]interface{}, session *domain.ClientSession) (interface{}, error) {
+		close(started)
+		select {
+		case <-release:
+			return "done", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+	return b.BuildStdioServer(stdio.WithShutdownTimeout(shutdownTimeout))
+}
+
+// listen runs Listen with the tools/call message of the slow tool as input,
+// and returns the channel its error is sent on.
+func listen(ctx context.Context, s *stdio.StdioServer, stdout io.Writer) (<-chan error, *io.PipeWriter) {
+	stdin, w := io.Pipe()
+	go func() { _, _ = w.Write([]byte(slowToolCall)) }()
+
+	errc := make(chan error, 1)
+	go func() { errc <- s.Listen(ctx, stdin, stdout) }()
+	return errc, w
+}
+
+func TestStdioServer_ListenFinishesMessageAfterCancel(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s := newSlowServer(5*time.Second, started, release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var stdout bytes.Buffer
+	errc, stdin := listen(ctx, s, &stdout)
+	defer stdin.Close()
+
+	<-started
+	cancel()
+	select {
+	case err := <-errc:
+		t.Fatalf("Listen returned with a message in flight: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case err := <-errc:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Listen did not return once the message in flight completed")
+	}
+	assert.Contains(t, stdout.String(), `"id":1`, "Response to the message in flight should be written")
+	assert.NotContains(t, stdout.String(), `"error"`)
+}
+
+func TestStdioServer_ListenCancelsMessageAfterShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	s := newSlowServer(50*time.Millisecond, started, make(chan struct{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var stdout bytes.Buffer
+	errc, stdin := listen(ctx, s, &stdout)
+	defer stdin.Close()
+
+	<-started
+	start := time.Now()
+	cancel()
+	select {
+	case err := <-errc:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Listen did not return after the shutdown timeout")
+	}
+	require.Less(t, time.Since(start), time.Second)
+	assert.True(t, strings.Count(stdout.String(), "\n") <= 1, "At most the response to the message in flight should be written")
+}
+
+// requestTracer records the requests it starts spans for.
+type requestTracer struct {
+	methods []string
+}
+
+func (f *requestTracer) StartRequest(ctx context.Context, method string, params interface{}, sessionID string) (context.Context, func(rpcErr *domain.JSONRPCError)) {
+	f.methods = append(f.methods, method)
+	return ctx, func(*domain.JSONRPCError) {}
+}
+
+func (f *requestTracer) StartToolCall(ctx context.Context, tool, sessionID string) (context.Context, func(err error)) {
+	return ctx, func(error) {}
+}
+
+func TestStdioServer_TracesRequests(t *testing.T) {
+	tracer := &requestTracer{}
+	b := builder.NewServerBuilder().WithName("test").WithVersion("1.0.0")
+	b.BuildService().SetTracer(tracer)
+	s := b.BuildStdioServer()
+
+	var stdout bytes.Buffer
+	err := s.Listen(context.Background(), strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`+"\n"), &stdout)
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), `"id":1`)
+	assert.Equal(t, []string{"ping"}, tracer.methods)
+}
@@ -0,0 +1,156 @@
+package usecases
+
+import (
+	"context"
+	"path"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+// ConcurrencyLimit bounds the number of calls that may run at the same time.
+type ConcurrencyLimit struct {
+	// MaxInFlight is the number of calls that may run concurrently.
+	MaxInFlight int
+
+	// MaxQueue is the number of calls that may wait for a free slot. Calls
+	// beyond it fail immediately; zero disables queueing.
+	MaxQueue int
+
+	// QueueTimeout is how long a queued call waits for a free slot before it
+	// fails. Zero waits until the call's context is done.
+	QueueTimeout time.Duration
+}
+
+// ConcurrencyConfig contains the concurrency limits applied to tool calls.
+type ConcurrencyConfig struct {
+	// Default applies to every tool without a more specific limit.
+	Default *ConcurrencyLimit
+
+	// PerTool limits the tools matching each glob pattern, e.g. "db.*". Every
+	// tool gets its own bulkhead; an exact name takes precedence over patterns.
+	PerTool map[string]ConcurrencyLimit
+}
+
+// limitFor returns the limit that applies to the named tool.
+func (c ConcurrencyConfig) limitFor(toolName string) (ConcurrencyLimit, bool) {
+	if limit, ok := c.PerTool[toolName]; ok {
+		return limit, true
+	}
+
+	patterns := make([]string, 0, len(c.PerTool))
+	for pattern := range c.PerTool {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, toolName); err == nil && ok {
+			return c.PerTool[pattern], true
+		}
+	}
+
+	if c.Default != nil {
+		return *c.Default, true
+	}
+	return ConcurrencyLimit{}, false
+}
+
+// Bulkhead isolates a tool or provider by bounding its concurrent calls, so that
+// a slow dependency cannot exhaust the resources of the whole server.
+type Bulkhead struct {
+	name    string
+	scope   string
+	limit   ConcurrencyLimit
+	slots   chan struct{}
+	waiting atomic.Int64
+}
+
+// NewBulkhead creates a new Bulkhead. Name and scope identify it in BusyError,
+// e.g. a provider ID and "provider".
+func NewBulkhead(name, scope string, limit ConcurrencyLimit) *Bulkhead {
+	if limit.MaxInFlight < 1 {
+		limit.MaxInFlight = 1
+	}
+	return &Bulkhead{
+		name:  name,
+		scope: scope,
+		limit: limit,
+		slots: make(chan struct{}, limit.MaxInFlight),
+	}
+}
+
+// Acquire takes a slot, queueing if allowed. The returned function releases the
+// slot and must be called once the call is complete. If the bulkhead is
+// saturated, a *domain.BusyError is returned.
+func (b *Bulkhead) Acquire(ctx context.Context) (func(), error) {
+	select {
+	case b.slots <- struct{}{}:
+		return b.release, nil
+	default:
+	}
+
+	if b.waiting.Add(1) > int64(b.limit.MaxQueue) {
+		b.waiting.Add(-1)
+		return nil, domain.NewBusyError(b.name, b.scope)
+	}
+	defer b.waiting.Add(-1)
+
+	var timeout <-chan time.Time
+	if b.limit.QueueTimeout > 0 {
+		timer := time.NewTimer(b.limit.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case b.slots <- struct{}{}:
+		return b.release, nil
+	case <-timeout:
+		return nil, domain.NewBusyError(b.name, b.scope)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// release frees a slot.
+func (b *Bulkhead) release() {
+	<-b.slots
+}
+
+// InFlight returns the number of calls currently running.
+func (b *Bulkhead) InFlight() int {
+	return len(b.slots)
+}
+
+// Waiting returns the number of calls currently queued.
+func (b *Bulkhead) Waiting() int {
+	return int(b.waiting.Load())
+}
+
+// bulkheads holds the per-tool bulkheads of a ServerService.
+type bulkheads struct {
+	config ConcurrencyConfig
+	mu     sync.Mutex
+	tools  map[string]*Bulkhead
+}
+
+// forTool returns the bulkhead of the named tool, or nil if it is not limited.
+func (b *bulkheads) forTool(toolName string) *Bulkhead {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if bulkhead, ok := b.tools[toolName]; ok {
+		return bulkhead
+	}
+
+	// Unlimited tools are cached as nil so the patterns are only matched once
+	var bulkhead *Bulkhead
+	if limit, ok := b.config.limitFor(toolName); ok {
+		bulkhead = NewBulkhead(toolName, "tool", limit)
+	}
+	b.tools[toolName] = bulkhead
+	return bulkhead
+}
@@ -0,0 +1,137 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+func TestBulkhead_Acquire(t *testing.T) {
+	ctx := context.Background()
+	bulkhead := NewBulkhead("db", "provider", ConcurrencyLimit{MaxInFlight: 1})
+
+	release, err := bulkhead.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if bulkhead.InFlight() != 1 {
+		t.Errorf("InFlight() = %v, want 1", bulkhead.InFlight())
+	}
+
+	// Without a queue, a saturated bulkhead fails immediately
+	_, err = bulkhead.Acquire(ctx)
+	var busyErr *domain.BusyError
+	if !errors.As(err, &busyErr) {
+		t.Fatalf("Acquire() error = %v, want BusyError", err)
+	}
+	if busyErr.Name != "db" || busyErr.Scope != "provider" {
+		t.Errorf("BusyError = %+v, want provider db", busyErr)
+	}
+
+	release()
+	if _, err := bulkhead.Acquire(ctx); err != nil {
+		t.Errorf("Acquire() after release error = %v", err)
+	}
+}
+
+func TestBulkhead_Queue(t *testing.T) {
+	ctx := context.Background()
+	bulkhead := NewBulkhead("slow", "tool", ConcurrencyLimit{
+		MaxInFlight:  1,
+		MaxQueue:     1,
+		QueueTimeout: 20 * time.Millisecond,
+	})
+
+	release, err := bulkhead.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	// A queued call gets the slot once it is released
+	acquired := make(chan error, 1)
+	go func() {
+		release, err := bulkhead.Acquire(ctx)
+		if err == nil {
+			release()
+		}
+		acquired <- err
+	}()
+	for bulkhead.Waiting() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The queue is full
+	if _, err := bulkhead.Acquire(ctx); err == nil {
+		t.Error("Acquire() with a full queue should fail")
+	}
+
+	release()
+	if err := <-acquired; err != nil {
+		t.Errorf("queued Acquire() error = %v", err)
+	}
+
+	// A queued call gives up after the queue timeout
+	release, _ = bulkhead.Acquire(ctx)
+	defer release()
+	start := time.Now()
+	_, err = bulkhead.Acquire(ctx)
+	var busyErr *domain.BusyError
+	if !errors.As(err, &busyErr) {
+		t.Errorf("Acquire() error = %v, want BusyError after queue timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Acquire() returned after %v, want at least the queue timeout", elapsed)
+	}
+}
+
+func TestServerService_ConcurrencyLimits(t *testing.T) {
+	ctx := context.Background()
+	service := NewServerService(ServerConfig{
+		ToolRepo:           NewMockToolRepository(),
+		ResourceRepo:       NewMockResourceRepository(),
+		PromptRepo:         NewMockPromptRepository(),
+		SessionRepo:        NewMockSessionRepository(),
+		NotificationSender: NewMockNotificationSender(),
+		Concurrency: &ConcurrencyConfig{
+			PerTool: map[string]ConcurrencyLimit{"db.*": {MaxInFlight: 1}},
+		},
+	})
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	service.RegisterToolHandler("db.query", func(ctx context.Context, params map[string]interface{}, session *domain.ClientSession) (interface{}, error) {
+		close(started)
+		<-unblock
+		return "done", nil
+	})
+	service.RegisterToolHandler("echo", func(ctx context.Context, params map[string]interface{}, session *domain.ClientSession) (interface{}, error) {
+		return "ok", nil
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := service.ExecuteTool(ctx, "db.query", nil, nil)
+		done <- err
+	}()
+	<-started
+
+	// A second call to the saturated tool is rejected as busy
+	_, err := service.ExecuteTool(ctx, "db.query", nil, nil)
+	var busyErr *domain.BusyError
+	if !errors.As(err, &busyErr) {
+		t.Errorf("ExecuteTool() error = %v, want BusyError", err)
+	}
+
+	// Tools without a limit are unaffected
+	if _, err := service.ExecuteTool(ctx, "echo", nil, nil); err != nil {
+		t.Errorf("ExecuteTool() for unlimited tool error = %v", err)
+	}
+
+	close(unblock)
+	if err := <-done; err != nil {
+		t.Errorf("ExecuteTool() error = %v", err)
+	}
+}
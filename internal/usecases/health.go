@@ -0,0 +1,158 @@
+package usecases
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HealthChecker is implemented by components that can report whether they are
+// able to serve requests, e.g. a provider checking its database connection.
+type HealthChecker interface {
+	// CheckHealth returns an error if the component is not healthy.
+	CheckHealth(ctx context.Context) error
+}
+
+// HealthCheckerFunc is a function type that implements HealthChecker
+type HealthCheckerFunc func(ctx context.Context) error
+
+// CheckHealth calls the health check function
+func (f HealthCheckerFunc) CheckHealth(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Health statuses
+const (
+	HealthStatusOK       = "ok"
+	HealthStatusFail     = "fail"
+	HealthStatusDraining = "draining"
+)
+
+// DefaultHealthCheckTimeout bounds how long a single health check may take.
+const DefaultHealthCheckTimeout = 5 * time.Second
+
+// HealthCheckResult is the outcome of a single health check.
+type HealthCheckResult struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// HealthReport is the aggregated readiness of the server.
+type HealthReport struct {
+	Status   string              `json:"status"`
+	Draining bool                `json:"draining"`
+	Checks   []HealthCheckResult `json:"checks,omitempty"`
+}
+
+// Ready reports whether the server should receive traffic.
+func (r HealthReport) Ready() bool {
+	return r.Status == HealthStatusOK
+}
+
+// healthChecks holds the registered health checks and the draining state.
+type healthChecks struct {
+	mu       sync.RWMutex
+	checkers map[string]HealthChecker
+	draining bool
+}
+
+// AddHealthCheck registers a health check that contributes to readiness. A check
+// with the same name is replaced.
+func (s *ServerService) AddHealthCheck(name string, checker HealthChecker) {
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+	if s.health.checkers == nil {
+		s.health.checkers = make(map[string]HealthChecker)
+	}
+	s.health.checkers[name] = checker
+}
+
+// RemoveHealthCheck removes the named health check.
+func (s *ServerService) RemoveHealthCheck(name string) {
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+	delete(s.health.checkers, name)
+}
+
+// SetDraining marks the server as draining. A draining server is not ready, so
+// that load balancers stop routing new traffic to it while it shuts down.
+func (s *ServerService) SetDraining(draining bool) {
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+	s.health.draining = draining
+}
+
+// Draining reports whether the server is draining.
+func (s *ServerService) Draining() bool {
+	s.health.mu.RLock()
+	defer s.health.mu.RUnlock()
+	return s.health.draining
+}
+
+// CheckReadiness runs all health checks concurrently and aggregates their
+// results. Each check is bounded by DefaultHealthCheckTimeout.
+func (s *ServerService) CheckReadiness(ctx context.Context) HealthReport {
+	s.health.mu.RLock()
+	draining := s.health.draining
+	names := make([]string, 0, len(s.health.checkers))
+	for name := range s.health.checkers {
+		names = append(names, name)
+	}
+	checkers := make([]HealthChecker, len(names))
+	sort.Strings(names)
+	for i, name := range names {
+		checkers[i] = s.health.checkers[name]
+	}
+	s.health.mu.RUnlock()
+
+	report := HealthReport{
+		Status:   HealthStatusOK,
+		Draining: draining,
+		Checks:   make([]HealthCheckResult, len(names)),
+	}
+
+	var wg sync.WaitGroup
+	for i := range names {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			report.Checks[i] = runHealthCheck(ctx, names[i], checkers[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for _, check := range report.Checks {
+		if check.Status != HealthStatusOK {
+			report.Status = HealthStatusFail
+		}
+	}
+	if draining {
+		report.Status = HealthStatusDraining
+	}
+	return report
+}
+
+// runHealthCheck runs a single health check, converting a panic into a failure.
+func runHealthCheck(ctx context.Context, name string, checker HealthChecker) (result HealthCheckResult) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultHealthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	result = HealthCheckResult{Name: name, Status: HealthStatusOK}
+	defer func() {
+		if r := recover(); r != nil {
+			result.Status = HealthStatusFail
+			result.Error = "health check panicked"
+		}
+		result.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	}()
+
+	if err := checker.CheckHealth(ctx); err != nil {
+		result.Status = HealthStatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,61 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestServerService_CheckReadiness(t *testing.T) {
+	ctx := context.Background()
+	service := createTestServerService(nil, nil, nil, nil, nil)
+
+	// Without checks the server is ready
+	report := service.CheckReadiness(ctx)
+	if !report.Ready() || len(report.Checks) != 0 {
+		t.Errorf("CheckReadiness() = %+v, want ready without checks", report)
+	}
+
+	healthy := HealthCheckerFunc(func(ctx context.Context) error { return nil })
+	failing := HealthCheckerFunc(func(ctx context.Context) error { return errors.New("connection refused") })
+	panicking := HealthCheckerFunc(func(ctx context.Context) error { panic("boom") })
+
+	service.AddHealthCheck("cache", healthy)
+	service.AddHealthCheck("database", failing)
+
+	report = service.CheckReadiness(ctx)
+	if report.Ready() || report.Status != HealthStatusFail {
+		t.Errorf("Status = %v, want %v", report.Status, HealthStatusFail)
+	}
+	if len(report.Checks) != 2 {
+		t.Fatalf("CheckReadiness() returned %d checks, want 2", len(report.Checks))
+	}
+	if report.Checks[0].Name != "cache" || report.Checks[0].Status != HealthStatusOK {
+		t.Errorf("Checks[0] = %+v, want cache ok", report.Checks[0])
+	}
+	if report.Checks[1].Name != "database" || report.Checks[1].Error != "connection refused" {
+		t.Errorf("Checks[1] = %+v, want failed database check", report.Checks[1])
+	}
+
+	// A panicking check fails instead of crashing the server
+	service.AddHealthCheck("database", panicking)
+	report = service.CheckReadiness(ctx)
+	if report.Ready() || report.Checks[1].Error != "health check panicked" {
+		t.Errorf("Checks[1] = %+v, want panicked check", report.Checks[1])
+	}
+
+	service.RemoveHealthCheck("database")
+	if report = service.CheckReadiness(ctx); !report.Ready() {
+		t.Errorf("CheckReadiness() after removing the failing check = %+v, want ready", report)
+	}
+
+	// A draining server is not ready even if every check passes
+	service.SetDraining(true)
+	report = service.CheckReadiness(ctx)
+	if report.Ready() || report.Status != HealthStatusDraining || !report.Draining {
+		t.Errorf("CheckReadiness() while draining = %+v", report)
+	}
+	if !service.Draining() {
+		t.Error("Draining() = false, want true")
+	}
+}
@@ -0,0 +1,125 @@
+package usecases
+
+import (
+	"context"
+	"encoding/base64"
+	"sort"
+	"strings"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+// cursorPrefix starts the decoded cursors of paged listings, so that malformed
+// cursors are rejected. Cursors are not signed: a client can craft one that
+// starts a page after any key, which only skips entries it may list anyway.
+const cursorPrefix = "after:"
+
+// encodeCursor returns the cursor of the page that starts after the entry
+// with the given key. Cursors are opaque to clients, and keep their position
+// when entries are added or removed between two pages.
+func encodeCursor(key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + key))
+}
+
+// decodeCursor returns the key of the entry after which the page of cursor
+// starts, or "" for the first page.
+func decodeCursor(cursor string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(data), cursorPrefix) {
+		return "", domain.NewValidationError("cursor", "invalid cursor")
+	}
+	return strings.TrimPrefix(string(data), cursorPrefix), nil
+}
+
+// pageLister lists at most limit entries whose keys sort after the given one,
+// ordered by key, and whether more follow, like the paged repositories.
+type pageLister[T any] func(ctx context.Context, after string, limit int) ([]T, bool, error)
+
+// listAll pages the entries of a repository that cannot list them a page at a
+// time, by listing all of them.
+func listAll[T any](list func(ctx context.Context) ([]T, error), key func(T) string) pageLister[T] {
+	return func(ctx context.Context, after string, limit int) ([]T, bool, error) {
+		items, err := list(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		sort.SliceStable(items, func(i, j int) bool { return key(items[i]) < key(items[j]) })
+		page, more := domain.PageAfter(items, key, after, limit)
+		return page, more, nil
+	}
+}
+
+// listPage returns the page of entries that cursor points to, with at most
+// size entries, or all of them if size is zero, and the cursor of the next
+// page, which is empty on the last page. Entries that allow rejects are left
+// out, without making the page shorter unless it is the last one.
+func listPage[T any](ctx context.Context, cursor string, size int, key func(T) string, allow func(T) bool, list pageLister[T]) ([]T, string, error) {
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	page := make([]T, 0, size)
+	for {
+		items, more, err := list(ctx, after, size-len(page))
+		if err != nil {
+			return nil, "", err
+		}
+		for _, item := range items {
+			after = key(item)
+			if allow == nil || allow(item) {
+				page = append(page, item)
+			}
+		}
+		if !more || len(items) == 0 {
+			return page, "", nil
+		}
+		if len(page) == size {
+			return page, encodeCursor(after), nil
+		}
+	}
+}
+
+// ListResourcesPage returns the page of resources that cursor points to,
+// ordered by URI, and the cursor of the next page, which is empty on the last
+// page. An empty cursor starts at the first page. Pages hold the number of
+// resources set by ServerConfig.PageSize, or all of them.
+func (s *ServerService) ListResourcesPage(ctx context.Context, cursor string) ([]*domain.Resource, string, error) {
+	key := func(resource *domain.Resource) string { return resource.URI }
+	list := listAll(s.resourceRepo.ListResources, key)
+	if paged, ok := s.resourceRepo.(domain.PagedResourceRepository); ok {
+		list = paged.ListResourcesPage
+	}
+	return listPage(ctx, cursor, s.currentPageSize(), key, nil, list)
+}
+
+// ListToolsPage returns the page of the tools available to the principal of
+// the request that cursor points to, ordered by name, like ListResourcesPage.
+func (s *ServerService) ListToolsPage(ctx context.Context, cursor string) ([]*domain.Tool, string, error) {
+	key := func(tool *domain.Tool) string { return tool.Name }
+	list := listAll(s.toolRepo.ListTools, key)
+	if paged, ok := s.toolRepo.(domain.PagedToolRepository); ok {
+		list = paged.ListToolsPage
+	}
+
+	var allow func(*domain.Tool) bool
+	if policy := s.currentToolPolicy(); policy != nil {
+		principal := domain.PrincipalFromContext(ctx)
+		allow = func(tool *domain.Tool) bool { return policy.AllowTool(principal, tool.Name) }
+	}
+	return listPage(ctx, cursor, s.currentPageSize(), key, allow, list)
+}
+
+// ListPromptsPage returns the page of prompts that cursor points to, ordered
+// by name, like ListResourcesPage.
+func (s *ServerService) ListPromptsPage(ctx context.Context, cursor string) ([]*domain.Prompt, string, error) {
+	key := func(prompt *domain.Prompt) string { return prompt.Name }
+	list := listAll(s.promptRepo.ListPrompts, key)
+	if paged, ok := s.promptRepo.(domain.PagedPromptRepository); ok {
+		list = paged.ListPromptsPage
+	}
+	return listPage(ctx, cursor, s.currentPageSize(), key, nil, list)
+}
@@ -0,0 +1,107 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+func TestCursor(t *testing.T) {
+	for _, key := range []string{"", "db.query", "file:///logs/a b?c"} {
+		got, err := decodeCursor(encodeCursor(key))
+		if err != nil || got != key {
+			t.Errorf("decodeCursor(encodeCursor(%q)) = %q, %v", key, got, err)
+		}
+	}
+
+	for _, cursor := range []string{"not base64!", "ZGIucXVlcnk"} {
+		var invalid *domain.ValidationError
+		if _, err := decodeCursor(cursor); !errors.As(err, &invalid) {
+			t.Errorf("decodeCursor(%q) error = %v, want a ValidationError", cursor, err)
+		}
+	}
+}
+
+func TestServerService_ListToolsPage(t *testing.T) {
+	toolRepo := NewMockToolRepository()
+	ctx := context.Background()
+	for i := 0; i < 7; i++ {
+		_ = toolRepo.AddTool(ctx, &domain.Tool{Name: fmt.Sprintf("tool%d", i)})
+	}
+
+	service := NewServerService(ServerConfig{
+		ToolRepo:           toolRepo,
+		ResourceRepo:       NewMockResourceRepository(),
+		PromptRepo:         NewMockPromptRepository(),
+		SessionRepo:        NewMockSessionRepository(),
+		NotificationSender: NewMockNotificationSender(),
+		ToolPolicy: NewRulePolicy(PolicyAllow,
+			PolicyRule{Effect: PolicyDeny, Tools: []string{"tool1", "tool2", "tool5"}},
+		),
+		PageSize: 2,
+	})
+
+	var names []string
+	var pages int
+	cursor := ""
+	for {
+		tools, next, err := service.ListToolsPage(ctx, cursor)
+		if err != nil {
+			t.Fatalf("ListToolsPage() error = %v", err)
+		}
+		if len(tools) > 2 {
+			t.Errorf("page %d has %d tools, want at most 2", pages, len(tools))
+		}
+		for _, tool := range tools {
+			names = append(names, tool.Name)
+		}
+		pages++
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	// Denied tools are skipped without making pages shorter
+	if want := []string{"tool0", "tool3", "tool4", "tool6"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("listed %v, want %v", names, want)
+	}
+	if pages != 2 {
+		t.Errorf("listed %d pages, want 2", pages)
+	}
+
+	if _, _, err := service.ListToolsPage(ctx, "not a cursor"); err == nil {
+		t.Error("ListToolsPage() with an invalid cursor error = nil")
+	}
+}
+
+func TestServerService_ListPagesWithoutPageSize(t *testing.T) {
+	resourceRepo := NewMockResourceRepository()
+	promptRepo := NewMockPromptRepository()
+	ctx := context.Background()
+	for _, name := range []string{"c", "a", "b"} {
+		_ = resourceRepo.AddResource(ctx, &domain.Resource{URI: "file:///" + name})
+		_ = promptRepo.AddPrompt(ctx, &domain.Prompt{Name: name})
+	}
+
+	service := NewServerService(ServerConfig{
+		ToolRepo:           NewMockToolRepository(),
+		ResourceRepo:       resourceRepo,
+		PromptRepo:         promptRepo,
+		SessionRepo:        NewMockSessionRepository(),
+		NotificationSender: NewMockNotificationSender(),
+	})
+
+	resources, next, err := service.ListResourcesPage(ctx, "")
+	if err != nil || next != "" || len(resources) != 3 || resources[0].URI != "file:///a" {
+		t.Errorf("ListResourcesPage() = %v, %q, %v, want all resources ordered by URI", resources, next, err)
+	}
+	prompts, next, err := service.ListPromptsPage(ctx, encodeCursor("a"))
+	if err != nil || next != "" || len(prompts) != 2 || prompts[0].Name != "b" {
+		t.Errorf("ListPromptsPage() = %v, %q, %v, want the prompts after a", prompts, next, err)
+	}
+}
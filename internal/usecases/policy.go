@@ -0,0 +1,121 @@
+package usecases
+
+import (
+	"path"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+// ToolPolicy decides which tools a principal may list and call.
+type ToolPolicy interface {
+	// AllowTool reports whether the principal may use the named tool.
+	// The principal is nil for unauthenticated callers.
+	AllowTool(principal *domain.Principal, toolName string) bool
+}
+
+// ToolPolicyFunc is a function type that implements ToolPolicy
+type ToolPolicyFunc func(principal *domain.Principal, toolName string) bool
+
+// AllowTool calls the policy function
+func (f ToolPolicyFunc) AllowTool(principal *domain.Principal, toolName string) bool {
+	return f(principal, toolName)
+}
+
+// PolicyEffect is the outcome of a matching policy rule.
+type PolicyEffect string
+
+// Available policy effects
+const (
+	PolicyAllow PolicyEffect = "allow"
+	PolicyDeny  PolicyEffect = "deny"
+)
+
+// PolicyRule grants or denies access to a set of tools for a set of principals.
+//
+// Tools holds glob patterns as understood by path.Match, e.g. "db.*"; an empty
+// list matches every tool. Principals, Roles and Scopes select who the rule
+// applies to: a principal matches if its ID, any of its roles or any of its
+// scopes is listed. A rule without any of these applies to every caller,
+// including unauthenticated ones.
+type PolicyRule struct {
+	Effect     PolicyEffect
+	Tools      []string
+	Principals []string
+	Roles      []string
+	Scopes     []string
+}
+
+// matchesTool reports whether the rule covers the named tool.
+func (r PolicyRule) matchesTool(toolName string) bool {
+	if len(r.Tools) == 0 {
+		return true
+	}
+	for _, pattern := range r.Tools {
+		if ok, err := path.Match(pattern, toolName); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPrincipal reports whether the rule applies to the principal.
+func (r PolicyRule) matchesPrincipal(principal *domain.Principal) bool {
+	if len(r.Principals) == 0 && len(r.Roles) == 0 && len(r.Scopes) == 0 {
+		return true
+	}
+	if principal == nil {
+		return false
+	}
+	for _, id := range r.Principals {
+		if id == "*" || id == principal.ID {
+			return true
+		}
+	}
+	for _, role := range r.Roles {
+		if principal.HasRole(role) {
+			return true
+		}
+	}
+	for _, scope := range r.Scopes {
+		if principal.HasScope(scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// RulePolicy is a ToolPolicy evaluated from a list of allow and deny rules.
+// A matching deny rule always wins over a matching allow rule; if no rule
+// matches, the default effect applies. Effects other than PolicyAllow, such as
+// a misspelled one, deny access, so that a mistake in a rule fails closed.
+type RulePolicy struct {
+	defaultEffect PolicyEffect
+	rules         []PolicyRule
+}
+
+// NewRulePolicy creates a new RulePolicy with the given default effect and rules.
+func NewRulePolicy(defaultEffect PolicyEffect, rules ...PolicyRule) *RulePolicy {
+	return &RulePolicy{
+		defaultEffect: defaultEffect,
+		rules:         rules,
+	}
+}
+
+// AllowTool reports whether the principal may use the named tool.
+func (p *RulePolicy) AllowTool(principal *domain.Principal, toolName string) bool {
+	allowed := false
+	for _, rule := range p.rules {
+		if !rule.matchesTool(toolName) || !rule.matchesPrincipal(principal) {
+			continue
+		}
+		if rule.Effect != PolicyAllow {
+			return false
+		}
+		allowed = true
+	}
+
+	if allowed {
+		return true
+	}
+	return p.defaultEffect == PolicyAllow
+}
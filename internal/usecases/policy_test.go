@@ -0,0 +1,135 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+func TestRulePolicy_AllowTool(t *testing.T) {
+	policy := NewRulePolicy(PolicyAllow,
+		PolicyRule{Effect: PolicyDeny, Tools: []string{"db.delete"}, Roles: []string{"read-only"}},
+		PolicyRule{Effect: PolicyDeny, Tools: []string{"admin.*"}},
+		PolicyRule{Effect: PolicyAllow, Tools: []string{"admin.*"}, Scopes: []string{"mcp:admin"}},
+	)
+
+	readOnly := &domain.Principal{ID: "alice", Roles: []string{"read-only"}}
+	admin := &domain.Principal{ID: "bob", Scopes: []string{"mcp:admin"}}
+
+	tests := []struct {
+		name      string
+		principal *domain.Principal
+		tool      string
+		want      bool
+	}{
+		{name: "Read-only user may query", principal: readOnly, tool: "db.query", want: true},
+		{name: "Read-only user may not delete", principal: readOnly, tool: "db.delete", want: false},
+		{name: "Admin may delete", principal: admin, tool: "db.delete", want: true},
+		{name: "Deny wins over allow", principal: admin, tool: "admin.reset", want: false},
+		{name: "Anonymous falls back to default", principal: nil, tool: "db.query", want: true},
+		{name: "Anonymous matches subject-less deny", principal: nil, tool: "admin.reset", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.AllowTool(tt.principal, tt.tool); got != tt.want {
+				t.Errorf("AllowTool() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRulePolicy_DefaultDeny(t *testing.T) {
+	policy := NewRulePolicy(PolicyDeny,
+		PolicyRule{Effect: PolicyAllow, Tools: []string{"weather.*"}, Principals: []string{"*"}},
+	)
+
+	if !policy.AllowTool(&domain.Principal{ID: "carol"}, "weather.forecast") {
+		t.Error("AllowTool() = false, want true for allowed tool")
+	}
+	if policy.AllowTool(&domain.Principal{ID: "carol"}, "db.query") {
+		t.Error("AllowTool() = true, want false for unmatched tool")
+	}
+	if policy.AllowTool(nil, "weather.forecast") {
+		t.Error("AllowTool() = true, want false for anonymous caller")
+	}
+}
+
+func TestRulePolicy_UnknownEffectDenies(t *testing.T) {
+	policy := NewRulePolicy(PolicyAllow,
+		PolicyRule{Effect: "Deny", Tools: []string{"db.delete"}},
+		PolicyRule{Effect: PolicyAllow, Tools: []string{"db.*"}},
+	)
+	if policy.AllowTool(&domain.Principal{ID: "carol"}, "db.delete") {
+		t.Error("AllowTool() = true, want false for a rule with an unknown effect")
+	}
+	if !policy.AllowTool(&domain.Principal{ID: "carol"}, "db.query") {
+		t.Error("AllowTool() = false, want true for an allowed tool")
+	}
+
+	// An unknown default effect denies as well
+	if NewRulePolicy("permit").AllowTool(&domain.Principal{ID: "carol"}, "db.query") {
+		t.Error("AllowTool() = true, want false for an unknown default effect")
+	}
+}
+
+func TestServerService_ToolPolicy(t *testing.T) {
+	toolRepo := NewMockToolRepository()
+	ctx := context.Background()
+	_ = toolRepo.AddTool(ctx, &domain.Tool{Name: "db.query"})
+	_ = toolRepo.AddTool(ctx, &domain.Tool{Name: "db.delete"})
+
+	service := NewServerService(ServerConfig{
+		ToolRepo:           toolRepo,
+		ResourceRepo:       NewMockResourceRepository(),
+		PromptRepo:         NewMockPromptRepository(),
+		SessionRepo:        NewMockSessionRepository(),
+		NotificationSender: NewMockNotificationSender(),
+		ToolPolicy: NewRulePolicy(PolicyAllow,
+			PolicyRule{Effect: PolicyDeny, Tools: []string{"db.delete"}, Roles: []string{"read-only"}},
+		),
+	})
+	service.RegisterToolHandler("db.delete", func(ctx context.Context, params map[string]interface{}, session *domain.ClientSession) (interface{}, error) {
+		return "deleted", nil
+	})
+
+	readOnly := &domain.Principal{ID: "alice", Roles: []string{"read-only"}}
+
+	// Denied tools are hidden from the list
+	tools, err := service.ListTools(domain.ContextWithPrincipal(ctx, readOnly))
+	if err != nil {
+		t.Fatalf("ListTools() error = %v", err)
+	}
+	if len(tools) != 1 || tools[0].Name != "db.query" {
+		t.Errorf("ListTools() = %v, want only db.query", tools)
+	}
+
+	// Other callers still see every tool
+	tools, err = service.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools() error = %v", err)
+	}
+	if len(tools) != 2 {
+		t.Errorf("ListTools() returned %v tools, want 2", len(tools))
+	}
+
+	// Calling a denied tool fails with a forbidden error
+	session := domain.NewClientSession("test-agent")
+	session.Principal = readOnly
+	_, err = service.ExecuteTool(ctx, "db.delete", nil, session)
+	var forbidden *domain.ToolForbiddenError
+	if !errors.As(err, &forbidden) {
+		t.Fatalf("ExecuteTool() error = %v, want ToolForbiddenError", err)
+	}
+	if forbidden.Name != "db.delete" {
+		t.Errorf("ToolForbiddenError.Name = %v, want db.delete", forbidden.Name)
+	}
+
+	// Callers not matched by the deny rule may call it
+	session.Principal = &domain.Principal{ID: "bob"}
+	if result, err := service.ExecuteTool(ctx, "db.delete", nil, session); err != nil || result != "deleted" {
+		t.Errorf("ExecuteTool() = %v, %v, want deleted", result, err)
+	}
+}
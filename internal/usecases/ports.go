@@ -0,0 +1,42 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+// LogFields are the structured fields of a log message.
+type LogFields = map[string]interface{}
+
+// Logger records what the service does. The builders inject the structured
+// logger of the infrastructure.
+type Logger interface {
+	Debug(msg string, fields ...LogFields)
+	Info(msg string, fields ...LogFields)
+	Warn(msg string, fields ...LogFields)
+	Error(msg string, fields ...LogFields)
+}
+
+// nopLogger discards every message, for services created without a logger.
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...LogFields) {}
+func (nopLogger) Info(string, ...LogFields)  {}
+func (nopLogger) Warn(string, ...LogFields)  {}
+func (nopLogger) Error(string, ...LogFields) {}
+
+// Tracer records a span for every request and tool call. The builders inject
+// the tracer of the infrastructure, and the transports use it through the
+// service.
+type Tracer interface {
+	// StartRequest starts the span of a JSON-RPC request in a session, whose ID
+	// is empty if there is none, and returns a copy of ctx carrying it and the
+	// function that ends it with the error of the response, if any.
+	StartRequest(ctx context.Context, method string, params interface{}, sessionID string) (context.Context, func(rpcErr *domain.JSONRPCError))
+
+	// StartToolCall starts the span of a call of the named tool in a session,
+	// whose ID is empty if there is none, and returns a copy of ctx carrying
+	// it and the function that ends it with the error of the call.
+	StartToolCall(ctx context.Context, tool, sessionID string) (context.Context, func(err error))
+}
@@ -0,0 +1,257 @@
+package usecases
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+// RateLimit is a token bucket limit of Requests per Per, allowing bursts of up
+// to Burst calls. Burst defaults to Requests.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// rate returns the refill rate in tokens per second.
+func (l RateLimit) rate() float64 {
+	if l.Per <= 0 {
+		return 0
+	}
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// burst returns the bucket capacity.
+func (l RateLimit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// RateLimitConfig contains the rate limits applied to tool calls. A call must
+// pass every configured limit; nil limits are not enforced.
+type RateLimitConfig struct {
+	// Global limits all tool calls on the server.
+	Global *RateLimit
+
+	// PerSession limits the tool calls of each client session.
+	PerSession *RateLimit
+
+	// PerPrincipal limits the tool calls of each authenticated principal,
+	// across all of its sessions.
+	PerPrincipal *RateLimit
+
+	// PerTool limits the calls of the tools matching each glob pattern, e.g.
+	// "search.*". Each pattern has its own bucket shared by all callers.
+	PerTool map[string]RateLimit
+}
+
+// Validate returns a *domain.ValidationError if a limit allows no calls or has
+// no period, which would otherwise leave it unenforced.
+func (c RateLimitConfig) Validate() error {
+	limits := map[string]*RateLimit{"global": c.Global, "perSession": c.PerSession, "perPrincipal": c.PerPrincipal}
+	for pattern, limit := range c.PerTool {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return domain.NewValidationError("perTool", fmt.Sprintf("invalid pattern %q", pattern))
+		}
+		limits["perTool["+pattern+"]"] = &limit
+	}
+	for field, limit := range limits {
+		if limit == nil {
+			continue
+		}
+		if limit.Requests <= 0 || limit.Per <= 0 || limit.Burst < 0 {
+			return domain.NewValidationError(field, "requests and period must be positive, and burst must not be negative")
+		}
+	}
+	return nil
+}
+
+// rateLimitSessionKey returns the key that the per-session rate limit counts
+// the calls of a session under. HTTP requests without a session ID get a new,
+// uninitialized session each, so their calls are counted per principal, or per
+// client address if they are anonymous.
+func rateLimitSessionKey(session *domain.ClientSession, principal *domain.Principal) string {
+	if session != nil && (session.Transport != domain.TransportHTTP || session.Initialized()) {
+		return session.ID
+	}
+	switch {
+	case principal != nil:
+		return "principal:" + principal.ID
+	case session != nil && session.RemoteAddr != "":
+		return "addr:" + session.RemoteAddr
+	case session != nil:
+		return session.ID
+	}
+	return ""
+}
+
+// tokenBucket is a single token bucket.
+type tokenBucket struct {
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens accumulated since the last refill.
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens += elapsed * b.limit.rate()
+		if burst := b.limit.burst(); b.tokens > burst {
+			b.tokens = burst
+		}
+	}
+	b.last = now
+}
+
+// wait returns how long it takes until the bucket holds a full token.
+func (b *tokenBucket) wait() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	rate := b.limit.rate()
+	if rate <= 0 {
+		return b.limit.Per
+	}
+	return time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+// RateLimiter enforces a RateLimitConfig on tool calls.
+type RateLimiter struct {
+	config    RateLimitConfig
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewRateLimiter creates a new RateLimiter with the given configuration, which
+// must be valid, see RateLimitConfig.Validate.
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	return &RateLimiter{
+		config:  config,
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// limitKey identifies a bucket and the limit that applies to it.
+type limitKey struct {
+	scope string
+	key   string
+	limit RateLimit
+}
+
+// Allow takes a token from every bucket that applies to the call. If any
+// bucket is empty, no token is taken and a *domain.RateLimitError is returned
+// that tells the caller when to retry. sessionKey identifies the caller for
+// the per-session limit, see rateLimitSessionKey.
+func (l *RateLimiter) Allow(toolName string, sessionKey string, principal *domain.Principal) error {
+	keys := l.keys(toolName, sessionKey, principal)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	buckets := make([]*tokenBucket, len(keys))
+	for i, k := range keys {
+		bucket, ok := l.buckets[k.key]
+		if !ok {
+			bucket = &tokenBucket{limit: k.limit, tokens: k.limit.burst(), last: now}
+			l.buckets[k.key] = bucket
+		}
+		bucket.refill(now)
+		if wait := bucket.wait(); wait > 0 {
+			return domain.NewRateLimitError(toolName, k.scope, wait)
+		}
+		buckets[i] = bucket
+	}
+
+	for _, bucket := range buckets {
+		bucket.tokens--
+	}
+	return nil
+}
+
+// keys returns the buckets that apply to a call.
+func (l *RateLimiter) keys(toolName string, sessionKey string, principal *domain.Principal) []limitKey {
+	var keys []limitKey
+	if l.config.Global != nil {
+		keys = append(keys, limitKey{scope: "global", key: "global", limit: *l.config.Global})
+	}
+	if l.config.PerSession != nil && sessionKey != "" {
+		keys = append(keys, limitKey{scope: "session", key: "session:" + sessionKey, limit: *l.config.PerSession})
+	}
+	if l.config.PerPrincipal != nil && principal != nil {
+		keys = append(keys, limitKey{scope: "principal", key: "principal:" + principal.ID, limit: *l.config.PerPrincipal})
+	}
+
+	// Visit patterns in a stable order so the reported scope is deterministic
+	patterns := make([]string, 0, len(l.config.PerTool))
+	for pattern := range l.config.PerTool {
+		if ok, err := path.Match(pattern, toolName); err == nil && ok {
+			patterns = append(patterns, pattern)
+		}
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		keys = append(keys, limitKey{scope: "tool", key: "tool:" + pattern, limit: l.config.PerTool[pattern]})
+	}
+	return keys
+}
+
+// sweep drops buckets that have refilled completely, since a new bucket would
+// be identical. It runs at most once a minute. Callers must hold l.mu.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	for key, bucket := range l.buckets {
+		bucket.refill(now)
+		if bucket.tokens >= bucket.limit.burst() {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RateLimitQuota reports the remaining calls of one rate limit bucket.
+type RateLimitQuota struct {
+	Scope     string
+	Limit     RateLimit
+	Remaining int
+}
+
+// Quota returns the remaining calls of every bucket that applies to a call,
+// without taking any tokens.
+func (l *RateLimiter) Quota(toolName string, sessionKey string, principal *domain.Principal) []RateLimitQuota {
+	keys := l.keys(toolName, sessionKey, principal)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	quotas := make([]RateLimitQuota, len(keys))
+	for i, k := range keys {
+		remaining := k.limit.burst()
+		if bucket, ok := l.buckets[k.key]; ok {
+			bucket.refill(now)
+			remaining = bucket.tokens
+		}
+		quotas[i] = RateLimitQuota{Scope: k.scope, Limit: k.limit, Remaining: int(remaining)}
+	}
+	return quotas
+}
@@ -0,0 +1,186 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := NewRateLimiter(RateLimitConfig{
+		PerSession: &RateLimit{Requests: 2, Per: time.Second},
+		PerTool:    map[string]RateLimit{"paid.*": {Requests: 1, Per: time.Minute}},
+	})
+	limiter.now = func() time.Time { return now }
+
+	// The session burst allows two calls
+	for i := 0; i < 2; i++ {
+		if err := limiter.Allow("echo", "s1", nil); err != nil {
+			t.Fatalf("Allow() call %d error = %v", i, err)
+		}
+	}
+
+	// The third call is throttled with a retry hint
+	err := limiter.Allow("echo", "s1", nil)
+	var rateErr *domain.RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("Allow() error = %v, want RateLimitError", err)
+	}
+	if rateErr.Scope != "session" {
+		t.Errorf("RateLimitError.Scope = %v, want session", rateErr.Scope)
+	}
+	if rateErr.RetryAfter != 500*time.Millisecond {
+		t.Errorf("RateLimitError.RetryAfter = %v, want 500ms", rateErr.RetryAfter)
+	}
+
+	// Other sessions have their own bucket
+	if err := limiter.Allow("echo", "s2", nil); err != nil {
+		t.Errorf("Allow() for another session error = %v", err)
+	}
+
+	// Tokens are refilled over time
+	now = now.Add(500 * time.Millisecond)
+	if err := limiter.Allow("echo", "s1", nil); err != nil {
+		t.Errorf("Allow() after refill error = %v", err)
+	}
+
+	// Tool limits are shared by all sessions
+	if err := limiter.Allow("paid.search", "s3", nil); err != nil {
+		t.Fatalf("Allow() for paid tool error = %v", err)
+	}
+	err = limiter.Allow("paid.search", "s4", nil)
+	if !errors.As(err, &rateErr) || rateErr.Scope != "tool" {
+		t.Errorf("Allow() error = %v, want tool RateLimitError", err)
+	}
+
+	// A rejected call does not consume tokens from the other buckets
+	quotas := limiter.Quota("paid.search", "s4", nil)
+	if len(quotas) != 2 || quotas[0].Scope != "session" || quotas[0].Remaining != 2 {
+		t.Errorf("Quota() = %+v, want an untouched session bucket for s4", quotas)
+	}
+}
+
+func TestRateLimiter_PerPrincipal(t *testing.T) {
+	limiter := NewRateLimiter(RateLimitConfig{
+		PerPrincipal: &RateLimit{Requests: 1, Per: time.Hour},
+	})
+	alice := &domain.Principal{ID: "alice"}
+
+	if err := limiter.Allow("echo", "s1", alice); err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+
+	// The limit spans all sessions of the principal
+	if err := limiter.Allow("echo", "s2", alice); err == nil {
+		t.Error("Allow() for a second session of the same principal should be throttled")
+	}
+
+	// Unauthenticated callers are not subject to principal limits
+	if err := limiter.Allow("echo", "s3", nil); err != nil {
+		t.Errorf("Allow() for anonymous caller error = %v", err)
+	}
+}
+
+func TestRateLimitConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  RateLimitConfig
+		wantErr bool
+	}{
+		{"empty", RateLimitConfig{}, false},
+		{"valid", RateLimitConfig{Global: &RateLimit{Requests: 10, Per: time.Second}}, false},
+		{"zero limit", RateLimitConfig{PerSession: &RateLimit{}}, true},
+		{"no period", RateLimitConfig{Global: &RateLimit{Requests: 10}}, true},
+		{"negative burst", RateLimitConfig{Global: &RateLimit{Requests: 10, Per: time.Second, Burst: -1}}, true},
+		{"zero tool limit", RateLimitConfig{PerTool: map[string]RateLimit{"search.*": {}}}, true},
+		{"invalid pattern", RateLimitConfig{PerTool: map[string]RateLimit{"[": {Requests: 1, Per: time.Second}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServerService_RateLimitsWithoutSessionID(t *testing.T) {
+	ctx := context.Background()
+	service := NewServerService(ServerConfig{
+		ToolRepo:           NewMockToolRepository(),
+		ResourceRepo:       NewMockResourceRepository(),
+		PromptRepo:         NewMockPromptRepository(),
+		SessionRepo:        NewMockSessionRepository(),
+		NotificationSender: NewMockNotificationSender(),
+		RateLimits: &RateLimitConfig{
+			PerSession: &RateLimit{Requests: 1, Per: time.Hour},
+		},
+	})
+	service.RegisterToolHandler("echo", func(ctx context.Context, params map[string]interface{}, session *domain.ClientSession) (interface{}, error) {
+		return "ok", nil
+	})
+
+	// Every HTTP request without a session ID gets a new session
+	request := func(addr string) *domain.ClientSession {
+		session := domain.NewClientSession("agent")
+		session.Transport = domain.TransportHTTP
+		session.RemoteAddr = addr
+		return session
+	}
+
+	if _, err := service.ExecuteTool(ctx, "echo", nil, request("10.0.0.1")); err != nil {
+		t.Fatalf("first call error = %v", err)
+	}
+	var rateLimitErr *domain.RateLimitError
+	if _, err := service.ExecuteTool(ctx, "echo", nil, request("10.0.0.1")); !errors.As(err, &rateLimitErr) {
+		t.Errorf("second call from the same address error = %v, want a RateLimitError", err)
+	}
+	if _, err := service.ExecuteTool(ctx, "echo", nil, request("10.0.0.2")); err != nil {
+		t.Errorf("call from another address error = %v", err)
+	}
+
+	// Initialized sessions are limited on their own
+	initialized := request("10.0.0.1")
+	initialized.Initialize(map[string]interface{}{})
+	if _, err := service.ExecuteTool(ctx, "echo", nil, initialized); err != nil {
+		t.Errorf("call of an initialized session error = %v", err)
+	}
+}
+
+func TestServerService_RateLimits(t *testing.T) {
+	ctx := context.Background()
+	service := NewServerService(ServerConfig{
+		ToolRepo:           NewMockToolRepository(),
+		ResourceRepo:       NewMockResourceRepository(),
+		PromptRepo:         NewMockPromptRepository(),
+		SessionRepo:        NewMockSessionRepository(),
+		NotificationSender: NewMockNotificationSender(),
+		RateLimits: &RateLimitConfig{
+			Global: &RateLimit{Requests: 1, Per: time.Hour},
+		},
+	})
+	service.RegisterToolHandler("echo", func(ctx context.Context, params map[string]interface{}, session *domain.ClientSession) (interface{}, error) {
+		return "ok", nil
+	})
+	session := domain.NewClientSession("test-agent")
+
+	if _, err := service.ExecuteTool(ctx, "echo", nil, session); err != nil {
+		t.Fatalf("ExecuteTool() error = %v", err)
+	}
+
+	_, err := service.ExecuteTool(ctx, "echo", nil, session)
+	var rateErr *domain.RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("ExecuteTool() error = %v, want RateLimitError", err)
+	}
+	if service.RateLimitedCount() != 1 {
+		t.Errorf("RateLimitedCount() = %v, want 1", service.RateLimitedCount())
+	}
+	if quotas := service.RateLimitQuota("echo", session); len(quotas) != 1 || quotas[0].Remaining != 0 {
+		t.Errorf("RateLimitQuota() = %+v, want no remaining calls", quotas)
+	}
+}
@@ -3,10 +3,22 @@ package usecases
 
 import (
 	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/FreePeak/cortex/internal/domain"
 )
 
+// ToolObserver is notified of every tool call dispatched by the ServerService,
+// including calls rejected by policy, rate limits or concurrency limits.
+type ToolObserver interface {
+	// ObserveToolCall records a completed tool call and its error, if any.
+	ObserveToolCall(name string, duration time.Duration, err error)
+}
+
 // ToolHandlerFunc defines a function type for handling tool calls
 type ToolHandlerFunc func(ctx context.Context, params map[string]interface{}, session *domain.ClientSession) (interface{}, error)
 
@@ -19,8 +31,22 @@ type ServerService struct {
 	toolRepo           domain.ToolRepository
 	promptRepo         domain.PromptRepository
 	sessionRepo        domain.SessionRepository
+	sessionStore       domain.SessionStoreBackend
+	sessionLimits      *SessionLimits
+	sessionLabeler     SessionLabeler
 	notificationSender domain.NotificationSender
 	toolHandlers       map[string]ToolHandlerFunc // Map of tool names to handler functions
+	logger             Logger
+	toolPolicy         ToolPolicy
+	rateLimiter        *RateLimiter
+	bulkheads          *bulkheads
+	toolObserver       ToolObserver
+	tracer             Tracer
+	health             healthChecks
+	settingsMu         sync.RWMutex // Guards the settings that may change while serving
+	pageSize           int          // Number of entries per page of list requests, 0 for all
+	toolPanics         atomic.Int64 // Number of panics recovered from tool handlers
+	rateLimited        atomic.Int64 // Number of tool calls rejected by rate limits
 }
 
 // ServerConfig contains configuration for the ServerService.
@@ -32,7 +58,17 @@ type ServerConfig struct {
 	ToolRepo           domain.ToolRepository
 	PromptRepo         domain.PromptRepository
 	SessionRepo        domain.SessionRepository
+	SessionStore       domain.SessionStoreBackend // Optional, keeps the key/value state of sessions
+	SessionLimits      *SessionLimits             // Optional, idle timeout and max lifetime of sessions
+	SessionLabeler     SessionLabeler             // Optional, labels sessions when they are initialized
 	NotificationSender domain.NotificationSender
+	Logger             Logger             // Optional, discards log messages if nil
+	ToolPolicy         ToolPolicy         // Optional, restricts which tools a principal may list and call
+	RateLimits         *RateLimitConfig   // Optional, rate limits applied to tool calls
+	Concurrency        *ConcurrencyConfig // Optional, concurrency limits applied to tool calls
+	ToolObserver       ToolObserver       // Optional, records the outcome of tool calls, e.g. for metrics
+	Tracer             Tracer             // Optional, records a span for every tool call
+	PageSize           int                // Optional, entries per page of list requests; 0 lists all in one response
 }
 
 // NewServerService creates a new ServerService with the given repositories and configuration.
@@ -45,8 +81,27 @@ func NewServerService(config ServerConfig) *ServerService {
 		toolRepo:           config.ToolRepo,
 		promptRepo:         config.PromptRepo,
 		sessionRepo:        config.SessionRepo,
+		sessionStore:       config.SessionStore,
+		sessionLimits:      config.SessionLimits,
+		sessionLabeler:     config.SessionLabeler,
 		notificationSender: config.NotificationSender,
 		toolHandlers:       make(map[string]ToolHandlerFunc),
+		logger:             config.Logger,
+		toolPolicy:         config.ToolPolicy,
+		toolObserver:       config.ToolObserver,
+		tracer:             config.Tracer,
+		pageSize:           config.PageSize,
+	}
+
+	if config.RateLimits != nil {
+		service.rateLimiter = NewRateLimiter(*config.RateLimits)
+	}
+	if config.Concurrency != nil {
+		service.bulkheads = &bulkheads{config: *config.Concurrency, tools: make(map[string]*Bulkhead)}
+	}
+
+	if service.logger == nil {
+		service.logger = nopLogger{}
 	}
 
 	// No longer automatically register built-in tool handlers
@@ -80,6 +135,134 @@ func (s *ServerService) GetAllToolHandlerNames() []string {
 	return names
 }
 
+// ExecuteTool runs the handler registered for the named tool.
+// It returns a *domain.ToolNotFoundError if no handler is registered.
+func (s *ServerService) ExecuteTool(ctx context.Context, name string, params map[string]interface{}, session *domain.ClientSession) (interface{}, error) {
+	handler := s.GetToolHandler(name)
+	if handler == nil {
+		return nil, domain.NewToolNotFoundError(name)
+	}
+
+	return s.InvokeToolHandler(ctx, name, handler, params, session)
+}
+
+// InvokeToolHandler calls the given handler on behalf of the named tool.
+// A panic inside the handler is recovered, logged with its stack trace and
+// returned as a *domain.ToolPanicError so that a single faulty tool cannot
+// bring down the server.
+func (s *ServerService) InvokeToolHandler(
+	ctx context.Context,
+	name string,
+	handler ToolHandlerFunc,
+	params map[string]interface{},
+	session *domain.ClientSession,
+) (result interface{}, err error) {
+	if session != nil {
+		session.AttachStore(s.currentSessionStore())
+	}
+	if s.toolObserver != nil {
+		start := time.Now()
+		defer func() {
+			s.toolObserver.ObserveToolCall(name, time.Since(start), err)
+		}()
+	}
+
+	// The span is carried by the ctx passed to the handler, so that providers
+	// and downstream calls join the trace
+	if tracer := s.Tracer(); tracer != nil {
+		sessionID := ""
+		if session != nil {
+			sessionID = session.ID
+		}
+		var end func(err error)
+		ctx, end = tracer.StartToolCall(ctx, name, sessionID)
+		defer func() { end(err) }()
+	}
+
+	principal := domain.PrincipalFromContext(ctx)
+	if session != nil && session.Principal != nil {
+		principal = session.Principal
+	}
+	if !s.ToolAllowed(principal, name) {
+		s.logger.Warn("Tool call denied by policy", LogFields{"tool": name, "principal": principalID(principal)})
+		return nil, domain.NewToolForbiddenError(name)
+	}
+
+	if limiter := s.currentRateLimiter(); limiter != nil {
+		sessionKey := rateLimitSessionKey(session, principal)
+		if err := limiter.Allow(name, sessionKey, principal); err != nil {
+			s.rateLimited.Add(1)
+			s.logger.Warn("Tool call rate limited", LogFields{"tool": name, "session": sessionKey, "error": err.Error()})
+			return nil, err
+		}
+	}
+
+	if limits := s.currentBulkheads(); limits != nil {
+		if bulkhead := limits.forTool(name); bulkhead != nil {
+			release, err := bulkhead.Acquire(ctx)
+			if err != nil {
+				s.logger.Warn("Tool call rejected by concurrency limit", LogFields{"tool": name, "error": err.Error()})
+				return nil, err
+			}
+			defer release()
+		}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			panicErr := domain.NewToolPanicError(name, r, debug.Stack())
+			s.toolPanics.Add(1)
+
+			fields := LogFields{
+				"tool":  name,
+				"panic": panicErr.Err.Message,
+				"stack": string(panicErr.Stack),
+			}
+			if session != nil {
+				fields["sessionID"] = session.ID
+			}
+			s.logger.Error("Recovered from panic in tool handler", fields)
+
+			result = nil
+			err = panicErr
+		}
+	}()
+
+	return handler(ctx, params, session)
+}
+
+// Tracer returns the tracer that records tool calls, which the transports also
+// use for requests. It is nil if tracing is not configured.
+func (s *ServerService) Tracer() Tracer {
+	s.settingsMu.RLock()
+	defer s.settingsMu.RUnlock()
+	return s.tracer
+}
+
+// ToolPanicCount returns the number of panics recovered from tool handlers.
+func (s *ServerService) ToolPanicCount() int64 {
+	return s.toolPanics.Load()
+}
+
+// RateLimitedCount returns the number of tool calls rejected by rate limits.
+func (s *ServerService) RateLimitedCount() int64 {
+	return s.rateLimited.Load()
+}
+
+// RateLimitQuota returns the remaining calls of every rate limit that applies to
+// the tool for the given session, or nil if no rate limits are configured.
+func (s *ServerService) RateLimitQuota(name string, session *domain.ClientSession) []RateLimitQuota {
+	limiter := s.currentRateLimiter()
+	if limiter == nil {
+		return nil
+	}
+	var principal *domain.Principal
+	if session != nil {
+		principal = session.Principal
+	}
+	return limiter.Quota(name, rateLimitSessionKey(session, principal), principal)
+}
+
 // ServerInfo returns information about the server.
 func (s *ServerService) ServerInfo() (string, string, string) {
 	return s.name, s.version, s.instructions
@@ -109,9 +292,32 @@ func (s *ServerService) DeleteResource(ctx context.Context, uri string) error {
 	return s.resourceRepo.DeleteResource(ctx, uri)
 }
 
-// ListTools returns all available tools.
+// ListTools returns all tools available to the principal of the request.
+// Tools denied by the tool policy are left out.
 func (s *ServerService) ListTools(ctx context.Context) ([]*domain.Tool, error) {
-	return s.toolRepo.ListTools(ctx)
+	tools, err := s.toolRepo.ListTools(ctx)
+	policy := s.currentToolPolicy()
+	if err != nil || policy == nil {
+		return tools, err
+	}
+
+	principal := domain.PrincipalFromContext(ctx)
+	allowed := make([]*domain.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if policy.AllowTool(principal, tool.Name) {
+			allowed = append(allowed, tool)
+		}
+	}
+	return allowed, nil
+}
+
+// ToolAllowed reports whether the tool policy lets the principal use the named tool.
+func (s *ServerService) ToolAllowed(principal *domain.Principal, name string) bool {
+	policy := s.currentToolPolicy()
+	if policy == nil {
+		return true
+	}
+	return policy.AllowTool(principal, name)
 }
 
 // GetTool returns a tool by its name.
@@ -183,28 +389,62 @@ func (s *ServerService) BroadcastNotification(ctx context.Context, notification
 	return s.notificationSender.BroadcastNotification(ctx, notification)
 }
 
+// NotifySessions sends a notification to the clients whose session is selected
+// by filter. If the notification sender cannot target sessions, the
+// notification is sent to each selected registered session in turn.
+func (s *ServerService) NotifySessions(ctx context.Context, filter domain.SessionFilter, notification *domain.Notification) error {
+	if sender, ok := s.notificationSender.(domain.TargetedNotificationSender); ok {
+		return sender.NotifySessions(ctx, filter, notification)
+	}
+
+	sessions, err := s.ListSessions(ctx)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, session := range sessions {
+		if !domain.SelectsSession(filter, session) {
+			continue
+		}
+		if err := s.SendNotification(ctx, session.ID, notification); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// principalID returns the ID of the principal for logging, or "anonymous".
+func principalID(principal *domain.Principal) string {
+	if principal == nil {
+		return "anonymous"
+	}
+	return principal.ID
+}
+
 // Helper methods for sending specific notifications
 
 func (s *ServerService) notifyResourceListChanged(ctx context.Context) {
-	notification := &domain.Notification{
-		Method: "resources/list/changed",
-		Params: map[string]interface{}{},
-	}
-	_ = s.BroadcastNotification(ctx, notification)
+	s.notifyListChanged(ctx, "resources/list/changed")
 }
 
 func (s *ServerService) notifyToolListChanged(ctx context.Context) {
-	notification := &domain.Notification{
-		Method: "tools/list/changed",
-		Params: map[string]interface{}{},
-	}
-	_ = s.BroadcastNotification(ctx, notification)
+	s.notifyListChanged(ctx, "tools/list/changed")
 }
 
 func (s *ServerService) notifyPromptListChanged(ctx context.Context) {
+	s.notifyListChanged(ctx, "prompts/list/changed")
+}
+
+// notifyListChanged sends a list changed notification to the sessions selected
+// by the notification filter of ctx, or to every session without one.
+func (s *ServerService) notifyListChanged(ctx context.Context, method string) {
 	notification := &domain.Notification{
-		Method: "prompts/list/changed",
+		Method: method,
 		Params: map[string]interface{}{},
 	}
+	if filter := domain.NotificationFilterFromContext(ctx); filter != nil {
+		_ = s.NotifySessions(ctx, filter, notification)
+		return
+	}
 	_ = s.BroadcastNotification(ctx, notification)
 }
@@ -2,8 +2,10 @@ package usecases
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/FreePeak/cortex/internal/domain"
 )
@@ -506,6 +508,164 @@ func TestServerService_Tool(t *testing.T) {
 	}
 }
 
+func TestServerService_ExecuteTool(t *testing.T) {
+	ctx := context.Background()
+	service := createTestServerService(nil, nil, nil, nil, nil)
+	session := domain.NewClientSession("test-agent")
+
+	service.RegisterToolHandler("echo", func(ctx context.Context, params map[string]interface{}, session *domain.ClientSession) (interface{}, error) {
+		return params["message"], nil
+	})
+	service.RegisterToolHandler("explode", func(ctx context.Context, params map[string]interface{}, session *domain.ClientSession) (interface{}, error) {
+		panic("boom")
+	})
+
+	// Test a regular tool call
+	result, err := service.ExecuteTool(ctx, "echo", map[string]interface{}{"message": "hello"}, session)
+	if err != nil {
+		t.Errorf("ExecuteTool() error = %v", err)
+	}
+	if result != "hello" {
+		t.Errorf("ExecuteTool() = %v, want hello", result)
+	}
+
+	// Test an unknown tool
+	_, err = service.ExecuteTool(ctx, "missing", nil, session)
+	var notFound *domain.ToolNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("ExecuteTool() error = %v, want ToolNotFoundError", err)
+	}
+
+	// Test that a panicking handler is converted into an error
+	result, err = service.ExecuteTool(ctx, "explode", nil, session)
+	if result != nil {
+		t.Errorf("ExecuteTool() = %v, want nil result after panic", result)
+	}
+	var panicErr *domain.ToolPanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("ExecuteTool() error = %v, want ToolPanicError", err)
+	}
+	if panicErr.Name != "explode" {
+		t.Errorf("ToolPanicError.Name = %v, want explode", panicErr.Name)
+	}
+	if len(panicErr.Stack) == 0 {
+		t.Error("ToolPanicError.Stack should not be empty")
+	}
+	if service.ToolPanicCount() != 1 {
+		t.Errorf("ToolPanicCount() = %v, want 1", service.ToolPanicCount())
+	}
+
+	// The service keeps working after a panic
+	if _, err := service.ExecuteTool(ctx, "echo", nil, session); err != nil {
+		t.Errorf("ExecuteTool() after panic error = %v", err)
+	}
+}
+
+// recordingObserver records the tool calls it observes.
+type recordingObserver struct {
+	names []string
+	errs  []error
+}
+
+func (o *recordingObserver) ObserveToolCall(name string, duration time.Duration, err error) {
+	o.names = append(o.names, name)
+	o.errs = append(o.errs, err)
+}
+
+func TestServerService_ToolObserver(t *testing.T) {
+	ctx := context.Background()
+	observer := &recordingObserver{}
+	service := NewServerService(ServerConfig{
+		ToolRepo:           NewMockToolRepository(),
+		ResourceRepo:       NewMockResourceRepository(),
+		PromptRepo:         NewMockPromptRepository(),
+		SessionRepo:        NewMockSessionRepository(),
+		NotificationSender: NewMockNotificationSender(),
+		ToolObserver:       observer,
+	})
+	session := domain.NewClientSession("test-agent")
+
+	service.RegisterToolHandler("echo", func(ctx context.Context, params map[string]interface{}, session *domain.ClientSession) (interface{}, error) {
+		return "ok", nil
+	})
+	service.RegisterToolHandler("explode", func(ctx context.Context, params map[string]interface{}, session *domain.ClientSession) (interface{}, error) {
+		panic("boom")
+	})
+
+	_, _ = service.ExecuteTool(ctx, "echo", nil, session)
+	_, _ = service.ExecuteTool(ctx, "explode", nil, session)
+
+	if len(observer.names) != 2 || observer.names[0] != "echo" || observer.names[1] != "explode" {
+		t.Fatalf("observed calls = %v, want [echo explode]", observer.names)
+	}
+	if observer.errs[0] != nil {
+		t.Errorf("observed error for echo = %v, want nil", observer.errs[0])
+	}
+	var panicErr *domain.ToolPanicError
+	if !errors.As(observer.errs[1], &panicErr) {
+		t.Errorf("observed error for explode = %v, want ToolPanicError", observer.errs[1])
+	}
+}
+
+// fakeTracer records the tool calls it starts spans for.
+type fakeTracer struct {
+	tools      []string
+	sessionIDs []string
+	errs       []error
+}
+
+type fakeSpanKey struct{}
+
+func (f *fakeTracer) StartRequest(ctx context.Context, method string, params interface{}, sessionID string) (context.Context, func(rpcErr *domain.JSONRPCError)) {
+	return ctx, func(*domain.JSONRPCError) {}
+}
+
+func (f *fakeTracer) StartToolCall(ctx context.Context, tool, sessionID string) (context.Context, func(err error)) {
+	f.tools = append(f.tools, tool)
+	f.sessionIDs = append(f.sessionIDs, sessionID)
+	return context.WithValue(ctx, fakeSpanKey{}, tool), func(err error) {
+		f.errs = append(f.errs, err)
+	}
+}
+
+func TestServerService_Tracing(t *testing.T) {
+	tracer := &fakeTracer{}
+	service := NewServerService(ServerConfig{
+		ToolRepo:           NewMockToolRepository(),
+		ResourceRepo:       NewMockResourceRepository(),
+		PromptRepo:         NewMockPromptRepository(),
+		SessionRepo:        NewMockSessionRepository(),
+		NotificationSender: NewMockNotificationSender(),
+		Tracer:             tracer,
+	})
+	session := domain.NewClientSession("test-agent")
+
+	// The handler receives the span of its tool call in ctx
+	var handlerSpan interface{}
+	service.RegisterToolHandler("fail", func(ctx context.Context, params map[string]interface{}, session *domain.ClientSession) (interface{}, error) {
+		handlerSpan = ctx.Value(fakeSpanKey{})
+		return nil, errors.New("failed")
+	})
+
+	_, _ = service.ExecuteTool(context.Background(), "fail", nil, session)
+	if len(tracer.tools) != 1 || tracer.tools[0] != "fail" || tracer.sessionIDs[0] != session.ID {
+		t.Fatalf("traced calls = %v %v, want fail in %s", tracer.tools, tracer.sessionIDs, session.ID)
+	}
+	if handlerSpan != "fail" {
+		t.Errorf("handler span = %v, want the span of the call", handlerSpan)
+	}
+	if len(tracer.errs) != 1 || tracer.errs[0] == nil || tracer.errs[0].Error() != "failed" {
+		t.Errorf("span errors = %v, want [failed]", tracer.errs)
+	}
+
+	// Tracing can be disabled while serving
+	service.SetTracer(nil)
+	_, _ = service.ExecuteTool(context.Background(), "fail", nil, session)
+	if len(tracer.tools) != 1 {
+		t.Errorf("traced %d calls after SetTracer(nil), want 1", len(tracer.tools))
+	}
+}
+
 func TestServerService_Prompt(t *testing.T) {
 	// Setup
 	ctx := context.Background()
@@ -0,0 +1,264 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+// DefaultSessionCheckInterval is the longest time between two checks for expired
+// sessions if SessionLimits.CheckInterval is not set.
+const DefaultSessionCheckInterval = time.Minute
+
+// DefaultHTTPSessionIdleTimeout is how long sessions of the streamable HTTP
+// transport may stay idle if SessionLimits.IdleTimeout is not set. They have
+// no connection whose end would end them, so clients that never delete their
+// session would otherwise leave it registered forever.
+const DefaultHTTPSessionIdleTimeout = 30 * time.Minute
+
+// SessionLimits bounds how long sessions of the HTTP transports may live. The
+// stdio session is not limited, as it lives as long as its process.
+type SessionLimits struct {
+	// IdleTimeout disconnects sessions whose client sent no request for this
+	// long. Sessions of the streamable HTTP transport default to
+	// DefaultHTTPSessionIdleTimeout.
+	IdleTimeout time.Duration
+
+	// MaxLifetime disconnects sessions this long after they were created,
+	// regardless of activity.
+	MaxLifetime time.Duration
+
+	// CheckInterval is how often sessions are checked. It defaults to half of the
+	// shortest limit, at most DefaultSessionCheckInterval.
+	CheckInterval time.Duration
+}
+
+// Expired reports whether the session exceeded one of the limits at now.
+func (l SessionLimits) Expired(session *domain.ClientSession, now time.Time) bool {
+	if session.Transport == domain.TransportStdio {
+		return false
+	}
+	idleTimeout := l.IdleTimeout
+	if idleTimeout <= 0 && session.Transport == domain.TransportHTTP {
+		idleTimeout = DefaultHTTPSessionIdleTimeout
+	}
+	if idleTimeout > 0 && now.Sub(session.LastSeen()) >= idleTimeout {
+		return true
+	}
+	return l.MaxLifetime > 0 && now.Sub(session.CreatedAt) >= l.MaxLifetime
+}
+
+// checkInterval returns how often sessions are checked, or zero if no limit is set.
+func (l SessionLimits) checkInterval() time.Duration {
+	if l.IdleTimeout <= 0 && l.MaxLifetime <= 0 {
+		return 0
+	}
+	if l.CheckInterval > 0 {
+		return l.CheckInterval
+	}
+	interval := DefaultSessionCheckInterval
+	for _, limit := range []time.Duration{l.IdleTimeout, l.MaxLifetime} {
+		if limit > 0 && limit/2 < interval {
+			interval = max(limit/2, time.Nanosecond)
+		}
+	}
+	return interval
+}
+
+// SessionLabeler returns the labels of a session when it is initialized, e.g.
+// its tenant taken from the claims of its principal. Notifications can be
+// targeted at labels with domain.LabelFilter.
+type SessionLabeler func(ctx context.Context, session *domain.ClientSession) map[string]string
+
+// StartSession registers a session once its transport connected, so that it can
+// be listed and disconnected before the client initializes it.
+func (s *ServerService) StartSession(ctx context.Context, session *domain.ClientSession) {
+	if s.sessionRepo == nil {
+		return
+	}
+	if err := s.sessionRepo.AddSession(ctx, session); err != nil {
+		s.logger.Warn("Failed to register session", LogFields{"sessionID": session.ID, "error": err.Error()})
+	}
+}
+
+// InitializeSession records the client info, capabilities and protocol version
+// from the params of an initialize request in session, labels it, and
+// registers it so that later requests of the client can find it by ID.
+func (s *ServerService) InitializeSession(ctx context.Context, session *domain.ClientSession, params interface{}) error {
+	p, _ := params.(map[string]interface{})
+	session.Initialize(p)
+	session.Touch()
+	session.AttachStore(s.currentSessionStore())
+	if labeler := s.currentSessionLabeler(); labeler != nil {
+		for key, value := range labeler(ctx, session) {
+			session.SetLabel(key, value)
+		}
+	}
+
+	if s.sessionRepo == nil {
+		return nil
+	}
+	if err := s.sessionRepo.AddSession(ctx, session); err != nil {
+		return err
+	}
+
+	s.logger.Info("Session initialized", LogFields{
+		"sessionID":       session.ID,
+		"client":          session.ClientInfo.Name,
+		"clientVersion":   session.ClientInfo.Version,
+		"protocolVersion": session.ProtocolVersion,
+	})
+	return nil
+}
+
+// GetSession returns the registered session with the given ID and records that
+// its client was seen. Sessions restored by a persistent repository get their
+// store here.
+func (s *ServerService) GetSession(ctx context.Context, id string) (*domain.ClientSession, error) {
+	if s.sessionRepo == nil {
+		return nil, domain.NewSessionNotFoundError(id)
+	}
+	session, err := s.sessionRepo.GetSession(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	session.Touch()
+	session.AttachStore(s.currentSessionStore())
+	return session, nil
+}
+
+// LookupSession returns the registered session with the given ID without
+// recording activity, e.g. for administration.
+func (s *ServerService) LookupSession(ctx context.Context, id string) (*domain.ClientSession, error) {
+	if s.sessionRepo == nil {
+		return nil, domain.NewSessionNotFoundError(id)
+	}
+	return s.sessionRepo.GetSession(ctx, id)
+}
+
+// ListSessions returns the registered sessions.
+func (s *ServerService) ListSessions(ctx context.Context) ([]*domain.ClientSession, error) {
+	if s.sessionRepo == nil {
+		return nil, nil
+	}
+	return s.sessionRepo.ListSessions(ctx)
+}
+
+// EndSession unregisters the session once its client disconnected or ended it,
+// and clears its store. Ending a session that was never registered is not an error.
+func (s *ServerService) EndSession(ctx context.Context, session *domain.ClientSession) {
+	if store := s.currentSessionStore(); store != nil && session.ID != "" {
+		if err := store.Clear(ctx, session.ID); err != nil {
+			s.logger.Warn("Failed to clear session store", LogFields{"sessionID": session.ID, "error": err.Error()})
+		}
+	}
+
+	if s.sessionRepo == nil {
+		return
+	}
+	if err := s.sessionRepo.DeleteSession(ctx, session.ID); err != nil {
+		s.logger.Debug("Session was already removed", LogFields{"sessionID": session.ID})
+	}
+}
+
+// DisconnectSession closes the registered session with the given ID, which
+// disconnects its client, and ends it. The stdio session cannot be disconnected.
+func (s *ServerService) DisconnectSession(ctx context.Context, id string) error {
+	session, err := s.LookupSession(ctx, id)
+	if err != nil {
+		return err
+	}
+	if session.Transport == domain.TransportStdio {
+		return fmt.Errorf("session %s uses the stdio transport and cannot be disconnected", id)
+	}
+
+	session.Close()
+	s.EndSession(ctx, session)
+	s.logger.Info("Session disconnected", LogFields{"sessionID": id})
+	return nil
+}
+
+// NotifySession sends a notification to the client of the registered session
+// with the given ID.
+func (s *ServerService) NotifySession(ctx context.Context, id string, notification *domain.Notification) error {
+	if _, err := s.LookupSession(ctx, id); err != nil {
+		return err
+	}
+	return s.SendNotification(ctx, id, notification)
+}
+
+// ExpireSessions disconnects the sessions that exceeded the session limits at
+// now, and returns how many it disconnected. Without limits, only idle
+// sessions of the streamable HTTP transport expire.
+func (s *ServerService) ExpireSessions(ctx context.Context, now time.Time) int {
+	limits := s.currentSessionLimits()
+	if limits == nil {
+		limits = &SessionLimits{}
+	}
+	sessions, err := s.ListSessions(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to list sessions", LogFields{"error": err.Error()})
+		return 0
+	}
+
+	expired := 0
+	for _, session := range sessions {
+		if !limits.Expired(session, now) {
+			continue
+		}
+		session.Close()
+		s.EndSession(ctx, session)
+		s.logger.Info("Session expired", LogFields{"sessionID": session.ID, "transport": session.Transport})
+		expired++
+	}
+	return expired
+}
+
+// SweepSessionStore removes the values of unknown sessions from a session store
+// that keeps them across restarts, i.e. of the sessions that ended while the
+// server was down. Other stores are left as they are.
+func (s *ServerService) SweepSessionStore(ctx context.Context) error {
+	sweeper, ok := s.currentSessionStore().(domain.SessionStoreSweeper)
+	if !ok {
+		return nil
+	}
+	sessions, err := s.ListSessions(ctx)
+	if err != nil {
+		return err
+	}
+	ids := make([]string, len(sessions))
+	for i, session := range sessions {
+		ids[i] = session.ID
+	}
+	return sweeper.Retain(ctx, ids)
+}
+
+// RunSessionReaper disconnects expired sessions periodically until ctx is done.
+// Session limits set while it runs take effect at the next check, at most
+// DefaultSessionCheckInterval later. It first sweeps the values of unknown
+// sessions from the session store, see SweepSessionStore.
+func (s *ServerService) RunSessionReaper(ctx context.Context) {
+	if err := s.SweepSessionStore(ctx); err != nil {
+		s.logger.Warn("Failed to sweep the session store", LogFields{"error": err.Error()})
+	}
+
+	for {
+		interval := DefaultSessionCheckInterval
+		if limits := s.currentSessionLimits(); limits != nil {
+			if check := limits.checkInterval(); check > 0 {
+				interval = check
+			}
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case now := <-timer.C:
+			s.ExpireSessions(ctx, now)
+		}
+	}
+}
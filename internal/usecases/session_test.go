@@ -0,0 +1,425 @@
+package usecases
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+func TestServerService_SessionLifecycle(t *testing.T) {
+	ctx := context.Background()
+	mockSessionRepo := NewMockSessionRepository()
+	service := createTestServerService(nil, nil, nil, mockSessionRepo, nil)
+
+	session := domain.NewClientSession("test-user-agent")
+
+	// Sessions are only registered once initialized
+	if _, err := service.GetSession(ctx, session.ID); err == nil {
+		t.Error("GetSession() should return error before the session is initialized")
+	}
+
+	params := map[string]interface{}{
+		"protocolVersion": "2025-03-26",
+		"clientInfo":      map[string]interface{}{"name": "test-client", "version": "1.0.0"},
+	}
+	if err := service.InitializeSession(ctx, session, params); err != nil {
+		t.Fatalf("InitializeSession() error = %v", err)
+	}
+	if !session.Initialized() || session.ClientInfo.Name != "test-client" {
+		t.Errorf("InitializeSession() did not initialize the session: %+v", session)
+	}
+
+	before := session.LastSeen()
+	time.Sleep(time.Millisecond)
+	got, err := service.GetSession(ctx, session.ID)
+	if err != nil {
+		t.Fatalf("GetSession() error = %v", err)
+	}
+	if got != session {
+		t.Errorf("GetSession() = %v, want %v", got, session)
+	}
+	if !session.LastSeen().After(before) {
+		t.Error("GetSession() should touch the session")
+	}
+
+	sessions, err := service.ListSessions(ctx)
+	if err != nil || len(sessions) != 1 {
+		t.Errorf("ListSessions() = %v, %v, want one session", sessions, err)
+	}
+
+	service.EndSession(ctx, session)
+	if _, err := service.GetSession(ctx, session.ID); err == nil {
+		t.Error("GetSession() should return error after EndSession()")
+	}
+
+	// Ending a session twice or one that was never initialized is a no-op
+	service.EndSession(ctx, session)
+	service.EndSession(ctx, domain.NewClientSession("test-user-agent"))
+}
+
+func TestServerService_SessionLifecycleWithoutRepository(t *testing.T) {
+	ctx := context.Background()
+	service := NewServerService(ServerConfig{Name: "Test Server", Version: "1.0.0"})
+
+	session := domain.NewClientSession("test-user-agent")
+	if err := service.InitializeSession(ctx, session, nil); err != nil {
+		t.Fatalf("InitializeSession() error = %v", err)
+	}
+	if !session.Initialized() {
+		t.Error("InitializeSession() should initialize the session")
+	}
+	if _, err := service.GetSession(ctx, session.ID); err == nil {
+		t.Error("GetSession() should return error without a session repository")
+	}
+	service.EndSession(ctx, session)
+}
+
+// MockSessionStoreBackend is a mock implementation of domain.SessionStoreBackend
+type MockSessionStoreBackend struct {
+	mu     sync.Mutex
+	values map[string]map[string]interface{}
+}
+
+func NewMockSessionStoreBackend() *MockSessionStoreBackend {
+	return &MockSessionStoreBackend{values: make(map[string]map[string]interface{})}
+}
+
+func (m *MockSessionStoreBackend) Get(ctx context.Context, sessionID, key string) (interface{}, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.values[sessionID][key]
+	return value, ok, nil
+}
+
+func (m *MockSessionStoreBackend) Set(ctx context.Context, sessionID, key string, value interface{}, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.values[sessionID] == nil {
+		m.values[sessionID] = make(map[string]interface{})
+	}
+	m.values[sessionID][key] = value
+	return nil
+}
+
+func (m *MockSessionStoreBackend) Delete(ctx context.Context, sessionID, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values[sessionID], key)
+	return nil
+}
+
+func (m *MockSessionStoreBackend) Clear(ctx context.Context, sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, sessionID)
+	return nil
+}
+
+func (m *MockSessionStoreBackend) Retain(ctx context.Context, sessionIDs []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	retained := make(map[string]map[string]interface{})
+	for _, id := range sessionIDs {
+		if values, ok := m.values[id]; ok {
+			retained[id] = values
+		}
+	}
+	m.values = retained
+	return nil
+}
+
+func TestServerService_SweepSessionStore(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMockSessionStoreBackend()
+	service := NewServerService(ServerConfig{
+		Name:         "Test Server",
+		Version:      "1.0.0",
+		SessionRepo:  NewMockSessionRepository(),
+		SessionStore: backend,
+	})
+
+	known := domain.NewClientSession("test-user-agent")
+	if err := service.InitializeSession(ctx, known, nil); err != nil {
+		t.Fatalf("InitializeSession() error = %v", err)
+	}
+	_ = backend.Set(ctx, known.ID, "cursor", 1, 0)
+	// Values of a session that ended while the server was down
+	_ = backend.Set(ctx, "orphan", "cursor", 2, 0)
+
+	if err := service.SweepSessionStore(ctx); err != nil {
+		t.Fatalf("SweepSessionStore() error = %v", err)
+	}
+	if _, ok, _ := backend.Get(ctx, known.ID, "cursor"); !ok {
+		t.Error("SweepSessionStore() should keep the values of known sessions")
+	}
+	if _, ok, _ := backend.Get(ctx, "orphan", "cursor"); ok {
+		t.Error("SweepSessionStore() should remove the values of unknown sessions")
+	}
+}
+
+func TestServerService_SessionStore(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMockSessionStoreBackend()
+	service := NewServerService(ServerConfig{
+		Name:         "Test Server",
+		Version:      "1.0.0",
+		SessionRepo:  NewMockSessionRepository(),
+		SessionStore: backend,
+	})
+
+	// Tool handlers reach the store through the session they are passed
+	session := domain.NewClientSession("test-user-agent")
+	handler := func(ctx context.Context, params map[string]interface{}, session *domain.ClientSession) (interface{}, error) {
+		return nil, session.Store().Set(ctx, "cursor", params["cursor"])
+	}
+	if _, err := service.InvokeToolHandler(ctx, "paginate", handler, map[string]interface{}{"cursor": 7}, session); err != nil {
+		t.Fatalf("InvokeToolHandler() error = %v", err)
+	}
+	if value, ok, _ := session.Store().Get(ctx, "cursor"); !ok || value != 7 {
+		t.Errorf("Get() = %v, %v, want 7, true", value, ok)
+	}
+
+	// The store is cleared when the session ends, even if it was never initialized
+	service.EndSession(ctx, session)
+	if _, ok, _ := backend.Get(ctx, session.ID, "cursor"); ok {
+		t.Error("EndSession() should clear the session store")
+	}
+
+	// Initialized sessions get a store right away
+	initialized := domain.NewClientSession("test-user-agent")
+	if err := service.InitializeSession(ctx, initialized, nil); err != nil {
+		t.Fatalf("InitializeSession() error = %v", err)
+	}
+	if initialized.Store() == nil {
+		t.Error("InitializeSession() should attach the session store")
+	}
+}
+
+func TestSessionLimits_Expired(t *testing.T) {
+	now := time.Now()
+	session := domain.NewClientSession("test-user-agent")
+	session.Transport = domain.TransportSSE
+	session.CreatedAt = now.Add(-2 * time.Hour)
+	session.State = domain.NewSessionState(now.Add(-10 * time.Minute))
+
+	tests := []struct {
+		name   string
+		limits SessionLimits
+		want   bool
+	}{
+		{"no limits", SessionLimits{}, false},
+		{"active", SessionLimits{IdleTimeout: time.Hour}, false},
+		{"idle", SessionLimits{IdleTimeout: 5 * time.Minute}, true},
+		{"young", SessionLimits{MaxLifetime: 3 * time.Hour}, false},
+		{"old", SessionLimits{MaxLifetime: time.Hour}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.limits.Expired(session, now); got != tt.want {
+				t.Errorf("Expired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Streamable HTTP sessions expire when idle even without limits
+	httpSession := domain.NewClientSession("http")
+	httpSession.Transport = domain.TransportHTTP
+	httpSession.State = domain.NewSessionState(now.Add(-DefaultHTTPSessionIdleTimeout))
+	if !(SessionLimits{}).Expired(httpSession, now) {
+		t.Error("Expired() should expire idle HTTP sessions by default")
+	}
+	if (SessionLimits{IdleTimeout: 2 * DefaultHTTPSessionIdleTimeout}).Expired(httpSession, now) {
+		t.Error("Expired() should use the idle timeout of the limits for HTTP sessions")
+	}
+
+	stdio := domain.NewClientSession("stdio")
+	stdio.Transport = domain.TransportStdio
+	stdio.CreatedAt = session.CreatedAt
+	if (SessionLimits{IdleTimeout: time.Nanosecond, MaxLifetime: time.Nanosecond}).Expired(stdio, now) {
+		t.Error("Expired() should never expire the stdio session")
+	}
+}
+
+func TestSessionLimits_CheckInterval(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits SessionLimits
+		want   time.Duration
+	}{
+		{"no limits", SessionLimits{CheckInterval: time.Second}, 0},
+		{"explicit", SessionLimits{IdleTimeout: time.Hour, CheckInterval: time.Second}, time.Second},
+		{"long limits", SessionLimits{IdleTimeout: time.Hour, MaxLifetime: 24 * time.Hour}, DefaultSessionCheckInterval},
+		{"short limit", SessionLimits{IdleTimeout: time.Hour, MaxLifetime: 10 * time.Second}, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.limits.checkInterval(); got != tt.want {
+				t.Errorf("checkInterval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerService_ExpireSessions(t *testing.T) {
+	ctx := context.Background()
+	service := NewServerService(ServerConfig{
+		Name:          "Test Server",
+		Version:       "1.0.0",
+		SessionRepo:   NewMockSessionRepository(),
+		SessionLimits: &SessionLimits{IdleTimeout: time.Minute},
+	})
+
+	// Only the session that was not seen for a minute expires
+	idle := domain.NewClientSession("test-user-agent")
+	idle.Transport = domain.TransportSSE
+	idle.State = domain.NewSessionState(time.Now().Add(-time.Hour))
+	closed := false
+	idle.OnClose(func() { closed = true })
+	active := domain.NewClientSession("test-user-agent")
+	active.Transport = domain.TransportHTTP
+	service.StartSession(ctx, idle)
+	service.StartSession(ctx, active)
+
+	if got := service.ExpireSessions(ctx, time.Now()); got != 1 {
+		t.Errorf("ExpireSessions() = %d, want 1", got)
+	}
+	if !closed || !idle.Closed() {
+		t.Error("ExpireSessions() should close the idle session")
+	}
+	if _, err := service.LookupSession(ctx, idle.ID); err == nil {
+		t.Error("ExpireSessions() should end the idle session")
+	}
+	if _, err := service.LookupSession(ctx, active.ID); err != nil {
+		t.Errorf("LookupSession() error = %v for the active session", err)
+	}
+}
+
+func TestServerService_ExpireAbandonedHTTPSessions(t *testing.T) {
+	ctx := context.Background()
+	service := NewServerService(ServerConfig{
+		Name:        "Test Server",
+		Version:     "1.0.0",
+		SessionRepo: NewMockSessionRepository(),
+	})
+
+	// Without limits, HTTP sessions whose client stopped sending requests are
+	// evicted, while SSE sessions live as long as their stream
+	abandoned := domain.NewClientSession("test-user-agent")
+	abandoned.Transport = domain.TransportHTTP
+	active := domain.NewClientSession("test-user-agent")
+	active.Transport = domain.TransportHTTP
+	stream := domain.NewClientSession("test-user-agent")
+	stream.Transport = domain.TransportSSE
+	for _, session := range []*domain.ClientSession{abandoned, active, stream} {
+		service.StartSession(ctx, session)
+	}
+
+	now := time.Now().Add(DefaultHTTPSessionIdleTimeout)
+	active.State = domain.NewSessionState(now)
+	if got := service.ExpireSessions(ctx, now); got != 1 {
+		t.Errorf("ExpireSessions() = %d, want 1", got)
+	}
+	if _, err := service.LookupSession(ctx, abandoned.ID); err == nil {
+		t.Error("ExpireSessions() should evict the abandoned HTTP session")
+	}
+	for _, session := range []*domain.ClientSession{active, stream} {
+		if _, err := service.LookupSession(ctx, session.ID); err != nil {
+			t.Errorf("LookupSession() error = %v for a %s session", err, session.Transport)
+		}
+	}
+}
+
+func TestServerService_DisconnectSession(t *testing.T) {
+	ctx := context.Background()
+	mockNotificationSender := NewMockNotificationSender()
+	service := createTestServerService(nil, nil, nil, nil, mockNotificationSender)
+
+	session := domain.NewClientSession("test-user-agent")
+	session.Transport = domain.TransportSSE
+	service.StartSession(ctx, session)
+
+	// Notifications reach registered sessions only
+	notification := &domain.Notification{Method: "notifications/message", Params: map[string]interface{}{"level": "info"}}
+	if err := service.NotifySession(ctx, session.ID, notification); err != nil {
+		t.Fatalf("NotifySession() error = %v", err)
+	}
+	if got := mockNotificationSender.GetSentNotifications(session.ID); len(got) != 1 {
+		t.Errorf("NotifySession() sent %d notifications, want 1", len(got))
+	}
+	if err := service.NotifySession(ctx, "unknown", notification); err == nil {
+		t.Error("NotifySession() should return error for an unknown session")
+	}
+
+	if err := service.DisconnectSession(ctx, session.ID); err != nil {
+		t.Fatalf("DisconnectSession() error = %v", err)
+	}
+	if !session.Closed() {
+		t.Error("DisconnectSession() should close the session")
+	}
+	if err := service.DisconnectSession(ctx, session.ID); err == nil {
+		t.Error("DisconnectSession() should return error once the session ended")
+	}
+
+	// The stdio session lives as long as the process
+	stdio := domain.NewClientSession("stdio")
+	stdio.Transport = domain.TransportStdio
+	service.StartSession(ctx, stdio)
+	if err := service.DisconnectSession(ctx, stdio.ID); err == nil {
+		t.Error("DisconnectSession() should refuse to disconnect the stdio session")
+	}
+	if stdio.Closed() {
+		t.Error("DisconnectSession() should not close the stdio session")
+	}
+}
+
+func TestServerService_NotifySessions(t *testing.T) {
+	ctx := context.Background()
+	mockNotificationSender := NewMockNotificationSender()
+	service := createTestServerService(nil, nil, nil, nil, mockNotificationSender)
+	service.sessionLabeler = func(ctx context.Context, session *domain.ClientSession) map[string]string {
+		return map[string]string{"tenant": session.Principal.Claims["tenant"].(string)}
+	}
+
+	sessions := make(map[string]*domain.ClientSession)
+	for _, tenant := range []string{"a", "b"} {
+		session := domain.NewClientSession("test-user-agent")
+		session.Principal = &domain.Principal{ID: "user-" + tenant, Claims: map[string]interface{}{"tenant": tenant}}
+		if err := service.InitializeSession(ctx, session, nil); err != nil {
+			t.Fatalf("InitializeSession() error = %v", err)
+		}
+		sessions[tenant] = session
+	}
+	if tenant, _ := sessions["a"].Label("tenant"); tenant != "a" {
+		t.Errorf("InitializeSession() labeled the session with tenant %q, want %q", tenant, "a")
+	}
+
+	// The mock sender cannot target sessions, so each selected session is notified in turn
+	notification := &domain.Notification{Method: "notifications/message"}
+	if err := service.NotifySessions(ctx, domain.LabelFilter("tenant", "a"), notification); err != nil {
+		t.Fatalf("NotifySessions() error = %v", err)
+	}
+	if got := mockNotificationSender.GetSentNotifications(sessions["a"].ID); len(got) != 1 {
+		t.Errorf("NotifySessions() sent %d notifications to tenant a, want 1", len(got))
+	}
+	if got := mockNotificationSender.GetSentNotifications(sessions["b"].ID); len(got) != 0 {
+		t.Errorf("NotifySessions() sent %d notifications to tenant b, want 0", len(got))
+	}
+
+	// List changes of a tenant do not reach the other tenants
+	tenantCtx := domain.ContextWithNotificationFilter(ctx, domain.LabelFilter("tenant", "b"))
+	if err := service.AddTool(tenantCtx, &domain.Tool{Name: "tenant-tool"}); err != nil {
+		t.Fatalf("AddTool() error = %v", err)
+	}
+	if got := mockNotificationSender.GetSentNotifications(sessions["b"].ID); len(got) != 1 || got[0].Method != "tools/list/changed" {
+		t.Errorf("AddTool() sent %v to tenant b, want a tools/list/changed notification", got)
+	}
+	if got := mockNotificationSender.GetSentNotifications(sessions["a"].ID); len(got) != 1 {
+		t.Errorf("AddTool() sent %d notifications to tenant a, want none besides the earlier one", len(got)-1)
+	}
+	if got := mockNotificationSender.GetBroadcastNotifications(); len(got) != 0 {
+		t.Errorf("AddTool() broadcast %d notifications, want 0", len(got))
+	}
+}
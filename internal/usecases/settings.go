@@ -0,0 +1,128 @@
+package usecases
+
+import (
+	"github.com/FreePeak/cortex/internal/domain"
+)
+
+// The settings below may be changed while the service is serving. Changes
+// apply to the requests and tool calls that start afterwards.
+
+// SetToolPolicy restricts which tools each principal may list and call, or
+// lifts the restriction if policy is nil.
+func (s *ServerService) SetToolPolicy(policy ToolPolicy) {
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+	s.toolPolicy = policy
+}
+
+// SetRateLimits replaces the rate limits applied to tool calls, or removes
+// them if config is nil. The quotas already used are forgotten.
+func (s *ServerService) SetRateLimits(config *RateLimitConfig) {
+	var limiter *RateLimiter
+	if config != nil {
+		limiter = NewRateLimiter(*config)
+	}
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+	s.rateLimiter = limiter
+}
+
+// SetConcurrencyLimits replaces the concurrency limits applied to tool calls,
+// or removes them if config is nil. Calls already running keep their slots in
+// the old limits.
+func (s *ServerService) SetConcurrencyLimits(config *ConcurrencyConfig) {
+	var limits *bulkheads
+	if config != nil {
+		limits = &bulkheads{config: *config, tools: make(map[string]*Bulkhead)}
+	}
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+	s.bulkheads = limits
+}
+
+// SetPageSize sets the number of entries per page of list requests, 0 for all.
+func (s *ServerService) SetPageSize(size int) {
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+	s.pageSize = size
+}
+
+// SetSessionLabeler sets the function that labels sessions when they are initialized.
+func (s *ServerService) SetSessionLabeler(labeler SessionLabeler) {
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+	s.sessionLabeler = labeler
+}
+
+// SetTracer sets the tracer that records tool calls, or disables
+// tracing if tracer is nil.
+func (s *ServerService) SetTracer(tracer Tracer) {
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+	s.tracer = tracer
+}
+
+// SetSessionStore sets the backend of the key/value stores of sessions.
+// Sessions that already have a store keep it.
+func (s *ServerService) SetSessionStore(store domain.SessionStoreBackend) {
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+	s.sessionStore = store
+}
+
+// SetSessionLimits sets the idle timeout and max lifetime of sessions, or
+// removes them if limits is nil.
+func (s *ServerService) SetSessionLimits(limits *SessionLimits) {
+	s.settingsMu.Lock()
+	defer s.settingsMu.Unlock()
+	s.sessionLimits = limits
+}
+
+// currentToolPolicy returns the tool policy, or nil.
+func (s *ServerService) currentToolPolicy() ToolPolicy {
+	s.settingsMu.RLock()
+	defer s.settingsMu.RUnlock()
+	return s.toolPolicy
+}
+
+// currentRateLimiter returns the rate limiter of tool calls, or nil.
+func (s *ServerService) currentRateLimiter() *RateLimiter {
+	s.settingsMu.RLock()
+	defer s.settingsMu.RUnlock()
+	return s.rateLimiter
+}
+
+// currentBulkheads returns the concurrency limits of tool calls, or nil.
+func (s *ServerService) currentBulkheads() *bulkheads {
+	s.settingsMu.RLock()
+	defer s.settingsMu.RUnlock()
+	return s.bulkheads
+}
+
+// currentPageSize returns the number of entries per page of list requests.
+func (s *ServerService) currentPageSize() int {
+	s.settingsMu.RLock()
+	defer s.settingsMu.RUnlock()
+	return s.pageSize
+}
+
+// currentSessionLabeler returns the session labeler, or nil.
+func (s *ServerService) currentSessionLabeler() SessionLabeler {
+	s.settingsMu.RLock()
+	defer s.settingsMu.RUnlock()
+	return s.sessionLabeler
+}
+
+// currentSessionStore returns the backend of the stores of sessions, or nil.
+func (s *ServerService) currentSessionStore() domain.SessionStoreBackend {
+	s.settingsMu.RLock()
+	defer s.settingsMu.RUnlock()
+	return s.sessionStore
+}
+
+// currentSessionLimits returns the session limits, or nil.
+func (s *ServerService) currentSessionLimits() *SessionLimits {
+	s.settingsMu.RLock()
+	defer s.settingsMu.RUnlock()
+	return s.sessionLimits
+}
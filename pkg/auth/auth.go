@@ -0,0 +1,218 @@
+// Package auth provides authenticators for the HTTP and SSE endpoints of the MCP server.
+package auth
+
+import (
+	"net/http"
+
+	"github.com/FreePeak/cortex/internal/domain"
+	internalAuth "github.com/FreePeak/cortex/internal/infrastructure/auth"
+	"github.com/FreePeak/cortex/pkg/types"
+)
+
+// Common authentication errors
+var (
+	// ErrMissingCredentials is returned when a request carries no credentials
+	ErrMissingCredentials = internalAuth.ErrMissingCredentials
+
+	// ErrInvalidCredentials is returned when the presented credentials are not valid
+	ErrInvalidCredentials = internalAuth.ErrInvalidCredentials
+)
+
+// Authenticator verifies the credentials carried by an HTTP request.
+type Authenticator interface {
+	// Authenticate returns the principal for the request, or an error if
+	// the request could not be authenticated.
+	Authenticate(r *http.Request) (*types.Principal, error)
+}
+
+// AuthenticatorFunc is a function type that implements Authenticator
+type AuthenticatorFunc func(r *http.Request) (*types.Principal, error)
+
+// Authenticate calls the authenticator function
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (*types.Principal, error) {
+	return f(r)
+}
+
+// Challenger is implemented by authenticators that want to customize the
+// WWW-Authenticate header sent along with a 401 response.
+type Challenger interface {
+	// Challenge returns the WWW-Authenticate header value for the failed request.
+	Challenge(r *http.Request, err error) string
+}
+
+// Claims holds the decoded payload of a JSON Web Token.
+type Claims = internalAuth.Claims
+
+// OAuthConfig contains configuration for an OAuth 2.1 protected resource.
+type OAuthConfig = internalAuth.OAuthConfig
+
+// OAuthResourceServer validates OAuth 2.1 JWT access tokens and serves the
+// protected resource metadata document.
+type OAuthResourceServer = internalAuth.OAuthResourceServer
+
+// KeySet holds the public keys used to verify access token signatures.
+type KeySet = internalAuth.KeySet
+
+// LocalIssuer is a minimal in-process token issuer for tests and local development.
+type LocalIssuer = internalAuth.LocalIssuer
+
+// ErrInsufficientScope is returned when a valid token lacks a required scope.
+var ErrInsufficientScope = internalAuth.ErrInsufficientScope
+
+// NewOAuthResourceServer creates a new OAuthResourceServer with the given configuration.
+func NewOAuthResourceServer(config OAuthConfig) (*OAuthResourceServer, error) {
+	return internalAuth.NewOAuthResourceServer(config)
+}
+
+// LoadJWKSFile reads and parses a JSON Web Key Set from a file.
+func LoadJWKSFile(path string) (*KeySet, error) {
+	return internalAuth.LoadJWKSFile(path)
+}
+
+// ParseJWKS parses a JSON Web Key Set document.
+func ParseJWKS(data []byte) (*KeySet, error) {
+	return internalAuth.ParseJWKS(data)
+}
+
+// NewLocalIssuer creates a new LocalIssuer with a freshly generated RSA key.
+func NewLocalIssuer(issuer string) (*LocalIssuer, error) {
+	return internalAuth.NewLocalIssuer(issuer)
+}
+
+// NewAPIKeyAuthenticator creates an authenticator that maps static API keys to principals.
+// Keys are read from the X-API-Key header or from an "Authorization: Bearer" header.
+func NewAPIKeyAuthenticator(keys map[string]*types.Principal) Authenticator {
+	internalKeys := make(map[string]*domain.Principal, len(keys))
+	for key, principal := range keys {
+		internalKeys[key] = ToInternalPrincipal(principal)
+	}
+	return &authenticatorAdapter{internalAuth.NewAPIKeyAuthenticator(internalKeys)}
+}
+
+// MinHMACSecretLength is the minimum length of the secret of NewHMACTokenAuthenticator.
+const MinHMACSecretLength = internalAuth.MinHMACSecretLength
+
+// NewHMACTokenAuthenticator creates an authenticator for bearer tokens that are
+// JSON Web Tokens signed with the given shared HMAC secret, which must be at
+// least MinHMACSecretLength bytes long. Tokens must carry "sub" and "exp"
+// claims. If issuer or audience are not empty, tokens must carry matching "iss"
+// and "aud" claims.
+func NewHMACTokenAuthenticator(secret []byte, issuer, audience string) (Authenticator, error) {
+	authenticator, err := internalAuth.NewHMACTokenAuthenticator(
+		secret,
+		internalAuth.WithHMACIssuer(issuer),
+		internalAuth.WithHMACAudience(audience),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &authenticatorAdapter{authenticator}, nil
+}
+
+// SignHMACToken issues an HS256 signed token carrying the given claims.
+func SignHMACToken(secret []byte, claims Claims) (string, error) {
+	return internalAuth.SignHMACToken(secret, claims)
+}
+
+// NewClientCertAuthenticator creates an authenticator for clients that present
+// a TLS client certificate verified by the HTTP transport, see
+// server.MCPServer.SetTLS. If principal is nil, the principal ID is the most
+// specific name of the certificate: its first URI, its common name or its first
+// DNS name.
+func NewClientCertAuthenticator(principal func(identity *types.ClientIdentity) (*types.Principal, error)) Authenticator {
+	var opts []internalAuth.ClientCertOption
+	if principal != nil {
+		opts = append(opts, internalAuth.WithClientCertPrincipal(func(identity *domain.ClientIdentity) (*domain.Principal, error) {
+			p, err := principal(identity)
+			if err != nil {
+				return nil, err
+			}
+			return ToInternalPrincipal(p), nil
+		}))
+	}
+	return &authenticatorAdapter{internalAuth.NewClientCertAuthenticator(opts...)}
+}
+
+// NewChainAuthenticator creates an authenticator that accepts a request if any
+// of the given authenticators accepts it. Authenticators are tried in order.
+func NewChainAuthenticator(authenticators ...Authenticator) Authenticator {
+	internalAuthenticators := make([]internalAuth.Authenticator, len(authenticators))
+	for i, a := range authenticators {
+		internalAuthenticators[i] = ToInternal(a)
+	}
+	return &authenticatorAdapter{internalAuth.NewChainAuthenticator(internalAuthenticators...)}
+}
+
+// ToInternal adapts a public Authenticator for use by the internal transports.
+func ToInternal(authenticator Authenticator) internalAuth.Authenticator {
+	if adapter, ok := authenticator.(*authenticatorAdapter); ok {
+		return adapter.internal
+	}
+	return &internalAdapter{authenticator}
+}
+
+// ToPublicPrincipal converts an internal principal to its public representation.
+func ToPublicPrincipal(principal *domain.Principal) *types.Principal {
+	if principal == nil {
+		return nil
+	}
+	return &types.Principal{
+		ID:     principal.ID,
+		Roles:  principal.Roles,
+		Scopes: principal.Scopes,
+		Claims: principal.Claims,
+	}
+}
+
+// ToInternalPrincipal converts a public principal to its internal representation.
+func ToInternalPrincipal(principal *types.Principal) *domain.Principal {
+	if principal == nil {
+		return nil
+	}
+	return &domain.Principal{
+		ID:     principal.ID,
+		Roles:  principal.Roles,
+		Scopes: principal.Scopes,
+		Claims: principal.Claims,
+	}
+}
+
+// authenticatorAdapter exposes an internal authenticator through the public API.
+type authenticatorAdapter struct {
+	internal internalAuth.Authenticator
+}
+
+func (a *authenticatorAdapter) Authenticate(r *http.Request) (*types.Principal, error) {
+	principal, err := a.internal.Authenticate(r)
+	if err != nil {
+		return nil, err
+	}
+	return ToPublicPrincipal(principal), nil
+}
+
+func (a *authenticatorAdapter) Challenge(r *http.Request, err error) string {
+	if challenger, ok := a.internal.(internalAuth.Challenger); ok {
+		return challenger.Challenge(r, err)
+	}
+	return `Bearer realm="mcp"`
+}
+
+// internalAdapter exposes a public authenticator to the internal transports.
+type internalAdapter struct {
+	public Authenticator
+}
+
+func (a *internalAdapter) Authenticate(r *http.Request) (*domain.Principal, error) {
+	principal, err := a.public.Authenticate(r)
+	if err != nil {
+		return nil, err
+	}
+	return ToInternalPrincipal(principal), nil
+}
+
+func (a *internalAdapter) Challenge(r *http.Request, err error) string {
+	if challenger, ok := a.public.(Challenger); ok {
+		return challenger.Challenge(r, err)
+	}
+	return `Bearer realm="mcp"`
+}
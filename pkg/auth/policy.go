@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"github.com/FreePeak/cortex/internal/domain"
+	"github.com/FreePeak/cortex/internal/usecases"
+	"github.com/FreePeak/cortex/pkg/types"
+)
+
+// ToolPolicy decides which tools a principal may list and call.
+type ToolPolicy interface {
+	// AllowTool reports whether the principal may use the named tool.
+	// The principal is nil for unauthenticated callers.
+	AllowTool(principal *types.Principal, toolName string) bool
+}
+
+// ToolPolicyFunc is a function type that implements ToolPolicy
+type ToolPolicyFunc func(principal *types.Principal, toolName string) bool
+
+// AllowTool calls the policy function
+func (f ToolPolicyFunc) AllowTool(principal *types.Principal, toolName string) bool {
+	return f(principal, toolName)
+}
+
+// PolicyEffect is the outcome of a matching policy rule.
+type PolicyEffect = usecases.PolicyEffect
+
+// Available policy effects
+const (
+	PolicyAllow = usecases.PolicyAllow
+	PolicyDeny  = usecases.PolicyDeny
+)
+
+// PolicyRule grants or denies access to a set of tools for a set of principals.
+// Tools holds glob patterns such as "db.*"; Principals, Roles and Scopes select
+// who the rule applies to. A matching deny rule always wins, and effects other
+// than PolicyAllow deny access.
+type PolicyRule = usecases.PolicyRule
+
+// NewRulePolicy creates a policy evaluated from allow and deny rules. If no rule
+// matches a request, the default effect applies.
+//
+// For example, to keep read-only users away from destructive tools:
+//
+//	policy := auth.NewRulePolicy(auth.PolicyAllow,
+//		auth.PolicyRule{Effect: auth.PolicyDeny, Tools: []string{"db.delete"}, Roles: []string{"read-only"}},
+//	)
+func NewRulePolicy(defaultEffect PolicyEffect, rules ...PolicyRule) ToolPolicy {
+	return &policyAdapter{usecases.NewRulePolicy(defaultEffect, rules...)}
+}
+
+// ToInternalPolicy adapts a public ToolPolicy for use by the server service.
+func ToInternalPolicy(policy ToolPolicy) usecases.ToolPolicy {
+	if policy == nil {
+		return nil
+	}
+	if adapter, ok := policy.(*policyAdapter); ok {
+		return adapter.internal
+	}
+	return usecases.ToolPolicyFunc(func(principal *domain.Principal, toolName string) bool {
+		return policy.AllowTool(ToPublicPrincipal(principal), toolName)
+	})
+}
+
+// policyAdapter exposes an internal tool policy through the public API.
+type policyAdapter struct {
+	internal usecases.ToolPolicy
+}
+
+func (a *policyAdapter) AllowTool(principal *types.Principal, toolName string) bool {
+	return a.internal.AllowTool(ToInternalPrincipal(principal), toolName)
+}
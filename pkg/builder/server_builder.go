@@ -7,6 +7,8 @@ import (
 	internalBuilder "github.com/FreePeak/cortex/internal/builder"
 	internalDomain "github.com/FreePeak/cortex/internal/domain"
 	"github.com/FreePeak/cortex/internal/interfaces/stdio"
+	"github.com/FreePeak/cortex/pkg/auth"
+	"github.com/FreePeak/cortex/pkg/tracing"
 	"github.com/FreePeak/cortex/pkg/types"
 )
 
@@ -49,6 +51,10 @@ func (b *ServerBuilder) WithAddress(address string) *ServerBuilder {
 // WithResourceRepository sets the resource repository.
 func (b *ServerBuilder) WithResourceRepository(repo types.ResourceRepository) *ServerBuilder {
 	// Type adaptation from pkg to internal
+	if paged, ok := repo.(types.PagedResourceRepository); ok {
+		b.internal.WithResourceRepository(&pagedResourceRepositoryAdapter{resourceRepositoryAdapter{repo}, paged})
+		return b
+	}
 	b.internal.WithResourceRepository(&resourceRepositoryAdapter{repo})
 	return b
 }
@@ -56,6 +62,10 @@ func (b *ServerBuilder) WithResourceRepository(repo types.ResourceRepository) *S
 // WithToolRepository sets the tool repository.
 func (b *ServerBuilder) WithToolRepository(repo types.ToolRepository) *ServerBuilder {
 	// Type adaptation from pkg to internal
+	if paged, ok := repo.(types.PagedToolRepository); ok {
+		b.internal.WithToolRepository(&pagedToolRepositoryAdapter{toolRepositoryAdapter{repo}, paged})
+		return b
+	}
 	b.internal.WithToolRepository(&toolRepositoryAdapter{repo})
 	return b
 }
@@ -63,6 +73,10 @@ func (b *ServerBuilder) WithToolRepository(repo types.ToolRepository) *ServerBui
 // WithPromptRepository sets the prompt repository.
 func (b *ServerBuilder) WithPromptRepository(repo types.PromptRepository) *ServerBuilder {
 	// Type adaptation from pkg to internal
+	if paged, ok := repo.(types.PagedPromptRepository); ok {
+		b.internal.WithPromptRepository(&pagedPromptRepositoryAdapter{promptRepositoryAdapter{repo}, paged})
+		return b
+	}
 	b.internal.WithPromptRepository(&promptRepositoryAdapter{repo})
 	return b
 }
@@ -74,6 +88,13 @@ func (b *ServerBuilder) WithSessionRepository(repo types.SessionRepository) *Ser
 	return b
 }
 
+// WithSessionStore sets the backend of the key/value stores that tool handlers
+// reach through ClientSession.Store. It defaults to an in-memory store.
+func (b *ServerBuilder) WithSessionStore(store types.SessionStoreBackend) *ServerBuilder {
+	b.internal.WithSessionStore(store)
+	return b
+}
+
 // WithNotificationSender sets the notification sender.
 func (b *ServerBuilder) WithNotificationSender(sender types.NotificationSender) *ServerBuilder {
 	// Type adaptation from pkg to internal
@@ -81,6 +102,30 @@ func (b *ServerBuilder) WithNotificationSender(sender types.NotificationSender)
 	return b
 }
 
+// WithAuthenticator sets the authenticator for the HTTP and SSE endpoints.
+func (b *ServerBuilder) WithAuthenticator(authenticator auth.Authenticator) *ServerBuilder {
+	b.internal.WithAuthenticator(auth.ToInternal(authenticator))
+	return b
+}
+
+// WithToolPolicy restricts which tools each principal may list and call.
+func (b *ServerBuilder) WithToolPolicy(policy auth.ToolPolicy) *ServerBuilder {
+	b.internal.WithToolPolicy(auth.ToInternalPolicy(policy))
+	return b
+}
+
+// WithPageSize sets the number of entries per page of list requests, 0 for all.
+func (b *ServerBuilder) WithPageSize(size int) *ServerBuilder {
+	b.internal.WithPageSize(size)
+	return b
+}
+
+// WithTracer records a span for every request and tool call.
+func (b *ServerBuilder) WithTracer(tracer *tracing.Tracer) *ServerBuilder {
+	b.internal.WithTracer(tracer)
+	return b
+}
+
 // AddTool adds a tool to the server's tool repository.
 func (b *ServerBuilder) AddTool(ctx context.Context, tool *types.Tool) *ServerBuilder {
 	// Convert pkg type to internal type
@@ -173,7 +218,11 @@ func (a *resourceRepositoryAdapter) ListResources(ctx context.Context) ([]*inter
 	if err != nil {
 		return nil, err
 	}
+	return toInternalResources(resources), nil
+}
 
+// toInternalResources converts pkg resources to internal resources.
+func toInternalResources(resources []*types.Resource) []*internalDomain.Resource {
 	internalResources := make([]*internalDomain.Resource, len(resources))
 	for i, resource := range resources {
 		internalResources[i] = &internalDomain.Resource{
@@ -184,7 +233,7 @@ func (a *resourceRepositoryAdapter) ListResources(ctx context.Context) ([]*inter
 		}
 	}
 
-	return internalResources, nil
+	return internalResources
 }
 
 func (a *resourceRepositoryAdapter) AddResource(ctx context.Context, resource *internalDomain.Resource) error {
@@ -200,6 +249,20 @@ func (a *resourceRepositoryAdapter) DeleteResource(ctx context.Context, uri stri
 	return a.repo.DeleteResource(ctx, uri)
 }
 
+// pagedResourceRepositoryAdapter adapts a pkg PagedResourceRepository to an internal PagedResourceRepository.
+type pagedResourceRepositoryAdapter struct {
+	resourceRepositoryAdapter
+	paged types.PagedResourceRepository
+}
+
+func (a *pagedResourceRepositoryAdapter) ListResourcesPage(ctx context.Context, after string, limit int) ([]*internalDomain.Resource, bool, error) {
+	resources, more, err := a.paged.ListResourcesPage(ctx, after, limit)
+	if err != nil {
+		return nil, false, err
+	}
+	return toInternalResources(resources), more, nil
+}
+
 // toolRepositoryAdapter adapts a pkg ToolRepository to an internal ToolRepository.
 type toolRepositoryAdapter struct {
 	repo types.ToolRepository
@@ -235,7 +298,11 @@ func (a *toolRepositoryAdapter) ListTools(ctx context.Context) ([]*internalDomai
 	if err != nil {
 		return nil, err
 	}
+	return toInternalTools(tools), nil
+}
 
+// toInternalTools converts pkg tools to internal tools.
+func toInternalTools(tools []*types.Tool) []*internalDomain.Tool {
 	internalTools := make([]*internalDomain.Tool, len(tools))
 	for i, tool := range tools {
 		internalTools[i] = &internalDomain.Tool{
@@ -255,7 +322,7 @@ func (a *toolRepositoryAdapter) ListTools(ctx context.Context) ([]*internalDomai
 		}
 	}
 
-	return internalTools, nil
+	return internalTools
 }
 
 func (a *toolRepositoryAdapter) AddTool(ctx context.Context, tool *internalDomain.Tool) error {
@@ -282,6 +349,20 @@ func (a *toolRepositoryAdapter) DeleteTool(ctx context.Context, name string) err
 	return a.repo.DeleteTool(ctx, name)
 }
 
+// pagedToolRepositoryAdapter adapts a pkg PagedToolRepository to an internal PagedToolRepository.
+type pagedToolRepositoryAdapter struct {
+	toolRepositoryAdapter
+	paged types.PagedToolRepository
+}
+
+func (a *pagedToolRepositoryAdapter) ListToolsPage(ctx context.Context, after string, limit int) ([]*internalDomain.Tool, bool, error) {
+	tools, more, err := a.paged.ListToolsPage(ctx, after, limit)
+	if err != nil {
+		return nil, false, err
+	}
+	return toInternalTools(tools), more, nil
+}
+
 // promptRepositoryAdapter adapts a pkg PromptRepository to an internal PromptRepository.
 type promptRepositoryAdapter struct {
 	repo types.PromptRepository
@@ -317,7 +398,11 @@ func (a *promptRepositoryAdapter) ListPrompts(ctx context.Context) ([]*internalD
 	if err != nil {
 		return nil, err
 	}
+	return toInternalPrompts(prompts), nil
+}
 
+// toInternalPrompts converts pkg prompts to internal prompts.
+func toInternalPrompts(prompts []*types.Prompt) []*internalDomain.Prompt {
 	internalPrompts := make([]*internalDomain.Prompt, len(prompts))
 	for i, prompt := range prompts {
 		internalPrompts[i] = &internalDomain.Prompt{
@@ -337,7 +422,7 @@ func (a *promptRepositoryAdapter) ListPrompts(ctx context.Context) ([]*internalD
 		}
 	}
 
-	return internalPrompts, nil
+	return internalPrompts
 }
 
 func (a *promptRepositoryAdapter) AddPrompt(ctx context.Context, prompt *internalDomain.Prompt) error {
@@ -364,6 +449,20 @@ func (a *promptRepositoryAdapter) DeletePrompt(ctx context.Context, name string)
 	return a.repo.DeletePrompt(ctx, name)
 }
 
+// pagedPromptRepositoryAdapter adapts a pkg PagedPromptRepository to an internal PagedPromptRepository.
+type pagedPromptRepositoryAdapter struct {
+	promptRepositoryAdapter
+	paged types.PagedPromptRepository
+}
+
+func (a *pagedPromptRepositoryAdapter) ListPromptsPage(ctx context.Context, after string, limit int) ([]*internalDomain.Prompt, bool, error) {
+	prompts, more, err := a.paged.ListPromptsPage(ctx, after, limit)
+	if err != nil {
+		return nil, false, err
+	}
+	return toInternalPrompts(prompts), more, nil
+}
+
 // sessionRepositoryAdapter adapts a pkg SessionRepository to an internal SessionRepository.
 type sessionRepositoryAdapter struct {
 	repo types.SessionRepository
@@ -376,9 +475,17 @@ func (a *sessionRepositoryAdapter) GetSession(ctx context.Context, id string) (*
 	}
 
 	return &internalDomain.ClientSession{
-		ID:        session.ID,
-		UserAgent: session.UserAgent,
-		Connected: session.Connected,
+		ID:                 session.ID,
+		UserAgent:          session.UserAgent,
+		Transport:          session.Transport,
+		Connected:          session.Connected,
+		Principal:          auth.ToInternalPrincipal(session.Principal),
+		ClientIdentity:     session.ClientIdentity,
+		ClientInfo:         session.ClientInfo,
+		ClientCapabilities: session.ClientCapabilities,
+		ProtocolVersion:    session.ProtocolVersion,
+		CreatedAt:          session.CreatedAt,
+		State:              session.State,
 	}, nil
 }
 
@@ -391,9 +498,17 @@ func (a *sessionRepositoryAdapter) ListSessions(ctx context.Context) ([]*interna
 	internalSessions := make([]*internalDomain.ClientSession, len(sessions))
 	for i, session := range sessions {
 		internalSessions[i] = &internalDomain.ClientSession{
-			ID:        session.ID,
-			UserAgent: session.UserAgent,
-			Connected: session.Connected,
+			ID:                 session.ID,
+			UserAgent:          session.UserAgent,
+			Transport:          session.Transport,
+			Connected:          session.Connected,
+			Principal:          auth.ToInternalPrincipal(session.Principal),
+			ClientIdentity:     session.ClientIdentity,
+			ClientInfo:         session.ClientInfo,
+			ClientCapabilities: session.ClientCapabilities,
+			ProtocolVersion:    session.ProtocolVersion,
+			CreatedAt:          session.CreatedAt,
+			State:              session.State,
 		}
 	}
 
@@ -402,9 +517,17 @@ func (a *sessionRepositoryAdapter) ListSessions(ctx context.Context) ([]*interna
 
 func (a *sessionRepositoryAdapter) AddSession(ctx context.Context, session *internalDomain.ClientSession) error {
 	return a.repo.AddSession(ctx, &types.ClientSession{
-		ID:        session.ID,
-		UserAgent: session.UserAgent,
-		Connected: session.Connected,
+		ID:                 session.ID,
+		UserAgent:          session.UserAgent,
+		Transport:          session.Transport,
+		Connected:          session.Connected,
+		Principal:          auth.ToPublicPrincipal(session.Principal),
+		ClientIdentity:     session.ClientIdentity,
+		ClientInfo:         session.ClientInfo,
+		ClientCapabilities: session.ClientCapabilities,
+		ProtocolVersion:    session.ProtocolVersion,
+		CreatedAt:          session.CreatedAt,
+		State:              session.State,
 	})
 }
 
@@ -19,6 +19,22 @@ type Provider interface {
 	ExecuteTool(ctx context.Context, request *ExecuteRequest) (*ExecuteResponse, error)
 }
 
+// HealthChecker is an optional interface that a Provider can implement to take
+// part in the server's readiness checks, e.g. by pinging its backing store.
+// While a check fails, the /readyz endpoint reports the server as not ready.
+type HealthChecker interface {
+	// CheckHealth returns an error if the provider cannot serve requests.
+	CheckHealth(ctx context.Context) error
+}
+
+// HealthCheckerFunc is a function type that implements HealthChecker
+type HealthCheckerFunc func(ctx context.Context) error
+
+// CheckHealth calls the health check function
+func (f HealthCheckerFunc) CheckHealth(ctx context.Context) error {
+	return f(ctx)
+}
+
 // ProviderInfo contains metadata about a tool provider.
 type ProviderInfo struct {
 	ID          string
@@ -0,0 +1,164 @@
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/FreePeak/cortex/pkg/types"
+)
+
+// NotificationHarness connects clients to a types.NotificationSender under test.
+type NotificationHarness struct {
+	// Sender is the notification sender under test.
+	Sender types.NotificationSender
+
+	// Connect connects a client with a session of the given ID, and returns
+	// the channel on which the client receives its notifications. Notifications
+	// must not be dropped while no more than 64 of them wait to be received.
+	Connect func(t *testing.T, sessionID string) <-chan *types.Notification
+}
+
+// notificationCase is a test of a notification sender.
+type notificationCase struct {
+	name string
+	run  func(t *testing.T, h NotificationHarness)
+}
+
+// notificationCases are the tests of a notification sender.
+var notificationCases = []notificationCase{
+	{
+		name: "send to unknown session",
+		run: func(t *testing.T, h NotificationHarness) {
+			err := h.Sender.SendNotification(context.Background(), "missing", &types.Notification{Method: "test"})
+			wantNotFound(t, "SendNotification", err)
+		},
+	},
+	{
+		name: "send reaches only its session",
+		run: func(t *testing.T, h NotificationHarness) {
+			first := h.Connect(t, "first")
+			second := h.Connect(t, "second")
+
+			sent := &types.Notification{Method: "notifications/message", Params: map[string]interface{}{"level": "info"}}
+			mustSend(t, h, "first", sent)
+			got := receiveNotification(t, first)
+			if got.Method != sent.Method || !reflect.DeepEqual(got.Params, sent.Params) {
+				t.Errorf("received %+v, want %+v", got, sent)
+			}
+
+			// The other session receives its own notification first
+			mustSend(t, h, "second", &types.Notification{Method: "second"})
+			if got := receiveNotification(t, second); got.Method != "second" {
+				t.Errorf("other session received %q, want only %q", got.Method, "second")
+			}
+		},
+	},
+	{
+		name: "broadcast reaches every session",
+		run: func(t *testing.T, h NotificationHarness) {
+			clients := []<-chan *types.Notification{h.Connect(t, "a"), h.Connect(t, "b"), h.Connect(t, "c")}
+			if err := h.Sender.BroadcastNotification(context.Background(), &types.Notification{Method: "broadcast"}); err != nil {
+				t.Fatalf("BroadcastNotification error = %v", err)
+			}
+			for i, client := range clients {
+				if got := receiveNotification(t, client); got.Method != "broadcast" {
+					t.Errorf("client %d received %q, want %q", i, got.Method, "broadcast")
+				}
+			}
+		},
+	},
+	{
+		name: "broadcast without sessions",
+		run: func(t *testing.T, h NotificationHarness) {
+			if err := h.Sender.BroadcastNotification(context.Background(), &types.Notification{Method: "broadcast"}); err != nil {
+				t.Errorf("BroadcastNotification error = %v, want nil", err)
+			}
+		},
+	},
+	{
+		name: "notifications of a session arrive in order",
+		run: func(t *testing.T, h NotificationHarness) {
+			client := h.Connect(t, "ordered")
+			for i := 0; i < concurrency; i++ {
+				mustSend(t, h, "ordered", &types.Notification{Method: fmt.Sprintf("n%02d", i)})
+			}
+			for i := 0; i < concurrency; i++ {
+				if got, want := receiveNotification(t, client).Method, fmt.Sprintf("n%02d", i); got != want {
+					t.Fatalf("notification %d = %q, want %q", i, got, want)
+				}
+			}
+		},
+	},
+	{
+		name: "concurrent use",
+		run: func(t *testing.T, h NotificationHarness) {
+			ctx := context.Background()
+			ids := []string{"a", "b", "c"}
+			clients := make([]<-chan *types.Notification, len(ids))
+			for i, id := range ids {
+				clients[i] = h.Connect(t, id)
+			}
+
+			errs := make(chan error, concurrency*(len(ids)+1))
+			runConcurrently(concurrency, func(i int) {
+				for _, id := range ids {
+					errs <- h.Sender.SendNotification(ctx, id, &types.Notification{Method: "send"})
+				}
+				errs <- h.Sender.BroadcastNotification(ctx, &types.Notification{Method: "broadcast"})
+			})
+			close(errs)
+			for err := range errs {
+				if err != nil {
+					t.Error(err)
+				}
+			}
+
+			// Every client receives every notification sent to it, once
+			for i, client := range clients {
+				counts := make(map[string]int)
+				for n := 0; n < 2*concurrency; n++ {
+					counts[receiveNotification(t, client).Method]++
+				}
+				if counts["send"] != concurrency || counts["broadcast"] != concurrency {
+					t.Errorf("client %d received %v, want %d of each", i, counts, concurrency)
+				}
+			}
+		},
+	},
+}
+
+// TestNotificationSender tests that the notification senders of the harnesses
+// created by newHarness conform to types.NotificationSender: notifications for
+// unknown sessions fail with an error matching types.ErrNotFound, reach only
+// the sessions they are sent to, in order, and the sender is safe for
+// concurrent use. Each test gets a new harness without connected clients.
+func TestNotificationSender(t *testing.T, newHarness func(t *testing.T) NotificationHarness) {
+	for _, tc := range notificationCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.run(t, newHarness(t))
+		})
+	}
+}
+
+// mustSend sends a notification to a session, failing the test on error.
+func mustSend(t *testing.T, h NotificationHarness, sessionID string, notification *types.Notification) {
+	t.Helper()
+	if err := h.Sender.SendNotification(context.Background(), sessionID, notification); err != nil {
+		t.Fatalf("SendNotification to %s error = %v", sessionID, err)
+	}
+}
+
+// receiveNotification returns the next notification received by a client.
+func receiveNotification(t *testing.T, client <-chan *types.Notification) *types.Notification {
+	t.Helper()
+	select {
+	case notification := <-client:
+		return notification
+	case <-time.After(receiveTimeout):
+		t.Fatal("no notification received")
+		return nil
+	}
+}
@@ -0,0 +1,442 @@
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/FreePeak/cortex/pkg/types"
+)
+
+// entry is an entry of a repository under test: its key, e.g. the name of a
+// tool, and a value stored in one of its fields, which tells entries added
+// with the same key apart.
+type entry struct {
+	key   string
+	value string
+}
+
+// keyedRepository is a repository under test reduced to its entries, so that
+// the behavior shared by all repositories is tested once.
+type keyedRepository struct {
+	add    func(ctx context.Context, e entry) error
+	get    func(ctx context.Context, key string) (entry, error)
+	list   func(ctx context.Context) ([]entry, error)
+	delete func(ctx context.Context, key string) error
+}
+
+// keyedCase is a test of the behavior shared by all repositories.
+type keyedCase struct {
+	name string
+	run  func(t *testing.T, repo keyedRepository)
+}
+
+// keyedCases are the tests of the behavior shared by all repositories.
+var keyedCases = []keyedCase{
+	{
+		name: "get missing entry",
+		run: func(t *testing.T, repo keyedRepository) {
+			_, err := repo.get(context.Background(), "missing")
+			wantNotFound(t, "get", err)
+		},
+	},
+	{
+		name: "delete missing entry",
+		run: func(t *testing.T, repo keyedRepository) {
+			wantNotFound(t, "delete", repo.delete(context.Background(), "missing"))
+		},
+	},
+	{
+		name: "list empty repository",
+		run: func(t *testing.T, repo keyedRepository) {
+			entries, err := repo.list(context.Background())
+			if err != nil {
+				t.Fatalf("list error = %v", err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("list = %v, want no entries", entries)
+			}
+		},
+	},
+	{
+		name: "add and get",
+		run: func(t *testing.T, repo keyedRepository) {
+			ctx := context.Background()
+			mustAdd(t, repo, entry{"alpha", "first"})
+			got, err := repo.get(ctx, "alpha")
+			if err != nil {
+				t.Fatalf("get error = %v", err)
+			}
+			if got != (entry{"alpha", "first"}) {
+				t.Errorf("get = %v, want %v", got, entry{"alpha", "first"})
+			}
+		},
+	},
+	{
+		name: "add replaces entry with same key",
+		run: func(t *testing.T, repo keyedRepository) {
+			ctx := context.Background()
+			mustAdd(t, repo, entry{"alpha", "first"})
+			mustAdd(t, repo, entry{"alpha", "second"})
+			got, err := repo.get(ctx, "alpha")
+			if err != nil {
+				t.Fatalf("get error = %v", err)
+			}
+			if got.value != "second" {
+				t.Errorf("get = %v, want the replacing entry %v", got, entry{"alpha", "second"})
+			}
+			wantEntries(t, repo, []entry{{"alpha", "second"}})
+		},
+	},
+	{
+		name: "delete entry",
+		run: func(t *testing.T, repo keyedRepository) {
+			ctx := context.Background()
+			mustAdd(t, repo, entry{"alpha", "first"})
+			mustAdd(t, repo, entry{"beta", "second"})
+			if err := repo.delete(ctx, "alpha"); err != nil {
+				t.Fatalf("delete error = %v", err)
+			}
+			_, err := repo.get(ctx, "alpha")
+			wantNotFound(t, "get after delete", err)
+			wantNotFound(t, "second delete", repo.delete(ctx, "alpha"))
+			wantEntries(t, repo, []entry{{"beta", "second"}})
+		},
+	},
+	{
+		name: "list is ordered by key",
+		run: func(t *testing.T, repo keyedRepository) {
+			for _, key := range []string{"charlie", "alpha", "delta", "bravo"} {
+				mustAdd(t, repo, entry{key, key})
+			}
+			want := []entry{{"alpha", "alpha"}, {"bravo", "bravo"}, {"charlie", "charlie"}, {"delta", "delta"}}
+			wantEntries(t, repo, want)
+			// The order is the same on every call
+			wantEntries(t, repo, want)
+		},
+	},
+	{
+		name: "concurrent use",
+		run: func(t *testing.T, repo keyedRepository) {
+			ctx := context.Background()
+			errs := make(chan error, concurrency*4)
+			runConcurrently(concurrency, func(i int) {
+				e := entry{fmt.Sprintf("entry-%02d", i), fmt.Sprint(i)}
+				errs <- repo.add(ctx, e)
+				if got, err := repo.get(ctx, e.key); err != nil {
+					errs <- err
+				} else if got != e {
+					errs <- fmt.Errorf("get = %v, want %v", got, e)
+				}
+				_, err := repo.list(ctx)
+				errs <- err
+				if i%2 == 1 {
+					errs <- repo.delete(ctx, e.key)
+				}
+			})
+			close(errs)
+			for err := range errs {
+				if err != nil {
+					t.Error(err)
+				}
+			}
+
+			var want []entry
+			for i := 0; i < concurrency; i += 2 {
+				want = append(want, entry{fmt.Sprintf("entry-%02d", i), fmt.Sprint(i)})
+			}
+			wantEntries(t, repo, want)
+		},
+	},
+}
+
+// runKeyedCases runs the tests of the behavior shared by all repositories,
+// each against a new repository.
+func runKeyedCases(t *testing.T, newRepository func(t *testing.T) keyedRepository) {
+	for _, tc := range keyedCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.run(t, newRepository(t))
+		})
+	}
+}
+
+// mustAdd adds an entry, failing the test on error.
+func mustAdd(t *testing.T, repo keyedRepository, e entry) {
+	t.Helper()
+	if err := repo.add(context.Background(), e); err != nil {
+		t.Fatalf("add %v error = %v", e, err)
+	}
+}
+
+// wantEntries fails the test unless the repository lists exactly want, in order.
+func wantEntries(t *testing.T, repo keyedRepository, want []entry) {
+	t.Helper()
+	got, err := repo.list(context.Background())
+	if err != nil {
+		t.Fatalf("list error = %v", err)
+	}
+	if len(got) != len(want) || (len(want) > 0 && !reflect.DeepEqual(got, want)) {
+		t.Errorf("list = %v, want %v", got, want)
+	}
+}
+
+// TestToolRepository tests that the repositories created by newRepository
+// conform to types.ToolRepository. Each test gets a new, empty repository.
+func TestToolRepository(t *testing.T, newRepository func(t *testing.T) types.ToolRepository) {
+	runKeyedCases(t, func(t *testing.T) keyedRepository {
+		repo := newRepository(t)
+		return keyedRepository{
+			add: func(ctx context.Context, e entry) error {
+				return repo.AddTool(ctx, &types.Tool{Name: e.key, Description: e.value})
+			},
+			get: func(ctx context.Context, key string) (entry, error) {
+				tool, err := repo.GetTool(ctx, key)
+				if err != nil {
+					return entry{}, err
+				}
+				return entry{tool.Name, tool.Description}, nil
+			},
+			list: func(ctx context.Context) ([]entry, error) {
+				tools, err := repo.ListTools(ctx)
+				entries := make([]entry, len(tools))
+				for i, tool := range tools {
+					entries[i] = entry{tool.Name, tool.Description}
+				}
+				return entries, err
+			},
+			delete: repo.DeleteTool,
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepository(t)
+		tool := &types.Tool{
+			Name:        "search",
+			Description: "Searches documents",
+			Parameters: []types.ToolParameter{
+				{Name: "query", Description: "Search terms", Type: "string", Required: true},
+				{Name: "tags", Type: "array", Items: map[string]interface{}{"type": "string"}},
+			},
+		}
+		if err := repo.AddTool(ctx, tool); err != nil {
+			t.Fatalf("AddTool error = %v", err)
+		}
+		got, err := repo.GetTool(ctx, tool.Name)
+		if err != nil {
+			t.Fatalf("GetTool error = %v", err)
+		}
+		if !reflect.DeepEqual(got, tool) {
+			t.Errorf("GetTool = %+v, want %+v", got, tool)
+		}
+	})
+}
+
+// TestResourceRepository tests that the repositories created by newRepository
+// conform to types.ResourceRepository. Each test gets a new, empty repository.
+func TestResourceRepository(t *testing.T, newRepository func(t *testing.T) types.ResourceRepository) {
+	runKeyedCases(t, func(t *testing.T) keyedRepository {
+		repo := newRepository(t)
+		return keyedRepository{
+			add: func(ctx context.Context, e entry) error {
+				return repo.AddResource(ctx, &types.Resource{URI: e.key, Name: e.value})
+			},
+			get: func(ctx context.Context, key string) (entry, error) {
+				resource, err := repo.GetResource(ctx, key)
+				if err != nil {
+					return entry{}, err
+				}
+				return entry{resource.URI, resource.Name}, nil
+			},
+			list: func(ctx context.Context) ([]entry, error) {
+				resources, err := repo.ListResources(ctx)
+				entries := make([]entry, len(resources))
+				for i, resource := range resources {
+					entries[i] = entry{resource.URI, resource.Name}
+				}
+				return entries, err
+			},
+			delete: repo.DeleteResource,
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepository(t)
+		resource := &types.Resource{
+			URI:         "file:///docs/readme.md",
+			Name:        "Readme",
+			Description: "Project overview",
+			MIMEType:    "text/markdown",
+		}
+		if err := repo.AddResource(ctx, resource); err != nil {
+			t.Fatalf("AddResource error = %v", err)
+		}
+		got, err := repo.GetResource(ctx, resource.URI)
+		if err != nil {
+			t.Fatalf("GetResource error = %v", err)
+		}
+		if !reflect.DeepEqual(got, resource) {
+			t.Errorf("GetResource = %+v, want %+v", got, resource)
+		}
+	})
+}
+
+// TestPromptRepository tests that the repositories created by newRepository
+// conform to types.PromptRepository. Each test gets a new, empty repository.
+func TestPromptRepository(t *testing.T, newRepository func(t *testing.T) types.PromptRepository) {
+	runKeyedCases(t, func(t *testing.T) keyedRepository {
+		repo := newRepository(t)
+		return keyedRepository{
+			add: func(ctx context.Context, e entry) error {
+				return repo.AddPrompt(ctx, &types.Prompt{Name: e.key, Template: e.value})
+			},
+			get: func(ctx context.Context, key string) (entry, error) {
+				prompt, err := repo.GetPrompt(ctx, key)
+				if err != nil {
+					return entry{}, err
+				}
+				return entry{prompt.Name, prompt.Template}, nil
+			},
+			list: func(ctx context.Context) ([]entry, error) {
+				prompts, err := repo.ListPrompts(ctx)
+				entries := make([]entry, len(prompts))
+				for i, prompt := range prompts {
+					entries[i] = entry{prompt.Name, prompt.Template}
+				}
+				return entries, err
+			},
+			delete: repo.DeletePrompt,
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepository(t)
+		prompt := &types.Prompt{
+			Name:        "greet",
+			Description: "Greets someone",
+			Template:    "Hello {{name}}",
+			Parameters:  []types.PromptParameter{{Name: "name", Description: "Who to greet", Type: "string", Required: true}},
+		}
+		if err := repo.AddPrompt(ctx, prompt); err != nil {
+			t.Fatalf("AddPrompt error = %v", err)
+		}
+		got, err := repo.GetPrompt(ctx, prompt.Name)
+		if err != nil {
+			t.Fatalf("GetPrompt error = %v", err)
+		}
+		if !reflect.DeepEqual(got, prompt) {
+			t.Errorf("GetPrompt = %+v, want %+v", got, prompt)
+		}
+	})
+}
+
+// TestSessionRepository tests that the repositories created by newRepository
+// conform to types.SessionRepository. Each test gets a new, empty repository.
+//
+// Besides the fields of a session, the repository must keep its State: the
+// sessions it returns share the State of the session that was added, so that
+// the activity and labels that the server records are seen by every request
+// of the session.
+func TestSessionRepository(t *testing.T, newRepository func(t *testing.T) types.SessionRepository) {
+	runKeyedCases(t, func(t *testing.T) keyedRepository {
+		repo := newRepository(t)
+		return keyedRepository{
+			add: func(ctx context.Context, e entry) error {
+				session := types.NewClientSession(e.value)
+				session.ID = e.key
+				return repo.AddSession(ctx, session)
+			},
+			get: func(ctx context.Context, key string) (entry, error) {
+				session, err := repo.GetSession(ctx, key)
+				if err != nil {
+					return entry{}, err
+				}
+				return entry{session.ID, session.UserAgent}, nil
+			},
+			list: func(ctx context.Context) ([]entry, error) {
+				sessions, err := repo.ListSessions(ctx)
+				entries := make([]entry, len(sessions))
+				for i, session := range sessions {
+					entries[i] = entry{session.ID, session.UserAgent}
+				}
+				return entries, err
+			},
+			delete: repo.DeleteSession,
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepository(t)
+		session := types.NewClientSession("agent/1.0")
+		session.Transport = "http"
+		session.Principal = &types.Principal{ID: "alice", Roles: []string{"admin"}, Scopes: []string{"tools:call"}}
+		session.ClientIdentity = &types.ClientIdentity{CommonName: "client", Fingerprint: "ab12"}
+		session.ClientInfo = types.ClientInfo{Name: "client", Version: "1.0"}
+		session.ClientCapabilities = map[string]interface{}{"roots": map[string]interface{}{}}
+		session.ProtocolVersion = "2025-03-26"
+		if err := repo.AddSession(ctx, session); err != nil {
+			t.Fatalf("AddSession error = %v", err)
+		}
+
+		got, err := repo.GetSession(ctx, session.ID)
+		if err != nil {
+			t.Fatalf("GetSession error = %v", err)
+		}
+		if got.ID != session.ID || got.UserAgent != session.UserAgent || got.Transport != session.Transport ||
+			got.ClientInfo != session.ClientInfo || got.ProtocolVersion != session.ProtocolVersion {
+			t.Errorf("GetSession = %+v, want %+v", got, session)
+		}
+		if !reflect.DeepEqual(got.Principal, session.Principal) {
+			t.Errorf("GetSession principal = %+v, want %+v", got.Principal, session.Principal)
+		}
+		if !reflect.DeepEqual(got.ClientIdentity, session.ClientIdentity) {
+			t.Errorf("GetSession client identity = %+v, want %+v", got.ClientIdentity, session.ClientIdentity)
+		}
+		if !reflect.DeepEqual(got.ClientCapabilities, session.ClientCapabilities) {
+			t.Errorf("GetSession capabilities = %v, want %v", got.ClientCapabilities, session.ClientCapabilities)
+		}
+		if !got.CreatedAt.Equal(session.CreatedAt) {
+			t.Errorf("GetSession CreatedAt = %v, want %v", got.CreatedAt, session.CreatedAt)
+		}
+	})
+
+	t.Run("sessions share their state", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepository(t)
+		session := types.NewClientSession("agent")
+		if err := repo.AddSession(ctx, session); err != nil {
+			t.Fatalf("AddSession error = %v", err)
+		}
+
+		// Changes made after the session was added are seen through the repository
+		session.State.SetLabel("tenant", "a")
+		got, err := repo.GetSession(ctx, session.ID)
+		if err != nil {
+			t.Fatalf("GetSession error = %v", err)
+		}
+		if tenant, _ := got.Label("tenant"); tenant != "a" {
+			t.Errorf("GetSession label tenant = %q, want %q", tenant, "a")
+		}
+
+		sessions, err := repo.ListSessions(ctx)
+		if err != nil {
+			t.Fatalf("ListSessions error = %v", err)
+		}
+		if len(sessions) != 1 || sessions[0].State != got.State {
+			t.Errorf("ListSessions does not return the state of the added session")
+		}
+
+		// And changes made through the repository are seen by the session
+		got.State.SetValue(stateKey{}, "cursor")
+		if value := session.State.Value(stateKey{}); value != "cursor" {
+			t.Errorf("session state value = %v, want %q", value, "cursor")
+		}
+	})
+}
+
+// stateKey is the key of the value stored in the state of a session under test.
+type stateKey struct{}
@@ -0,0 +1,51 @@
+// Package repotest provides conformance test suites for implementations of the
+// repository interfaces of package types and of types.NotificationSender.
+// Run a suite from a test of the implementation:
+//
+//	func TestToolRepository(t *testing.T) {
+//		repotest.TestToolRepository(t, func(t *testing.T) types.ToolRepository {
+//			return mystore.NewToolRepository(t.TempDir())
+//		})
+//	}
+//
+// The suites check the behavior that the server relies on: missing entries
+// are reported with errors matching types.ErrNotFound, adding an entry with an
+// existing key replaces it, lists are ordered by key, and implementations are
+// safe for concurrent use. Run them with -race to also detect data races.
+package repotest
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FreePeak/cortex/pkg/types"
+)
+
+// concurrency is the number of goroutines of the concurrency tests.
+const concurrency = 16
+
+// receiveTimeout is how long the notification tests wait for a notification.
+const receiveTimeout = 5 * time.Second
+
+// wantNotFound fails the test unless err matches types.ErrNotFound.
+func wantNotFound(t *testing.T, op string, err error) {
+	t.Helper()
+	if !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("%s error = %v, want an error matching types.ErrNotFound", op, err)
+	}
+}
+
+// runConcurrently calls fn from n goroutines, with their index, and waits for them.
+func runConcurrently(n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
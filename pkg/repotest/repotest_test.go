@@ -0,0 +1,252 @@
+package repotest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/FreePeak/cortex/internal/domain"
+	"github.com/FreePeak/cortex/internal/infrastructure/server"
+	"github.com/FreePeak/cortex/pkg/auth"
+	"github.com/FreePeak/cortex/pkg/repotest"
+	"github.com/FreePeak/cortex/pkg/types"
+)
+
+// The built-in repositories and notification sender must pass the suites
+// offered to third-party implementations.
+
+func TestInMemoryRepositories(t *testing.T) {
+	repotest.TestToolRepository(t, func(t *testing.T) types.ToolRepository {
+		return toolRepository{server.NewInMemoryToolRepository()}
+	})
+	repotest.TestResourceRepository(t, func(t *testing.T) types.ResourceRepository {
+		return resourceRepository{server.NewInMemoryResourceRepository()}
+	})
+	repotest.TestPromptRepository(t, func(t *testing.T) types.PromptRepository {
+		return promptRepository{server.NewInMemoryPromptRepository()}
+	})
+	repotest.TestSessionRepository(t, func(t *testing.T) types.SessionRepository {
+		return sessionRepository{server.NewInMemorySessionRepository()}
+	})
+}
+
+func TestFileRepositories(t *testing.T) {
+	open := func(t *testing.T) *server.FileRepositories {
+		repos, err := server.OpenFileRepositories(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repos
+	}
+	repotest.TestToolRepository(t, func(t *testing.T) types.ToolRepository {
+		return toolRepository{open(t).Tools}
+	})
+	repotest.TestResourceRepository(t, func(t *testing.T) types.ResourceRepository {
+		return resourceRepository{open(t).Resources}
+	})
+	repotest.TestPromptRepository(t, func(t *testing.T) types.PromptRepository {
+		return promptRepository{open(t).Prompts}
+	})
+	repotest.TestSessionRepository(t, func(t *testing.T) types.SessionRepository {
+		return sessionRepository{open(t).Sessions}
+	})
+}
+
+func TestNotificationSender(t *testing.T) {
+	repotest.TestNotificationSender(t, func(t *testing.T) repotest.NotificationHarness {
+		sender := server.NewNotificationSender("2.0")
+		return repotest.NotificationHarness{
+			Sender: notificationSender{sender},
+			Connect: func(t *testing.T, sessionID string) <-chan *types.Notification {
+				session := server.NewMCPSession(sessionID, "agent", 64)
+				sender.RegisterSession(session)
+				t.Cleanup(func() { sender.UnregisterSession(sessionID) })
+
+				received := make(chan *types.Notification, 64)
+				go func() {
+					for notification := range session.NotificationChannel() {
+						received <- &types.Notification{Method: notification.Method, Params: notification.Params}
+					}
+				}()
+				return received
+			},
+		}
+	})
+}
+
+// toolRepository adapts an internal ToolRepository to types.ToolRepository.
+type toolRepository struct{ repo domain.ToolRepository }
+
+func (a toolRepository) GetTool(ctx context.Context, name string) (*types.Tool, error) {
+	tool, err := a.repo.GetTool(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return toPublicTool(tool), nil
+}
+
+func (a toolRepository) ListTools(ctx context.Context) ([]*types.Tool, error) {
+	tools, err := a.repo.ListTools(ctx)
+	public := make([]*types.Tool, len(tools))
+	for i, tool := range tools {
+		public[i] = toPublicTool(tool)
+	}
+	return public, err
+}
+
+func (a toolRepository) AddTool(ctx context.Context, tool *types.Tool) error {
+	internal := &domain.Tool{Name: tool.Name, Description: tool.Description}
+	for _, param := range tool.Parameters {
+		internal.Parameters = append(internal.Parameters, domain.ToolParameter(param))
+	}
+	return a.repo.AddTool(ctx, internal)
+}
+
+func (a toolRepository) DeleteTool(ctx context.Context, name string) error {
+	return a.repo.DeleteTool(ctx, name)
+}
+
+func toPublicTool(tool *domain.Tool) *types.Tool {
+	public := &types.Tool{Name: tool.Name, Description: tool.Description}
+	for _, param := range tool.Parameters {
+		public.Parameters = append(public.Parameters, types.ToolParameter(param))
+	}
+	return public
+}
+
+// resourceRepository adapts an internal ResourceRepository to types.ResourceRepository.
+type resourceRepository struct{ repo domain.ResourceRepository }
+
+func (a resourceRepository) GetResource(ctx context.Context, uri string) (*types.Resource, error) {
+	resource, err := a.repo.GetResource(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	public := types.Resource(*resource)
+	return &public, nil
+}
+
+func (a resourceRepository) ListResources(ctx context.Context) ([]*types.Resource, error) {
+	resources, err := a.repo.ListResources(ctx)
+	public := make([]*types.Resource, len(resources))
+	for i, resource := range resources {
+		r := types.Resource(*resource)
+		public[i] = &r
+	}
+	return public, err
+}
+
+func (a resourceRepository) AddResource(ctx context.Context, resource *types.Resource) error {
+	internal := domain.Resource(*resource)
+	return a.repo.AddResource(ctx, &internal)
+}
+
+func (a resourceRepository) DeleteResource(ctx context.Context, uri string) error {
+	return a.repo.DeleteResource(ctx, uri)
+}
+
+// promptRepository adapts an internal PromptRepository to types.PromptRepository.
+type promptRepository struct{ repo domain.PromptRepository }
+
+func (a promptRepository) GetPrompt(ctx context.Context, name string) (*types.Prompt, error) {
+	prompt, err := a.repo.GetPrompt(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return toPublicPrompt(prompt), nil
+}
+
+func (a promptRepository) ListPrompts(ctx context.Context) ([]*types.Prompt, error) {
+	prompts, err := a.repo.ListPrompts(ctx)
+	public := make([]*types.Prompt, len(prompts))
+	for i, prompt := range prompts {
+		public[i] = toPublicPrompt(prompt)
+	}
+	return public, err
+}
+
+func (a promptRepository) AddPrompt(ctx context.Context, prompt *types.Prompt) error {
+	internal := &domain.Prompt{Name: prompt.Name, Description: prompt.Description, Template: prompt.Template}
+	for _, param := range prompt.Parameters {
+		internal.Parameters = append(internal.Parameters, domain.PromptParameter(param))
+	}
+	return a.repo.AddPrompt(ctx, internal)
+}
+
+func (a promptRepository) DeletePrompt(ctx context.Context, name string) error {
+	return a.repo.DeletePrompt(ctx, name)
+}
+
+func toPublicPrompt(prompt *domain.Prompt) *types.Prompt {
+	public := &types.Prompt{Name: prompt.Name, Description: prompt.Description, Template: prompt.Template}
+	for _, param := range prompt.Parameters {
+		public.Parameters = append(public.Parameters, types.PromptParameter(param))
+	}
+	return public
+}
+
+// sessionRepository adapts an internal SessionRepository to types.SessionRepository.
+type sessionRepository struct{ repo domain.SessionRepository }
+
+func (a sessionRepository) GetSession(ctx context.Context, id string) (*types.ClientSession, error) {
+	session, err := a.repo.GetSession(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toPublicSession(session), nil
+}
+
+func (a sessionRepository) ListSessions(ctx context.Context) ([]*types.ClientSession, error) {
+	sessions, err := a.repo.ListSessions(ctx)
+	public := make([]*types.ClientSession, len(sessions))
+	for i, session := range sessions {
+		public[i] = toPublicSession(session)
+	}
+	return public, err
+}
+
+func (a sessionRepository) AddSession(ctx context.Context, session *types.ClientSession) error {
+	return a.repo.AddSession(ctx, &domain.ClientSession{
+		ID:                 session.ID,
+		UserAgent:          session.UserAgent,
+		Connected:          session.Connected,
+		Principal:          auth.ToInternalPrincipal(session.Principal),
+		Transport:          session.Transport,
+		ClientIdentity:     session.ClientIdentity,
+		ClientInfo:         session.ClientInfo,
+		ClientCapabilities: session.ClientCapabilities,
+		ProtocolVersion:    session.ProtocolVersion,
+		CreatedAt:          session.CreatedAt,
+		State:              session.State,
+	})
+}
+
+func (a sessionRepository) DeleteSession(ctx context.Context, id string) error {
+	return a.repo.DeleteSession(ctx, id)
+}
+
+func toPublicSession(session *domain.ClientSession) *types.ClientSession {
+	return &types.ClientSession{
+		ID:                 session.ID,
+		UserAgent:          session.UserAgent,
+		Connected:          session.Connected,
+		Principal:          auth.ToPublicPrincipal(session.Principal),
+		Transport:          session.Transport,
+		ClientIdentity:     session.ClientIdentity,
+		ClientInfo:         session.ClientInfo,
+		ClientCapabilities: session.ClientCapabilities,
+		ProtocolVersion:    session.ProtocolVersion,
+		CreatedAt:          session.CreatedAt,
+		State:              session.State,
+	}
+}
+
+// notificationSender adapts an internal NotificationSender to types.NotificationSender.
+type notificationSender struct{ sender domain.NotificationSender }
+
+func (a notificationSender) SendNotification(ctx context.Context, sessionID string, notification *types.Notification) error {
+	return a.sender.SendNotification(ctx, sessionID, &domain.Notification{Method: notification.Method, Params: notification.Params})
+}
+
+func (a notificationSender) BroadcastNotification(ctx context.Context, notification *types.Notification) error {
+	return a.sender.BroadcastNotification(ctx, &domain.Notification{Method: notification.Method, Params: notification.Params})
+}
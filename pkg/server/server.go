@@ -3,17 +3,207 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"sync"
 
 	"github.com/FreePeak/cortex/internal/builder"
 	"github.com/FreePeak/cortex/internal/domain"
+	infraServer "github.com/FreePeak/cortex/internal/infrastructure/server"
+	"github.com/FreePeak/cortex/internal/interfaces/rest"
 	"github.com/FreePeak/cortex/internal/interfaces/stdio"
+	"github.com/FreePeak/cortex/internal/usecases"
+	"github.com/FreePeak/cortex/pkg/auth"
 	"github.com/FreePeak/cortex/pkg/plugin"
+	"github.com/FreePeak/cortex/pkg/tracing"
 	"github.com/FreePeak/cortex/pkg/types"
 )
 
+// CORSConfig contains the cross-origin policy of the HTTP and SSE endpoints.
+// Requests whose Origin or Host header is not allowed are rejected.
+type CORSConfig = infraServer.CORSConfig
+
+// LocalhostOrigins are the origins of pages served from the local machine,
+// for use in CORSConfig.AllowedOrigins.
+var LocalhostOrigins = infraServer.LocalhostOrigins
+
+// LocalhostHosts are the host names of the local machine, for use in
+// CORSConfig.AllowedHosts.
+var LocalhostHosts = infraServer.LocalhostHosts
+
+// InMemorySessionStore keeps the key/value state of sessions in memory. Values
+// are stored as they are, so they may be anything, e.g. a database cursor.
+type InMemorySessionStore = infraServer.InMemorySessionStore
+
+// NewInMemorySessionStore creates a new, empty InMemorySessionStore.
+var NewInMemorySessionStore = infraServer.NewInMemorySessionStore
+
+// FileSessionStore keeps the key/value state of each session in a JSON file, so
+// that it survives restarts. Values must be serializable with encoding/json.
+// Sessions are only found again after a restart with SetFileRepositories, and
+// the files of sessions that are unknown when the server starts are removed.
+type FileSessionStore = infraServer.FileSessionStore
+
+// NewFileSessionStore creates a FileSessionStore in a directory, creating the
+// directory if it does not exist.
+var NewFileSessionStore = infraServer.NewFileSessionStore
+
+// SessionLimits bounds how long sessions of the HTTP transports may live: an
+// idle timeout, a max lifetime, and how often they are checked.
+type SessionLimits = usecases.SessionLimits
+
+// DefaultHTTPSessionIdleTimeout is how long sessions of the streamable HTTP
+// transport may stay idle if SessionLimits.IdleTimeout is not set.
+const DefaultHTTPSessionIdleTimeout = usecases.DefaultHTTPSessionIdleTimeout
+
+// TLSConfig contains the TLS configuration of the HTTP transport: the server
+// certificate files, which are reloaded when they change, or a tls.Config, and
+// optionally the CAs that client certificates are verified against.
+type TLSConfig = infraServer.TLSConfig
+
+// RateLimit is a token bucket limit of Requests per Per, allowing bursts of up
+// to Burst calls.
+type RateLimit = usecases.RateLimit
+
+// RateLimitConfig contains the rate limits applied to tool calls globally, per
+// session, per principal and per tool.
+type RateLimitConfig = usecases.RateLimitConfig
+
+// ConcurrencyLimit bounds the number of calls that may run at the same time,
+// optionally queueing excess calls for up to QueueTimeout.
+type ConcurrencyLimit = usecases.ConcurrencyLimit
+
+// ConcurrencyConfig contains the concurrency limits applied to tool calls.
+type ConcurrencyConfig = usecases.ConcurrencyConfig
+
+// NotificationConfig sets how many notifications are queued per session and
+// what happens when a slow client lets its queue fill up.
+type NotificationConfig = infraServer.NotificationConfig
+
+// OverflowPolicy decides what happens to a notification when the queue of a
+// session is full.
+type OverflowPolicy = infraServer.OverflowPolicy
+
+// Overflow policies for NotificationConfig
+const (
+	OverflowDropNewest = infraServer.OverflowDropNewest
+	OverflowDropOldest = infraServer.OverflowDropOldest
+	OverflowBlock      = infraServer.OverflowBlock
+	OverflowDisconnect = infraServer.OverflowDisconnect
+)
+
+// NotificationBus relays notifications and messages between the instances of a
+// horizontally scaled server, see NotificationConfig.Bus.
+type NotificationBus = domain.NotificationBus
+
+// NewInMemoryNotificationBus creates a bus for instances running in the same process.
+var NewInMemoryNotificationBus = infraServer.NewInMemoryNotificationBus
+
+// NotificationBroker is a reference broker that relays the messages of
+// NotificationBus connections over TCP or a Unix socket.
+type NotificationBroker = infraServer.NotificationBroker
+
+// ListenNotificationBroker starts a NotificationBroker on a network and
+// address. Connections must authenticate with the shared secret. Whoever can
+// publish on the bus can post messages to any session, so also set
+// NotificationConfig.BusSecret, and keep the broker on a private network, as
+// its frames are not encrypted.
+var ListenNotificationBroker = infraServer.ListenNotificationBroker
+
+// DialNotificationBus connects a NotificationBus to a NotificationBroker,
+// authenticating with the shared secret of the broker.
+var DialNotificationBus = infraServer.DialNotificationBus
+
+// NewSignedNotificationBus wraps a NotificationBus so that its messages are
+// signed with a secret shared by the instances. NotificationConfig.BusSecret
+// applies it to the bus of the notification config.
+var NewSignedNotificationBus = infraServer.NewSignedNotificationBus
+
+// SessionFilter selects the sessions that a notification is sent to, see
+// NotifySessions. Build filters with the functions below or SessionFilterFunc.
+// With a notification bus, only the filters of the functions below reach the
+// sessions of other instances.
+type SessionFilter = domain.SessionFilter
+
+// SessionSelector is the SessionFilter returned by the functions below, which
+// other instances apply to their sessions.
+type SessionSelector = domain.SessionSelector
+
+// Session filters for NotifySessions and ContextWithNotificationFilter
+var (
+	// PrincipalFilter selects the sessions of the principal with the given ID.
+	PrincipalFilter = domain.PrincipalFilter
+
+	// LabelFilter selects the sessions whose label has the given value.
+	LabelFilter = domain.LabelFilter
+
+	// SubscriptionFilter selects the sessions subscribed to a topic.
+	SubscriptionFilter = domain.SubscriptionFilter
+
+	// ProtocolVersionFilter selects the sessions that initialized with a protocol version.
+	ProtocolVersionFilter = domain.ProtocolVersionFilter
+
+	// AllFilters selects the sessions selected by every filter.
+	AllFilters = domain.AllFilters
+
+	// ContextWithNotificationFilter restricts the list changed notifications
+	// caused by the operations given the returned context, such as adding a
+	// tool, to the sessions selected by a filter.
+	ContextWithNotificationFilter = domain.ContextWithNotificationFilter
+)
+
+// SessionFilterFunc returns a SessionFilter that selects the sessions for which
+// fn returns true. It cannot be relayed over a notification bus, so
+// NotifySessions only notifies the sessions of this instance with it.
+func SessionFilterFunc(fn func(session *types.ClientSession) bool) SessionFilter {
+	return domain.SessionFilterFunc(func(session *domain.ClientSession) bool {
+		return fn(convertToPublicSession(session))
+	})
+}
+
+// ErrFilterNotRelayable is returned by NotifySessions when a filter built with
+// SessionFilterFunc is used with a notification bus.
+var ErrFilterNotRelayable = infraServer.ErrFilterNotRelayable
+
+// SessionLabeler returns the labels of a session when it is initialized.
+type SessionLabeler func(ctx context.Context, session *types.ClientSession) map[string]string
+
+// Errors returned for invalid lifecycle transitions
+var (
+	// ErrServerRunning is returned when a server that is already serving is started again
+	ErrServerRunning = errors.New("server is already running")
+
+	// ErrServerNotRunning is returned when a server that was never started is shut down
+	ErrServerNotRunning = errors.New("server is not running")
+
+	// ErrServerStopped is returned when a server that has been shut down is started or shut down again
+	ErrServerStopped = errors.New("server has been stopped")
+)
+
+// serverState is the lifecycle state of an MCPServer.
+type serverState int
+
+const (
+	stateNew serverState = iota
+	stateRunning
+	stateStopped
+)
+
+// HandlerOptions configures the handler returned by Handler.
+type HandlerOptions struct {
+	// BasePath is the path prefix the handler is mounted at, e.g. /api/mcp.
+	// Every endpoint, including /sse and /message, is served under it.
+	BasePath string
+
+	// BaseURL is the scheme and host clients use to reach the server, e.g.
+	// https://example.com, if it differs from the host they connected to. It is
+	// used for the message endpoint URL sent to SSE clients.
+	BaseURL string
+}
+
 // ToolHandler is a function that handles tool calls.
 type ToolHandler func(ctx context.Context, request ToolCallRequest) (interface{}, error)
 
@@ -34,6 +224,16 @@ type MCPServer struct {
 	registry plugin.Registry
 	builder  *builder.ServerBuilder
 	logger   *log.Logger
+
+	// Bulkheads isolating providers from each other, keyed by provider ID
+	providerMu        sync.RWMutex
+	providerBulkheads map[string]*usecases.Bulkhead
+
+	// Lifecycle of the transport that is serving
+	lifecycleMu sync.Mutex
+	state       serverState
+	stop        func(ctx context.Context) error // Stops the transport that is serving
+	done        chan struct{}                   // Closed once the serving call returns
 }
 
 // NewMCPServer creates a new MCP server with the specified name and version.
@@ -52,6 +252,8 @@ func NewMCPServer(name, version string, logger *log.Logger) *MCPServer {
 		registry: registry,
 		builder:  builder.NewServerBuilder().WithName(name).WithVersion(version),
 		logger:   logger,
+
+		providerBulkheads: make(map[string]*usecases.Bulkhead),
 	}
 }
 
@@ -82,11 +284,7 @@ func (s *MCPServer) AddTool(ctx context.Context, tool *types.Tool, handler ToolH
 		s.logger.Printf("Service handler called for tool: %s", originalName)
 
 		// Convert domain session to public session
-		pubSession := &types.ClientSession{
-			ID:        session.ID,
-			UserAgent: session.UserAgent,
-			Connected: session.Connected,
-		}
+		pubSession := convertToPublicSession(session)
 
 		// Create request and call the handler
 		request := ToolCallRequest{
@@ -98,11 +296,9 @@ func (s *MCPServer) AddTool(ctx context.Context, tool *types.Tool, handler ToolH
 		return handler(ctx, request)
 	}
 
-	// Get the service from the builder
-	service := s.builder.BuildService()
-
-	// Register with original name
-	service.RegisterToolHandler(originalName, serviceAdapter)
+	// Register with original name once the service is built, so that the
+	// server can still be configured after adding tools
+	s.builder.RegisterToolHandler(originalName, serviceAdapter)
 	s.logger.Printf("Registered tool: %s", originalName)
 
 	return nil
@@ -150,11 +346,7 @@ func (s *MCPServer) RegisterProvider(ctx context.Context, provider plugin.Provid
 		// Create an adapter to convert from our API to the internal API
 		serviceAdapter := func(ctx context.Context, params map[string]interface{}, session *domain.ClientSession) (interface{}, error) {
 			// Convert domain session to public session
-			pubSession := &types.ClientSession{
-				ID:        session.ID,
-				UserAgent: session.UserAgent,
-				Connected: session.Connected,
-			}
+			pubSession := convertToPublicSession(session)
 
 			// Create request and execute the tool through the provider
 			request := &plugin.ExecuteRequest{
@@ -169,6 +361,15 @@ func (s *MCPServer) RegisterProvider(ctx context.Context, provider plugin.Provid
 				return nil, fmt.Errorf("failed to get tool %s: %w", originalName, err)
 			}
 
+			// Keep within the provider's concurrency limit, if any
+			release, err := s.acquireProvider(ctx, provider)
+			if err != nil {
+				return nil, err
+			}
+			if release != nil {
+				defer release()
+			}
+
 			// Execute the tool through the provider
 			response, err := provider.ExecuteTool(ctx, request)
 			if err != nil {
@@ -182,17 +383,32 @@ func (s *MCPServer) RegisterProvider(ctx context.Context, provider plugin.Provid
 			return response.Content, nil
 		}
 
-		// Get the service from the builder
-		service := s.builder.BuildService()
-
-		// Register with original name
-		service.RegisterToolHandler(originalName, serviceAdapter)
+		// Register with original name once the service is built
+		s.builder.RegisterToolHandler(originalName, serviceAdapter)
 		s.logger.Printf("Registered tool: %s", originalName)
 	}
 
+	// Include the provider in readiness checks if it can report its health
+	if checker, ok := provider.(plugin.HealthChecker); ok {
+		if info, err := provider.GetProviderInfo(ctx); err == nil && info != nil {
+			s.AddHealthCheck(providerHealthCheckName(info.ID), checker)
+		}
+	}
+
 	return nil
 }
 
+// providerHealthCheckName returns the name of a provider's health check.
+func providerHealthCheckName(providerID string) string {
+	return "provider:" + providerID
+}
+
+// AddHealthCheck registers a health check that is run by the /readyz endpoint.
+// The server is reported as not ready while any check fails.
+func (s *MCPServer) AddHealthCheck(name string, checker plugin.HealthChecker) {
+	s.builder.AddHealthCheck(name, checker)
+}
+
 // UnregisterProvider removes a tool provider from the server.
 func (s *MCPServer) UnregisterProvider(ctx context.Context, providerID string) error {
 	// Get the provider first to retrieve its tools
@@ -218,6 +434,7 @@ func (s *MCPServer) UnregisterProvider(ctx context.Context, providerID string) e
 	if err != nil {
 		return fmt.Errorf("failed to unregister provider %s: %w", providerID, err)
 	}
+	s.builder.RemoveHealthCheck(providerHealthCheckName(providerID))
 
 	return nil
 }
@@ -247,8 +464,20 @@ func (s *MCPServer) ServeStdio() error {
 		s.logger.Printf("Available tools in the server: %v", toolHandlers)
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done, err := s.begin(func(context.Context) error {
+		cancel()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	defer s.end(done)
+
 	// Start the stdio server with our custom handler
-	return s.builder.ServeStdio(stdioOpts...)
+	return s.builder.ServeStdioContext(ctx, stdioOpts...)
 }
 
 // SetAddress sets the HTTP address for the server.
@@ -256,27 +485,445 @@ func (s *MCPServer) SetAddress(addr string) {
 	s.builder.WithAddress(addr)
 }
 
-// GetAddress returns the HTTP address for the server.
+// SetSessionStore sets the backend of the key/value stores that tool handlers
+// reach through ClientSession.Store. It defaults to an in-memory store; use
+// NewFileSessionStore with SetFileRepositories to keep session state across
+// restarts.
+func (s *MCPServer) SetSessionStore(store types.SessionStoreBackend) {
+	s.builder.WithSessionStore(store)
+}
+
+// SetFileRepositories keeps the tools, resources, prompts and sessions of the
+// server in JSON files under dir, so that those added at runtime survive
+// restarts, and restores the ones already there. Call it before adding tools,
+// resources or prompts and before serving. Restored tools are listed, but can
+// only be called once their handler is added again.
+func (s *MCPServer) SetFileRepositories(dir string) error {
+	repos, err := infraServer.OpenFileRepositories(dir)
+	if err != nil {
+		return err
+	}
+	s.builder.WithToolRepository(repos.Tools)
+	s.builder.WithResourceRepository(repos.Resources)
+	s.builder.WithPromptRepository(repos.Prompts)
+	s.builder.WithSessionRepository(repos.Sessions)
+	return nil
+}
+
+// SetSessionLimits disconnects sessions whose client was idle for longer than
+// the idle timeout, or that are older than the max lifetime, so that stuck
+// clients do not hold on to resources. Without an idle timeout, sessions of the
+// streamable HTTP transport are disconnected after being idle for
+// DefaultHTTPSessionIdleTimeout.
+func (s *MCPServer) SetSessionLimits(limits SessionLimits) {
+	s.builder.WithSessionLimits(limits)
+}
+
+// Sessions returns the sessions of connected clients, with what they reported
+// on initialize and when they were last seen.
+func (s *MCPServer) Sessions(ctx context.Context) ([]*types.ClientSession, error) {
+	sessions, err := s.builder.BuildService().ListSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	public := make([]*types.ClientSession, len(sessions))
+	for i, session := range sessions {
+		public[i] = convertToPublicSession(session)
+	}
+	return public, nil
+}
+
+// NotifySessions sends a notification to the clients of the sessions selected
+// by filter, e.g. every session of a tenant. Only clients with an open SSE
+// stream can receive notifications. With a notification bus, filters built
+// with SessionFilterFunc only notify the sessions of this instance and return
+// ErrFilterNotRelayable.
+func (s *MCPServer) NotifySessions(ctx context.Context, filter SessionFilter, method string, params map[string]interface{}) error {
+	return s.builder.BuildService().NotifySessions(ctx, filter, &domain.Notification{Method: method, Params: params})
+}
+
+// SetSessionLabeler sets the function that labels sessions when their client
+// initializes them, e.g. with the tenant of the principal, so that
+// notifications can be targeted with LabelFilter.
+func (s *MCPServer) SetSessionLabeler(labeler SessionLabeler) {
+	s.builder.WithSessionLabeler(func(ctx context.Context, session *domain.ClientSession) map[string]string {
+		return labeler(ctx, convertToPublicSession(session))
+	})
+}
+
+// DisconnectSession disconnects the client of a session, e.g. to kick a stuck
+// agent, and ends the session. The stdio session cannot be disconnected.
+func (s *MCPServer) DisconnectSession(ctx context.Context, sessionID string) error {
+	return s.builder.BuildService().DisconnectSession(ctx, sessionID)
+}
+
+// NotifySession sends a notification to the client of a session. Only clients
+// with an open SSE stream can receive notifications.
+func (s *MCPServer) NotifySession(ctx context.Context, sessionID, method string, params map[string]interface{}) error {
+	return s.builder.BuildService().NotifySession(ctx, sessionID, &domain.Notification{Method: method, Params: params})
+}
+
+// SessionAdminHandler returns an HTTP handler that lists sessions, disconnects
+// them and notifies their clients, under /sessions. It performs no
+// authorization, so mount it behind your own, e.g.
+// mux.Handle("/admin/", requireAdmin(http.StripPrefix("/admin", handler))).
+func (s *MCPServer) SessionAdminHandler() http.Handler {
+	return rest.NewSessionAdminHandler(s.builder.BuildService())
+}
+
+// SetAuthenticator requires every HTTP and SSE request to be authenticated.
+// The authenticated principal is available to tool handlers through the request
+// session. It panics once the transport is built by Handler or serving.
+func (s *MCPServer) SetAuthenticator(authenticator auth.Authenticator) {
+	s.builder.WithAuthenticator(auth.ToInternal(authenticator))
+}
+
+// SetOAuthResourceServer protects every HTTP and SSE endpoint with OAuth 2.1 access
+// tokens and serves the protected resource metadata document. It panics once
+// the transport is built by Handler or serving.
+func (s *MCPServer) SetOAuthResourceServer(rs *auth.OAuthResourceServer) {
+	s.builder.WithOAuthResourceServer(rs)
+}
+
+// SetToolPolicy restricts which tools each principal may list and call.
+// Tools denied to a principal are hidden from tools/list and calling them fails
+// with a forbidden error.
+func (s *MCPServer) SetToolPolicy(policy auth.ToolPolicy) {
+	s.builder.WithToolPolicy(auth.ToInternalPolicy(policy))
+}
+
+// SetPageSize splits the responses of tools/list, resources/list and
+// prompts/list into pages of at most size entries. Clients fetch the next page
+// by passing the nextCursor of a response as the cursor of the next request.
+// A size of 0, the default, lists all entries in one response.
+func (s *MCPServer) SetPageSize(size int) {
+	s.builder.WithPageSize(size)
+}
+
+// SetRateLimits limits how often tools may be called. Throttled calls fail with
+// a JSON-RPC rate limit error that tells the client when to retry; over HTTP
+// they are also answered with 429 Too Many Requests. HTTP requests without a
+// session ID are limited per principal, or per client address, instead of per
+// session. It returns an error if a limit allows no calls or has no period.
+func (s *MCPServer) SetRateLimits(config RateLimitConfig) error {
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid rate limits: %w", err)
+	}
+	s.builder.WithRateLimits(config)
+	return nil
+}
+
+// SetConcurrencyLimits bounds how many calls of each tool may run at the same
+// time. Calls beyond the limit are queued if configured, and otherwise fail
+// with a JSON-RPC busy error.
+func (s *MCPServer) SetConcurrencyLimits(config ConcurrencyConfig) {
+	s.builder.WithConcurrencyLimits(config)
+}
+
+// SetNotificationConfig sets the queue size of each session and the policy
+// applied when a client does not keep up with its notifications: drop the
+// newest or oldest notification, block for a while, or disconnect the client.
+// It must be called before serving or accessing sessions, and panics otherwise.
+func (s *MCPServer) SetNotificationConfig(config NotificationConfig) {
+	s.builder.WithNotificationConfig(config)
+}
+
+// SetProviderConcurrencyLimit bounds how many calls to the tools of a provider
+// may run at the same time, isolating a slow provider from the rest of the server.
+func (s *MCPServer) SetProviderConcurrencyLimit(providerID string, limit ConcurrencyLimit) {
+	s.providerMu.Lock()
+	defer s.providerMu.Unlock()
+	s.providerBulkheads[providerID] = usecases.NewBulkhead(providerID, "provider", limit)
+}
+
+// acquireProvider takes a slot in the provider's bulkhead. It returns a nil
+// release function if the provider has no concurrency limit.
+func (s *MCPServer) acquireProvider(ctx context.Context, provider plugin.Provider) (func(), error) {
+	info, err := provider.GetProviderInfo(ctx)
+	if err != nil || info == nil {
+		return nil, nil
+	}
+
+	s.providerMu.RLock()
+	bulkhead, ok := s.providerBulkheads[info.ID]
+	s.providerMu.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+	return bulkhead.Acquire(ctx)
+}
+
+// SetTracer records a span for every request and tool call. Tool handlers and
+// providers receive the span of their call in ctx, see tracing.SpanFromContext.
+func (s *MCPServer) SetTracer(tracer *tracing.Tracer) {
+	s.builder.WithTracer(tracer)
+}
+
+// SetTLS serves the HTTP transport over HTTPS. If config.ClientCAFile is set,
+// clients must present a certificate signed by one of its CAs, and the verified
+// identity is available as ClientSession.ClientIdentity in tool handlers. Use
+// auth.NewClientCertAuthenticator to also turn it into the session's principal.
+// It panics once the transport is built by Handler or serving.
+func (s *MCPServer) SetTLS(config TLSConfig) error {
+	tlsConfig, err := infraServer.NewTLSConfig(config)
+	if err != nil {
+		return fmt.Errorf("invalid TLS configuration: %w", err)
+	}
+	s.builder.WithTLS(tlsConfig)
+	return nil
+}
+
+// SetCORS sets the cross-origin policy of the HTTP and SSE endpoints. By
+// default cross-origin browser requests are rejected, and requests are only
+// accepted if sent to local host names, the host of the listen address or the
+// host of the base URL, which protects servers against DNS rebinding. Set
+// AllowedHosts on servers reached by other names. It panics once the transport
+// is built by Handler or serving.
+func (s *MCPServer) SetCORS(config CORSConfig) {
+	s.builder.WithCORS(config)
+}
+
+// SetLocalhostOnly binds the HTTP server to the loopback interface only. Unless
+// SetCORS is used, browser access is restricted to local origins as well, which
+// protects local servers against DNS rebinding. It panics once the transport is
+// built by Handler or serving.
+func (s *MCPServer) SetLocalhostOnly() {
+	s.builder.WithLocalhostOnly()
+}
+
+// GetAddress returns the HTTP address the server listens on. It does not build
+// the transport, so the HTTP settings can still be changed afterwards.
 func (s *MCPServer) GetAddress() string {
-	// Build the MCP server to get the address
-	restServer := s.builder.BuildMCPServer()
-	return restServer.GetAddress()
+	return s.builder.Address()
 }
 
-// ServeHTTP starts the HTTP server.
-func (s *MCPServer) ServeHTTP() error {
-	// Create an HTTP server with all our tools already registered through the builder
+// Handler returns an http.Handler serving the MCP endpoints, for mounting in an
+// existing server with its own router, TLS and middleware instead of calling
+// ServeHTTP. Requests must carry the full path, including the base path:
+//
+//	mux.Handle("/api/mcp/", mcpServer.Handler(server.HandlerOptions{BasePath: "/api/mcp"}))
+//
+// With SetOAuthResourceServer, the protected resource metadata is served at
+// /.well-known/oauth-protected-resource followed by the path of the resource,
+// e.g. /api/mcp, which must be routed to the handler as well.
+//
+// The handler is built once, by the first call to Handler or GetAddress.
+// Handler panics if called again with other options. Call Shutdown to drain
+// in-flight requests and close SSE streams before shutting down the server it
+// is mounted in. A mounted handler cannot be combined with ServeHTTP or Serve.
+func (s *MCPServer) Handler(opts HandlerOptions) http.Handler {
+	s.builder.WithBasePath(opts.BasePath).WithBaseURL(opts.BaseURL)
 	mcpServer := s.builder.BuildMCPServer()
 
-	// Start the HTTP server
-	return mcpServer.Start()
+	// Shutdown drains the mounted handler like a served transport. Nothing
+	// blocks while it is mounted, so serving is done from the start.
+	if done, err := s.begin(mcpServer.Stop); err == nil {
+		close(done)
+	}
+	return mcpServer.Handler()
+}
+
+// ServeHTTP starts the HTTP server and blocks until it is shut down. It returns
+// nil after a graceful Shutdown, and ErrServerRunning or ErrServerStopped if the
+// server is already serving or has been shut down.
+func (s *MCPServer) ServeHTTP() error {
+	return s.Serve(context.Background(), HTTPTransport())
 }
 
-// Shutdown gracefully shuts down the HTTP server.
+// Serve runs the given transports at the same time, e.g. stdio for a local IDE
+// and HTTP for remote agents, sharing the same tools, providers and sessions.
+// It blocks until every transport has stopped.
+//
+// The HTTP transports, HTTPTransport, SSETransport and StreamableHTTPTransport,
+// share one HTTP server on the address set with SetAddress.
+//
+// Canceling ctx or calling Shutdown stops all transports gracefully, in which
+// case Serve returns nil. If a transport fails, the others are stopped and
+// Serve returns the error of the transport that failed first. A transport that
+// stops on its own without an error, e.g. stdio once its input is closed, does
+// not stop the others.
+func (s *MCPServer) Serve(ctx context.Context, transports ...Transport) error {
+	if len(transports) == 0 {
+		return errors.New("no transports to serve")
+	}
+
+	seen := make(map[string]bool, len(transports))
+	for _, transport := range transports {
+		if seen[transport.Name()] {
+			return fmt.Errorf("transport %s is given more than once", transport.Name())
+		}
+		seen[transport.Name()] = true
+	}
+
+	// The builder is configured before opening, so a server that has already
+	// been built is reported rather than reconfigured
+	if err := s.checkNew(); err != nil {
+		return err
+	}
+	transports = s.shareHTTPServer(transports)
+
+	runners := make([]transportRunner, len(transports))
+	for i, transport := range transports {
+		runner, err := transport.open(s)
+		if err != nil {
+			return fmt.Errorf("failed to open %s transport: %w", transport.Name(), err)
+		}
+		runners[i] = runner
+	}
+
+	done, err := s.begin(func(ctx context.Context) error {
+		return stopTransports(ctx, runners)
+	})
+	if err != nil {
+		return err
+	}
+	defer s.end(done)
+
+	type result struct {
+		name string
+		err  error
+	}
+	results := make(chan result, len(runners))
+	for i, runner := range runners {
+		go func(name string, serve func() error) {
+			results <- result{name: name, err: serve()}
+		}(transports[i].Name(), runner.serve)
+	}
+
+	var serveErr error
+	ctxDone := ctx.Done()
+	for remaining := len(runners); remaining > 0; {
+		select {
+		case res := <-results:
+			remaining--
+			if res.err == nil || serveErr != nil {
+				continue
+			}
+			serveErr = fmt.Errorf("%s transport failed: %w", res.name, res.err)
+			s.logger.Printf("Stopping all transports: %v", serveErr)
+			s.stopAfterFailure(ctx)
+		case <-ctxDone:
+			ctxDone = nil
+			// Stopping must not be cut short by the context that requested it
+			if stop, _, err := s.stopping(); err == nil {
+				if err := stop(context.WithoutCancel(ctx)); err != nil {
+					s.logger.Printf("Error stopping transports: %v", err)
+				}
+			}
+		}
+	}
+	return serveErr
+}
+
+// stopAfterFailure stops the remaining transports once one of them has failed.
+func (s *MCPServer) stopAfterFailure(ctx context.Context) {
+	stop, _, err := s.stopping()
+	if err != nil {
+		// Already shutting down
+		return
+	}
+	go func() {
+		if err := stop(context.WithoutCancel(ctx)); err != nil {
+			s.logger.Printf("Error stopping transports: %v", err)
+		}
+	}()
+}
+
+// stopTransports stops the transports concurrently and joins their errors.
+func stopTransports(ctx context.Context, runners []transportRunner) error {
+	errs := make([]error, len(runners))
+	var wg sync.WaitGroup
+	for i, runner := range runners {
+		wg.Add(1)
+		go func(i int, stop func(ctx context.Context) error) {
+			defer wg.Done()
+			errs[i] = stop(ctx)
+		}(i, runner.stop)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Shutdown gracefully shuts down the transports that are serving, letting
+// in-flight requests complete until ctx is done, and waits for the serving call
+// to return. It returns ErrServerNotRunning if the server was never started,
+// and ErrServerStopped if it has already been shut down.
 func (s *MCPServer) Shutdown(ctx context.Context) error {
-	// Build the MCP server to get access to the Stop method
-	mcpServer := s.builder.BuildMCPServer()
-	return mcpServer.Stop(ctx)
+	stop, done, err := s.stopping()
+	if err != nil {
+		return err
+	}
+
+	err = stop(ctx)
+
+	// Wait for the serving call to return, so that the transport has been released
+	select {
+	case <-done:
+	case <-ctx.Done():
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+	return err
+}
+
+// begin moves the server to the running state, recording how to stop the
+// transport. The returned channel must be passed to end once serving returns.
+func (s *MCPServer) begin(stop func(ctx context.Context) error) (chan struct{}, error) {
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+
+	if err := s.stateErr(); err != nil {
+		return nil, err
+	}
+	s.state = stateRunning
+	s.stop = stop
+	s.done = make(chan struct{})
+	return s.done, nil
+}
+
+// checkNew returns ErrServerRunning or ErrServerStopped if the server is
+// already serving or has been shut down.
+func (s *MCPServer) checkNew() error {
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+	return s.stateErr()
+}
+
+// stateErr is checkNew with lifecycleMu held.
+func (s *MCPServer) stateErr() error {
+	switch s.state {
+	case stateRunning:
+		return ErrServerRunning
+	case stateStopped:
+		return ErrServerStopped
+	}
+	return nil
+}
+
+// stopping moves a running server to the stopped state, returning how to stop
+// its transports and the channel that is closed once serving has returned.
+func (s *MCPServer) stopping() (func(ctx context.Context) error, chan struct{}, error) {
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+
+	switch s.state {
+	case stateNew:
+		return nil, nil, ErrServerNotRunning
+	case stateStopped:
+		return nil, nil, ErrServerStopped
+	}
+	s.state = stateStopped
+	return s.stop, s.done, nil
+}
+
+// end records that serving has returned. A transport that failed on its own,
+// e.g. because its address is in use, leaves the server stopped.
+func (s *MCPServer) end(done chan struct{}) {
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+	s.state = stateStopped
+	close(done)
 }
 
 // Helper function to convert a public tool to an internal tool
@@ -299,3 +946,37 @@ func convertToInternalTool(tool *types.Tool) *domain.Tool {
 
 	return internalTool
 }
+
+// Helper function to convert an internal session to a public session
+func convertToPublicSession(session *domain.ClientSession) *types.ClientSession {
+	if session == nil {
+		return nil
+	}
+
+	// Hand the same public session to every handler of the session
+	if public, ok := session.State.Value(publicSessionKey{}).(*types.ClientSession); ok {
+		return public
+	}
+
+	public := &types.ClientSession{
+		ID:                 session.ID,
+		UserAgent:          session.UserAgent,
+		Transport:          session.Transport,
+		Connected:          session.Connected,
+		Principal:          auth.ToPublicPrincipal(session.Principal),
+		ClientIdentity:     session.ClientIdentity,
+		ClientInfo:         session.ClientInfo,
+		ClientCapabilities: session.ClientCapabilities,
+		ProtocolVersion:    session.ProtocolVersion,
+		CreatedAt:          session.CreatedAt,
+		State:              session.State,
+	}
+	// What the client reported is only complete once the session is initialized
+	if session.Initialized() {
+		session.State.SetValue(publicSessionKey{}, public)
+	}
+	return public
+}
+
+// publicSessionKey is the session state key of the public representation of a session.
+type publicSessionKey struct{}
@@ -0,0 +1,557 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FreePeak/cortex/internal/domain"
+	"github.com/FreePeak/cortex/pkg/auth"
+	"github.com/FreePeak/cortex/pkg/types"
+)
+
+// newTestServer creates a server with an echo tool that discards its logs.
+func newTestServer(t *testing.T) *MCPServer {
+	t.Helper()
+	s := NewMCPServer("test", "1.0.0", log.New(io.Discard, "", 0))
+	err := s.AddTool(context.Background(), &types.Tool{Name: "echo", Description: "Echoes"}, func(ctx context.Context, request ToolCallRequest) (interface{}, error) {
+		return "ok", nil
+	})
+	if err != nil {
+		t.Fatalf("AddTool error = %v", err)
+	}
+	return s
+}
+
+// callTool calls a tool over HTTP and returns the status code and the JSON-RPC
+// error of the response, if any.
+func callTool(t *testing.T, url, name string) (int, map[string]interface{}) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/call",
+		"params":  map[string]interface{}{"name": name, "arguments": map[string]interface{}{}},
+	})
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Error map[string]interface{} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	return resp.StatusCode, response.Error
+}
+
+func TestMCPServer_SettingsAfterAddTool(t *testing.T) {
+	s := newTestServer(t)
+	if err := s.SetRateLimits(RateLimitConfig{Global: &RateLimit{Requests: 1, Per: time.Hour}}); err != nil {
+		t.Fatalf("SetRateLimits error = %v", err)
+	}
+
+	ts := httptest.NewServer(s.Handler(HandlerOptions{}))
+	defer ts.Close()
+
+	if status, rpcErr := callTool(t, ts.URL, "echo"); status != http.StatusOK || rpcErr != nil {
+		t.Fatalf("first call = %d %v, want 200 without error", status, rpcErr)
+	}
+	if status, _ := callTool(t, ts.URL, "echo"); status != http.StatusTooManyRequests {
+		t.Errorf("second call = %d, want %d", status, http.StatusTooManyRequests)
+	}
+}
+
+func TestMCPServer_SettingsWhileServing(t *testing.T) {
+	s := newTestServer(t)
+	ts := httptest.NewServer(s.Handler(HandlerOptions{}))
+	defer ts.Close()
+
+	if status, rpcErr := callTool(t, ts.URL, "echo"); status != http.StatusOK || rpcErr != nil {
+		t.Fatalf("call before policy = %d %v, want 200 without error", status, rpcErr)
+	}
+
+	s.SetToolPolicy(auth.ToolPolicyFunc(func(principal *types.Principal, toolName string) bool { return false }))
+	if _, rpcErr := callTool(t, ts.URL, "echo"); rpcErr == nil {
+		t.Error("call after policy succeeded, want a forbidden error")
+	}
+}
+
+func TestMCPServer_NotificationConfigAfterBuild(t *testing.T) {
+	s := newTestServer(t)
+	// Adding tools leaves the server configurable
+	s.SetNotificationConfig(NotificationConfig{BufferSize: 8})
+
+	if _, err := s.Sessions(context.Background()); err != nil {
+		t.Fatalf("Sessions error = %v", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("SetNotificationConfig after build did not panic")
+		}
+	}()
+	s.SetNotificationConfig(NotificationConfig{BufferSize: 16})
+}
+
+// freeAddress returns a loopback address that nothing listens on.
+func freeAddress(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen error = %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	return addr
+}
+
+// waitServing waits until the HTTP transport answers on addr.
+func waitServing(t *testing.T, addr string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		resp, err := http.Get("http://" + addr + "/healthz")
+		if err == nil {
+			resp.Body.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("server did not start on %s", addr)
+}
+
+// serveAsync runs Serve in the background and returns the channel its error is sent on.
+func serveAsync(ctx context.Context, s *MCPServer, transports ...Transport) <-chan error {
+	errc := make(chan error, 1)
+	go func() { errc <- s.Serve(ctx, transports...) }()
+	return errc
+}
+
+// waitServe returns the error of a Serve call started with serveAsync.
+func waitServe(t *testing.T, errc <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-errc:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return")
+		return nil
+	}
+}
+
+// pipeStdin replaces standard input with a pipe for the duration of the test,
+// so that the stdio transport runs until the returned writer is closed.
+func pipeStdin(t *testing.T) *os.File {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe error = %v", err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		w.Close()
+		r.Close()
+	})
+	return w
+}
+
+func TestMCPServer_ServeDuplicateTransports(t *testing.T) {
+	s := newTestServer(t)
+	err := s.Serve(context.Background(), HTTPTransport(), HTTPTransport())
+	if err == nil || !strings.Contains(err.Error(), "more than once") {
+		t.Fatalf("Serve error = %v, want a duplicate transport error", err)
+	}
+	if err := s.Serve(context.Background()); err == nil {
+		t.Error("Serve without transports succeeded")
+	}
+}
+
+func TestMCPServer_ServeFailureStopsOtherTransports(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen error = %v", err)
+	}
+	defer busy.Close()
+	pipeStdin(t)
+
+	s := newTestServer(t)
+	s.SetAddress(busy.Addr().String())
+
+	// The stdio transport would run until its input is closed
+	err = waitServe(t, serveAsync(context.Background(), s, StdioTransport(), HTTPTransport()))
+	if err == nil || !strings.Contains(err.Error(), "http transport failed") {
+		t.Fatalf("Serve error = %v, want the error of the HTTP transport", err)
+	}
+}
+
+func TestMCPServer_ServeContextCanceled(t *testing.T) {
+	s := newTestServer(t)
+	addr := freeAddress(t)
+	s.SetAddress(addr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := serveAsync(ctx, s, HTTPTransport())
+	waitServing(t, addr)
+	cancel()
+	if err := waitServe(t, errc); err != nil {
+		t.Fatalf("Serve error = %v, want nil after cancel", err)
+	}
+	if _, err := http.Get("http://" + addr + "/healthz"); err == nil {
+		t.Error("HTTP transport still serving after cancel")
+	}
+}
+
+func TestMCPServer_ServeSeparateHTTPTransports(t *testing.T) {
+	tests := []struct {
+		name       string
+		transports []Transport
+		sse, http  bool
+	}{
+		{"sse", []Transport{SSETransport()}, true, false},
+		{"streamable http", []Transport{StreamableHTTPTransport()}, false, true},
+		{"both on one server", []Transport{SSETransport(), StreamableHTTPTransport()}, true, true},
+	}
+
+	// Report redirects to the SSE endpoint instead of following them
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	status := func(t *testing.T, method, url, body string) int {
+		t.Helper()
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("NewRequest error = %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s error = %v", method, url, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			addr := freeAddress(t)
+			s.SetAddress(addr)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			errc := serveAsync(ctx, s, tt.transports...)
+			waitServing(t, addr)
+
+			sse := status(t, http.MethodGet, "http://"+addr+"/events", "") == http.StatusFound
+			streamable := status(t, http.MethodPost, "http://"+addr+"/jsonrpc", `{"jsonrpc":"2.0","id":1,"method":"ping"}`) == http.StatusOK
+			if sse != tt.sse || streamable != tt.http {
+				t.Errorf("serves SSE = %v, streamable HTTP = %v, want %v and %v", sse, streamable, tt.sse, tt.http)
+			}
+
+			cancel()
+			if err := waitServe(t, errc); err != nil {
+				t.Fatalf("Serve error = %v", err)
+			}
+		})
+	}
+}
+
+func TestMCPServer_ServeAfterHandler(t *testing.T) {
+	s := newTestServer(t)
+	s.Handler(HandlerOptions{})
+	if err := s.Serve(context.Background(), SSETransport()); !errors.Is(err, ErrServerRunning) {
+		t.Fatalf("Serve error = %v, want ErrServerRunning", err)
+	}
+}
+
+func TestMCPServer_ShutdownWhileServing(t *testing.T) {
+	w := pipeStdin(t)
+	defer w.Close()
+
+	s := newTestServer(t)
+	addr := freeAddress(t)
+	s.SetAddress(addr)
+
+	errc := serveAsync(context.Background(), s, StdioTransport(), HTTPTransport())
+	waitServing(t, addr)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown error = %v", err)
+	}
+	if err := waitServe(t, errc); err != nil {
+		t.Fatalf("Serve error = %v, want nil after Shutdown", err)
+	}
+}
+
+func TestMCPServer_ServeKeepsStdoutClean(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe error = %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	stdin := pipeStdin(t)
+
+	s := newTestServer(t)
+	addr := freeAddress(t)
+	s.SetAddress(addr)
+
+	errc := serveAsync(context.Background(), s, StdioTransport(), HTTPTransport())
+	waitServing(t, addr)
+	stdin.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil && !errors.Is(err, ErrServerStopped) {
+		t.Fatalf("Shutdown error = %v", err)
+	}
+	waitServe(t, errc)
+
+	os.Stdout = stdout
+	w.Close()
+	written, _ := io.ReadAll(r)
+	if len(written) > 0 {
+		t.Errorf("stdout = %q, want nothing without JSON-RPC messages", written)
+	}
+}
+
+func TestMCPServer_HandlerOAuthMetadataPath(t *testing.T) {
+	issuer, err := auth.NewLocalIssuer("https://auth.example.com")
+	if err != nil {
+		t.Fatalf("NewLocalIssuer error = %v", err)
+	}
+	rs, err := auth.NewOAuthResourceServer(auth.OAuthConfig{
+		Resource:             "https://example.com/api/mcp",
+		AuthorizationServers: []string{issuer.Issuer()},
+		Keys:                 issuer.KeySet(),
+	})
+	if err != nil {
+		t.Fatalf("NewOAuthResourceServer error = %v", err)
+	}
+
+	s := newTestServer(t)
+	s.SetOAuthResourceServer(rs)
+	ts := httptest.NewServer(s.Handler(HandlerOptions{BasePath: "/api/mcp"}))
+	defer ts.Close()
+
+	// The metadata is found by inserting the well-known path before the resource path
+	resp, err := http.Get(ts.URL + "/.well-known/oauth-protected-resource/api/mcp")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("metadata status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	resp, err = http.Post(ts.URL+"/api/mcp/", "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	if err != nil {
+		t.Fatalf("POST error = %v", err)
+	}
+	resp.Body.Close()
+	want := `resource_metadata="https://example.com/.well-known/oauth-protected-resource/api/mcp"`
+	if challenge := resp.Header.Get("WWW-Authenticate"); !strings.Contains(challenge, want) {
+		t.Errorf("WWW-Authenticate = %q, want it to contain %s", challenge, want)
+	}
+}
+
+func TestMCPServer_HandlerOptionsAfterBuild(t *testing.T) {
+	s := newTestServer(t)
+	s.Handler(HandlerOptions{})
+	// The same options can be passed again
+	s.Handler(HandlerOptions{})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Handler with another base path after build did not panic")
+		}
+	}()
+	s.Handler(HandlerOptions{BasePath: "/api/mcp"})
+}
+
+func TestMCPServer_SettingsAfterGetAddress(t *testing.T) {
+	s := newTestServer(t)
+	s.SetAddress("127.0.0.1:9090")
+	if addr := s.GetAddress(); addr != "127.0.0.1:9090" {
+		t.Errorf("GetAddress() = %q, want %q", addr, "127.0.0.1:9090")
+	}
+
+	// GetAddress does not build the transport, so the authenticator applies
+	s.SetAuthenticator(auth.NewAPIKeyAuthenticator(map[string]*types.Principal{"secret": {ID: "alice"}}))
+	ts := httptest.NewServer(s.Handler(HandlerOptions{}))
+	defer ts.Close()
+	resp, err := http.Post(ts.URL+"/", "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	if err != nil {
+		t.Fatalf("POST error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("unauthenticated tools/list status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	// Transport settings are not silently dropped once it is built
+	defer func() {
+		if recover() == nil {
+			t.Error("SetAuthenticator after build did not panic")
+		}
+	}()
+	s.SetAuthenticator(auth.NewAPIKeyAuthenticator(nil))
+}
+
+func TestMCPServer_RejectsReboundHosts(t *testing.T) {
+	// Servers listening on every interface are protected like loopback ones
+	for _, addr := range []string{"127.0.0.1:0", ":8080"} {
+		t.Run(addr, func(t *testing.T) {
+			s := newTestServer(t)
+			s.SetAddress(addr)
+			ts := httptest.NewServer(s.Handler(HandlerOptions{}))
+			defer ts.Close()
+
+			if status, rpcErr := callTool(t, ts.URL, "echo"); status != http.StatusOK || rpcErr != nil {
+				t.Fatalf("local call = %d %v, want 200 without error", status, rpcErr)
+			}
+
+			// A page whose name resolves to the server cannot reach it
+			r, _ := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+			r.Host = "attacker.example"
+			r.Header.Set("Origin", "http://attacker.example")
+			resp, err := http.DefaultClient.Do(r)
+			if err != nil {
+				t.Fatalf("POST error = %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusForbidden {
+				t.Errorf("rebound call = %d, want %d", resp.StatusCode, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestMCPServer_ShutdownBeforeStart(t *testing.T) {
+	s := newTestServer(t)
+	if err := s.Shutdown(context.Background()); !errors.Is(err, ErrServerNotRunning) {
+		t.Fatalf("Shutdown error = %v, want %v", err, ErrServerNotRunning)
+	}
+	// The server can still be started afterwards
+	addr := freeAddress(t)
+	s.SetAddress(addr)
+	errc := serveAsync(context.Background(), s, HTTPTransport())
+	waitServing(t, addr)
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown error = %v", err)
+	}
+	waitServe(t, errc)
+}
+
+func TestMCPServer_ServeHTTPShutdown(t *testing.T) {
+	s := newTestServer(t)
+	addr := freeAddress(t)
+	s.SetAddress(addr)
+
+	errc := make(chan error, 1)
+	go func() { errc <- s.ServeHTTP() }()
+	waitServing(t, addr)
+
+	// Serving is exclusive while running
+	if err := s.Serve(context.Background(), StdioTransport()); !errors.Is(err, ErrServerRunning) {
+		t.Errorf("Serve while serving error = %v, want %v", err, ErrServerRunning)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown error = %v", err)
+	}
+	if err := waitServe(t, errc); err != nil {
+		t.Fatalf("ServeHTTP error = %v, want nil after Shutdown", err)
+	}
+	if _, err := http.Get("http://" + addr + "/healthz"); err == nil {
+		t.Error("HTTP transport still serving after Shutdown")
+	}
+
+	// A server that has been shut down cannot be started or shut down again
+	if err := s.ServeHTTP(); !errors.Is(err, ErrServerStopped) {
+		t.Errorf("ServeHTTP after Shutdown error = %v, want %v", err, ErrServerStopped)
+	}
+	if err := s.Serve(context.Background(), HTTPTransport()); !errors.Is(err, ErrServerStopped) {
+		t.Errorf("Serve after Shutdown error = %v, want %v", err, ErrServerStopped)
+	}
+	if err := s.Shutdown(ctx); !errors.Is(err, ErrServerStopped) {
+		t.Errorf("second Shutdown error = %v, want %v", err, ErrServerStopped)
+	}
+}
+
+func TestMCPServer_ShutdownMountedHandler(t *testing.T) {
+	s := newTestServer(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	err := s.AddTool(context.Background(), &types.Tool{Name: "slow", Description: "Waits to be released"}, func(ctx context.Context, request ToolCallRequest) (interface{}, error) {
+		close(started)
+		<-release
+		return "done", nil
+	})
+	if err != nil {
+		t.Fatalf("AddTool error = %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/mcp/", s.Handler(HandlerOptions{BasePath: "/api/mcp"}))
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	type callResult struct {
+		status int
+		err    map[string]interface{}
+	}
+	calls := make(chan callResult, 1)
+	go func() {
+		status, rpcErr := callTool(t, ts.URL+"/api/mcp/", "slow")
+		calls <- callResult{status, rpcErr}
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- s.Shutdown(ctx)
+	}()
+
+	// Shutdown waits for the tool call in flight
+	select {
+	case err := <-shutdown:
+		t.Fatalf("Shutdown returned with a tool call in flight: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+
+	if err := <-shutdown; err != nil {
+		t.Fatalf("Shutdown error = %v", err)
+	}
+	if call := <-calls; call.status != http.StatusOK || call.err != nil {
+		t.Errorf("in-flight tool call = %d %v, want a result", call.status, call.err)
+	}
+
+	// New requests are rejected once the handler is drained
+	if _, rpcErr := callTool(t, ts.URL+"/api/mcp/", "echo"); rpcErr == nil || rpcErr["code"] != float64(domain.BusyCode) {
+		t.Errorf("tool call after Shutdown error = %v, want a busy error", rpcErr)
+	}
+	if err := s.Shutdown(context.Background()); !errors.Is(err, ErrServerStopped) {
+		t.Errorf("second Shutdown error = %v, want %v", err, ErrServerStopped)
+	}
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/FreePeak/cortex/internal/domain"
+	"github.com/FreePeak/cortex/internal/interfaces/stdio"
+)
+
+// Transport is a way for clients to reach the server. Pass one or more
+// transports to Serve to run them at the same time, sharing the same tools,
+// providers and sessions.
+type Transport interface {
+	// Name identifies the transport in logs and errors
+	Name() string
+
+	// open prepares the transport to serve s
+	open(s *MCPServer) (transportRunner, error)
+}
+
+// transportRunner runs an opened transport.
+type transportRunner struct {
+	serve func() error                    // Blocks until the transport stops
+	stop  func(ctx context.Context) error // Stops the transport gracefully
+}
+
+// HTTPTransport returns the HTTP transport, which listens on the address set
+// with SetAddress. It serves the SSE transport on /sse and /message, the
+// streamable HTTP transport on / and /jsonrpc, as well as the health and
+// metrics endpoints.
+func HTTPTransport() Transport {
+	return httpTransport{name: "http", transports: []string{domain.TransportSSE, domain.TransportHTTP}}
+}
+
+// SSETransport returns the SSE transport, which serves an SSE stream on /sse
+// and its message endpoint on /message of the HTTP server, as well as the
+// health and metrics endpoints.
+func SSETransport() Transport {
+	return httpTransport{name: "sse", transports: []string{domain.TransportSSE}}
+}
+
+// StreamableHTTPTransport returns the streamable HTTP transport, which serves
+// JSON-RPC over HTTP POST on / and /jsonrpc of the HTTP server, as well as the
+// health and metrics endpoints.
+func StreamableHTTPTransport() Transport {
+	return httpTransport{name: "streamable-http", transports: []string{domain.TransportHTTP}}
+}
+
+// httpTransport serves some of the HTTP transports. The HTTP transports given
+// to Serve share a single HTTP server and address.
+type httpTransport struct {
+	name       string
+	transports []string
+}
+
+func (t httpTransport) Name() string {
+	return t.name
+}
+
+func (httpTransport) open(s *MCPServer) (transportRunner, error) {
+	mcpServer := s.builder.BuildMCPServer()
+	return transportRunner{
+		serve: func() error {
+			if err := mcpServer.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+		stop: mcpServer.Stop,
+	}, nil
+}
+
+// shareHTTPServer replaces the HTTP transports by one that serves the endpoints
+// of all of them on the same HTTP server, and configures the builder for it.
+func (s *MCPServer) shareHTTPServer(transports []Transport) []Transport {
+	shared := httpTransport{}
+	var names []string
+	result := make([]Transport, 0, len(transports))
+	for _, transport := range transports {
+		t, ok := transport.(httpTransport)
+		if !ok {
+			result = append(result, transport)
+			continue
+		}
+		if names == nil {
+			// Keep the place of the first HTTP transport
+			result = append(result, nil)
+		}
+		names = append(names, t.name)
+		shared.transports = append(shared.transports, t.transports...)
+	}
+	if names == nil {
+		return transports
+	}
+
+	shared.name = strings.Join(names, "+")
+	for i, transport := range result {
+		if transport == nil {
+			result[i] = shared
+		}
+	}
+	s.builder.WithHTTPTransports(shared.transports...)
+	return result
+}
+
+// StdioTransport returns the stdio transport, which reads JSON-RPC messages
+// from standard input and writes responses to standard output. It stops when
+// standard input is closed, without stopping the other transports.
+//
+// The server logs to stderr, as stdout is reserved for JSON-RPC messages.
+func StdioTransport() Transport {
+	return stdioTransport{}
+}
+
+type stdioTransport struct{}
+
+func (stdioTransport) Name() string {
+	return "stdio"
+}
+
+func (stdioTransport) open(s *MCPServer) (transportRunner, error) {
+	stdioServer := s.builder.BuildStdioServer(stdio.WithErrorLogger(s.logger))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return transportRunner{
+		serve: func() error {
+			defer cancel()
+			if err := stdioServer.Listen(ctx, os.Stdin, os.Stdout); err != nil && !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		},
+		stop: func(context.Context) error {
+			// The message in flight may complete within the stdio shutdown timeout
+			cancel()
+			return nil
+		},
+	}, nil
+}
@@ -0,0 +1,95 @@
+// Package tracing records a span for every JSON-RPC request and tool call of
+// the MCP server. Spans follow the OpenTelemetry data model and join the
+// caller's trace through the W3C traceparent header or the traceparent key
+// of the request's _meta.
+//
+// Tool handlers and providers receive the span of their tool call in ctx, and
+// can add attributes to it or start child spans:
+//
+//	span := tracing.SpanFromContext(ctx)
+//	span.SetAttribute("db.rows", rows)
+package tracing
+
+import (
+	"github.com/FreePeak/cortex/internal/domain"
+	internalTracing "github.com/FreePeak/cortex/internal/infrastructure/tracing"
+)
+
+// Tracer creates spans and hands them to an Exporter once they end.
+type Tracer = internalTracing.Tracer
+
+// NewTracer creates a new Tracer that exports spans to the given exporter.
+var NewTracer = internalTracing.NewTracer
+
+// Span is an operation within a trace.
+type Span = internalTracing.Span
+
+// SpanData is the record of an ended span that is passed to exporters.
+type SpanData = internalTracing.SpanData
+
+// SpanContext identifies a span within a trace.
+type SpanContext = domain.SpanContext
+
+// SpanKind describes the relationship of a span to its parent.
+type SpanKind = internalTracing.SpanKind
+
+// Available span kinds
+const (
+	SpanKindInternal = internalTracing.SpanKindInternal
+	SpanKindServer   = internalTracing.SpanKindServer
+	SpanKindClient   = internalTracing.SpanKindClient
+)
+
+// StatusCode is the status of a span.
+type StatusCode = internalTracing.StatusCode
+
+// Available status codes
+const (
+	StatusUnset = internalTracing.StatusUnset
+	StatusOK    = internalTracing.StatusOK
+	StatusError = internalTracing.StatusError
+)
+
+// Span attribute keys set by the server
+const (
+	AttrRPCSystem    = internalTracing.AttrRPCSystem
+	AttrRPCMethod    = internalTracing.AttrRPCMethod
+	AttrRPCErrorCode = internalTracing.AttrRPCErrorCode
+	AttrMCPMethod    = internalTracing.AttrMCPMethod
+	AttrToolName     = internalTracing.AttrToolName
+	AttrSessionID    = internalTracing.AttrSessionID
+	AttrErrorType    = internalTracing.AttrErrorType
+)
+
+// Exporter receives spans once they end, e.g. to send them to a tracing backend.
+type Exporter = internalTracing.Exporter
+
+// ExporterFunc is a function type that implements Exporter
+type ExporterFunc = internalTracing.ExporterFunc
+
+// InMemoryExporter keeps exported spans in memory, for use in tests.
+type InMemoryExporter = internalTracing.InMemoryExporter
+
+// NewInMemoryExporter creates a new, empty InMemoryExporter.
+var NewInMemoryExporter = internalTracing.NewInMemoryExporter
+
+// WriterExporter writes each span as a line of JSON.
+type WriterExporter = internalTracing.WriterExporter
+
+// NewWriterExporter creates a new WriterExporter that writes to w.
+var NewWriterExporter = internalTracing.NewWriterExporter
+
+// Context helpers
+var (
+	// SpanFromContext returns the current span stored in ctx, or nil if there is none
+	SpanFromContext = internalTracing.SpanFromContext
+
+	// ContextWithSpan returns a copy of ctx that carries the span
+	ContextWithSpan = internalTracing.ContextWithSpan
+
+	// Extract returns a copy of ctx that carries the trace context of an HTTP header
+	Extract = internalTracing.Extract
+
+	// Inject sets the traceparent header of an outgoing HTTP request from ctx
+	Inject = internalTracing.Inject
+)
@@ -3,8 +3,11 @@ package types
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/FreePeak/cortex/internal/domain"
 )
 
 // ClientSession represents an active client connection to the MCP server.
@@ -12,14 +15,123 @@ type ClientSession struct {
 	ID        string
 	UserAgent string
 	Connected bool
+	Principal *Principal // Authenticated caller, nil for unauthenticated transports
+	Transport string     // Transport that carries the session: "stdio", "sse" or "http"
+
+	// ClientIdentity is the verified TLS client certificate, nil without mutual TLS
+	ClientIdentity *ClientIdentity
+
+	// Reported by the client on initialize
+	ClientInfo         ClientInfo
+	ClientCapabilities map[string]interface{}
+	ProtocolVersion    string
+
+	CreatedAt time.Time
+
+	// State holds the mutable state of the session, such as values stored by
+	// handlers, shared by every handler that receives the session.
+	State *SessionState
+}
+
+// LastSeen returns when the client last sent a request in this session.
+func (s *ClientSession) LastSeen() time.Time {
+	return s.State.LastSeen()
+}
+
+// Store returns the key/value store in which tool handlers keep state for the
+// session, such as cursors or upstream tokens. It is cleared when the session
+// ends, and nil if the server has no session store.
+func (s *ClientSession) Store() *SessionStore {
+	return s.State.Store()
+}
+
+// Label returns the value of a label of the session, and whether it is set.
+func (s *ClientSession) Label(key string) (string, bool) {
+	return s.State.Label(key)
+}
+
+// Labels returns a copy of the labels of the session.
+func (s *ClientSession) Labels() map[string]string {
+	return s.State.Labels()
+}
+
+// Subscribe subscribes the session to a topic, so that notifications sent to
+// the subscribers of the topic reach its client.
+func (s *ClientSession) Subscribe(topic string) {
+	s.State.Subscribe(topic)
+}
+
+// Unsubscribe unsubscribes the session from a topic.
+func (s *ClientSession) Unsubscribe(topic string) {
+	s.State.Unsubscribe(topic)
+}
+
+// Subscribed reports whether the session is subscribed to a topic.
+func (s *ClientSession) Subscribed(topic string) bool {
+	return s.State.Subscribed(topic)
+}
+
+// SessionStore is the key/value state of one session, with optional expiry.
+type SessionStore = domain.SessionStore
+
+// SessionStoreBackend persists the key/value state of sessions. Implement it to
+// keep session state in an external store such as Redis.
+type SessionStoreBackend = domain.SessionStoreBackend
+
+// ClientInfo is the name and version of the client implementation.
+type ClientInfo = domain.ClientInfo
+
+// SessionState is the mutable state of a session. Handlers can keep per-session
+// values in it with SetValue and Value. It is safe for concurrent use.
+type SessionState = domain.SessionState
+
+// ClientIdentity is the identity of a client, taken from the TLS client
+// certificate it presented and the server verified during a mutual TLS handshake.
+type ClientIdentity = domain.ClientIdentity
+
+// Principal represents an authenticated caller of the MCP server.
+type Principal struct {
+	ID     string
+	Roles  []string
+	Scopes []string
+	Claims map[string]interface{}
+}
+
+// HasRole reports whether the principal has been granted the given role.
+func (p *Principal) HasRole(role string) bool {
+	if p == nil {
+		return false
+	}
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the principal has been granted the given scope.
+func (p *Principal) HasScope(scope string) bool {
+	if p == nil {
+		return false
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
 }
 
 // NewClientSession creates a new ClientSession with a unique ID.
 func NewClientSession(userAgent string) *ClientSession {
+	now := time.Now()
 	return &ClientSession{
 		ID:        uuid.New().String(),
 		UserAgent: userAgent,
 		Connected: true,
+		CreatedAt: now,
+		State:     domain.NewSessionState(now),
 	}
 }
 
@@ -103,6 +215,11 @@ type Notification struct {
 	Params map[string]interface{}
 }
 
+// ErrNotFound is matched, with errors.Is, by the errors that repositories
+// return for missing tools, resources, prompts and sessions. Custom
+// repositories should return errors that match it too, e.g. by wrapping it.
+var ErrNotFound = domain.ErrNotFound
+
 // ResourceRepository defines the interface for managing resources.
 type ResourceRepository interface {
 	// GetResource retrieves a resource by its URI.
@@ -148,6 +265,36 @@ type PromptRepository interface {
 	DeletePrompt(ctx context.Context, name string) error
 }
 
+// PagedResourceRepository is a ResourceRepository that can list its resources
+// a page at a time. Repositories that do not implement it are listed in full
+// and paged by the server.
+type PagedResourceRepository interface {
+	ResourceRepository
+
+	// ListResourcesPage returns at most limit resources whose URIs sort after
+	// the given one, ordered by URI, and whether more follow. An empty after
+	// starts at the first resource, and a limit of zero or less returns all.
+	ListResourcesPage(ctx context.Context, after string, limit int) ([]*Resource, bool, error)
+}
+
+// PagedToolRepository is a ToolRepository that can list its tools a page at a time.
+type PagedToolRepository interface {
+	ToolRepository
+
+	// ListToolsPage returns at most limit tools whose names sort after the
+	// given one, ordered by name, and whether more follow, like ListResourcesPage.
+	ListToolsPage(ctx context.Context, after string, limit int) ([]*Tool, bool, error)
+}
+
+// PagedPromptRepository is a PromptRepository that can list its prompts a page at a time.
+type PagedPromptRepository interface {
+	PromptRepository
+
+	// ListPromptsPage returns at most limit prompts whose names sort after the
+	// given one, ordered by name, and whether more follow, like ListResourcesPage.
+	ListPromptsPage(ctx context.Context, after string, limit int) ([]*Prompt, bool, error)
+}
+
 // SessionRepository defines the interface for managing client sessions.
 type SessionRepository interface {
 	// GetSession retrieves a session by its ID.